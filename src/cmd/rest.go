@@ -37,11 +37,7 @@ func restServer(_ *cobra.Command, _ []string) {
 	engine.AddFunc("isEnableBasicAuth", func(token any) bool {
 		return token != nil
 	})
-	app := fiber.New(fiber.Config{
-		Views:     engine,
-		BodyLimit: int(config.WhatsappSettingMaxVideoSize),
-		Network:   "tcp",
-	})
+	app := fiber.New(rest.ServerConfig(engine, int(config.WhatsappSettingMaxVideoSize)))
 
 	app.Static(config.AppBasePath+"/statics", "./statics")
 	app.Use(config.AppBasePath+"/components", filesystem.New(filesystem.Config{
@@ -56,13 +52,14 @@ func restServer(_ *cobra.Command, _ []string) {
 	}))
 
 	app.Use(middleware.Recovery())
+	app.Use(middleware.BodyLimit(config.WhatsappSettingMaxVideoSize))
 	app.Use(middleware.BasicAuth())
 	if config.AppDebug {
 		app.Use(logger.New())
 	}
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept",
+		AllowHeaders: "Origin, Content-Type, Accept, " + middleware.IdempotencyKeyHeader,
 	}))
 
 	if len(config.AppBasicAuthCredential) > 0 {
@@ -77,6 +74,7 @@ func restServer(_ *cobra.Command, _ []string) {
 
 		app.Use(basicauth.New(basicauth.Config{
 			Users: account,
+			Next:  middleware.SignedMediaRequest,
 		}))
 	}
 
@@ -86,6 +84,11 @@ func restServer(_ *cobra.Command, _ []string) {
 		apiGroup = app.Group(config.AppBasePath)
 	}
 
+	// Replay responses of retried send requests instead of sending twice
+	if config.AppIdempotencyTTL > 0 {
+		apiGroup.Use("/send", middleware.Idempotency(chatStorageRepo))
+	}
+
 	// Rest
 	rest.InitRestApp(apiGroup, appUsecase)
 	rest.InitRestChat(apiGroup, chatUsecase)
@@ -94,6 +97,7 @@ func restServer(_ *cobra.Command, _ []string) {
 	rest.InitRestMessage(apiGroup, messageUsecase)
 	rest.InitRestGroup(apiGroup, groupUsecase)
 	rest.InitRestNewsletter(apiGroup, newsletterUsecase)
+	rest.InitRestStatus(apiGroup, statusUsecase)
 
 	apiGroup.Get("/", func(c *fiber.Ctx) error {
 		return c.Render("views/index", fiber.Map{
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"go.mau.fi/whatsmeow/proto/waCompanionReg"
 )
 
@@ -12,14 +14,20 @@ var (
 	AppPlatform            = waCompanionReg.DeviceProps_PlatformType(1)
 	AppBasicAuthCredential []string
 	AppBasePath            = ""
+	AppIdempotencyTTL      = 24 * time.Hour   // How long responses of requests with an Idempotency-Key are replayed, 0 disables it
+	AppPublicURL           = ""               // Address this server is reached at from outside, enables signed media URLs in webhooks
+	AppMediaURLSecret      = ""               // Key signing media URLs, a key generated into PathStorages is used when empty
+	AppMediaURLTTL         = 15 * time.Minute // Default lifetime of signed media URLs
 
 	McpPort = "8080"
 	McpHost = "localhost"
 
-	PathQrCode    = "statics/qrcode"
-	PathSendItems = "statics/senditems"
-	PathMedia     = "statics/media"
-	PathStorages  = "storages"
+	PathQrCode     = "statics/qrcode"
+	PathSendItems  = "statics/senditems"
+	PathMedia      = "statics/media"
+	PathStorages   = "storages"
+	PathStatuses   = "statics/statuses"
+	PathMediaCache = "storages/media-cache"
 
 	DBURI     = "file:storages/whatsapp.db?_foreign_keys=on"
 	DBKeysURI = ""
@@ -36,8 +44,37 @@ var (
 	WhatsappTypeUser                     = "@s.whatsapp.net"
 	WhatsappTypeGroup                    = "@g.us"
 	WhatsappAccountValidation            = true
+	WhatsappStatusAutoDownload           = false            // Auto-download media of incoming status updates
+	WhatsappMediaCache                   = false            // Keep media decrypted for streaming until retention or quota removes it, not only for an hour
+	WhatsappMediaRetryTimeout            = 30 * time.Second // How long the phone gets to upload expired media again, it does not answer while offline
+	WhatsappStatusAutoMarkViewed         = false            // Auto-mark incoming status updates as viewed
+	WhatsappRevokeKeepContent            = true             // Keep the content of revoked messages in their revision history
+	WhatsappSimulateTyping               = false            // Show a typing indicator before each sent message
+	WhatsappSimulateTypingMaxDelay       = 8 * time.Second  // Upper bound of the simulated typing delay
+
+	WhatsappAutoDownload                      = true              // Auto-download media of incoming messages
+	WhatsappAutoDownloadTypes                 = []string{"image"} // Media types auto-downloaded, other types are downloaded on request
+	WhatsappAutoDownloadMaxSize      int64    = 0                 // Largest media auto-downloaded in bytes, 0 leaves only WhatsappSettingMaxDownloadSize
+	WhatsappAutoDownloadGroups                = true              // Auto-download media of group messages
+	WhatsappAutoDownloadChats        []string                     // Only auto-download media of these chats when set
+	WhatsappAutoDownloadExcludeChats []string                     // Never auto-download media of these chats
+
+	MediaStorage     = "local"     // Where downloaded media is kept, local or s3
+	MediaS3Endpoint  = ""          // S3 compatible endpoint, AWS of the region when empty
+	MediaS3Region    = "us-east-1" // Region requests to the bucket are signed for
+	MediaS3Bucket    = ""
+	MediaS3AccessKey = ""
+	MediaS3SecretKey = ""
+	MediaS3PathStyle = false // Address the bucket in the path instead of the host name, as MinIO expects
+
+	StorageRetention       []string                 // How long files are kept per directory or media type, e.g. media=720h,video=168h
+	StorageQuota           int64    = 0             // Bytes the managed directories may use before the oldest modified files are removed, 0 for no quota
+	StorageCleanupInterval          = 1 * time.Hour // How often the storage janitor runs, 0 disables it
+	StorageRemoveOrphans            = false         // Remove downloaded media no message or status refers to
 
 	ChatStorageURI               = "file:storages/chatstorage.db"
 	ChatStorageEnableForeignKeys = true
 	ChatStorageEnableWAL         = true
+	ChatStorageRawMessages       = true // Keep the protobuf of stored messages for the full message, quotes and forwards
+	ChatStorageCompressRaw       = true // Gzip stored protobufs when that makes them smaller
 )
@@ -12,6 +12,8 @@ type IAppUsecase interface {
 	Reconnect(ctx context.Context) (err error)
 	FirstDevice(ctx context.Context) (response DevicesResponse, err error)
 	FetchDevices(ctx context.Context) (response []DevicesResponse, err error)
+	StorageUsage(ctx context.Context) (response StorageUsageResponse, err error)
+	CleanupStorage(ctx context.Context) (response StorageCleanupResponse, err error)
 }
 
 type DevicesResponse struct {
@@ -24,3 +26,32 @@ type LoginResponse struct {
 	Duration  time.Duration `json:"duration"`
 	Code      string        `json:"code"`
 }
+
+type StorageUsageResponse struct {
+	Directories []StorageDirectoryUsage `json:"directories"`
+	TotalFiles  int                     `json:"total_files"`
+	TotalSize   int64                   `json:"total_size"`
+	Quota       int64                   `json:"quota"`
+	// OrphanFiles are downloaded media no message or status refers to anymore
+	OrphanFiles int                     `json:"orphan_files"`
+	OrphanSize  int64                   `json:"orphan_size"`
+	LastCleanup *StorageCleanupResponse `json:"last_cleanup,omitempty"`
+}
+
+type StorageDirectoryUsage struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	Files     int    `json:"files"`
+	Size      int64  `json:"size"`
+	Retention string `json:"retention,omitempty"`
+}
+
+type StorageCleanupResponse struct {
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	ExpiredFiles int       `json:"expired_files"`
+	OrphanFiles  int       `json:"orphan_files"`
+	EvictedFiles int       `json:"evicted_files"`
+	RemovedFiles int       `json:"removed_files"`
+	RemovedSize  int64     `json:"removed_size"`
+}
@@ -3,10 +3,14 @@ package chat
 // Request and Response structures for chat operations
 
 type ListChatsRequest struct {
-	Limit    int    `json:"limit" query:"limit"`
-	Offset   int    `json:"offset" query:"offset"`
-	Search   string `json:"search" query:"search"`
-	HasMedia bool   `json:"has_media" query:"has_media"`
+	Limit        int    `json:"limit" query:"limit"`
+	Offset       int    `json:"offset" query:"offset"`
+	Search       string `json:"search" query:"search"`
+	HasMedia     bool   `json:"has_media" query:"has_media"`
+	Archived     *bool  `json:"archived" query:"archived"`
+	Muted        *bool  `json:"muted" query:"muted"`
+	MarkedUnread *bool  `json:"marked_unread" query:"marked_unread"`
+	Pinned       *bool  `json:"pinned" query:"pinned"`
 }
 
 type ListChatsResponse struct {
@@ -23,6 +27,9 @@ type GetChatMessagesRequest struct {
 	MediaOnly bool    `json:"media_only" query:"media_only"`
 	IsFromMe  *bool   `json:"is_from_me" query:"is_from_me"`
 	Search    string  `json:"search" query:"search"`
+	Format    string  `json:"format" query:"format"` // Convert content to "markdown" or "html"
+	// IncludeRevisions adds the edit and revoke history of every message, e.g. for compliance exports
+	IncludeRevisions bool `json:"include_revisions" query:"include_revisions"`
 }
 
 type GetChatMessagesResponse struct {
@@ -31,6 +38,30 @@ type GetChatMessagesResponse struct {
 	ChatInfo   ChatInfo           `json:"chat_info"`
 }
 
+// Full-text search across all chats
+type SearchMessagesRequest struct {
+	Query     string  `json:"q" query:"q"` // Words, "phrases", prefix* and OR
+	ChatJID   string  `json:"chat_jid" query:"chat_jid"`
+	Sender    string  `json:"sender" query:"sender"`
+	MediaType string  `json:"media_type" query:"media_type"` // text, image, video, audio, document or sticker
+	StartTime *string `json:"start_time" query:"start_time"`
+	EndTime   *string `json:"end_time" query:"end_time"`
+	IsFromMe  *bool   `json:"is_from_me" query:"is_from_me"`
+	Limit     int     `json:"limit" query:"limit"`
+	Cursor    string  `json:"cursor" query:"cursor"` // next_cursor of the previous page
+}
+
+type SearchMessagesResponse struct {
+	Data       []SearchMessageResult `json:"data"`
+	NextCursor string                `json:"next_cursor"` // Empty on the last page
+}
+
+type SearchMessageResult struct {
+	MessageInfo
+	Snippet string  `json:"snippet"` // Content around the matches, wrapped in <mark></mark>
+	Score   float64 `json:"score"`   // bm25 relevance, lower is better
+}
+
 // Pin Chat operations
 type PinChatRequest struct {
 	ChatJID string `json:"chat_jid" uri:"chat_jid"`
@@ -44,13 +75,85 @@ type PinChatResponse struct {
 	Pinned  bool   `json:"pinned"`
 }
 
-type ChatInfo struct {
-	JID                 string `json:"jid"`
-	Name                string `json:"name"`
-	LastMessageTime     string `json:"last_message_time"`
+// Archive Chat operations
+type ArchiveChatRequest struct {
+	ChatJID  string `json:"chat_jid" uri:"chat_jid"`
+	Archived bool   `json:"archived"`
+}
+
+type ArchiveChatResponse struct {
+	Status   string `json:"status"`
+	Message  string `json:"message"`
+	ChatJID  string `json:"chat_jid"`
+	Archived bool   `json:"archived"`
+}
+
+// Mute Chat operations
+type MuteChatRequest struct {
+	ChatJID  string `json:"chat_jid" uri:"chat_jid"`
+	Muted    bool   `json:"muted"`
+	Duration string `json:"duration"` // 8h, 1w or forever, defaults to forever
+}
+
+type MuteChatResponse struct {
+	Status     string `json:"status"`
+	Message    string `json:"message"`
+	ChatJID    string `json:"chat_jid"`
+	Muted      bool   `json:"muted"`
+	MutedUntil string `json:"muted_until,omitempty"`
+}
+
+// Mark chat as read or unread operations
+type MarkChatAsReadRequest struct {
+	ChatJID string `json:"chat_jid" uri:"chat_jid"`
+	Read    bool   `json:"read"`
+}
+
+type MarkChatAsReadResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	ChatJID string `json:"chat_jid"`
+	Read    bool   `json:"read"`
+}
+
+// Clear and delete chat operations
+type ChatActionRequest struct {
+	ChatJID string `json:"chat_jid" uri:"chat_jid"`
+}
+
+type ChatActionResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	ChatJID string `json:"chat_jid"`
+}
+
+// Disappearing messages timer operations
+type SetDisappearingTimerRequest struct {
+	ChatJID string `json:"chat_jid" uri:"chat_jid"`
+	Timer   string `json:"timer"`
+}
+
+type SetDisappearingTimerResponse struct {
+	Status              string `json:"status"`
+	Message             string `json:"message"`
+	ChatJID             string `json:"chat_jid"`
+	Timer               string `json:"timer"`
 	EphemeralExpiration uint32 `json:"ephemeral_expiration"`
-	CreatedAt           string `json:"created_at"`
-	UpdatedAt           string `json:"updated_at"`
+}
+
+type ChatInfo struct {
+	JID                 string   `json:"jid"`
+	Name                string   `json:"name"`
+	LastMessageTime     string   `json:"last_message_time"`
+	EphemeralExpiration uint32   `json:"ephemeral_expiration"`
+	Archived            bool     `json:"archived"`
+	Muted               bool     `json:"muted"`
+	MutedUntil          string   `json:"muted_until,omitempty"` // Empty while muted means muted forever
+	MarkedUnread        bool     `json:"marked_unread"`
+	Pinned              bool     `json:"pinned"`
+	Labels              []string `json:"labels"`
+	CreatedAt           string   `json:"created_at"`
+	UpdatedAt           string   `json:"updated_at"`
 }
 
 type MessageInfo struct {
@@ -64,8 +167,35 @@ type MessageInfo struct {
 	Filename   string `json:"filename"`
 	URL        string `json:"url"`
 	FileLength uint64 `json:"file_length"`
+	MediaPath  string `json:"media_path,omitempty"` // Where the media was downloaded to, s3://bucket/key with S3 media storage
 	CreatedAt  string `json:"created_at"`
 	UpdatedAt  string `json:"updated_at"`
+	// DeliveryStatus is only set for outgoing messages
+	DeliveryStatus *MessageDeliveryStatus `json:"delivery_status,omitempty"`
+	// ReactionCounts counts the reactions per emoji, see /message/:message_id/reactions for who reacted
+	ReactionCounts map[string]int `json:"reaction_counts,omitempty"`
+	EditedAt       string         `json:"edited_at,omitempty"`
+	RevokedAt      string         `json:"revoked_at,omitempty"`
+	// Revisions is only set when requested with include_revisions
+	Revisions []MessageRevision `json:"revisions,omitempty"`
+}
+
+// MessageRevision is an edit or the revoke of a message
+type MessageRevision struct {
+	Type            string `json:"type"` // edit or revoke
+	EditorJID       string `json:"editor_jid"`
+	Content         string `json:"content"`
+	PreviousContent string `json:"previous_content"`
+	Timestamp       string `json:"timestamp"`
+}
+
+// MessageDeliveryStatus aggregates the receipts of an outgoing message over its recipients
+type MessageDeliveryStatus struct {
+	Status     string `json:"status"` // Stage reached by every recipient: sent, delivered, read or played
+	Recipients int    `json:"recipients"`
+	Delivered  int    `json:"delivered"`
+	Read       int    `json:"read"`
+	Played     int    `json:"played"`
 }
 
 type PaginationResponse struct {
@@ -8,5 +8,12 @@ import (
 type IChatUsecase interface {
 	ListChats(ctx context.Context, request ListChatsRequest) (response ListChatsResponse, err error)
 	GetChatMessages(ctx context.Context, request GetChatMessagesRequest) (response GetChatMessagesResponse, err error)
+	SearchMessages(ctx context.Context, request SearchMessagesRequest) (response SearchMessagesResponse, err error)
 	PinChat(ctx context.Context, request PinChatRequest) (response PinChatResponse, err error)
+	ArchiveChat(ctx context.Context, request ArchiveChatRequest) (response ArchiveChatResponse, err error)
+	MuteChat(ctx context.Context, request MuteChatRequest) (response MuteChatResponse, err error)
+	MarkChatAsRead(ctx context.Context, request MarkChatAsReadRequest) (response MarkChatAsReadResponse, err error)
+	ClearChat(ctx context.Context, request ChatActionRequest) (response ChatActionResponse, err error)
+	DeleteChat(ctx context.Context, request ChatActionRequest) (response ChatActionResponse, err error)
+	SetDisappearingTimer(ctx context.Context, request SetDisappearingTimerRequest) (response SetDisappearingTimerResponse, err error)
 }
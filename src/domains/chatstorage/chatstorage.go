@@ -1,15 +1,29 @@
 package chatstorage
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 // Chat represents a WhatsApp chat/conversation
 type Chat struct {
-	JID                 string    `db:"jid"`
-	Name                string    `db:"name"`
-	LastMessageTime     time.Time `db:"last_message_time"`
-	EphemeralExpiration uint32    `db:"ephemeral_expiration"`
-	CreatedAt           time.Time `db:"created_at"`
-	UpdatedAt           time.Time `db:"updated_at"`
+	JID                 string     `db:"jid"`
+	Name                string     `db:"name"`
+	LastMessageTime     time.Time  `db:"last_message_time"`
+	EphemeralExpiration uint32     `db:"ephemeral_expiration"`
+	Archived            bool       `db:"archived"`
+	Muted               bool       `db:"muted"`
+	MutedUntil          *time.Time `db:"muted_until"`   // Nil while muted means muted forever
+	MarkedUnread        bool       `db:"marked_unread"` // Marked as unread on a device, not whether it has unread messages
+	Pinned              bool       `db:"pinned"`
+	Labels              []string   `db:"-"` // Names of the labels attached to the chat
+	CreatedAt           time.Time  `db:"created_at"`
+	UpdatedAt           time.Time  `db:"updated_at"`
+}
+
+// IsMuted reports whether notifications of the chat are muted at the given time
+func (chat *Chat) IsMuted(now time.Time) bool {
+	return chat.Muted && (chat.MutedUntil == nil || chat.MutedUntil.After(now))
 }
 
 // Message represents a WhatsApp message
@@ -23,10 +37,14 @@ type Message struct {
 	MediaType     string    `db:"media_type"`
 	Filename      string    `db:"filename"`
 	URL           string    `db:"url"`
+	DirectPath    string    `db:"direct_path"` // Path of the media on any WhatsApp media host, kept when the URL expires
 	MediaKey      []byte    `db:"media_key"`
 	FileSHA256    []byte    `db:"file_sha256"`
 	FileEncSHA256 []byte    `db:"file_enc_sha256"`
 	FileLength    uint64    `db:"file_length"`
+	MediaPath     string    `db:"media_path"`
+	RawMessage    []byte    `db:"raw_message"` // Protobuf of the message as received, only read by GetFullMessage
+	RawInfo       string    `db:"raw_info"`    // JSON of the info WhatsApp sent with the message
 	CreatedAt     time.Time `db:"created_at"`
 	UpdatedAt     time.Time `db:"updated_at"`
 }
@@ -55,10 +73,162 @@ type MessageFilter struct {
 	IsFromMe  *bool
 }
 
+// ErrFullTextSearchUnavailable is returned by message search when SQLite was built without FTS5
+var ErrFullTextSearchUnavailable = errors.New("full-text search is not available, build with -tags sqlite_fts5")
+
+// MessageSearchFilter represents a full-text search over the messages of all chats
+type MessageSearchFilter struct {
+	Query     string // FTS5 match expression
+	ChatJID   string
+	Sender    string
+	MediaType string // "text" matches messages without media
+	StartTime *time.Time
+	EndTime   *time.Time
+	IsFromMe  *bool
+	Limit     int
+	After     *MessageSearchCursor // Position of the last result of the previous page
+}
+
+// MessageSearchCursor is the keyset position of a search result, results are ordered by score then row.
+// The next page starts after it whatever was stored or deleted meanwhile.
+type MessageSearchCursor struct {
+	Score float64
+	RowID int64
+}
+
+// MessageSearchResult is a message matching a search with its highlighted snippet
+type MessageSearchResult struct {
+	Message *Message
+	Snippet string
+	Score   float64 // Lower is a better match
+	Cursor  MessageSearchCursor
+}
+
 // ChatFilter represents query filters for chats
 type ChatFilter struct {
-	Limit      int
-	Offset     int
-	SearchName string
-	HasMedia   bool
+	Limit        int
+	Offset       int
+	SearchName   string
+	HasMedia     bool
+	Archived     *bool
+	Muted        *bool
+	MarkedUnread *bool
+	Pinned       *bool
+}
+
+// Contact is an address book entry synced from the phone
+type Contact struct {
+	JID       string    `db:"jid"`
+	FullName  string    `db:"full_name"`
+	FirstName string    `db:"first_name"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// Label is a chat label of a business account synced from the phone
+type Label struct {
+	ID        string    `db:"id"`
+	Name      string    `db:"name"`
+	Color     int32     `db:"color"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// Status represents a status update posted by a contact to status@broadcast
+type Status struct {
+	ID            string    `db:"id"`
+	Sender        string    `db:"sender"`
+	PushName      string    `db:"push_name"`
+	Content       string    `db:"content"`
+	MediaType     string    `db:"media_type"`
+	MimeType      string    `db:"mime_type"`
+	Filename      string    `db:"filename"`
+	URL           string    `db:"url"`
+	MediaKey      []byte    `db:"media_key"`
+	FileSHA256    []byte    `db:"file_sha256"`
+	FileEncSHA256 []byte    `db:"file_enc_sha256"`
+	FileLength    uint64    `db:"file_length"`
+	MediaPath     string    `db:"media_path"` // Local path when the media was auto-downloaded
+	Viewed        bool      `db:"viewed"`
+	Timestamp     time.Time `db:"timestamp"`
+	ExpiresAt     time.Time `db:"expires_at"`
+	CreatedAt     time.Time `db:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at"`
+}
+
+// StatusFilter represents query filters for status updates
+type StatusFilter struct {
+	Senders        []string // JIDs of one account, its phone number and LID, matching any of its devices
+	MediaOnly      bool
+	IncludeExpired bool
+	Limit          int
+	Offset         int
+}
+
+// IdempotencyRecord is the stored response of a request sent with an idempotency key.
+// A record without a status code is a request still running, on this instance or on another one sharing the storage.
+type IdempotencyRecord struct {
+	Key          string    `db:"idempotency_key"`
+	RequestHash  string    `db:"request_hash"` // Fingerprint of the request, a reused key with another body is rejected
+	StatusCode   int       `db:"status_code"`  // 0 while the request is running
+	ResponseBody []byte    `db:"response_body"`
+	CreatedAt    time.Time `db:"created_at"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+// InFlight reports a record reserved by a request that has not completed yet
+func (record *IdempotencyRecord) InFlight() bool {
+	return record.StatusCode == 0
+}
+
+// Receipt types stored per recipient for outgoing messages
+const (
+	ReceiptDelivered = "delivered"
+	ReceiptRead      = "read"
+	ReceiptPlayed    = "played"
+)
+
+// Reaction is the emoji a user reacted to a message with, a user has at most one reaction per message
+type Reaction struct {
+	MessageID  string    `db:"message_id"`
+	ChatJID    string    `db:"chat_jid"`
+	ReactorJID string    `db:"reactor_jid"` // Without device, reactions from any device of a user replace each other
+	Emoji      string    `db:"emoji"`
+	Timestamp  time.Time `db:"timestamp"`
+	CreatedAt  time.Time `db:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at"`
+}
+
+// Revision types of a message
+const (
+	RevisionEdit   = "edit"
+	RevisionRevoke = "revoke"
+)
+
+// MessageRevision records an edit of a message or its revoke. A revoke is a tombstone keeping
+// the content the message had, unless content of revoked messages is not retained
+type MessageRevision struct {
+	ID              int64     `db:"id"`
+	MessageID       string    `db:"message_id"`
+	ChatJID         string    `db:"chat_jid"`
+	Type            string    `db:"revision_type"`
+	EditorJID       string    `db:"editor_jid"`       // User who edited or revoked the message, without device
+	Content         string    `db:"content"`          // Content after an edit, empty for revokes
+	PreviousContent string    `db:"previous_content"` // Content the stored message had when the revision was recorded
+	Timestamp       time.Time `db:"timestamp"`
+	CreatedAt       time.Time `db:"created_at"`
+	RawMessage      []byte    `db:"-"` // Protobuf of an edited message, replaces the raw message of the stored message
+}
+
+// MessageReceipt tracks how far an outgoing message got for a single recipient,
+// group messages have one receipt per member that acknowledged it
+type MessageReceipt struct {
+	MessageID    string     `db:"message_id"`
+	ChatJID      string     `db:"chat_jid"`
+	RecipientJID string     `db:"recipient_jid"`
+	DeliveredAt  *time.Time `db:"delivered_at"`
+	ReadAt       *time.Time `db:"read_at"`
+	PlayedAt     *time.Time `db:"played_at"`
+	CreatedAt    time.Time  `db:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at"`
 }
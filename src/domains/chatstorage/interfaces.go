@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
 )
@@ -14,6 +15,13 @@ type IChatStorageRepository interface {
 	StoreChat(chat *Chat) error
 	GetChat(jid string) (*Chat, error)
 	GetChats(filter *ChatFilter) ([]*Chat, error)
+	SetChatEphemeralExpiration(jid string, expiration uint32, changedAt time.Time) error
+	SetChatArchived(jid string, archived bool) error
+	SetChatMuted(jid string, muted bool, mutedUntil *time.Time) error
+	SetChatMarkedUnread(jid string, markedUnread bool) error
+	SetChatPinned(jid string, pinned bool) error
+	SetChatLabel(chatJID, labelID string, labeled bool) error
+	ClearChatMessages(jid string, until time.Time) error
 	DeleteChat(jid string) error
 
 	// Message operations
@@ -21,9 +29,46 @@ type IChatStorageRepository interface {
 	StoreMessagesBatch(messages []*Message) error
 	GetMessageByID(id string) (*Message, error) // New method for efficient ID-only search
 	GetMessages(filter *MessageFilter) ([]*Message, error)
-	SearchMessages(chatJID, searchText string, limit int) ([]*Message, error) // Database-level search
+	SearchMessages(chatJID, searchText string, limit int) ([]*Message, error)      // Database-level search
+	SearchAllMessages(filter *MessageSearchFilter) ([]*MessageSearchResult, error) // Full-text search across chats
+	GetFullMessage(id, chatJID string) (*Message, error)                           // With the raw message, chatJID may be empty
 	DeleteMessage(id, chatJID string) error
-	StoreSentMessageWithContext(ctx context.Context, messageID string, senderJID string, recipientJID string, content string, timestamp time.Time) error
+	UpdateMessageDirectPath(id, chatJID, directPath string) error
+	UpdateMessageMediaPath(id, chatJID, mediaPath string) error
+	GetMediaPaths() ([]string, error)
+	ClearMediaPath(mediaPath string) error
+	StoreSentMessageWithContext(ctx context.Context, messageID string, senderJID string, recipientJID string, content string, timestamp time.Time, message *waE2E.Message) error
+
+	// Status operations
+	StoreStatus(status *Status) error
+	GetStatuses(filter *StatusFilter) ([]*Status, error)
+	GetStatusCount(filter *StatusFilter) (int64, error)
+	DeleteStatus(id, sender string) error
+
+	// Receipt operations
+	StoreMessageReceipts(chatJID, recipientJID string, messageIDs []string, receiptType string, timestamp time.Time) error
+	GetMessageReceipts(chatJID string, messageIDs []string) ([]*MessageReceipt, error)
+
+	// Reaction operations
+	StoreReaction(reaction *Reaction) error
+	GetMessageReactions(chatJID string, messageIDs []string) ([]*Reaction, error)
+
+	// Revision operations
+	StoreMessageEdit(revision *MessageRevision) error
+	StoreMessageRevoke(revision *MessageRevision, keepContent bool) error
+	GetMessageRevisions(chatJID string, messageIDs []string) ([]*MessageRevision, error)
+
+	// Contact and label operations
+	StoreContact(contact *Contact) error
+	GetContact(jid string) (*Contact, error)
+	StoreLabel(label *Label) error
+	DeleteLabel(id string) error
+
+	// Idempotency operations
+	GetIdempotencyRecord(key string) (*IdempotencyRecord, error)
+	ReserveIdempotencyKey(record *IdempotencyRecord) (bool, error) // False when the key is already taken
+	StoreIdempotencyRecord(record *IdempotencyRecord) error
+	ReleaseIdempotencyKey(key string) error // Frees a key whose request did not complete
 
 	// Statistics
 	GetChatMessageCount(chatJID string) (int64, error)
@@ -0,0 +1,17 @@
+package mediastore
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// IMediaStore keeps downloaded media on local disk or in an S3 compatible bucket, so replicas can share it
+type IMediaStore interface {
+	Put(ctx context.Context, key string, content io.Reader, size int64, mimeType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (*Object, error)
+	Delete(ctx context.Context, key string) error
+	// URL returns a presigned URL reading the media until expiresIn, empty for stores without direct access
+	URL(ctx context.Context, key string, expiresIn time.Duration) (string, error)
+}
@@ -0,0 +1,18 @@
+package mediastore
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned for keys that are not in the store
+var ErrNotFound = errors.New("media not found in store")
+
+// Object is stored media
+type Object struct {
+	Key      string
+	Size     int64
+	MimeType string
+	Location string    // Local path, or s3://bucket/key for buckets
+	ModTime  time.Time // Last time the media was written
+}
@@ -10,6 +10,7 @@ type IMessageActions interface {
 	ReactMessage(ctx context.Context, request ReactionRequest) (response GenericResponse, err error)
 	RevokeMessage(ctx context.Context, request RevokeRequest) (response GenericResponse, err error)
 	UpdateMessage(ctx context.Context, request UpdateMessageRequest) (response GenericResponse, err error)
+	ForwardMessage(ctx context.Context, request ForwardMessageRequest) (response GenericResponse, err error)
 }
 
 // IMessageManagement handles message management operations
@@ -17,6 +18,12 @@ type IMessageManagement interface {
 	DeleteMessage(ctx context.Context, request DeleteRequest) (err error)
 	StarMessage(ctx context.Context, request StarRequest) (err error)
 	DownloadMedia(ctx context.Context, request DownloadMediaRequest) (response DownloadMediaResponse, err error)
+	StreamMedia(ctx context.Context, request StreamMediaRequest) (response StreamMediaResponse, err error)
+	GetMediaURL(ctx context.Context, request MediaURLRequest) (response MediaURLResponse, err error)
+	GetMessageStatus(ctx context.Context, request MessageStatusRequest) (response MessageStatusResponse, err error)
+	GetMessageReactions(ctx context.Context, request MessageReactionsRequest) (response MessageReactionsResponse, err error)
+	GetMessageRevisions(ctx context.Context, request MessageRevisionsRequest) (response MessageRevisionsResponse, err error)
+	GetFullMessage(ctx context.Context, request FullMessageRequest) (response FullMessageResponse, err error)
 }
 
 // IMessageUsecase combines all message interfaces
@@ -1,5 +1,10 @@
 package message
 
+import (
+	"encoding/json"
+	"io"
+)
+
 type GenericResponse struct {
 	MessageID string `json:"message_id"`
 	Status    string `json:"status"`
@@ -49,5 +54,115 @@ type DownloadMediaResponse struct {
 	MediaType string `json:"media_type"`
 	Filename  string `json:"filename"`
 	FilePath  string `json:"file_path"`
+	MediaURL  string `json:"media_url,omitempty"` // Presigned URL when media is kept in a bucket
 	FileSize  int64  `json:"file_size"`
 }
+
+type StreamMediaRequest struct {
+	MessageID string `json:"message_id" uri:"message_id"`
+	Phone     string `json:"phone" form:"phone"`
+}
+
+// StreamMediaResponse is decrypted media ready to be sent to the client, the caller must close Content
+type StreamMediaResponse struct {
+	MessageID string
+	MediaType string
+	MimeType  string
+	Filename  string
+	FileSize  int64
+	Content   io.ReadSeekCloser
+}
+
+type MediaURLRequest struct {
+	MessageID string `json:"message_id" uri:"message_id"`
+	Phone     string `json:"phone" form:"phone"`
+	ExpiresIn int    `json:"expires_in" form:"expires_in"` // Seconds the URL stays valid, 0 uses the configured default
+}
+
+type MediaURLResponse struct {
+	MessageID string `json:"message_id"`
+	URL       string `json:"url"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+type MessageStatusRequest struct {
+	MessageID string `json:"message_id" uri:"message_id"`
+	Phone     string `json:"phone" form:"phone"` // Optional, restricts the lookup to this chat
+}
+
+type MessageStatusResponse struct {
+	MessageID   string            `json:"message_id"`
+	ChatJID     string            `json:"chat_jid"`
+	Status      string            `json:"status"` // Stage reached by every recipient: sent, delivered, read or played
+	DeliveredAt string            `json:"delivered_at,omitempty"`
+	ReadAt      string            `json:"read_at,omitempty"`
+	PlayedAt    string            `json:"played_at,omitempty"`
+	Recipients  []RecipientStatus `json:"recipients"`
+}
+
+type MessageReactionsRequest struct {
+	MessageID string `json:"message_id" uri:"message_id"`
+	Phone     string `json:"phone" form:"phone"` // Optional, restricts the lookup to this chat
+}
+
+type MessageReactionsResponse struct {
+	MessageID      string            `json:"message_id"`
+	ChatJID        string            `json:"chat_jid"`
+	ReactionCounts map[string]int    `json:"reaction_counts"`
+	Reactions      []MessageReaction `json:"reactions"`
+}
+
+type MessageReaction struct {
+	ReactorJID string `json:"reactor_jid"`
+	Emoji      string `json:"emoji"`
+	Timestamp  string `json:"timestamp"`
+}
+
+type MessageRevisionsRequest struct {
+	MessageID string `json:"message_id" uri:"message_id"`
+	Phone     string `json:"phone" form:"phone"` // Optional, restricts the lookup to this chat
+}
+
+type MessageRevisionsResponse struct {
+	MessageID string            `json:"message_id"`
+	ChatJID   string            `json:"chat_jid"`
+	Revisions []MessageRevision `json:"revisions"`
+}
+
+type MessageRevision struct {
+	Type            string `json:"type"` // edit or revoke
+	EditorJID       string `json:"editor_jid"`
+	Content         string `json:"content"`
+	PreviousContent string `json:"previous_content"`
+	Timestamp       string `json:"timestamp"`
+}
+
+type ForwardMessageRequest struct {
+	MessageID string `json:"message_id" uri:"message_id"`
+	Phone     string `json:"phone" form:"phone"` // Chat the message is forwarded to
+}
+
+type FullMessageRequest struct {
+	MessageID string `json:"message_id" uri:"message_id"`
+	Phone     string `json:"phone" form:"phone"` // Optional, restricts the lookup to this chat
+}
+
+type FullMessageResponse struct {
+	MessageID string          `json:"message_id"`
+	ChatJID   string          `json:"chat_jid"`
+	SenderJID string          `json:"sender_jid"`
+	Content   string          `json:"content"`
+	Timestamp string          `json:"timestamp"`
+	IsFromMe  bool            `json:"is_from_me"`
+	MediaType string          `json:"media_type,omitempty"`
+	Info      json.RawMessage `json:"info,omitempty"`    // Info WhatsApp sent with the message
+	Message   json.RawMessage `json:"message,omitempty"` // The message proto rendered as JSON, absent when it was not kept
+}
+
+type RecipientStatus struct {
+	RecipientJID string `json:"recipient_jid"`
+	Status       string `json:"status"`
+	DeliveredAt  string `json:"delivered_at,omitempty"`
+	ReadAt       string `json:"read_at,omitempty"`
+	PlayedAt     string `json:"played_at,omitempty"`
+}
@@ -1,11 +1,129 @@
 package newsletter
 
-import "context"
+import (
+	"context"
+	"mime/multipart"
+
+	"go.mau.fi/whatsmeow/types"
+)
 
 type INewsletterUsecase interface {
 	Unfollow(ctx context.Context, request UnfollowRequest) (err error)
+	SendMessage(ctx context.Context, request SendMessageRequest) (response SendMessageResponse, err error)
+	ListMessages(ctx context.Context, request ListMessagesRequest) (response ListMessagesResponse, err error)
+	Create(ctx context.Context, request CreateRequest) (response InfoResponse, err error)
+	Update(ctx context.Context, request UpdateRequest) (response InfoResponse, err error)
+	Follow(ctx context.Context, request FollowRequest) (response InfoResponse, err error)
+	Mute(ctx context.Context, request MuteRequest) (err error)
+	InfoFromInvite(ctx context.Context, request InfoFromInviteRequest) (response InfoResponse, err error)
+	ListUpdates(ctx context.Context, request ListUpdatesRequest) (response ListMessagesResponse, err error)
+	React(ctx context.Context, request ReactRequest) (err error)
+	MarkViewed(ctx context.Context, request MarkViewedRequest) (err error)
 }
 
 type UnfollowRequest struct {
 	NewsletterID string `json:"newsletter_id" form:"newsletter_id"`
 }
+
+// SendMessageType is the kind of content published to a newsletter
+type SendMessageType string
+
+const (
+	SendMessageTypeText  SendMessageType = "text"
+	SendMessageTypeImage SendMessageType = "image"
+	SendMessageTypeVideo SendMessageType = "video"
+	SendMessageTypePoll  SendMessageType = "poll"
+)
+
+type SendMessageRequest struct {
+	NewsletterID string                `json:"newsletter_id" uri:"newsletter_id"`
+	Type         SendMessageType       `json:"type" form:"type"`
+	Message      string                `json:"message" form:"message"`
+	Caption      string                `json:"caption" form:"caption"`
+	Image        *multipart.FileHeader `json:"image" form:"image"`
+	ImageURL     *string               `json:"image_url" form:"image_url"`
+	Video        *multipart.FileHeader `json:"video" form:"video"`
+	VideoURL     *string               `json:"video_url" form:"video_url"`
+	Question     string                `json:"question" form:"question"`
+	Options      []string              `json:"options" form:"options"`
+	MaxAnswer    int                   `json:"max_answer" form:"max_answer"`
+}
+
+type SendMessageResponse struct {
+	MessageID string `json:"message_id"`
+	ServerID  int    `json:"server_id"`
+	Status    string `json:"status"`
+}
+
+type ListMessagesRequest struct {
+	NewsletterID string `json:"newsletter_id" uri:"newsletter_id"`
+	Count        int    `json:"count" query:"count"`
+	Before       int    `json:"before" query:"before"`
+}
+
+type ListMessagesResponse struct {
+	Data []MessageInfo `json:"data"`
+}
+
+type MessageInfo struct {
+	ServerID       int            `json:"server_id"`
+	MessageID      string         `json:"message_id"`
+	Type           string         `json:"type"`
+	Content        string         `json:"content"`
+	MediaType      string         `json:"media_type"`
+	Timestamp      string         `json:"timestamp"`
+	ViewsCount     int            `json:"views_count"`
+	ReactionCounts map[string]int `json:"reaction_counts"`
+}
+
+type CreateRequest struct {
+	Name        string                `json:"name" form:"name"`
+	Description string                `json:"description" form:"description"`
+	Picture     *multipart.FileHeader `json:"picture" form:"picture"`
+}
+
+// UpdateRequest changes only the fields that are provided, leave a field empty to keep it as is
+type UpdateRequest struct {
+	NewsletterID string                `json:"newsletter_id" uri:"newsletter_id"`
+	Name         string                `json:"name" form:"name"`
+	Description  string                `json:"description" form:"description"`
+	Picture      *multipart.FileHeader `json:"picture" form:"picture"`
+}
+
+// FollowRequest identifies the newsletter either by its JID or by an invite link / code
+type FollowRequest struct {
+	NewsletterID string `json:"newsletter_id" form:"newsletter_id"`
+	InviteLink   string `json:"invite_link" form:"invite_link"`
+}
+
+type MuteRequest struct {
+	NewsletterID string `json:"newsletter_id" uri:"newsletter_id"`
+	Mute         bool   `json:"mute" form:"mute"`
+}
+
+type InfoFromInviteRequest struct {
+	InviteLink string `json:"invite_link" query:"invite_link"`
+}
+
+type InfoResponse struct {
+	Data *types.NewsletterMetadata `json:"data"`
+}
+
+type ListUpdatesRequest struct {
+	NewsletterID string `json:"newsletter_id" uri:"newsletter_id"`
+	Count        int    `json:"count" query:"count"`
+	Since        string `json:"since" query:"since"`
+	After        int    `json:"after" query:"after"`
+}
+
+// ReactRequest sends a reaction to a newsletter post, an empty reaction removes the previous one
+type ReactRequest struct {
+	NewsletterID string `json:"newsletter_id" uri:"newsletter_id"`
+	ServerID     int    `json:"server_id" uri:"server_id"`
+	Reaction     string `json:"reaction" form:"reaction"`
+}
+
+type MarkViewedRequest struct {
+	NewsletterID string `json:"newsletter_id" uri:"newsletter_id"`
+	ServerIDs    []int  `json:"server_ids" form:"server_ids"`
+}
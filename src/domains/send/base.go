@@ -4,4 +4,11 @@ type BaseRequest struct {
 	Phone       string `json:"phone" form:"phone"`
 	Duration    *int   `json:"duration,omitempty" form:"duration"`
 	IsForwarded bool   `json:"is_forwarded,omitempty" form:"is_forwarded"`
+	// IdempotencyKey makes retries replay the first response instead of sending again,
+	// it may also be passed as the Idempotency-Key header
+	IdempotencyKey string `json:"idempotency_key,omitempty" form:"idempotency_key"`
+	// DryRun runs the whole pipeline but stops before uploading and sending
+	DryRun bool `json:"dry_run,omitempty" form:"dry_run"`
+	// SimulateTyping shows a typing indicator before sending, defaults to the --simulate-typing setting
+	SimulateTyping *bool `json:"simulate_typing,omitempty" form:"simulate_typing"`
 }
@@ -6,4 +6,5 @@ type FileRequest struct {
 	BaseRequest
 	File    *multipart.FileHeader `json:"file" form:"file"`
 	Caption string                `json:"caption" form:"caption"`
+	Format  string                `json:"format,omitempty" form:"format"` // Format of the caption
 }
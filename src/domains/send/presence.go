@@ -3,4 +3,5 @@ package send
 type PresenceRequest struct {
 	Type        string `json:"type" form:"type"`
 	IsForwarded bool   `json:"is_forwarded" form:"is_forwarded"`
+	DryRun      bool   `json:"dry_run,omitempty" form:"dry_run"`
 }
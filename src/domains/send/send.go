@@ -1,6 +1,37 @@
 package send
 
+import "encoding/json"
+
 type GenericResponse struct {
-	MessageID string `json:"message_id"`
-	Status    string `json:"status"`
+	MessageID string                  `json:"message_id"`
+	Status    string                  `json:"status"`
+	DryRun    *DryRunResponse         `json:"dry_run,omitempty"`
+	Transcode *VideoTranscodeResponse `json:"transcode,omitempty"`
+}
+
+// VideoTranscodeResponse reports how a video was prepared before sending
+type VideoTranscodeResponse struct {
+	Transcoded       bool     `json:"transcoded"`
+	Remuxed          bool     `json:"remuxed"`           // Streams were copied into a new MP4 without re-encoding
+	Reasons          []string `json:"reasons,omitempty"` // Why the video had to be transcoded
+	SourceFormat     string   `json:"source_format"`
+	SourceVideoCodec string   `json:"source_video_codec"`
+	SourceAudioCodec string   `json:"source_audio_codec,omitempty"`
+	OriginalSize     int64    `json:"original_size"`
+	FinalSize        int64    `json:"final_size"`
+	VideoBitrate     int64    `json:"video_bitrate,omitempty"` // Bitrate cap applied to fit the size limit, in bits per second
+	Duration         uint32   `json:"duration"`                // Seconds
+	Width            int      `json:"width"`
+	Height           int      `json:"height"`
+}
+
+// DryRunResponse describes the message a dry run would have sent
+type DryRunResponse struct {
+	RecipientJID  string          `json:"recipient_jid"`
+	MimeType      string          `json:"mime_type,omitempty"`
+	OriginalSize  int             `json:"original_size,omitempty"` // Size of the media as received or downloaded
+	FinalSize     int             `json:"final_size,omitempty"`    // Size after conversion and compression
+	ThumbnailSize int             `json:"thumbnail_size,omitempty"`
+	PayloadSize   int             `json:"payload_size"` // Size of the encoded message proto
+	Message       json.RawMessage `json:"message"`      // The message proto rendered as JSON
 }
@@ -4,4 +4,6 @@ type MessageRequest struct {
 	BaseRequest
 	Message        string  `json:"message" form:"message"`
 	ReplyMessageID *string `json:"reply_message_id" form:"reply_message_id"`
+	// Format of the message, "markdown" converts it to WhatsApp formatting before sending
+	Format string `json:"format,omitempty" form:"format"`
 }
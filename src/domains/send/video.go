@@ -9,4 +9,7 @@ type VideoRequest struct {
 	ViewOnce bool                  `json:"view_once" form:"view_once"`
 	Compress bool                  `json:"compress"`
 	VideoURL *string               `json:"video_url" form:"video_url"`
+	Format   string                `json:"format,omitempty" form:"format"` // Format of the caption
+	// GifPlayback sends the video as a looping silent GIF, GIF files are converted to MP4
+	GifPlayback bool `json:"gif_playback" form:"gif_playback"`
 }
@@ -0,0 +1,10 @@
+package status
+
+import (
+	"context"
+)
+
+// IStatusUsecase defines the interface for status update operations
+type IStatusUsecase interface {
+	GetFeed(ctx context.Context, request FeedRequest) (response FeedResponse, err error)
+}
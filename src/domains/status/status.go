@@ -0,0 +1,38 @@
+package status
+
+// Request and Response structures for status operations
+
+type FeedRequest struct {
+	Sender         string `json:"sender" query:"sender"`
+	MediaOnly      bool   `json:"media_only" query:"media_only"`
+	IncludeExpired bool   `json:"include_expired" query:"include_expired"`
+	Limit          int    `json:"limit" query:"limit"`
+	Offset         int    `json:"offset" query:"offset"`
+}
+
+type FeedResponse struct {
+	Data       []StatusInfo       `json:"data"`
+	Pagination PaginationResponse `json:"pagination"`
+}
+
+type StatusInfo struct {
+	ID         string `json:"id"`
+	SenderJID  string `json:"sender_jid"`
+	PushName   string `json:"push_name"`
+	Content    string `json:"content"`
+	MediaType  string `json:"media_type"`
+	MimeType   string `json:"mime_type"`
+	Filename   string `json:"filename"`
+	URL        string `json:"url"`
+	FileLength uint64 `json:"file_length"`
+	MediaPath  string `json:"media_path"`
+	Viewed     bool   `json:"viewed"`
+	Timestamp  string `json:"timestamp"`
+	ExpiresAt  string `json:"expires_at"`
+}
+
+type PaginationResponse struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Total  int `json:"total"`
+}
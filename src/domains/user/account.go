@@ -74,6 +74,10 @@ type ChangePushNameRequest struct {
 	PushName string `json:"push_name" form:"push_name"`
 }
 
+type SetDefaultDisappearingTimerRequest struct {
+	Timer string `json:"timer" form:"timer"`
+}
+
 type CheckRequest struct {
 	Phone string `json:"phone" query:"phone"`
 }
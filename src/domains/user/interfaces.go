@@ -28,6 +28,7 @@ type IUserListing interface {
 // IUserPrivacy handles user privacy operations
 type IUserPrivacy interface {
 	MyPrivacySetting(ctx context.Context) (response MyPrivacySettingResponse, err error)
+	SetDefaultDisappearingTimer(ctx context.Context, request SetDefaultDisappearingTimerRequest) (err error)
 }
 
 // IUserUsecase combines all user interfaces for backward compatibility
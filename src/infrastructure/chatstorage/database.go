@@ -0,0 +1,87 @@
+package chatstorage
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// database runs the statements of the repository, which are written with ? placeholders.
+// PostgreSQL numbers its placeholders, they are rewritten to $1, $2... before running.
+type database struct {
+	db       *sql.DB
+	postgres bool
+}
+
+func (d *database) Exec(query string, args ...any) (sql.Result, error) {
+	return d.db.Exec(d.rebind(query), args...)
+}
+
+func (d *database) Query(query string, args ...any) (*sql.Rows, error) {
+	return d.db.Query(d.rebind(query), args...)
+}
+
+func (d *database) QueryRow(query string, args ...any) *sql.Row {
+	return d.db.QueryRow(d.rebind(query), args...)
+}
+
+func (d *database) Begin() (*transaction, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{tx: tx, db: d}, nil
+}
+
+// rebind numbers the placeholders of a query for PostgreSQL, question marks in string literals are kept
+func (d *database) rebind(query string) string {
+	if !d.postgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var builder strings.Builder
+	builder.Grow(len(query) + 16)
+	placeholder, quoted := 0, false
+	for _, char := range query {
+		switch {
+		case char == '\'':
+			quoted = !quoted
+		case char == '?' && !quoted:
+			placeholder++
+			builder.WriteString("$" + strconv.Itoa(placeholder))
+			continue
+		}
+		builder.WriteRune(char)
+	}
+	return builder.String()
+}
+
+// transaction is a database transaction whose statements are rebound like those of database
+type transaction struct {
+	tx *sql.Tx
+	db *database
+}
+
+func (t *transaction) Exec(query string, args ...any) (sql.Result, error) {
+	return t.tx.Exec(t.db.rebind(query), args...)
+}
+
+func (t *transaction) Query(query string, args ...any) (*sql.Rows, error) {
+	return t.tx.Query(t.db.rebind(query), args...)
+}
+
+func (t *transaction) QueryRow(query string, args ...any) *sql.Row {
+	return t.tx.QueryRow(t.db.rebind(query), args...)
+}
+
+func (t *transaction) Prepare(query string) (*sql.Stmt, error) {
+	return t.tx.Prepare(t.db.rebind(query))
+}
+
+func (t *transaction) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *transaction) Rollback() error {
+	return t.tx.Rollback()
+}
@@ -0,0 +1,213 @@
+package chatstorage
+
+// messageSearchVector is the text search document of a message, it must match the expression of the index
+const messageSearchVector = "to_tsvector('simple', COALESCE(m.content, ''))"
+
+// postgresMigrations create the schema of the SQLite migrations in PostgreSQL, version by version
+var postgresMigrations = []string{
+	// Migration 1: Initial schema with only chats and messages tables
+	`
+	CREATE TABLE IF NOT EXISTS chats (
+		jid TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		last_message_time TIMESTAMPTZ NOT NULL,
+		ephemeral_expiration BIGINT DEFAULT 0,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS messages (
+		id TEXT NOT NULL,
+		chat_jid TEXT NOT NULL,
+		sender TEXT NOT NULL,
+		content TEXT,
+		timestamp TIMESTAMPTZ NOT NULL,
+		is_from_me BOOLEAN DEFAULT FALSE,
+		media_type TEXT,
+		filename TEXT,
+		url TEXT,
+		media_key BYTEA,
+		file_sha256 BYTEA,
+		file_enc_sha256 BYTEA,
+		file_length BIGINT DEFAULT 0,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (id, chat_jid),
+		FOREIGN KEY (chat_jid) REFERENCES chats(jid) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_messages_chat_jid ON messages(chat_jid);
+	CREATE INDEX IF NOT EXISTS idx_messages_timestamp ON messages(timestamp);
+	CREATE INDEX IF NOT EXISTS idx_messages_media_type ON messages(media_type);
+	CREATE INDEX IF NOT EXISTS idx_messages_sender ON messages(sender);
+	CREATE INDEX IF NOT EXISTS idx_chats_last_message ON chats(last_message_time);
+	CREATE INDEX IF NOT EXISTS idx_chats_name ON chats(name);
+	`,
+
+	// Migration 2: Add index for message ID lookups (performance optimization)
+	`
+	CREATE INDEX IF NOT EXISTS idx_messages_id ON messages(id);
+	`,
+
+	// Migration 3: Status updates received from status@broadcast
+	`
+	CREATE TABLE IF NOT EXISTS statuses (
+		id TEXT NOT NULL,
+		sender TEXT NOT NULL,
+		push_name TEXT DEFAULT '',
+		content TEXT DEFAULT '',
+		media_type TEXT DEFAULT '',
+		mime_type TEXT DEFAULT '',
+		filename TEXT DEFAULT '',
+		url TEXT DEFAULT '',
+		media_key BYTEA,
+		file_sha256 BYTEA,
+		file_enc_sha256 BYTEA,
+		file_length BIGINT DEFAULT 0,
+		media_path TEXT DEFAULT '',
+		viewed BOOLEAN DEFAULT FALSE,
+		timestamp TIMESTAMPTZ NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (id, sender)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_statuses_sender ON statuses(sender);
+	CREATE INDEX IF NOT EXISTS idx_statuses_timestamp ON statuses(timestamp);
+	CREATE INDEX IF NOT EXISTS idx_statuses_expires_at ON statuses(expires_at);
+	`,
+
+	// Migration 4: Responses of send requests made with an idempotency key
+	`
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+		idempotency_key TEXT PRIMARY KEY,
+		request_hash TEXT NOT NULL,
+		status_code INTEGER NOT NULL,
+		response_body BYTEA,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMPTZ NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+	`,
+
+	// Migration 5: Delivery, read and played receipts of outgoing messages per recipient
+	`
+	CREATE TABLE IF NOT EXISTS message_receipts (
+		message_id TEXT NOT NULL,
+		chat_jid TEXT NOT NULL,
+		recipient_jid TEXT NOT NULL,
+		delivered_at TIMESTAMPTZ,
+		read_at TIMESTAMPTZ,
+		played_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (message_id, chat_jid, recipient_jid)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_message_receipts_chat_jid ON message_receipts(chat_jid);
+	`,
+
+	// Migration 6: Full-text search index over message content, row_id orders results with equal rank
+	`
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS row_id BIGSERIAL;
+
+	CREATE INDEX IF NOT EXISTS idx_messages_search ON messages USING GIN (to_tsvector('simple', COALESCE(content, '')));
+	`,
+
+	// Migration 7: Archive, mute and unread state of chats mirrored from app state
+	`
+	ALTER TABLE chats ADD COLUMN IF NOT EXISTS archived BOOLEAN DEFAULT FALSE;
+	ALTER TABLE chats ADD COLUMN IF NOT EXISTS muted BOOLEAN DEFAULT FALSE;
+	ALTER TABLE chats ADD COLUMN IF NOT EXISTS muted_until TIMESTAMPTZ;
+	ALTER TABLE chats ADD COLUMN IF NOT EXISTS unread BOOLEAN DEFAULT FALSE;
+
+	CREATE INDEX IF NOT EXISTS idx_chats_archived ON chats(archived);
+	`,
+
+	// Migration 8: Pinned chats, contacts and labels mirrored from app state
+	`
+	ALTER TABLE chats ADD COLUMN IF NOT EXISTS pinned BOOLEAN DEFAULT FALSE;
+
+	CREATE TABLE IF NOT EXISTS contacts (
+		jid TEXT PRIMARY KEY,
+		full_name TEXT DEFAULT '',
+		first_name TEXT DEFAULT '',
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS labels (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL DEFAULT '',
+		color INTEGER DEFAULT 0,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS chat_labels (
+		chat_jid TEXT NOT NULL,
+		label_id TEXT NOT NULL,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (chat_jid, label_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_chat_labels_label_id ON chat_labels(label_id);
+	`,
+
+	// Migration 9: Reactions per message and reactor
+	`
+	CREATE TABLE IF NOT EXISTS message_reactions (
+		message_id TEXT NOT NULL,
+		chat_jid TEXT NOT NULL,
+		reactor_jid TEXT NOT NULL,
+		emoji TEXT NOT NULL,
+		timestamp TIMESTAMPTZ NOT NULL,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (message_id, chat_jid, reactor_jid)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_message_reactions_chat_jid ON message_reactions(chat_jid);
+	`,
+
+	// Migration 10: Edit history and revoke tombstones of messages
+	`
+	CREATE TABLE IF NOT EXISTS message_revisions (
+		id BIGSERIAL PRIMARY KEY,
+		message_id TEXT NOT NULL,
+		chat_jid TEXT NOT NULL,
+		revision_type TEXT NOT NULL,
+		editor_jid TEXT NOT NULL DEFAULT '',
+		content TEXT DEFAULT '',
+		previous_content TEXT DEFAULT '',
+		timestamp TIMESTAMPTZ NOT NULL,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (chat_jid, message_id, revision_type, timestamp)
+	);
+	`,
+
+	// Migration 11: Where the media of a message was downloaded to
+	`
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS media_path TEXT DEFAULT '';
+	`,
+
+	// Migration 12: Raw protobuf and info of messages
+	`
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS raw_message BYTEA;
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS raw_info TEXT DEFAULT '';
+	`,
+
+	// Migration 13: Chats marked as unread, apart from whether they have unread messages
+	`
+	ALTER TABLE chats ADD COLUMN IF NOT EXISTS marked_unread BOOLEAN DEFAULT FALSE;
+	UPDATE chats SET marked_unread = unread;
+	ALTER TABLE chats DROP COLUMN IF EXISTS unread;
+	`,
+
+	// Migration 14: Direct path of media, downloads fall back to it once the URL expired
+	`
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS direct_path TEXT DEFAULT '';
+	`,
+}
@@ -0,0 +1,2199 @@
+package chatstorage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// SQLRepository implements Repository using SQLite or PostgreSQL, the statements are shared
+// and only the schema and full-text search differ per database
+type SQLRepository struct {
+	db             *database
+	fullTextSearch bool // Message search is available, set by InitializeSchema
+}
+
+// NewStorageRepository creates a new SQLite repository
+func NewStorageRepository(db *sql.DB) domainChatStorage.IChatStorageRepository {
+	return &SQLRepository{db: &database{db: db}}
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *sql.DB) domainChatStorage.IChatStorageRepository {
+	return &SQLRepository{db: &database{db: db, postgres: true}}
+}
+
+// StoreChat creates or updates a chat
+func (r *SQLRepository) StoreChat(chat *domainChatStorage.Chat) error {
+	now := time.Now()
+	chat.UpdatedAt = now
+
+	query := `
+		INSERT INTO chats (jid, name, last_message_time, ephemeral_expiration, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(jid) DO UPDATE SET
+			name = excluded.name,
+			last_message_time = excluded.last_message_time,
+			ephemeral_expiration = excluded.ephemeral_expiration,
+			updated_at = excluded.updated_at
+	`
+
+	_, err := r.db.Exec(query, chat.JID, chat.Name, chat.LastMessageTime, chat.EphemeralExpiration, now, chat.UpdatedAt)
+	return err
+}
+
+// SetChatEphemeralExpiration updates the disappearing messages timer of a chat. A chat created by the change
+// takes its time as last activity, so it is not listed as the oldest chat.
+func (r *SQLRepository) SetChatEphemeralExpiration(jid string, expiration uint32, changedAt time.Time) error {
+	parsedJID, err := types.ParseJID(jid)
+	if err != nil {
+		return fmt.Errorf("invalid chat JID %s: %w", jid, err)
+	}
+
+	now := time.Now()
+	query := `
+		INSERT INTO chats (jid, name, last_message_time, ephemeral_expiration, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(jid) DO UPDATE SET
+			ephemeral_expiration = excluded.ephemeral_expiration,
+			updated_at = excluded.updated_at
+	`
+
+	name := r.GetChatNameWithPushName(parsedJID, jid, "", "")
+	_, err = r.db.Exec(query, jid, name, changedAt, expiration, now, now)
+	return err
+}
+
+// SetChatArchived updates whether a chat is archived
+func (r *SQLRepository) SetChatArchived(jid string, archived bool) error {
+	return r.updateChatState(jid, []string{"archived"}, archived)
+}
+
+// SetChatMuted updates the notification mute of a chat, a nil mutedUntil mutes forever
+func (r *SQLRepository) SetChatMuted(jid string, muted bool, mutedUntil *time.Time) error {
+	if !muted {
+		mutedUntil = nil
+	}
+	return r.updateChatState(jid, []string{"muted", "muted_until"}, muted, mutedUntil)
+}
+
+// SetChatMarkedUnread updates whether a chat is marked as unread
+func (r *SQLRepository) SetChatMarkedUnread(jid string, markedUnread bool) error {
+	return r.updateChatState(jid, []string{"marked_unread"}, markedUnread)
+}
+
+// SetChatPinned updates whether a chat is pinned to the top of the chat list
+func (r *SQLRepository) SetChatPinned(jid string, pinned bool) error {
+	return r.updateChatState(jid, []string{"pinned"}, pinned)
+}
+
+// SetChatLabel attaches a label to a chat or removes it
+func (r *SQLRepository) SetChatLabel(chatJID, labelID string, labeled bool) error {
+	if !labeled {
+		_, err := r.db.Exec("DELETE FROM chat_labels WHERE chat_jid = ? AND label_id = ?", chatJID, labelID)
+		return err
+	}
+
+	query := `
+		INSERT INTO chat_labels (chat_jid, label_id, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(chat_jid, label_id) DO NOTHING
+	`
+	_, err := r.db.Exec(query, chatJID, labelID, time.Now())
+	return err
+}
+
+// updateChatState is a private helper updating state columns of a chat. App state often arrives before
+// any message of the chat, so a chat that was never stored is created with the state and filled in later.
+func (r *SQLRepository) updateChatState(jid string, columns []string, values ...any) error {
+	parsedJID, err := types.ParseJID(jid)
+	if err != nil {
+		return fmt.Errorf("invalid chat JID %s: %w", jid, err)
+	}
+
+	assignments := make([]string, 0, len(columns))
+	for _, column := range columns {
+		assignments = append(assignments, column+" = excluded."+column)
+	}
+
+	now := time.Now()
+	query := `
+		INSERT INTO chats (jid, name, last_message_time, created_at, updated_at, ` + strings.Join(columns, ", ") + `)
+		VALUES (?, ?, ?, ?, ?` + strings.Repeat(", ?", len(columns)) + `)
+		ON CONFLICT(jid) DO UPDATE SET
+			` + strings.Join(assignments, ", ") + `,
+			updated_at = excluded.updated_at
+	`
+
+	name := r.GetChatNameWithPushName(parsedJID, jid, "", "")
+	args := append([]any{jid, name, time.Time{}, now, now}, values...)
+	_, err = r.db.Exec(query, args...)
+	return err
+}
+
+// GetChat retrieves a chat by JID
+func (r *SQLRepository) GetChat(jid string) (*domainChatStorage.Chat, error) {
+	query := `
+		SELECT c.jid, c.name, c.last_message_time, c.ephemeral_expiration,
+			c.archived, c.muted, c.muted_until, c.marked_unread, c.pinned, ` + chatLabelsColumn + `,
+			c.created_at, c.updated_at
+		FROM chats c
+		WHERE c.jid = ?
+	`
+
+	chat, err := r.scanChat(r.db.QueryRow(query, jid))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return chat, err
+}
+
+// GetMessageByID retrieves a message by its ID from any chat
+// This is more efficient than searching through all chats
+func (r *SQLRepository) GetMessageByID(id string) (*domainChatStorage.Message, error) {
+	query := `
+		SELECT id, chat_jid, sender, content, timestamp, is_from_me,
+			media_type, filename, url, direct_path, media_key, file_sha256,
+			file_enc_sha256, file_length, media_path, created_at, updated_at
+		FROM messages
+		WHERE id = ?
+		LIMIT 1
+	`
+
+	message, err := r.scanMessage(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return message, err
+}
+
+// GetFullMessage retrieves a message with its raw message and info, from any chat when chatJID is empty
+func (r *SQLRepository) GetFullMessage(id, chatJID string) (*domainChatStorage.Message, error) {
+	query := `
+		SELECT id, chat_jid, sender, content, timestamp, is_from_me,
+			media_type, filename, url, direct_path, media_key, file_sha256,
+			file_enc_sha256, file_length, media_path, raw_message, COALESCE(raw_info, ''), created_at, updated_at
+		FROM messages
+		WHERE id = ?
+	`
+	args := []any{id}
+	if chatJID != "" {
+		query += " AND chat_jid = ?"
+		args = append(args, chatJID)
+	}
+	query += " LIMIT 1"
+
+	message := &domainChatStorage.Message{}
+	err := r.db.QueryRow(query, args...).Scan(
+		&message.ID, &message.ChatJID, &message.Sender, &message.Content,
+		&message.Timestamp, &message.IsFromMe, &message.MediaType, &message.Filename,
+		&message.URL, &message.DirectPath, &message.MediaKey, &message.FileSHA256, &message.FileEncSHA256,
+		&message.FileLength, &message.MediaPath, &message.RawMessage, &message.RawInfo,
+		&message.CreatedAt, &message.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// GetChats retrieves chats with filtering
+func (r *SQLRepository) GetChats(filter *domainChatStorage.ChatFilter) ([]*domainChatStorage.Chat, error) {
+	var conditions []string
+	var args []any
+
+	query := `
+		SELECT c.jid, c.name, c.last_message_time, c.ephemeral_expiration,
+			c.archived, c.muted, c.muted_until, c.marked_unread, c.pinned, ` + chatLabelsColumn + `,
+			c.created_at, c.updated_at
+		FROM chats c
+	`
+
+	if filter.SearchName != "" {
+		// LIKE ignores case in SQLite but not in PostgreSQL
+		conditions = append(conditions, "LOWER(c.name) LIKE LOWER(?)")
+		args = append(args, "%"+filter.SearchName+"%")
+	}
+
+	if filter.HasMedia {
+		query += " INNER JOIN messages m ON c.jid = m.chat_jid"
+		conditions = append(conditions, "m.media_type != ''")
+	}
+
+	if filter.Archived != nil {
+		conditions = append(conditions, "c.archived = ?")
+		args = append(args, *filter.Archived)
+	}
+
+	if filter.Muted != nil {
+		// Timed mutes end by themselves, without an unmute event
+		muted := "(c.muted AND (c.muted_until IS NULL OR c.muted_until > ?))"
+		if !*filter.Muted {
+			muted = "NOT " + muted
+		}
+		conditions = append(conditions, muted)
+		args = append(args, time.Now())
+	}
+
+	if filter.MarkedUnread != nil {
+		conditions = append(conditions, "c.marked_unread = ?")
+		args = append(args, *filter.MarkedUnread)
+	}
+
+	if filter.Pinned != nil {
+		conditions = append(conditions, "c.pinned = ?")
+		args = append(args, *filter.Pinned)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// Pinned chats stay on top like on the phone
+	query += " ORDER BY c.pinned DESC, c.last_message_time DESC"
+
+	// Safely add LIMIT and OFFSET using parameterized values
+	if filter.Limit > 0 {
+		// Validate limit to prevent abuse
+		if filter.Limit > 1000 {
+			filter.Limit = 1000
+		}
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+
+		if filter.Offset > 0 {
+			query += " OFFSET ?"
+			args = append(args, filter.Offset)
+		}
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chats []*domainChatStorage.Chat
+	for rows.Next() {
+		chat, err := r.scanChat(rows)
+		if err != nil {
+			return nil, err
+		}
+		chats = append(chats, chat)
+	}
+
+	return chats, rows.Err()
+}
+
+// ClearChatMessages deletes the messages of a chat sent up to the given time but keeps the chat
+func (r *SQLRepository) ClearChatMessages(jid string, until time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		DELETE FROM message_receipts
+		WHERE chat_jid = ? AND message_id IN (SELECT id FROM messages WHERE chat_jid = ? AND timestamp <= ?)
+	`, jid, jid, until)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM message_reactions
+		WHERE chat_jid = ? AND message_id IN (SELECT id FROM messages WHERE chat_jid = ? AND timestamp <= ?)
+	`, jid, jid, until)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM message_revisions
+		WHERE chat_jid = ? AND message_id IN (SELECT id FROM messages WHERE chat_jid = ? AND timestamp <= ?)
+	`, jid, jid, until)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec("DELETE FROM messages WHERE chat_jid = ? AND timestamp <= ?", jid, until); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteChat deletes a chat and all its messages
+func (r *SQLRepository) DeleteChat(jid string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Delete messages first (foreign key constraint)
+	_, err = tx.Exec("DELETE FROM messages WHERE chat_jid = ?", jid)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM message_receipts WHERE chat_jid = ?", jid)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM message_reactions WHERE chat_jid = ?", jid)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM message_revisions WHERE chat_jid = ?", jid)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM chat_labels WHERE chat_jid = ?", jid)
+	if err != nil {
+		return err
+	}
+
+	// Delete chat
+	_, err = tx.Exec("DELETE FROM chats WHERE jid = ?", jid)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// StoreMessage creates or updates a message
+func (r *SQLRepository) StoreMessage(message *domainChatStorage.Message) error {
+	now := time.Now()
+	message.CreatedAt = now
+	message.UpdatedAt = now
+
+	// Skip empty messages, locations, contact cards and polls only have their raw message
+	if message.Content == "" && message.MediaType == "" && len(message.RawMessage) == 0 {
+		// This is not an error, just skip storing empty messages
+		return nil
+	}
+
+	query := `
+		INSERT INTO messages (
+			id, chat_jid, sender, content, timestamp, is_from_me, 
+			media_type, filename, url, direct_path, media_key, file_sha256, 
+			file_enc_sha256, file_length, raw_message, raw_info, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id, chat_jid) DO UPDATE SET
+			sender = excluded.sender,
+			content = excluded.content,
+			timestamp = excluded.timestamp,
+			is_from_me = excluded.is_from_me,
+			media_type = excluded.media_type,
+			filename = excluded.filename,
+			url = excluded.url,
+			direct_path = excluded.direct_path,
+			media_key = excluded.media_key,
+			file_sha256 = excluded.file_sha256,
+			file_enc_sha256 = excluded.file_enc_sha256,
+			file_length = excluded.file_length,
+			raw_message = COALESCE(excluded.raw_message, messages.raw_message),
+			raw_info = CASE WHEN excluded.raw_info != '' THEN excluded.raw_info ELSE messages.raw_info END,
+			updated_at = excluded.updated_at
+	`
+
+	_, err := r.db.Exec(query,
+		message.ID, message.ChatJID, message.Sender, message.Content,
+		message.Timestamp, message.IsFromMe, message.MediaType, message.Filename,
+		message.URL, message.DirectPath, message.MediaKey, message.FileSHA256, message.FileEncSHA256,
+		message.FileLength, message.RawMessage, message.RawInfo, message.CreatedAt, message.UpdatedAt,
+	)
+
+	return err
+}
+
+// StoreMessagesBatch creates or updates multiple messages in a single transaction
+func (r *SQLRepository) StoreMessagesBatch(messages []*domainChatStorage.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Prepare the statement once for better performance
+	stmt, err := tx.Prepare(`
+		INSERT INTO messages (
+			id, chat_jid, sender, content, timestamp, is_from_me, 
+			media_type, filename, url, direct_path, media_key, file_sha256, 
+			file_enc_sha256, file_length, raw_message, raw_info, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id, chat_jid) DO UPDATE SET
+			sender = excluded.sender,
+			content = excluded.content,
+			timestamp = excluded.timestamp,
+			is_from_me = excluded.is_from_me,
+			media_type = excluded.media_type,
+			filename = excluded.filename,
+			url = excluded.url,
+			direct_path = excluded.direct_path,
+			media_key = excluded.media_key,
+			file_sha256 = excluded.file_sha256,
+			file_enc_sha256 = excluded.file_enc_sha256,
+			file_length = excluded.file_length,
+			raw_message = COALESCE(excluded.raw_message, messages.raw_message),
+			raw_info = CASE WHEN excluded.raw_info != '' THEN excluded.raw_info ELSE messages.raw_info END,
+			updated_at = excluded.updated_at
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, message := range messages {
+		// Skip empty messages
+		if message.Content == "" && message.MediaType == "" && len(message.RawMessage) == 0 {
+			continue
+		}
+
+		message.CreatedAt = now
+		message.UpdatedAt = now
+
+		_, err = stmt.Exec(
+			message.ID, message.ChatJID, message.Sender, message.Content,
+			message.Timestamp, message.IsFromMe, message.MediaType, message.Filename,
+			message.URL, message.DirectPath, message.MediaKey, message.FileSHA256, message.FileEncSHA256,
+			message.FileLength, message.RawMessage, message.RawInfo, message.CreatedAt, message.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to store message %s: %w", message.ID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetMessages retrieves messages with filtering
+func (r *SQLRepository) GetMessages(filter *domainChatStorage.MessageFilter) ([]*domainChatStorage.Message, error) {
+	var conditions []string
+	var args []any
+
+	conditions = append(conditions, "chat_jid = ?")
+	args = append(args, filter.ChatJID)
+
+	if filter.StartTime != nil {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, *filter.StartTime)
+	}
+
+	if filter.EndTime != nil {
+		conditions = append(conditions, "timestamp <= ?")
+		args = append(args, *filter.EndTime)
+	}
+
+	if filter.MediaOnly {
+		conditions = append(conditions, "media_type != ''")
+	}
+
+	if filter.IsFromMe != nil {
+		conditions = append(conditions, "is_from_me = ?")
+		args = append(args, *filter.IsFromMe)
+	}
+
+	query := `
+		SELECT id, chat_jid, sender, content, timestamp, is_from_me,
+			media_type, filename, url, direct_path, media_key, file_sha256,
+			file_enc_sha256, file_length, media_path, created_at, updated_at
+		FROM messages
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY timestamp DESC
+	`
+
+	// Safely add LIMIT and OFFSET using parameterized values
+	if filter.Limit > 0 {
+		// Validate limit to prevent abuse
+		if filter.Limit > 1000 {
+			filter.Limit = 1000
+		}
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+
+		if filter.Offset > 0 {
+			query += " OFFSET ?"
+			args = append(args, filter.Offset)
+		}
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*domainChatStorage.Message
+	for rows.Next() {
+		message, err := r.scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+// SearchMessages performs database-level search for messages containing specific text
+func (r *SQLRepository) SearchMessages(chatJID, searchText string, limit int) ([]*domainChatStorage.Message, error) {
+	// Return empty results for empty search text
+	if strings.TrimSpace(searchText) == "" {
+		return []*domainChatStorage.Message{}, nil
+	}
+
+	var conditions []string
+	var args []any
+
+	// Always filter by chat JID
+	conditions = append(conditions, "chat_jid = ?")
+	args = append(args, chatJID)
+
+	// Add search condition using LIKE operator for case-insensitive search
+	conditions = append(conditions, "LOWER(content) LIKE ?")
+	args = append(args, "%"+strings.ToLower(searchText)+"%")
+
+	query := `
+		SELECT id, chat_jid, sender, content, timestamp, is_from_me,
+			media_type, filename, url, direct_path, media_key, file_sha256,
+			file_enc_sha256, file_length, media_path, created_at, updated_at
+		FROM messages
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY timestamp DESC
+	`
+
+	// Add limit with validation
+	if limit > 0 {
+		// Validate limit to prevent abuse
+		if limit > 1000 {
+			limit = 1000
+		}
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []*domainChatStorage.Message
+	for rows.Next() {
+		message, err := r.scanMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		messages = append(messages, message)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// SearchAllMessages runs a ranked full-text search over the messages of all chats.
+// Results are ordered by relevance then row, the cursor of the last result continues the search.
+func (r *SQLRepository) SearchAllMessages(filter *domainChatStorage.MessageSearchFilter) ([]*domainChatStorage.MessageSearchResult, error) {
+	if !r.fullTextSearch {
+		return nil, domainChatStorage.ErrFullTextSearchUnavailable
+	}
+
+	// bm25 is lower for better matches, the row id breaks ties so pages never overlap
+	ranking := `snippet(messages_fts, 0, '<mark>', '</mark>', '…', 24) AS snippet,
+				bm25(messages_fts) AS score, m.rowid AS row_id`
+	source := "messages_fts JOIN messages m ON m.rowid = messages_fts.rowid"
+	conditions := []string{"messages_fts MATCH ?"}
+	args := []any{filter.Query}
+	if r.db.postgres {
+		// The rank is negated to order like bm25
+		ranking = `ts_headline('simple', COALESCE(m.content, ''), search.query, 'StartSel=<mark>, StopSel=</mark>, MaxWords=24, MinWords=12') AS snippet,
+				-ts_rank(` + messageSearchVector + `, search.query)::float8 AS score, m.row_id`
+		source = "messages m, to_tsquery('simple', ?) AS search(query)"
+		conditions = []string{messageSearchVector + " @@ search.query"}
+		args = []any{utils.FullTextQueryToTSQuery(filter.Query)}
+	}
+
+	if filter.ChatJID != "" {
+		conditions = append(conditions, "m.chat_jid = ?")
+		args = append(args, filter.ChatJID)
+	}
+
+	if filter.Sender != "" {
+		// Senders are stored with their device, match every device of the account
+		conditions = append(conditions, "(m.sender = ? OR m.sender LIKE ?)")
+		args = append(args, filter.Sender)
+		if user, server, found := strings.Cut(filter.Sender, "@"); found {
+			args = append(args, user+":%@"+server)
+		} else {
+			args = append(args, filter.Sender)
+		}
+	}
+
+	switch filter.MediaType {
+	case "":
+	case "text":
+		conditions = append(conditions, "COALESCE(m.media_type, '') = ''")
+	default:
+		conditions = append(conditions, "m.media_type = ?")
+		args = append(args, filter.MediaType)
+	}
+
+	if filter.StartTime != nil {
+		conditions = append(conditions, "m.timestamp >= ?")
+		args = append(args, *filter.StartTime)
+	}
+
+	if filter.EndTime != nil {
+		conditions = append(conditions, "m.timestamp <= ?")
+		args = append(args, *filter.EndTime)
+	}
+
+	if filter.IsFromMe != nil {
+		conditions = append(conditions, "m.is_from_me = ?")
+		args = append(args, *filter.IsFromMe)
+	}
+
+	query := `
+		SELECT * FROM (
+			SELECT m.id, m.chat_jid, m.sender, m.content, m.timestamp, m.is_from_me,
+				m.media_type, m.filename, m.url, m.direct_path, m.media_key, m.file_sha256,
+				m.file_enc_sha256, m.file_length, m.media_path, m.created_at, m.updated_at,
+				` + ranking + `
+			FROM ` + source + `
+			WHERE ` + strings.Join(conditions, " AND ") + `
+		) AS results`
+	if filter.After != nil {
+		// Keyset pagination, rows stored or deleted before the cursor do not shift the next page
+		query += " WHERE score > ? OR (score = ? AND row_id > ?)"
+		args = append(args, filter.After.Score, filter.After.Score, filter.After.RowID)
+	}
+	query += " ORDER BY score, row_id"
+
+	if filter.Limit > 0 {
+		// Validate limit to prevent abuse
+		if filter.Limit > 1000 {
+			filter.Limit = 1000
+		}
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*domainChatStorage.MessageSearchResult
+	for rows.Next() {
+		message := &domainChatStorage.Message{}
+		result := &domainChatStorage.MessageSearchResult{Message: message}
+		err := rows.Scan(
+			&message.ID, &message.ChatJID, &message.Sender, &message.Content,
+			&message.Timestamp, &message.IsFromMe, &message.MediaType, &message.Filename,
+			&message.URL, &message.DirectPath, &message.MediaKey, &message.FileSHA256, &message.FileEncSHA256,
+			&message.FileLength, &message.MediaPath, &message.CreatedAt, &message.UpdatedAt,
+			&result.Snippet, &result.Cursor.Score, &result.Cursor.RowID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		result.Score = result.Cursor.Score
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating messages: %w", err)
+	}
+
+	return results, nil
+}
+
+// DeleteMessage deletes a specific message
+func (r *SQLRepository) DeleteMessage(id, chatJID string) error {
+	if _, err := r.db.Exec("DELETE FROM messages WHERE id = ? AND chat_jid = ?", id, chatJID); err != nil {
+		return err
+	}
+
+	if _, err := r.db.Exec("DELETE FROM message_receipts WHERE message_id = ? AND chat_jid = ?", id, chatJID); err != nil {
+		return err
+	}
+
+	if _, err := r.db.Exec("DELETE FROM message_reactions WHERE message_id = ? AND chat_jid = ?", id, chatJID); err != nil {
+		return err
+	}
+
+	_, err := r.db.Exec("DELETE FROM message_revisions WHERE message_id = ? AND chat_jid = ?", id, chatJID)
+	return err
+}
+
+// UpdateMessageDirectPath records where the phone uploaded expired media again. The URL of the expired media
+// is cleared, media is then downloaded by its direct path from whichever media host whatsmeow is connected to.
+func (r *SQLRepository) UpdateMessageDirectPath(id, chatJID, directPath string) error {
+	_, err := r.db.Exec("UPDATE messages SET url = '', direct_path = ?, updated_at = ? WHERE id = ? AND chat_jid = ?", directPath, time.Now(), id, chatJID)
+	return err
+}
+
+// UpdateMessageMediaPath records where the media of a message was downloaded to
+func (r *SQLRepository) UpdateMessageMediaPath(id, chatJID, mediaPath string) error {
+	_, err := r.db.Exec("UPDATE messages SET media_path = ?, updated_at = ? WHERE id = ? AND chat_jid = ?", mediaPath, time.Now(), id, chatJID)
+	return err
+}
+
+// GetMediaPaths lists where the media of messages and statuses was downloaded to
+func (r *SQLRepository) GetMediaPaths() ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT media_path FROM messages WHERE media_path != ''
+		UNION
+		SELECT media_path FROM statuses WHERE media_path != ''
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var mediaPath string
+		if err := rows.Scan(&mediaPath); err != nil {
+			return nil, err
+		}
+		paths = append(paths, mediaPath)
+	}
+	return paths, rows.Err()
+}
+
+// ClearMediaPath forgets a downloaded media file that was removed from disk
+func (r *SQLRepository) ClearMediaPath(mediaPath string) error {
+	if _, err := r.db.Exec("UPDATE messages SET media_path = '' WHERE media_path = ?", mediaPath); err != nil {
+		return err
+	}
+	_, err := r.db.Exec("UPDATE statuses SET media_path = '' WHERE media_path = ?", mediaPath)
+	return err
+}
+
+// getCount is a private helper for count queries
+func (r *SQLRepository) getCount(query string, args ...any) (int64, error) {
+	var count int64
+	err := r.db.QueryRow(query, args...).Scan(&count)
+	return count, err
+}
+
+// scanMessage is a private helper for scanning message rows
+func (r *SQLRepository) scanMessage(scanner interface{ Scan(...any) error }) (*domainChatStorage.Message, error) {
+	message := &domainChatStorage.Message{}
+	err := scanner.Scan(
+		&message.ID, &message.ChatJID, &message.Sender, &message.Content,
+		&message.Timestamp, &message.IsFromMe, &message.MediaType, &message.Filename,
+		&message.URL, &message.DirectPath, &message.MediaKey, &message.FileSHA256, &message.FileEncSHA256,
+		&message.FileLength, &message.MediaPath, &message.CreatedAt, &message.UpdatedAt,
+	)
+	return message, err
+}
+
+const chatLabelsSeparator = "\x1f"
+
+// chatLabelsColumn selects the label names of a chat joined by chatLabelsSeparator,
+// a label whose definition has not been synced yet is listed by its ID
+const chatLabelsColumn = `(
+			SELECT string_agg(COALESCE(NULLIF(l.name, ''), cl.label_id), '` + chatLabelsSeparator + `')
+			FROM chat_labels cl LEFT JOIN labels l ON l.id = cl.label_id
+			WHERE cl.chat_jid = c.jid
+		)`
+
+// scanChat is a private helper for scanning chat rows
+func (r *SQLRepository) scanChat(scanner interface{ Scan(...any) error }) (*domainChatStorage.Chat, error) {
+	chat := &domainChatStorage.Chat{}
+	var labels sql.NullString
+	err := scanner.Scan(
+		&chat.JID, &chat.Name, &chat.LastMessageTime, &chat.EphemeralExpiration,
+		&chat.Archived, &chat.Muted, &chat.MutedUntil, &chat.MarkedUnread, &chat.Pinned, &labels,
+		&chat.CreatedAt, &chat.UpdatedAt,
+	)
+	if labels.String != "" {
+		chat.Labels = strings.Split(labels.String, chatLabelsSeparator)
+	}
+	return chat, err
+}
+
+// GetChatMessageCount returns the number of messages in a chat
+func (r *SQLRepository) GetChatMessageCount(chatJID string) (int64, error) {
+	return r.getCount("SELECT COUNT(*) FROM messages WHERE chat_jid = ?", chatJID)
+}
+
+// GetTotalMessageCount returns the total number of messages
+func (r *SQLRepository) GetTotalMessageCount() (int64, error) {
+	return r.getCount("SELECT COUNT(*) FROM messages")
+}
+
+// GetTotalChatCount returns the total number of chats
+func (r *SQLRepository) GetTotalChatCount() (int64, error) {
+	return r.getCount("SELECT COUNT(*) FROM chats")
+}
+
+// TruncateAllChats deletes all chats from the database
+// Note: Due to foreign key constraints, messages must be deleted first
+func (r *SQLRepository) TruncateAllChats() error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Delete messages first (foreign key constraint)
+	_, err = tx.Exec("DELETE FROM messages")
+	if err != nil {
+		return fmt.Errorf("failed to delete messages: %w", err)
+	}
+
+	// Delete chats
+	_, err = tx.Exec("DELETE FROM chats")
+	if err != nil {
+		return fmt.Errorf("failed to delete chats: %w", err)
+	}
+
+	// Delete status updates
+	_, err = tx.Exec("DELETE FROM statuses")
+	if err != nil {
+		return fmt.Errorf("failed to delete statuses: %w", err)
+	}
+
+	_, err = tx.Exec("DELETE FROM message_receipts")
+	if err != nil {
+		return fmt.Errorf("failed to delete message receipts: %w", err)
+	}
+
+	_, err = tx.Exec("DELETE FROM message_reactions")
+	if err != nil {
+		return fmt.Errorf("failed to delete message reactions: %w", err)
+	}
+
+	_, err = tx.Exec("DELETE FROM message_revisions")
+	if err != nil {
+		return fmt.Errorf("failed to delete message revisions: %w", err)
+	}
+
+	// Contacts and labels belong to the account, they are synced again on the next login
+	for _, table := range []string{"chat_labels", "labels", "contacts"} {
+		if _, err = tx.Exec("DELETE FROM " + table); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", table, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetChatNameWithPushName determines the appropriate name for a chat with pushname support
+func (r *SQLRepository) GetChatNameWithPushName(jid types.JID, chatJID string, senderUser string, pushName string) string {
+	// First, check if chat already exists with a name
+	existingChat, err := r.GetChat(chatJID)
+	if err == nil && existingChat != nil && existingChat.Name != "" {
+		// If we have a pushname and the existing name is just a phone number/JID user, update it
+		if pushName != "" && (existingChat.Name == jid.User || existingChat.Name == senderUser) {
+			return pushName
+		}
+		return existingChat.Name
+	}
+
+	// Determine chat type and name
+	var name string
+
+	switch jid.Server {
+	case "g.us":
+		// This is a group chat
+		// For now, use a generic name - this can be enhanced later with group info
+		name = fmt.Sprintf("Group %s", jid.User)
+	case "newsletter":
+		// This is a newsletter/channel
+		name = fmt.Sprintf("Newsletter %s", jid.User)
+	default:
+		// This is an individual contact
+		// Priority: saved contact name > pushName > senderUser > JID user
+		if contact, err := r.GetContact(chatJID); err == nil && contact != nil && contact.FullName != "" {
+			name = contact.FullName
+		} else if pushName != "" && pushName != senderUser && pushName != jid.User {
+			name = pushName
+		} else if senderUser != "" {
+			name = senderUser
+		} else {
+			name = jid.User
+		}
+	}
+
+	return name
+}
+
+func (r *SQLRepository) CreateMessage(ctx context.Context, evt *events.Message) error {
+	if evt == nil || evt.Message == nil {
+		return nil
+	}
+
+	// Extract chat and sender information
+	chatJID := evt.Info.Chat.String()
+	// Store the full sender JID (user@server) to ensure consistency between received and sent messages
+	sender := evt.Info.Sender.String()
+
+	// Reactions are stored against the message they react to instead of as messages,
+	// like on the phone they do not move the chat up
+	if reactionMessage := evt.Message.GetReactionMessage(); reactionMessage != nil {
+		timestamp := evt.Info.Timestamp
+		if senderTimestamp := reactionMessage.GetSenderTimestampMS(); senderTimestamp > 0 {
+			timestamp = time.UnixMilli(senderTimestamp)
+		}
+		return r.StoreReaction(&domainChatStorage.Reaction{
+			MessageID:  reactionMessage.GetKey().GetID(),
+			ChatJID:    chatJID,
+			ReactorJID: evt.Info.Sender.ToNonAD().String(),
+			Emoji:      reactionMessage.GetText(),
+			Timestamp:  timestamp,
+		})
+	}
+
+	// Get appropriate chat name using pushname if available
+	chatName := r.GetChatNameWithPushName(evt.Info.Chat, chatJID, evt.Info.Sender.User, evt.Info.PushName)
+
+	// Get existing chat to preserve ephemeral_expiration if needed
+	existingChat, err := r.GetChat(chatJID)
+	if err != nil {
+		return fmt.Errorf("failed to get existing chat: %w", err)
+	}
+
+	// Extract ephemeral expiration from incoming message
+	ephemeralExpiration := utils.ExtractEphemeralExpiration(evt.Message)
+
+	// Create or update chat
+	chat := &domainChatStorage.Chat{
+		JID:             chatJID,
+		Name:            chatName,
+		LastMessageTime: evt.Info.Timestamp,
+	}
+
+	// Set ephemeral expiration: an explicit setting change always wins (including turning it off),
+	// otherwise use incoming message value if > 0, otherwise preserve existing
+	if protocolMessage := evt.Message.GetProtocolMessage(); protocolMessage.GetType() == waE2E.ProtocolMessage_EPHEMERAL_SETTING {
+		chat.EphemeralExpiration = protocolMessage.GetEphemeralExpiration()
+	} else if ephemeralExpiration > 0 {
+		chat.EphemeralExpiration = ephemeralExpiration
+	} else if existingChat != nil {
+		// Preserve existing ephemeral_expiration if incoming message doesn't have one
+		chat.EphemeralExpiration = existingChat.EphemeralExpiration
+	}
+
+	// Store or update the chat
+	if err := r.StoreChat(chat); err != nil {
+		return fmt.Errorf("failed to store chat: %w", err)
+	}
+
+	// Extract message content and media info
+	content := utils.ExtractMessageTextFromProto(evt.Message)
+	mediaType, filename, url, directPath, mediaKey, fileSHA256, fileEncSHA256, fileLength := utils.ExtractMediaInfo(evt.Message)
+
+	// Skip if there's no content and no media, unless it is kept with its raw message
+	if content == "" && mediaType == "" && !utils.HasStandaloneContent(evt.Message) {
+		logrus.Debugf("Skipping message %s - no content or media", evt.Info.ID)
+		return nil
+	}
+
+	// The raw message keeps the ephemeral and view once wrappers that evt.Message lost
+	raw := evt.RawMessage
+	if raw == nil {
+		raw = evt.Message
+	}
+	rawMessage, rawInfo := utils.RawMessageFields(raw, &evt.Info)
+
+	// Create message object
+	message := &domainChatStorage.Message{
+		ID:            evt.Info.ID,
+		ChatJID:       chatJID,
+		Sender:        sender,
+		Content:       content,
+		Timestamp:     evt.Info.Timestamp,
+		IsFromMe:      evt.Info.IsFromMe,
+		MediaType:     mediaType,
+		Filename:      filename,
+		URL:           url,
+		DirectPath:    directPath,
+		MediaKey:      mediaKey,
+		FileSHA256:    fileSHA256,
+		FileEncSHA256: fileEncSHA256,
+		FileLength:    fileLength,
+		RawMessage:    rawMessage,
+		RawInfo:       rawInfo,
+	}
+
+	// Store the message
+	return r.StoreMessage(message)
+}
+
+// GetStorageStatistics returns current storage statistics for logging purposes
+func (r *SQLRepository) GetStorageStatistics() (chatCount int64, messageCount int64, err error) {
+	// Count all chats using efficient query
+	chatCount, err = r.GetTotalChatCount()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get chat count: %w", err)
+	}
+
+	// Count all messages
+	messageCount, err = r.GetTotalMessageCount()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get message count: %w", err)
+	}
+
+	return chatCount, messageCount, nil
+}
+
+// TruncateAllDataWithLogging performs truncation with detailed logging
+func (r *SQLRepository) TruncateAllDataWithLogging(logPrefix string) error {
+	// Get statistics before truncation
+	chatCount, messageCount, err := r.GetStorageStatistics()
+	if err != nil {
+		logrus.Warnf("[%s] Failed to get storage statistics before truncation: %v", logPrefix, err)
+	} else {
+		logrus.Infof("[%s] Storage before truncation: %d chats, %d messages", logPrefix, chatCount, messageCount)
+	}
+
+	// Perform truncation
+	if err := r.TruncateAllChats(); err != nil {
+		return fmt.Errorf("failed to truncate chatstorage data: %w", err)
+	}
+
+	// Verify truncation
+	chatCountAfter, messageCountAfter, err := r.GetStorageStatistics()
+	if err != nil {
+		logrus.Warnf("[%s] Failed to get storage statistics after truncation: %v", logPrefix, err)
+	} else {
+		logrus.Infof("[%s] Storage after truncation: %d chats, %d messages", logPrefix, chatCountAfter, messageCountAfter)
+		if chatCountAfter == 0 && messageCountAfter == 0 {
+			logrus.Infof("[%s] ✅ Chatstorage truncation completed successfully", logPrefix)
+		} else {
+			logrus.Warnf("[%s] ⚠️ Truncation may not have completed fully", logPrefix)
+		}
+	}
+
+	return nil
+}
+
+// StoreSentMessageWithContext stores a message that was sent by the user with context cancellation support,
+// message is kept as its raw message when given
+func (r *SQLRepository) StoreSentMessageWithContext(ctx context.Context, messageID string, senderJID string, recipientJID string, content string, timestamp time.Time, message *waE2E.Message) error {
+	// Check if context is already cancelled before starting
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	// Ensure JID is properly formatted
+	jid, err := types.ParseJID(recipientJID)
+	if err != nil {
+		return fmt.Errorf("invalid JID format: %w", err)
+	}
+
+	chatJID := jid.String()
+
+	// Get chat name (no pushname available for sent messages)
+	chatName := r.GetChatNameWithPushName(jid, chatJID, jid.User, "")
+
+	// Check context again before database operations
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	// Get existing chat to preserve ephemeral_expiration
+	existingChat, err := r.GetChat(chatJID)
+	if err != nil {
+		return fmt.Errorf("failed to get existing chat: %w", err)
+	}
+
+	// Store or update chat, preserving existing ephemeral_expiration
+	chat := &domainChatStorage.Chat{
+		JID:             chatJID,
+		Name:            chatName,
+		LastMessageTime: timestamp,
+	}
+
+	// Preserve existing ephemeral_expiration if chat exists
+	if existingChat != nil {
+		chat.EphemeralExpiration = existingChat.EphemeralExpiration
+	}
+
+	if err := r.StoreChat(chat); err != nil {
+		return fmt.Errorf("failed to store chat: %w", err)
+	}
+
+	// Check context one more time before storing message
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	// Keep the sent message with the info it would have been received with
+	info := &types.MessageInfo{
+		MessageSource: types.MessageSource{Chat: jid, IsFromMe: true, IsGroup: jid.Server == types.GroupServer},
+		ID:            messageID,
+		Timestamp:     timestamp,
+	}
+	if sender, err := types.ParseJID(senderJID); err == nil {
+		info.Sender = sender
+	}
+	rawMessage, rawInfo := utils.RawMessageFields(message, info)
+
+	// Store the sent message
+	return r.StoreMessage(&domainChatStorage.Message{
+		ID:         messageID,
+		ChatJID:    chatJID,
+		Sender:     senderJID,
+		Content:    content,
+		Timestamp:  timestamp,
+		IsFromMe:   true,
+		RawMessage: rawMessage,
+		RawInfo:    rawInfo,
+	})
+}
+
+// StoreStatus creates or updates a status update
+func (r *SQLRepository) StoreStatus(status *domainChatStorage.Status) error {
+	now := time.Now()
+	status.CreatedAt = now
+	status.UpdatedAt = now
+
+	query := `
+		INSERT INTO statuses (
+			id, sender, push_name, content, media_type, mime_type, filename,
+			url, media_key, file_sha256, file_enc_sha256, file_length,
+			media_path, viewed, timestamp, expires_at, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id, sender) DO UPDATE SET
+			push_name = excluded.push_name,
+			content = excluded.content,
+			media_type = excluded.media_type,
+			mime_type = excluded.mime_type,
+			filename = excluded.filename,
+			url = excluded.url,
+			media_key = excluded.media_key,
+			file_sha256 = excluded.file_sha256,
+			file_enc_sha256 = excluded.file_enc_sha256,
+			file_length = excluded.file_length,
+			media_path = CASE WHEN excluded.media_path != '' THEN excluded.media_path ELSE statuses.media_path END,
+			viewed = statuses.viewed OR excluded.viewed,
+			timestamp = excluded.timestamp,
+			expires_at = excluded.expires_at,
+			updated_at = excluded.updated_at
+	`
+
+	_, err := r.db.Exec(query,
+		status.ID, status.Sender, status.PushName, status.Content, status.MediaType,
+		status.MimeType, status.Filename, status.URL, status.MediaKey, status.FileSHA256,
+		status.FileEncSHA256, status.FileLength, status.MediaPath, status.Viewed,
+		status.Timestamp, status.ExpiresAt, status.CreatedAt, status.UpdatedAt,
+	)
+
+	return err
+}
+
+// GetStatuses retrieves status updates with filtering, newest first
+func (r *SQLRepository) GetStatuses(filter *domainChatStorage.StatusFilter) ([]*domainChatStorage.Status, error) {
+	conditions, args := r.buildStatusConditions(filter)
+
+	query := `
+		SELECT id, sender, push_name, content, media_type, mime_type, filename,
+			url, media_key, file_sha256, file_enc_sha256, file_length,
+			media_path, viewed, timestamp, expires_at, created_at, updated_at
+		FROM statuses
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY timestamp DESC"
+
+	// Safely add LIMIT and OFFSET using parameterized values
+	if filter.Limit > 0 {
+		// Validate limit to prevent abuse
+		if filter.Limit > 1000 {
+			filter.Limit = 1000
+		}
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+
+		if filter.Offset > 0 {
+			query += " OFFSET ?"
+			args = append(args, filter.Offset)
+		}
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statuses []*domainChatStorage.Status
+	for rows.Next() {
+		status, err := r.scanStatus(rows)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, rows.Err()
+}
+
+// GetStatusCount returns the number of status updates matching the filter, ignoring pagination
+func (r *SQLRepository) GetStatusCount(filter *domainChatStorage.StatusFilter) (int64, error) {
+	conditions, args := r.buildStatusConditions(filter)
+
+	query := "SELECT COUNT(*) FROM statuses"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	return r.getCount(query, args...)
+}
+
+// DeleteStatus deletes a single status update, e.g. when the poster revokes it
+func (r *SQLRepository) DeleteStatus(id, sender string) error {
+	_, err := r.db.Exec("DELETE FROM statuses WHERE id = ? AND sender = ?", id, sender)
+	return err
+}
+
+// StoreMessageReceipts records a receipt of a recipient for one or more messages. The earliest timestamp of each
+// stage is kept, and reaching a later stage fills the earlier ones, e.g. a read message was also delivered.
+// Receipts are only kept for stored outgoing messages, receipts of other messages are dropped.
+func (r *SQLRepository) StoreMessageReceipts(chatJID, recipientJID string, messageIDs []string, receiptType string, timestamp time.Time) error {
+	var deliveredAt, readAt, playedAt *time.Time
+	switch receiptType {
+	case domainChatStorage.ReceiptPlayed:
+		playedAt = &timestamp
+		fallthrough
+	case domainChatStorage.ReceiptRead:
+		readAt = &timestamp
+		fallthrough
+	case domainChatStorage.ReceiptDelivered:
+		deliveredAt = &timestamp
+	default:
+		return fmt.Errorf("unknown receipt type %q", receiptType)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	messageIDs, err = outgoingMessageIDs(tx, chatJID, messageIDs)
+	if err != nil || len(messageIDs) == 0 {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO message_receipts (
+			message_id, chat_jid, recipient_jid, delivered_at, read_at, played_at, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(message_id, chat_jid, recipient_jid) DO UPDATE SET
+			delivered_at = COALESCE(message_receipts.delivered_at, excluded.delivered_at),
+			read_at = COALESCE(message_receipts.read_at, excluded.read_at),
+			played_at = COALESCE(message_receipts.played_at, excluded.played_at),
+			updated_at = excluded.updated_at
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, messageID := range messageIDs {
+		if _, err = stmt.Exec(messageID, chatJID, recipientJID, deliveredAt, readAt, playedAt, now, now); err != nil {
+			return fmt.Errorf("failed to store receipt for message %s: %w", messageID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// outgoingMessageIDs keeps the IDs of messages of a chat that are stored as sent by this device
+func outgoingMessageIDs(tx *transaction, chatJID string, messageIDs []string) ([]string, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(messageIDs)), ",")
+	args := []any{chatJID, true}
+	for _, messageID := range messageIDs {
+		args = append(args, messageID)
+	}
+
+	rows, err := tx.Query(`
+		SELECT id FROM messages
+		WHERE chat_jid = ? AND is_from_me = ? AND id IN (`+placeholders+`)
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up messages of receipts: %w", err)
+	}
+	defer rows.Close()
+
+	var outgoing []string
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		outgoing = append(outgoing, id)
+	}
+	return outgoing, rows.Err()
+}
+
+// GetMessageReceipts retrieves the receipts of the given messages in a chat, ordered by message and recipient
+func (r *SQLRepository) GetMessageReceipts(chatJID string, messageIDs []string) ([]*domainChatStorage.MessageReceipt, error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(messageIDs)), ",")
+	args := []any{chatJID}
+	for _, messageID := range messageIDs {
+		args = append(args, messageID)
+	}
+
+	query := `
+		SELECT message_id, chat_jid, recipient_jid, delivered_at, read_at, played_at, created_at, updated_at
+		FROM message_receipts
+		WHERE chat_jid = ? AND message_id IN (` + placeholders + `)
+		ORDER BY message_id, recipient_jid
+	`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var receipts []*domainChatStorage.MessageReceipt
+	for rows.Next() {
+		receipt, err := r.scanMessageReceipt(rows)
+		if err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, receipt)
+	}
+
+	return receipts, rows.Err()
+}
+
+// StoreReaction stores the reaction of a user to a message, an empty emoji removes it.
+// A reaction older than the stored one of the same user is ignored, they may arrive out of order
+func (r *SQLRepository) StoreReaction(reaction *domainChatStorage.Reaction) error {
+	if reaction.Emoji == "" {
+		_, err := r.db.Exec(`
+			DELETE FROM message_reactions
+			WHERE message_id = ? AND chat_jid = ? AND reactor_jid = ? AND timestamp <= ?
+		`, reaction.MessageID, reaction.ChatJID, reaction.ReactorJID, reaction.Timestamp)
+		return err
+	}
+
+	now := time.Now()
+	query := `
+		INSERT INTO message_reactions (message_id, chat_jid, reactor_jid, emoji, timestamp, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(message_id, chat_jid, reactor_jid) DO UPDATE SET
+			emoji = excluded.emoji,
+			timestamp = excluded.timestamp,
+			updated_at = excluded.updated_at
+		WHERE excluded.timestamp >= message_reactions.timestamp
+	`
+
+	_, err := r.db.Exec(query,
+		reaction.MessageID, reaction.ChatJID, reaction.ReactorJID,
+		reaction.Emoji, reaction.Timestamp, now, now,
+	)
+	return err
+}
+
+// GetMessageReactions retrieves the reactions to the given messages of a chat, oldest first
+func (r *SQLRepository) GetMessageReactions(chatJID string, messageIDs []string) ([]*domainChatStorage.Reaction, error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(messageIDs)), ",")
+	args := []any{chatJID}
+	for _, messageID := range messageIDs {
+		args = append(args, messageID)
+	}
+
+	query := `
+		SELECT message_id, chat_jid, reactor_jid, emoji, timestamp, created_at, updated_at
+		FROM message_reactions
+		WHERE chat_jid = ? AND message_id IN (` + placeholders + `)
+		ORDER BY message_id, timestamp
+	`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reactions []*domainChatStorage.Reaction
+	for rows.Next() {
+		reaction := &domainChatStorage.Reaction{}
+		err := rows.Scan(
+			&reaction.MessageID, &reaction.ChatJID, &reaction.ReactorJID, &reaction.Emoji,
+			&reaction.Timestamp, &reaction.CreatedAt, &reaction.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		reactions = append(reactions, reaction)
+	}
+
+	return reactions, rows.Err()
+}
+
+// StoreMessageEdit records an edit of a message and updates the stored content. The raw message is
+// replaced by the edited one, the protobuf from before the edit would quote and forward the old text
+func (r *SQLRepository) StoreMessageEdit(revision *domainChatStorage.MessageRevision) error {
+	revision.Type = domainChatStorage.RevisionEdit
+
+	return r.storeMessageRevision(revision, true, func(tx *transaction) error {
+		// Edits may arrive out of order, the message keeps the content of the latest one
+		_, err := tx.Exec(`
+			UPDATE messages SET content = ?, raw_message = ?, updated_at = ?
+			WHERE id = ? AND chat_jid = ? AND NOT EXISTS (
+				SELECT 1 FROM message_revisions
+				WHERE chat_jid = ? AND message_id = ? AND revision_type = ? AND timestamp > ?
+			)
+		`, revision.Content, revision.RawMessage, time.Now(), revision.MessageID, revision.ChatJID,
+			revision.ChatJID, revision.MessageID, domainChatStorage.RevisionEdit, revision.Timestamp)
+		return err
+	})
+}
+
+// StoreMessageRevoke records the revoke of a message as a tombstone. Without keepContent the
+// content and media of the message and its earlier revisions are erased
+func (r *SQLRepository) StoreMessageRevoke(revision *domainChatStorage.MessageRevision, keepContent bool) error {
+	revision.Type = domainChatStorage.RevisionRevoke
+	revision.Content = ""
+
+	return r.storeMessageRevision(revision, keepContent, func(tx *transaction) error {
+		if keepContent {
+			return nil
+		}
+
+		_, err := tx.Exec(`
+			UPDATE messages SET content = '', filename = '', url = '', media_key = NULL,
+				file_sha256 = NULL, file_enc_sha256 = NULL, file_length = 0, media_path = '', raw_message = NULL, updated_at = ?
+			WHERE id = ? AND chat_jid = ?
+		`, time.Now(), revision.MessageID, revision.ChatJID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			UPDATE message_revisions SET content = '', previous_content = ''
+			WHERE chat_jid = ? AND message_id = ?
+		`, revision.ChatJID, revision.MessageID)
+		return err
+	})
+}
+
+// storeMessageRevision is a private helper recording a revision with the content the message had before,
+// then applying it to the message. Revisions that were already recorded are skipped
+func (r *SQLRepository) storeMessageRevision(revision *domainChatStorage.MessageRevision, keepPreviousContent bool, apply func(tx *transaction) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	revision.PreviousContent = ""
+	if keepPreviousContent {
+		err = tx.QueryRow("SELECT content FROM messages WHERE id = ? AND chat_jid = ?", revision.MessageID, revision.ChatJID).Scan(&revision.PreviousContent)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+	}
+
+	revision.CreatedAt = time.Now()
+	err = tx.QueryRow(`
+		INSERT INTO message_revisions (message_id, chat_jid, revision_type, editor_jid, content, previous_content, timestamp, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(chat_jid, message_id, revision_type, timestamp) DO NOTHING
+		RETURNING id
+	`, revision.MessageID, revision.ChatJID, revision.Type, revision.EditorJID,
+		revision.Content, revision.PreviousContent, revision.Timestamp, revision.CreatedAt).Scan(&revision.ID)
+	if err == sql.ErrNoRows {
+		// Already recorded
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = apply(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetMessageRevisions retrieves the revision history of the given messages of a chat, oldest first
+func (r *SQLRepository) GetMessageRevisions(chatJID string, messageIDs []string) ([]*domainChatStorage.MessageRevision, error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(messageIDs)), ",")
+	args := []any{chatJID}
+	for _, messageID := range messageIDs {
+		args = append(args, messageID)
+	}
+
+	query := `
+		SELECT id, message_id, chat_jid, revision_type, editor_jid, content, previous_content, timestamp, created_at
+		FROM message_revisions
+		WHERE chat_jid = ? AND message_id IN (` + placeholders + `)
+		ORDER BY message_id, timestamp, id
+	`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*domainChatStorage.MessageRevision
+	for rows.Next() {
+		revision := &domainChatStorage.MessageRevision{}
+		err := rows.Scan(
+			&revision.ID, &revision.MessageID, &revision.ChatJID, &revision.Type, &revision.EditorJID,
+			&revision.Content, &revision.PreviousContent, &revision.Timestamp, &revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+// StoreContact stores an address book entry and renames the chat with the contact to the saved name
+func (r *SQLRepository) StoreContact(contact *domainChatStorage.Contact) error {
+	now := time.Now()
+	contact.UpdatedAt = now
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO contacts (jid, full_name, first_name, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(jid) DO UPDATE SET
+			full_name = excluded.full_name,
+			first_name = excluded.first_name,
+			updated_at = excluded.updated_at
+	`
+	if _, err = tx.Exec(query, contact.JID, contact.FullName, contact.FirstName, now, now); err != nil {
+		return err
+	}
+
+	if contact.FullName != "" {
+		if _, err = tx.Exec("UPDATE chats SET name = ?, updated_at = ? WHERE jid = ?", contact.FullName, now, contact.JID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetContact retrieves an address book entry by JID
+func (r *SQLRepository) GetContact(jid string) (*domainChatStorage.Contact, error) {
+	query := `
+		SELECT jid, full_name, first_name, created_at, updated_at
+		FROM contacts
+		WHERE jid = ?
+	`
+
+	var contact domainChatStorage.Contact
+	err := r.db.QueryRow(query, jid).Scan(
+		&contact.JID, &contact.FullName, &contact.FirstName, &contact.CreatedAt, &contact.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &contact, nil
+}
+
+// StoreLabel creates or updates a chat label
+func (r *SQLRepository) StoreLabel(label *domainChatStorage.Label) error {
+	now := time.Now()
+	label.UpdatedAt = now
+
+	query := `
+		INSERT INTO labels (id, name, color, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name,
+			color = excluded.color,
+			updated_at = excluded.updated_at
+	`
+	_, err := r.db.Exec(query, label.ID, label.Name, label.Color, now, now)
+	return err
+}
+
+// DeleteLabel deletes a label and detaches it from all chats
+func (r *SQLRepository) DeleteLabel(id string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("DELETE FROM chat_labels WHERE label_id = ?", id); err != nil {
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM labels WHERE id = ?", id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetIdempotencyRecord retrieves the stored response for an idempotency key, expired records are ignored
+func (r *SQLRepository) GetIdempotencyRecord(key string) (*domainChatStorage.IdempotencyRecord, error) {
+	query := `
+		SELECT idempotency_key, request_hash, status_code, response_body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE idempotency_key = ? AND expires_at > ?
+	`
+
+	var record domainChatStorage.IdempotencyRecord
+	err := r.db.QueryRow(query, key, time.Now()).Scan(
+		&record.Key, &record.RequestHash, &record.StatusCode,
+		&record.ResponseBody, &record.CreatedAt, &record.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// ReserveIdempotencyKey takes an idempotency key for a request about to run, it fails when another request,
+// possibly on another instance, holds the key. Expired records are pruned first.
+func (r *SQLRepository) ReserveIdempotencyKey(record *domainChatStorage.IdempotencyRecord) (bool, error) {
+	now := time.Now()
+	record.CreatedAt = now
+	record.StatusCode = 0
+
+	if _, err := r.db.Exec("DELETE FROM idempotency_keys WHERE expires_at <= ?", now); err != nil {
+		return false, err
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO idempotency_keys (idempotency_key, request_hash, status_code, response_body, created_at, expires_at)
+		VALUES (?, ?, 0, NULL, ?, ?)
+		ON CONFLICT(idempotency_key) DO NOTHING
+	`, record.Key, record.RequestHash, record.CreatedAt, record.ExpiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	inserted, err := result.RowsAffected()
+	return inserted > 0, err
+}
+
+// ReleaseIdempotencyKey removes the reservation of a request that failed so it can be retried with the same key,
+// stored responses are kept
+func (r *SQLRepository) ReleaseIdempotencyKey(key string) error {
+	_, err := r.db.Exec("DELETE FROM idempotency_keys WHERE idempotency_key = ? AND status_code = 0", key)
+	return err
+}
+
+// StoreIdempotencyRecord stores the response for an idempotency key, replacing its reservation
+func (r *SQLRepository) StoreIdempotencyRecord(record *domainChatStorage.IdempotencyRecord) error {
+	record.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO idempotency_keys (idempotency_key, request_hash, status_code, response_body, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(idempotency_key) DO UPDATE SET
+			request_hash = excluded.request_hash,
+			status_code = excluded.status_code,
+			response_body = excluded.response_body,
+			created_at = excluded.created_at,
+			expires_at = excluded.expires_at
+	`
+
+	_, err := r.db.Exec(query,
+		record.Key, record.RequestHash, record.StatusCode,
+		record.ResponseBody, record.CreatedAt, record.ExpiresAt,
+	)
+	return err
+}
+
+// buildStatusConditions is a private helper shared by status queries
+func (r *SQLRepository) buildStatusConditions(filter *domainChatStorage.StatusFilter) ([]string, []any) {
+	var conditions []string
+	var args []any
+
+	if len(filter.Senders) > 0 {
+		// A contact posts under its phone number or its LID, from any of its devices
+		senderConditions := make([]string, 0, len(filter.Senders))
+		for _, sender := range filter.Senders {
+			senderConditions = append(senderConditions, "sender = ? OR sender LIKE ?")
+			args = append(args, sender)
+			if user, server, found := strings.Cut(sender, "@"); found {
+				args = append(args, user+":%@"+server)
+			} else {
+				args = append(args, sender)
+			}
+		}
+		conditions = append(conditions, "("+strings.Join(senderConditions, " OR ")+")")
+	}
+
+	if filter.MediaOnly {
+		conditions = append(conditions, "media_type != ''")
+	}
+
+	if !filter.IncludeExpired {
+		conditions = append(conditions, "expires_at > ?")
+		args = append(args, time.Now())
+	}
+
+	return conditions, args
+}
+
+// scanStatus is a private helper for scanning status rows
+func (r *SQLRepository) scanStatus(scanner interface{ Scan(...any) error }) (*domainChatStorage.Status, error) {
+	status := &domainChatStorage.Status{}
+	err := scanner.Scan(
+		&status.ID, &status.Sender, &status.PushName, &status.Content, &status.MediaType,
+		&status.MimeType, &status.Filename, &status.URL, &status.MediaKey, &status.FileSHA256,
+		&status.FileEncSHA256, &status.FileLength, &status.MediaPath, &status.Viewed,
+		&status.Timestamp, &status.ExpiresAt, &status.CreatedAt, &status.UpdatedAt,
+	)
+	return status, err
+}
+
+// scanMessageReceipt is a private helper for scanning receipt rows
+func (r *SQLRepository) scanMessageReceipt(scanner interface{ Scan(...any) error }) (*domainChatStorage.MessageReceipt, error) {
+	receipt := &domainChatStorage.MessageReceipt{}
+	var deliveredAt, readAt, playedAt sql.NullTime
+	err := scanner.Scan(
+		&receipt.MessageID, &receipt.ChatJID, &receipt.RecipientJID, &deliveredAt,
+		&readAt, &playedAt, &receipt.CreatedAt, &receipt.UpdatedAt,
+	)
+	if deliveredAt.Valid {
+		receipt.DeliveredAt = &deliveredAt.Time
+	}
+	if readAt.Valid {
+		receipt.ReadAt = &readAt.Time
+	}
+	if playedAt.Valid {
+		receipt.PlayedAt = &playedAt.Time
+	}
+	return receipt, err
+}
+
+// _____________________________________________________________________________________________________________________
+
+// initializeSchema creates or migrates the database schema
+func (r *SQLRepository) InitializeSchema() error {
+	// Get current schema version
+	version, err := r.getSchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	fullTextSearch, err := r.fullTextSearchSupported()
+	if err != nil {
+		return err
+	}
+
+	// Run migrations based on version
+	migrations := r.getMigrations()
+	for i := version; i < len(migrations); i++ {
+		migration := migrations[i]
+		if migration == messageSearchMigration && !fullTextSearch {
+			// Keep the version moving, the index is created by the first start with FTS5
+			migration = "SELECT 1"
+		}
+		if err := r.runMigration(migration, i+1); err != nil {
+			return fmt.Errorf("failed to run migration %d: %w", i+1, err)
+		}
+	}
+
+	return r.ensureMessageSearchIndex(fullTextSearch)
+}
+
+// fullTextSearchSupported reports whether the linked SQLite was compiled with FTS5,
+// PostgreSQL always has its text search
+func (r *SQLRepository) fullTextSearchSupported() (bool, error) {
+	if r.db.postgres {
+		return true, nil
+	}
+
+	var enabled bool
+	if err := r.db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled); err != nil {
+		return false, err
+	}
+	return enabled, nil
+}
+
+// ensureMessageSearchIndex creates the full-text index skipped by a previous start without FTS5.
+// Without FTS5 the sync triggers are dropped, writing to messages would fail on them.
+func (r *SQLRepository) ensureMessageSearchIndex(fullTextSearch bool) error {
+	if r.db.postgres {
+		// The index is created by the migrations
+		r.fullTextSearch = true
+		return nil
+	}
+
+	if !fullTextSearch {
+		logrus.Warn("SQLite was built without FTS5, full-text search is disabled (build with -tags sqlite_fts5)")
+		_, err := r.db.Exec(`
+			DROP TRIGGER IF EXISTS messages_fts_insert;
+			DROP TRIGGER IF EXISTS messages_fts_delete;
+			DROP TRIGGER IF EXISTS messages_fts_update;
+		`)
+		return err
+	}
+
+	var count int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name = 'messages_fts_insert'").Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		logrus.Info("Building full-text search index of messages")
+		if _, err := r.db.Exec(messageSearchMigration); err != nil {
+			return fmt.Errorf("failed to create full-text search index: %w", err)
+		}
+	}
+
+	r.fullTextSearch = true
+	return nil
+}
+
+// getSchemaVersion returns the current schema version
+func (r *SQLRepository) getSchemaVersion() (int, error) {
+	// Create schema_info table if it doesn't exist
+	_, err := r.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_info (
+			version INTEGER PRIMARY KEY DEFAULT 0,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	// Get current version
+	var version int
+	err = r.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_info").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// runMigration executes a migration
+func (r *SQLRepository) runMigration(migration string, version int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Execute migration
+	if _, err := tx.Exec(migration); err != nil {
+		return err
+	}
+
+	// Update schema version
+	if _, err := tx.Exec("INSERT INTO schema_info (version) VALUES (?) ON CONFLICT(version) DO NOTHING", version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// messageSearchMigration indexes message content with FTS5, the triggers keep the index in sync with messages
+const messageSearchMigration = `
+		CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(
+			content,
+			content = 'messages',
+			content_rowid = 'rowid',
+			tokenize = 'unicode61 remove_diacritics 2'
+		);
+
+		CREATE TRIGGER IF NOT EXISTS messages_fts_insert AFTER INSERT ON messages BEGIN
+			INSERT INTO messages_fts(rowid, content) VALUES (new.rowid, new.content);
+		END;
+
+		CREATE TRIGGER IF NOT EXISTS messages_fts_delete AFTER DELETE ON messages BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+		END;
+
+		CREATE TRIGGER IF NOT EXISTS messages_fts_update AFTER UPDATE OF content ON messages BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+			INSERT INTO messages_fts(rowid, content) VALUES (new.rowid, new.content);
+		END;
+
+		INSERT INTO messages_fts(messages_fts) VALUES ('rebuild');
+		`
+
+// getMigrations returns all database migrations, a migration added here is added to postgresMigrations as well
+func (r *SQLRepository) getMigrations() []string {
+	if r.db.postgres {
+		return postgresMigrations
+	}
+
+	return []string{
+		// Migration 1: Initial schema with only chats and messages tables
+		`
+		-- Create chats table
+		CREATE TABLE IF NOT EXISTS chats (
+			jid TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			last_message_time TIMESTAMP NOT NULL,
+			ephemeral_expiration INTEGER DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		-- Create messages table
+		CREATE TABLE IF NOT EXISTS messages (
+			id TEXT NOT NULL,
+			chat_jid TEXT NOT NULL,
+			sender TEXT NOT NULL,
+			content TEXT,
+			timestamp TIMESTAMP NOT NULL,
+			is_from_me BOOLEAN DEFAULT FALSE,
+			media_type TEXT,
+			filename TEXT,
+			url TEXT,
+			media_key BLOB,
+			file_sha256 BLOB,
+			file_enc_sha256 BLOB,
+			file_length INTEGER DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id, chat_jid),
+			FOREIGN KEY (chat_jid) REFERENCES chats(jid) ON DELETE CASCADE
+		);
+
+		-- Create indexes for performance
+		CREATE INDEX IF NOT EXISTS idx_messages_chat_jid ON messages(chat_jid);
+		CREATE INDEX IF NOT EXISTS idx_messages_timestamp ON messages(timestamp);
+		CREATE INDEX IF NOT EXISTS idx_messages_media_type ON messages(media_type);
+		CREATE INDEX IF NOT EXISTS idx_messages_sender ON messages(sender);
+		CREATE INDEX IF NOT EXISTS idx_chats_last_message ON chats(last_message_time);
+		CREATE INDEX IF NOT EXISTS idx_chats_name ON chats(name);
+		`,
+
+		// Migration 2: Add index for message ID lookups (performance optimization)
+		`
+		CREATE INDEX IF NOT EXISTS idx_messages_id ON messages(id);
+		`,
+
+		// Migration 3: Status updates received from status@broadcast
+		`
+		CREATE TABLE IF NOT EXISTS statuses (
+			id TEXT NOT NULL,
+			sender TEXT NOT NULL,
+			push_name TEXT DEFAULT '',
+			content TEXT DEFAULT '',
+			media_type TEXT DEFAULT '',
+			mime_type TEXT DEFAULT '',
+			filename TEXT DEFAULT '',
+			url TEXT DEFAULT '',
+			media_key BLOB,
+			file_sha256 BLOB,
+			file_enc_sha256 BLOB,
+			file_length INTEGER DEFAULT 0,
+			media_path TEXT DEFAULT '',
+			viewed BOOLEAN DEFAULT FALSE,
+			timestamp TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id, sender)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_statuses_sender ON statuses(sender);
+		CREATE INDEX IF NOT EXISTS idx_statuses_timestamp ON statuses(timestamp);
+		CREATE INDEX IF NOT EXISTS idx_statuses_expires_at ON statuses(expires_at);
+		`,
+
+		// Migration 4: Responses of send requests made with an idempotency key
+		`
+		CREATE TABLE IF NOT EXISTS idempotency_keys (
+			idempotency_key TEXT PRIMARY KEY,
+			request_hash TEXT NOT NULL,
+			status_code INTEGER NOT NULL,
+			response_body BLOB,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+		`,
+
+		// Migration 5: Delivery, read and played receipts of outgoing messages per recipient
+		`
+		CREATE TABLE IF NOT EXISTS message_receipts (
+			message_id TEXT NOT NULL,
+			chat_jid TEXT NOT NULL,
+			recipient_jid TEXT NOT NULL,
+			delivered_at TIMESTAMP,
+			read_at TIMESTAMP,
+			played_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (message_id, chat_jid, recipient_jid)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_message_receipts_chat_jid ON message_receipts(chat_jid);
+		`,
+
+		// Migration 6: Full-text search index over message content, skipped when SQLite lacks FTS5
+		messageSearchMigration,
+
+		// Migration 7: Archive, mute and unread state of chats mirrored from app state
+		`
+		ALTER TABLE chats ADD COLUMN archived BOOLEAN DEFAULT FALSE;
+		ALTER TABLE chats ADD COLUMN muted BOOLEAN DEFAULT FALSE;
+		ALTER TABLE chats ADD COLUMN muted_until TIMESTAMP;
+		ALTER TABLE chats ADD COLUMN unread BOOLEAN DEFAULT FALSE;
+
+		CREATE INDEX IF NOT EXISTS idx_chats_archived ON chats(archived);
+		`,
+
+		// Migration 8: Pinned chats, contacts and labels mirrored from app state
+		`
+		ALTER TABLE chats ADD COLUMN pinned BOOLEAN DEFAULT FALSE;
+
+		CREATE TABLE IF NOT EXISTS contacts (
+			jid TEXT PRIMARY KEY,
+			full_name TEXT DEFAULT '',
+			first_name TEXT DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS labels (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL DEFAULT '',
+			color INTEGER DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS chat_labels (
+			chat_jid TEXT NOT NULL,
+			label_id TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (chat_jid, label_id)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_chat_labels_label_id ON chat_labels(label_id);
+		`,
+
+		// Migration 9: Reactions per message and reactor
+		`
+		CREATE TABLE IF NOT EXISTS message_reactions (
+			message_id TEXT NOT NULL,
+			chat_jid TEXT NOT NULL,
+			reactor_jid TEXT NOT NULL,
+			emoji TEXT NOT NULL,
+			timestamp TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (message_id, chat_jid, reactor_jid)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_message_reactions_chat_jid ON message_reactions(chat_jid);
+		`,
+
+		// Migration 10: Edit history and revoke tombstones of messages
+		`
+		CREATE TABLE IF NOT EXISTS message_revisions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			message_id TEXT NOT NULL,
+			chat_jid TEXT NOT NULL,
+			revision_type TEXT NOT NULL,
+			editor_jid TEXT NOT NULL DEFAULT '',
+			content TEXT DEFAULT '',
+			previous_content TEXT DEFAULT '',
+			timestamp TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (chat_jid, message_id, revision_type, timestamp)
+		);
+		`,
+
+		// Migration 11: Where the media of a message was downloaded to
+		`
+		ALTER TABLE messages ADD COLUMN media_path TEXT DEFAULT '';
+		`,
+
+		// Migration 12: Raw protobuf and info of messages
+		`
+		ALTER TABLE messages ADD COLUMN raw_message BLOB;
+		ALTER TABLE messages ADD COLUMN raw_info TEXT DEFAULT '';
+		`,
+
+		// Migration 13: Chats marked as unread, apart from whether they have unread messages
+		`
+		ALTER TABLE chats ADD COLUMN marked_unread BOOLEAN DEFAULT FALSE;
+		UPDATE chats SET marked_unread = unread;
+		ALTER TABLE chats DROP COLUMN unread;
+		`,
+
+		// Migration 14: Direct path of media, downloads fall back to it once the URL expired
+		`
+		ALTER TABLE messages ADD COLUMN direct_path TEXT DEFAULT '';
+		`,
+	}
+}
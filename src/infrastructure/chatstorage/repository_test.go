@@ -0,0 +1,684 @@
+package chatstorage_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+// postgresTestURI points the suite at a PostgreSQL database, its chat storage tables are dropped before every test
+const postgresTestURI = "CHATSTORAGE_TEST_POSTGRES_URI"
+
+var chatStorageTables = []string{
+	"schema_info", "messages", "chats", "statuses", "idempotency_keys", "message_receipts",
+	"contacts", "labels", "chat_labels", "message_reactions", "message_revisions",
+}
+
+// RepositoryTestSuite runs against every database the chat storage supports
+type RepositoryTestSuite struct {
+	suite.Suite
+	open func(t *testing.T) (*sql.DB, domainChatStorage.IChatStorageRepository)
+	db   *sql.DB
+	repo domainChatStorage.IChatStorageRepository
+}
+
+func (suite *RepositoryTestSuite) SetupTest() {
+	suite.db, suite.repo = suite.open(suite.T())
+	require.NoError(suite.T(), suite.repo.InitializeSchema())
+}
+
+func (suite *RepositoryTestSuite) TearDownTest() {
+	suite.db.Close()
+}
+
+// at returns a time without the precision databases disagree on
+func at(minutes int) time.Time {
+	return time.Date(2025, 1, 2, 3, 4, 0, 0, time.UTC).Add(time.Duration(minutes) * time.Minute)
+}
+
+func (suite *RepositoryTestSuite) storeChat(jid string, lastMessage time.Time) {
+	require.NoError(suite.T(), suite.repo.StoreChat(&domainChatStorage.Chat{JID: jid, Name: jid, LastMessageTime: lastMessage}))
+}
+
+func (suite *RepositoryTestSuite) storeMessage(id, chatJID, content string, timestamp time.Time) {
+	require.NoError(suite.T(), suite.repo.StoreMessage(&domainChatStorage.Message{
+		ID: id, ChatJID: chatJID, Sender: "6281111111111@s.whatsapp.net", Content: content, Timestamp: timestamp,
+	}))
+}
+
+func (suite *RepositoryTestSuite) TestInitializeSchemaTwice() {
+	assert.NoError(suite.T(), suite.repo.InitializeSchema())
+}
+
+func (suite *RepositoryTestSuite) TestChats() {
+	t := suite.T()
+	suite.storeChat("1@s.whatsapp.net", at(0))
+	suite.storeChat("2@s.whatsapp.net", at(10))
+	suite.storeChat("3@g.us", at(5))
+
+	require.NoError(t, suite.repo.StoreChat(&domainChatStorage.Chat{JID: "3@g.us", Name: "Family Group", LastMessageTime: at(5)}))
+	require.NoError(t, suite.repo.SetChatEphemeralExpiration("3@g.us", 604800, at(20)))
+	require.NoError(t, suite.repo.SetChatArchived("1@s.whatsapp.net", true))
+	require.NoError(t, suite.repo.SetChatPinned("3@g.us", true))
+	require.NoError(t, suite.repo.SetChatMuted("2@s.whatsapp.net", true, nil))
+	require.NoError(t, suite.repo.StoreLabel(&domainChatStorage.Label{ID: "1", Name: "Work", Color: 3}))
+	require.NoError(t, suite.repo.StoreLabel(&domainChatStorage.Label{ID: "2", Name: "Family"}))
+	require.NoError(t, suite.repo.SetChatLabel("3@g.us", "1", true))
+	require.NoError(t, suite.repo.SetChatLabel("3@g.us", "2", true))
+	require.NoError(t, suite.repo.SetChatLabel("3@g.us", "2", true))
+
+	chat, err := suite.repo.GetChat("3@g.us")
+	require.NoError(t, err)
+	require.NotNil(t, chat)
+	assert.Equal(t, "Family Group", chat.Name)
+	assert.Equal(t, uint32(604800), chat.EphemeralExpiration)
+	assert.True(t, chat.Pinned)
+	assert.ElementsMatch(t, []string{"Work", "Family"}, chat.Labels)
+	assert.True(t, chat.LastMessageTime.Equal(at(5)))
+
+	chat, err = suite.repo.GetChat("missing@s.whatsapp.net")
+	assert.NoError(t, err)
+	assert.Nil(t, chat)
+
+	// Pinned chats first, then the latest message
+	chats, err := suite.repo.GetChats(&domainChatStorage.ChatFilter{})
+	require.NoError(t, err)
+	require.Len(t, chats, 3)
+	assert.Equal(t, []string{"3@g.us", "2@s.whatsapp.net", "1@s.whatsapp.net"}, []string{chats[0].JID, chats[1].JID, chats[2].JID})
+
+	chats, err = suite.repo.GetChats(&domainChatStorage.ChatFilter{SearchName: "family"})
+	require.NoError(t, err)
+	require.Len(t, chats, 1)
+	assert.Equal(t, "3@g.us", chats[0].JID)
+
+	archived, muted := true, true
+	chats, err = suite.repo.GetChats(&domainChatStorage.ChatFilter{Archived: &archived})
+	require.NoError(t, err)
+	require.Len(t, chats, 1)
+	assert.Equal(t, "1@s.whatsapp.net", chats[0].JID)
+
+	chats, err = suite.repo.GetChats(&domainChatStorage.ChatFilter{Muted: &muted})
+	require.NoError(t, err)
+	require.Len(t, chats, 1)
+	assert.Equal(t, "2@s.whatsapp.net", chats[0].JID)
+
+	chats, err = suite.repo.GetChats(&domainChatStorage.ChatFilter{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, chats, 1)
+	assert.Equal(t, "2@s.whatsapp.net", chats[0].JID)
+
+	require.NoError(t, suite.repo.DeleteLabel("1"))
+	chat, err = suite.repo.GetChat("3@g.us")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Family"}, chat.Labels)
+
+	require.NoError(t, suite.repo.DeleteChat("3@g.us"))
+	count, err := suite.repo.GetTotalChatCount()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func (suite *RepositoryTestSuite) TestChatStateBeforeFirstMessage() {
+	t := suite.T()
+	markedUnread := true
+
+	// App state arrives before any message of these chats
+	require.NoError(t, suite.repo.SetChatPinned("1@s.whatsapp.net", true))
+	require.NoError(t, suite.repo.SetChatArchived("2@s.whatsapp.net", true))
+	require.NoError(t, suite.repo.SetChatMuted("2@s.whatsapp.net", true, nil))
+	require.NoError(t, suite.repo.SetChatMarkedUnread("3@g.us", true))
+	require.NoError(t, suite.repo.SetChatLabel("3@g.us", "7", true))
+
+	chats, err := suite.repo.GetChats(&domainChatStorage.ChatFilter{MarkedUnread: &markedUnread})
+	require.NoError(t, err)
+	require.Len(t, chats, 1)
+	assert.Equal(t, "3@g.us", chats[0].JID)
+	assert.Equal(t, []string{"7"}, chats[0].Labels, "labels not synced yet are listed by ID")
+
+	// The first message fills in the chat and keeps its state
+	require.NoError(t, suite.repo.StoreChat(&domainChatStorage.Chat{JID: "1@s.whatsapp.net", Name: "Jane", LastMessageTime: at(1)}))
+	chat, err := suite.repo.GetChat("1@s.whatsapp.net")
+	require.NoError(t, err)
+	require.NotNil(t, chat)
+	assert.Equal(t, "Jane", chat.Name)
+	assert.True(t, chat.Pinned)
+	assert.True(t, chat.LastMessageTime.Equal(at(1)))
+
+	chat, err = suite.repo.GetChat("2@s.whatsapp.net")
+	require.NoError(t, err)
+	require.NotNil(t, chat)
+	assert.True(t, chat.Archived)
+	assert.True(t, chat.Muted)
+	assert.False(t, chat.MarkedUnread)
+
+	require.NoError(t, suite.repo.StoreLabel(&domainChatStorage.Label{ID: "7", Name: "Clients"}))
+	require.NoError(t, suite.repo.SetChatMarkedUnread("3@g.us", false))
+	chat, err = suite.repo.GetChat("3@g.us")
+	require.NoError(t, err)
+	require.NotNil(t, chat)
+	assert.False(t, chat.MarkedUnread)
+	assert.Equal(t, []string{"Clients"}, chat.Labels)
+}
+
+func (suite *RepositoryTestSuite) TestEphemeralTimerCreatesChat() {
+	t := suite.T()
+	suite.storeChat("1@s.whatsapp.net", at(5))
+
+	// The timer of a group is announced before any of its messages arrive
+	require.NoError(t, suite.repo.SetChatEphemeralExpiration("2@g.us", 86400, at(10)))
+	chat, err := suite.repo.GetChat("2@g.us")
+	require.NoError(t, err)
+	require.NotNil(t, chat)
+	assert.Equal(t, uint32(86400), chat.EphemeralExpiration)
+	assert.True(t, chat.LastMessageTime.Equal(at(10)), "the chat is dated by the change, not the zero time")
+
+	// A later change keeps the time of the last message
+	require.NoError(t, suite.repo.SetChatEphemeralExpiration("1@s.whatsapp.net", 604800, at(20)))
+	chat, err = suite.repo.GetChat("1@s.whatsapp.net")
+	require.NoError(t, err)
+	assert.True(t, chat.LastMessageTime.Equal(at(5)))
+
+	chats, err := suite.repo.GetChats(&domainChatStorage.ChatFilter{})
+	require.NoError(t, err)
+	require.Len(t, chats, 2)
+	assert.Equal(t, "2@g.us", chats[0].JID)
+}
+
+func (suite *RepositoryTestSuite) TestMessages() {
+	t := suite.T()
+	chatJID := "1@s.whatsapp.net"
+	suite.storeChat(chatJID, at(0))
+
+	suite.storeMessage("A", chatJID, "Hello World", at(1))
+	require.NoError(t, suite.repo.StoreMessagesBatch([]*domainChatStorage.Message{
+		{ID: "B", ChatJID: chatJID, Sender: chatJID, Content: "second", Timestamp: at(2)},
+		{ID: "C", ChatJID: chatJID, Sender: chatJID, Timestamp: at(3), MediaType: "image",
+			MediaKey: []byte{0, 1, 2, 255}, FileLength: 1 << 40, URL: "https://mmg.whatsapp.net/c", DirectPath: "/v/t62.7118-24/c"},
+		{ID: "D", ChatJID: chatJID, Sender: chatJID, Timestamp: at(4)},
+	}))
+	// Stored again with new content
+	suite.storeMessage("A", chatJID, "Hello again", at(1))
+
+	message, err := suite.repo.GetMessageByID("C")
+	require.NoError(t, err)
+	require.NotNil(t, message)
+	assert.Equal(t, []byte{0, 1, 2, 255}, message.MediaKey)
+	assert.Equal(t, uint64(1<<40), message.FileLength)
+	assert.True(t, message.Timestamp.Equal(at(3)))
+
+	message, err = suite.repo.GetMessageByID("D")
+	assert.NoError(t, err)
+	assert.Nil(t, message, "empty messages are skipped")
+
+	messages, err := suite.repo.GetMessages(&domainChatStorage.MessageFilter{ChatJID: chatJID})
+	require.NoError(t, err)
+	require.Len(t, messages, 3)
+	assert.Equal(t, []string{"C", "B", "A"}, []string{messages[0].ID, messages[1].ID, messages[2].ID})
+	assert.Equal(t, "Hello again", messages[2].Content)
+
+	start := at(2)
+	messages, err = suite.repo.GetMessages(&domainChatStorage.MessageFilter{ChatJID: chatJID, StartTime: &start, MediaOnly: true})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "C", messages[0].ID)
+
+	messages, err = suite.repo.SearchMessages(chatJID, "HELLO", 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "A", messages[0].ID)
+
+	message, err = suite.repo.GetMessageByID("C")
+	require.NoError(t, err)
+	assert.Equal(t, "/v/t62.7118-24/c", message.DirectPath)
+
+	// Media uploaded again by the phone is only known by its direct path
+	require.NoError(t, suite.repo.UpdateMessageDirectPath("C", chatJID, "/v/t62.7118-24/new"))
+	require.NoError(t, suite.repo.UpdateMessageMediaPath("C", chatJID, "statics/media/c.jpg"))
+	message, err = suite.repo.GetMessageByID("C")
+	require.NoError(t, err)
+	assert.Empty(t, message.URL)
+	assert.Equal(t, "/v/t62.7118-24/new", message.DirectPath)
+	assert.Equal(t, "statics/media/c.jpg", message.MediaPath)
+
+	paths, err := suite.repo.GetMediaPaths()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"statics/media/c.jpg"}, paths)
+	require.NoError(t, suite.repo.ClearMediaPath("statics/media/c.jpg"))
+	paths, err = suite.repo.GetMediaPaths()
+	require.NoError(t, err)
+	assert.Empty(t, paths)
+
+	count, err := suite.repo.GetChatMessageCount(chatJID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	require.NoError(t, suite.repo.DeleteMessage("B", chatJID))
+	require.NoError(t, suite.repo.ClearChatMessages(chatJID, at(1)))
+	messages, err = suite.repo.GetMessages(&domainChatStorage.MessageFilter{ChatJID: chatJID})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "C", messages[0].ID)
+}
+
+func (suite *RepositoryTestSuite) TestStoreSentMessageWithContext() {
+	t := suite.T()
+	sent := &waE2E.Message{Conversation: proto.String("sent")}
+	require.NoError(t, suite.repo.StoreSentMessageWithContext(context.Background(), "S", "me@s.whatsapp.net", "6282222222222@s.whatsapp.net", "sent", at(0), sent))
+
+	message, err := suite.repo.GetMessageByID("S")
+	require.NoError(t, err)
+	require.NotNil(t, message)
+	assert.True(t, message.IsFromMe)
+
+	chat, err := suite.repo.GetChat("6282222222222@s.whatsapp.net")
+	require.NoError(t, err)
+	require.NotNil(t, chat)
+	assert.Equal(t, "6282222222222", chat.Name)
+
+	full, err := suite.repo.GetFullMessage("S", "")
+	require.NoError(t, err)
+	require.NotNil(t, full)
+	raw, err := utils.DecodeRawMessage(full.RawMessage)
+	require.NoError(t, err)
+	assert.Equal(t, "sent", raw.GetConversation())
+	assert.Contains(t, full.RawInfo, `"IsFromMe":true`)
+}
+
+func (suite *RepositoryTestSuite) TestGetFullMessage() {
+	t := suite.T()
+	chatJID := "1@s.whatsapp.net"
+	suite.storeChat(chatJID, at(0))
+
+	rawMessage, err := utils.EncodeRawMessage(&waE2E.Message{LocationMessage: &waE2E.LocationMessage{DegreesLatitude: proto.Float64(-6.2)}})
+	require.NoError(t, err)
+	// A location has no content, it is stored for its raw message
+	require.NoError(t, suite.repo.StoreMessage(&domainChatStorage.Message{
+		ID: "L", ChatJID: chatJID, Sender: chatJID, Timestamp: at(1), RawMessage: rawMessage, RawInfo: `{"ID":"L"}`,
+	}))
+	// Storing it again without its raw message keeps it
+	require.NoError(t, suite.repo.StoreMessage(&domainChatStorage.Message{ID: "L", ChatJID: chatJID, Sender: chatJID, Content: "location", Timestamp: at(1)}))
+
+	message, err := suite.repo.GetFullMessage("L", chatJID)
+	require.NoError(t, err)
+	require.NotNil(t, message)
+	assert.Equal(t, "location", message.Content)
+	assert.Equal(t, `{"ID":"L"}`, message.RawInfo)
+	raw, err := utils.DecodeRawMessage(message.RawMessage)
+	require.NoError(t, err)
+	assert.Equal(t, -6.2, raw.GetLocationMessage().GetDegreesLatitude())
+
+	message, err = suite.repo.GetFullMessage("L", "2@s.whatsapp.net")
+	require.NoError(t, err)
+	assert.Nil(t, message)
+
+	require.NoError(t, suite.repo.StoreMessageRevoke(&domainChatStorage.MessageRevision{MessageID: "L", ChatJID: chatJID, Timestamp: at(2)}, false))
+	message, err = suite.repo.GetFullMessage("L", "")
+	require.NoError(t, err)
+	require.NotNil(t, message)
+	assert.Empty(t, message.RawMessage)
+}
+
+func (suite *RepositoryTestSuite) TestSearchPagesWhileMessagesChange() {
+	t := suite.T()
+	chatJID := "1@s.whatsapp.net"
+	suite.storeChat(chatJID, at(0))
+	// Equal content scores equally, the stored order decides
+	for i, id := range []string{"M1", "M2", "M3", "M4", "M5"} {
+		suite.storeMessage(id, chatJID, "monthly report", at(i+1))
+	}
+
+	search := func(after *domainChatStorage.MessageSearchCursor) []*domainChatStorage.MessageSearchResult {
+		results, err := suite.repo.SearchAllMessages(&domainChatStorage.MessageSearchFilter{
+			Query: utils.BuildFullTextQuery("report"), Limit: 2, After: after,
+		})
+		if errors.Is(err, domainChatStorage.ErrFullTextSearchUnavailable) {
+			t.Skip("full-text search is not available in this build")
+		}
+		require.NoError(t, err)
+		return results
+	}
+	ids := func(results []*domainChatStorage.MessageSearchResult) (ids []string) {
+		for _, result := range results {
+			ids = append(ids, result.Message.ID)
+		}
+		return ids
+	}
+
+	page := search(nil)
+	assert.Equal(t, []string{"M1", "M2"}, ids(page))
+
+	// A message is deleted from the page already read and another one is stored
+	require.NoError(t, suite.repo.DeleteMessage("M1", chatJID))
+	suite.storeMessage("M6", chatJID, "monthly report", at(6))
+	page = search(&page[len(page)-1].Cursor)
+	assert.Equal(t, []string{"M3", "M4"}, ids(page), "nothing is skipped or repeated")
+
+	require.NoError(t, suite.repo.DeleteMessage("M2", chatJID))
+	page = search(&page[len(page)-1].Cursor)
+	assert.Equal(t, []string{"M5", "M6"}, ids(page))
+	assert.Empty(t, search(&page[len(page)-1].Cursor))
+}
+
+func (suite *RepositoryTestSuite) TestSearchAllMessages() {
+	t := suite.T()
+	suite.storeChat("1@s.whatsapp.net", at(0))
+	suite.storeChat("2@s.whatsapp.net", at(0))
+	suite.storeMessage("A", "1@s.whatsapp.net", "the invoice is attached", at(1))
+	suite.storeMessage("B", "2@s.whatsapp.net", "invoice invoice reminder", at(2))
+	suite.storeMessage("C", "2@s.whatsapp.net", "see you tomorrow", at(3))
+
+	results, err := suite.repo.SearchAllMessages(&domainChatStorage.MessageSearchFilter{Query: utils.BuildFullTextQuery("invoice"), Limit: 10})
+	if errors.Is(err, domainChatStorage.ErrFullTextSearchUnavailable) {
+		t.Skip("full-text search is not available in this build")
+	}
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "B", results[0].Message.ID, "more matches rank higher")
+	assert.Contains(t, results[0].Snippet, "<mark>")
+
+	suite.storeMessage("D", "1@s.whatsapp.net", "invoice invoice invoice", at(5))
+
+	// Paging one result at a time returns every match once
+	var paged []string
+	var after *domainChatStorage.MessageSearchCursor
+	for {
+		results, err = suite.repo.SearchAllMessages(&domainChatStorage.MessageSearchFilter{
+			Query: utils.BuildFullTextQuery("invoice"), Limit: 1, After: after,
+		})
+		require.NoError(t, err)
+		if len(results) == 0 {
+			break
+		}
+		paged = append(paged, results[0].Message.ID)
+		after = &results[0].Cursor
+	}
+	assert.Equal(t, []string{"D", "B", "A"}, paged)
+
+	results, err = suite.repo.SearchAllMessages(&domainChatStorage.MessageSearchFilter{
+		Query: utils.BuildFullTextQuery("invoice OR tomor*"), ChatJID: "2@s.whatsapp.net", Limit: 10,
+	})
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	results, err = suite.repo.SearchAllMessages(&domainChatStorage.MessageSearchFilter{Query: utils.BuildFullTextQuery(`"you tomorrow"`), Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "C", results[0].Message.ID)
+
+	// Edited content is searchable
+	require.NoError(t, suite.repo.StoreMessageEdit(&domainChatStorage.MessageRevision{
+		MessageID: "C", ChatJID: "2@s.whatsapp.net", Content: "see you next week", Timestamp: at(4),
+	}))
+	results, err = suite.repo.SearchAllMessages(&domainChatStorage.MessageSearchFilter{Query: utils.BuildFullTextQuery("tomorrow"), Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func (suite *RepositoryTestSuite) TestStoreMessageEditReplacesRawMessage() {
+	t := suite.T()
+	chatJID := "1@s.whatsapp.net"
+	suite.storeChat(chatJID, at(0))
+
+	rawMessage := func(text string) []byte {
+		data, err := utils.EncodeRawMessage(&waE2E.Message{Conversation: proto.String(text)})
+		require.NoError(t, err)
+		return data
+	}
+	require.NoError(t, suite.repo.StoreMessage(&domainChatStorage.Message{
+		ID: "E", ChatJID: chatJID, Sender: chatJID, Content: "first", Timestamp: at(0), RawMessage: rawMessage("first"),
+	}))
+
+	require.NoError(t, suite.repo.StoreMessageEdit(&domainChatStorage.MessageRevision{
+		MessageID: "E", ChatJID: chatJID, Content: "second", Timestamp: at(2), RawMessage: rawMessage("second"),
+	}))
+	// An older edit arriving late keeps the protobuf of the latest one
+	require.NoError(t, suite.repo.StoreMessageEdit(&domainChatStorage.MessageRevision{
+		MessageID: "E", ChatJID: chatJID, Content: "older", Timestamp: at(1), RawMessage: rawMessage("older"),
+	}))
+
+	message, err := suite.repo.GetFullMessage("E", chatJID)
+	require.NoError(t, err)
+	require.NotNil(t, message)
+	assert.Equal(t, "second", message.Content)
+	raw, err := utils.DecodeRawMessage(message.RawMessage)
+	require.NoError(t, err)
+	assert.Equal(t, "second", raw.GetConversation())
+
+	// Without the edited protobuf the outdated one is dropped rather than served
+	require.NoError(t, suite.repo.StoreMessageEdit(&domainChatStorage.MessageRevision{
+		MessageID: "E", ChatJID: chatJID, Content: "third", Timestamp: at(3),
+	}))
+	message, err = suite.repo.GetFullMessage("E", chatJID)
+	require.NoError(t, err)
+	assert.Equal(t, "third", message.Content)
+	assert.Empty(t, message.RawMessage)
+}
+
+func (suite *RepositoryTestSuite) TestRevisionsReactionsAndReceipts() {
+	t := suite.T()
+	chatJID := "1@s.whatsapp.net"
+	suite.storeChat(chatJID, at(0))
+	suite.storeMessage("A", chatJID, "first", at(0))
+
+	edit := &domainChatStorage.MessageRevision{MessageID: "A", ChatJID: chatJID, Content: "second", Timestamp: at(2)}
+	require.NoError(t, suite.repo.StoreMessageEdit(edit))
+	assert.NotZero(t, edit.ID)
+	// An older edit arriving late keeps the latest content
+	require.NoError(t, suite.repo.StoreMessageEdit(&domainChatStorage.MessageRevision{MessageID: "A", ChatJID: chatJID, Content: "older", Timestamp: at(1)}))
+	// Recorded already
+	duplicate := &domainChatStorage.MessageRevision{MessageID: "A", ChatJID: chatJID, Content: "second", Timestamp: at(2)}
+	require.NoError(t, suite.repo.StoreMessageEdit(duplicate))
+	assert.Zero(t, duplicate.ID)
+
+	message, err := suite.repo.GetMessageByID("A")
+	require.NoError(t, err)
+	assert.Equal(t, "second", message.Content)
+
+	revisions, err := suite.repo.GetMessageRevisions(chatJID, []string{"A"})
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "older", revisions[0].Content)
+	assert.Equal(t, "first", revisions[1].PreviousContent)
+
+	require.NoError(t, suite.repo.StoreMessageRevoke(&domainChatStorage.MessageRevision{MessageID: "A", ChatJID: chatJID, Timestamp: at(3)}, false))
+	message, err = suite.repo.GetMessageByID("A")
+	require.NoError(t, err)
+	assert.Empty(t, message.Content)
+
+	require.NoError(t, suite.repo.StoreReaction(&domainChatStorage.Reaction{MessageID: "A", ChatJID: chatJID, ReactorJID: "2@s.whatsapp.net", Emoji: "👍", Timestamp: at(2)}))
+	require.NoError(t, suite.repo.StoreReaction(&domainChatStorage.Reaction{MessageID: "A", ChatJID: chatJID, ReactorJID: "2@s.whatsapp.net", Emoji: "❤️", Timestamp: at(1)}))
+	reactions, err := suite.repo.GetMessageReactions(chatJID, []string{"A", "B"})
+	require.NoError(t, err)
+	require.Len(t, reactions, 1)
+	assert.Equal(t, "👍", reactions[0].Emoji)
+
+	require.NoError(t, suite.repo.StoreMessage(&domainChatStorage.Message{
+		ID: "O", ChatJID: chatJID, Sender: "6282222222222@s.whatsapp.net", Content: "sent", Timestamp: at(0), IsFromMe: true,
+	}))
+	require.NoError(t, suite.repo.StoreMessageReceipts(chatJID, "2@s.whatsapp.net", []string{"O"}, domainChatStorage.ReceiptDelivered, at(1)))
+	require.NoError(t, suite.repo.StoreMessageReceipts(chatJID, "2@s.whatsapp.net", []string{"O"}, domainChatStorage.ReceiptRead, at(2)))
+	// Incoming and unknown messages have no receipts of ours to track
+	require.NoError(t, suite.repo.StoreMessageReceipts(chatJID, "2@s.whatsapp.net", []string{"A", "unknown"}, domainChatStorage.ReceiptRead, at(2)))
+	receipts, err := suite.repo.GetMessageReceipts(chatJID, []string{"A", "O", "unknown"})
+	require.NoError(t, err)
+	require.Len(t, receipts, 1)
+	assert.Equal(t, "O", receipts[0].MessageID)
+	require.NotNil(t, receipts[0].ReadAt)
+	assert.True(t, receipts[0].DeliveredAt.Equal(at(1)))
+	assert.True(t, receipts[0].ReadAt.Equal(at(2)))
+	assert.Nil(t, receipts[0].PlayedAt)
+}
+
+func (suite *RepositoryTestSuite) TestStatuses() {
+	t := suite.T()
+	now := time.Now().Truncate(time.Second)
+	require.NoError(t, suite.repo.StoreStatus(&domainChatStorage.Status{
+		ID: "S1", Sender: "1@s.whatsapp.net", MediaType: "image", MediaPath: "statics/statuses/s1.jpg",
+		Timestamp: now, ExpiresAt: now.Add(time.Hour),
+	}))
+	require.NoError(t, suite.repo.StoreStatus(&domainChatStorage.Status{
+		ID: "S1", Sender: "1@s.whatsapp.net", MediaType: "image", Viewed: true,
+		Timestamp: now, ExpiresAt: now.Add(time.Hour),
+	}))
+	require.NoError(t, suite.repo.StoreStatus(&domainChatStorage.Status{
+		ID: "S2", Sender: "2@s.whatsapp.net", Content: "expired", Timestamp: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour),
+	}))
+
+	statuses, err := suite.repo.GetStatuses(&domainChatStorage.StatusFilter{})
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.True(t, statuses[0].Viewed)
+	assert.Equal(t, "statics/statuses/s1.jpg", statuses[0].MediaPath, "a stored download is kept")
+
+	count, err := suite.repo.GetStatusCount(&domainChatStorage.StatusFilter{IncludeExpired: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// A contact posts under its phone number or its LID, from any device
+	require.NoError(t, suite.repo.StoreStatus(&domainChatStorage.Status{
+		ID: "S3", Sender: "100@lid", Timestamp: now, ExpiresAt: now.Add(time.Hour),
+	}))
+	require.NoError(t, suite.repo.StoreStatus(&domainChatStorage.Status{
+		ID: "S4", Sender: "1:5@s.whatsapp.net", Timestamp: now.Add(time.Second), ExpiresAt: now.Add(time.Hour),
+	}))
+	statuses, err = suite.repo.GetStatuses(&domainChatStorage.StatusFilter{Senders: []string{"1@s.whatsapp.net", "100@lid"}})
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	assert.ElementsMatch(t, []string{"S1", "S3", "S4"}, []string{statuses[0].ID, statuses[1].ID, statuses[2].ID})
+	count, err = suite.repo.GetStatusCount(&domainChatStorage.StatusFilter{Senders: []string{"1@s.whatsapp.net"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	require.NoError(t, suite.repo.DeleteStatus("S3", "100@lid"))
+	require.NoError(t, suite.repo.DeleteStatus("S4", "1:5@s.whatsapp.net"))
+
+	require.NoError(t, suite.repo.DeleteStatus("S1", "1@s.whatsapp.net"))
+	count, err = suite.repo.GetStatusCount(&domainChatStorage.StatusFilter{})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func (suite *RepositoryTestSuite) TestContactsAndIdempotency() {
+	t := suite.T()
+	suite.storeChat("1@s.whatsapp.net", at(0))
+	require.NoError(t, suite.repo.StoreContact(&domainChatStorage.Contact{JID: "1@s.whatsapp.net", FullName: "Jane Doe", FirstName: "Jane"}))
+
+	contact, err := suite.repo.GetContact("1@s.whatsapp.net")
+	require.NoError(t, err)
+	require.NotNil(t, contact)
+	assert.Equal(t, "Jane", contact.FirstName)
+
+	chat, err := suite.repo.GetChat("1@s.whatsapp.net")
+	require.NoError(t, err)
+	assert.Equal(t, "Jane Doe", chat.Name, "the chat is renamed to the saved name")
+
+	require.NoError(t, suite.repo.StoreIdempotencyRecord(&domainChatStorage.IdempotencyRecord{
+		Key: "key", RequestHash: "hash", StatusCode: 200, ResponseBody: []byte(`{"code":"SUCCESS"}`), ExpiresAt: time.Now().Add(time.Hour),
+	}))
+	record, err := suite.repo.GetIdempotencyRecord("key")
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.Equal(t, []byte(`{"code":"SUCCESS"}`), record.ResponseBody)
+
+	record, err = suite.repo.GetIdempotencyRecord("missing")
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+}
+
+func (suite *RepositoryTestSuite) TestIdempotencyReservation() {
+	t := suite.T()
+	reservation := func(key string, expiresIn time.Duration) *domainChatStorage.IdempotencyRecord {
+		return &domainChatStorage.IdempotencyRecord{Key: key, RequestHash: "hash", ExpiresAt: time.Now().Add(expiresIn)}
+	}
+
+	reserved, err := suite.repo.ReserveIdempotencyKey(reservation("key", time.Minute))
+	require.NoError(t, err)
+	assert.True(t, reserved)
+
+	// Another request, or another instance, can not take the key while it runs
+	reserved, err = suite.repo.ReserveIdempotencyKey(reservation("key", time.Minute))
+	require.NoError(t, err)
+	assert.False(t, reserved)
+	record, err := suite.repo.GetIdempotencyRecord("key")
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.True(t, record.InFlight())
+
+	// A failed request frees the key
+	require.NoError(t, suite.repo.ReleaseIdempotencyKey("key"))
+	reserved, err = suite.repo.ReserveIdempotencyKey(reservation("key", time.Minute))
+	require.NoError(t, err)
+	assert.True(t, reserved)
+
+	// A stored response is kept when released
+	require.NoError(t, suite.repo.StoreIdempotencyRecord(&domainChatStorage.IdempotencyRecord{
+		Key: "key", RequestHash: "hash", StatusCode: 200, ResponseBody: []byte(`{}`), ExpiresAt: time.Now().Add(time.Hour),
+	}))
+	require.NoError(t, suite.repo.ReleaseIdempotencyKey("key"))
+	record, err = suite.repo.GetIdempotencyRecord("key")
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.False(t, record.InFlight())
+
+	// The reservation of a request that never completed expires
+	reserved, err = suite.repo.ReserveIdempotencyKey(reservation("crashed", -time.Second))
+	require.NoError(t, err)
+	assert.True(t, reserved)
+	reserved, err = suite.repo.ReserveIdempotencyKey(reservation("crashed", time.Minute))
+	require.NoError(t, err)
+	assert.True(t, reserved)
+}
+
+func (suite *RepositoryTestSuite) TestTruncateAllChats() {
+	t := suite.T()
+	suite.storeChat("1@s.whatsapp.net", at(0))
+	suite.storeMessage("A", "1@s.whatsapp.net", "hello", at(0))
+
+	chatCount, messageCount, err := suite.repo.GetStorageStatistics()
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 1}, []int64{chatCount, messageCount})
+
+	require.NoError(t, suite.repo.TruncateAllDataWithLogging("TEST"))
+	chatCount, messageCount, err = suite.repo.GetStorageStatistics()
+	require.NoError(t, err)
+	assert.Equal(t, []int64{0, 0}, []int64{chatCount, messageCount})
+}
+
+func TestSQLiteRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, &RepositoryTestSuite{open: func(t *testing.T) (*sql.DB, domainChatStorage.IChatStorageRepository) {
+		db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "chatstorage.db")+"?_foreign_keys=on")
+		require.NoError(t, err)
+		return db, chatstorage.NewStorageRepository(db)
+	}})
+}
+
+func TestPostgresRepositoryTestSuite(t *testing.T) {
+	uri := os.Getenv(postgresTestURI)
+	if uri == "" {
+		t.Skipf("set %s to run the repository tests against PostgreSQL", postgresTestURI)
+	}
+
+	suite.Run(t, &RepositoryTestSuite{open: func(t *testing.T) (*sql.DB, domainChatStorage.IChatStorageRepository) {
+		db, err := sql.Open("postgres", uri)
+		require.NoError(t, err)
+		for _, table := range chatStorageTables {
+			_, err = db.Exec("DROP TABLE IF EXISTS " + table + " CASCADE")
+			require.NoError(t, err)
+		}
+		return db, chatstorage.NewPostgresRepository(db)
+	}})
+}
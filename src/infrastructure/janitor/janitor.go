@@ -0,0 +1,420 @@
+package janitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainApp "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/app"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/mediastore"
+)
+
+// gracePeriod keeps files that were just written, they may still be downloading, sending or waiting to be recorded
+const gracePeriod = 10 * time.Minute
+
+// mediaCacheStreamRetention keeps media decrypted for streaming when the media cache setting is off,
+// long enough for a player to come back for the rest of a video
+const mediaCacheStreamRetention = time.Hour
+
+// mediaTypes can be given a retention of their own in directories of downloaded media
+var mediaTypes = []string{"image", "video", "audio", "document", "sticker"}
+
+// directory is a folder whose files the janitor manages
+type directory struct {
+	name    string
+	path    string
+	pattern string // Only files matching it belong to the directory
+	media   bool   // Files are downloaded media referenced by messages and statuses, subdirectories are included
+}
+
+// directories are listed on every run, the paths are taken from the config as it is at that time.
+// The janitor only manages local files, media kept in S3 is left to the lifecycle rules of the bucket.
+func directories() []directory {
+	return []directory{
+		{name: "media", path: config.PathMedia, pattern: "*", media: true},
+		{name: "statuses", path: config.PathStatuses, pattern: "*", media: true},
+		{name: "senditems", path: config.PathSendItems, pattern: "*"},
+		{name: "media-cache", path: config.PathMediaCache, pattern: "*"},
+		{name: "history", path: config.PathStorages, pattern: "history-*"},
+		{name: "qrcode", path: config.PathQrCode, pattern: "scan-*"},
+	}
+}
+
+type file struct {
+	dir     directory
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// Janitor removes files past their retention, downloaded media nothing refers to anymore,
+// and the oldest modified files once the directories outgrow the storage quota
+type Janitor struct {
+	chatStorageRepo domainChatStorage.IChatStorageRepository
+	retention       map[string]time.Duration
+
+	// mu lets one cleanup run at a time, whether started by the interval or by a request
+	mu          sync.Mutex
+	lastCleanup *domainApp.StorageCleanupResponse
+}
+
+// New checks the retention settings of the janitor
+func New(chatStorageRepo domainChatStorage.IChatStorageRepository) (*Janitor, error) {
+	retention, err := parseRetention(config.StorageRetention)
+	if err != nil {
+		return nil, err
+	}
+	if config.StorageQuota < 0 {
+		return nil, fmt.Errorf("storage quota must not be negative")
+	}
+
+	return &Janitor{chatStorageRepo: chatStorageRepo, retention: retention}, nil
+}
+
+// parseRetention reads entries like media=720h, keyed by directory name or media type
+func parseRetention(entries []string) (map[string]time.Duration, error) {
+	known := make(map[string]bool)
+	for _, dir := range directories() {
+		known[dir.name] = true
+	}
+	for _, mediaType := range mediaTypes {
+		known[mediaType] = true
+	}
+
+	retention := make(map[string]time.Duration)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, value, found := strings.Cut(entry, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !found || !known[name] {
+			return nil, fmt.Errorf("invalid storage retention %q, expected <directory or media type>=<duration>", entry)
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid storage retention %q, the duration must be positive like 168h", entry)
+		}
+		retention[name] = duration
+	}
+	return retention, nil
+}
+
+// Start runs a cleanup now and then every StorageCleanupInterval until ctx is done
+func (j *Janitor) Start(ctx context.Context) {
+	if config.MediaStorage == "s3" {
+		logrus.Info("[JANITOR] Media is kept in S3, use a lifecycle rule on the bucket to expire it, only local files are cleaned up")
+	}
+	if config.StorageCleanupInterval <= 0 {
+		logrus.Info("[JANITOR] Storage cleanup interval is 0, files are only cleaned up on request")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(config.StorageCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := j.Cleanup(ctx); err != nil {
+				logrus.Errorf("[JANITOR] Storage cleanup failed: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Usage reports the files per directory, the orphaned media and the last cleanup
+func (j *Janitor) Usage(_ context.Context) (response domainApp.StorageUsageResponse, err error) {
+	referenced, err := j.referencedMedia()
+	if err != nil {
+		return response, err
+	}
+
+	now := time.Now()
+	response.Quota = config.StorageQuota
+	response.Directories = make([]domainApp.StorageDirectoryUsage, 0, len(directories()))
+	for _, dir := range directories() {
+		files, err := listFiles(dir)
+		if err != nil {
+			return response, err
+		}
+
+		usage := domainApp.StorageDirectoryUsage{Name: dir.name, Path: dir.path, Files: len(files)}
+		if retention, ok := j.directoryRetention(dir); ok {
+			usage.Retention = retention.String()
+		}
+		for _, f := range files {
+			usage.Size += f.size
+			if isOrphan(f, referenced, now) {
+				response.OrphanFiles++
+				response.OrphanSize += f.size
+			}
+		}
+
+		response.Directories = append(response.Directories, usage)
+		response.TotalFiles += usage.Files
+		response.TotalSize += usage.Size
+	}
+
+	j.mu.Lock()
+	response.LastCleanup = j.lastCleanup
+	j.mu.Unlock()
+	return response, nil
+}
+
+// Cleanup removes expired files, orphaned media when enabled, and evicts the oldest modified files over the quota
+func (j *Janitor) Cleanup(_ context.Context) (report domainApp.StorageCleanupResponse, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	report.StartedAt = time.Now()
+
+	var referenced map[string]bool
+	if config.StorageRemoveOrphans {
+		if referenced, err = j.referencedMedia(); err != nil {
+			return report, err
+		}
+	}
+
+	var kept []file
+	for _, dir := range directories() {
+		files, err := listFiles(dir)
+		if err != nil {
+			return report, err
+		}
+
+		for _, f := range files {
+			switch {
+			case j.expired(f, report.StartedAt):
+				if j.remove(f, &report) {
+					report.ExpiredFiles++
+				}
+			case config.StorageRemoveOrphans && isOrphan(f, referenced, report.StartedAt):
+				if j.remove(f, &report) {
+					report.OrphanFiles++
+				}
+			default:
+				kept = append(kept, f)
+			}
+		}
+	}
+
+	if config.StorageQuota > 0 {
+		report.EvictedFiles = j.evict(kept, report.StartedAt, &report)
+	}
+
+	for _, dir := range directories() {
+		if dir.media {
+			removeEmptyDirs(dir.path)
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	j.lastCleanup = &report
+
+	if report.RemovedFiles > 0 {
+		logrus.Infof("[JANITOR] Removed %d files (%d bytes): %d expired, %d orphaned, %d over quota",
+			report.RemovedFiles, report.RemovedSize, report.ExpiredFiles, report.OrphanFiles, report.EvictedFiles)
+	}
+	return report, nil
+}
+
+// evict removes the files modified the longest time ago until the rest fits in the quota.
+// Only the media cache renews a file when it serves it, other files go by when they were written.
+func (j *Janitor) evict(files []file, now time.Time, report *domainApp.StorageCleanupResponse) (evicted int) {
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	if total <= config.StorageQuota {
+		return 0
+	}
+
+	sort.Slice(files, func(a, b int) bool {
+		return files[a].modTime.Before(files[b].modTime)
+	})
+	for _, f := range files {
+		if total <= config.StorageQuota {
+			break
+		}
+		if now.Sub(f.modTime) < gracePeriod {
+			continue
+		}
+		if j.remove(f, report) {
+			total -= f.size
+			evicted++
+		}
+	}
+
+	if total > config.StorageQuota {
+		logrus.Warnf("[JANITOR] Storage uses %d bytes after cleanup, more than the quota of %d bytes", total, config.StorageQuota)
+	}
+	return evicted
+}
+
+// expired applies the retention of the media type of a file, or else the retention of its directory
+func (j *Janitor) expired(f file, now time.Time) bool {
+	retention, ok := j.directoryRetention(f.dir)
+	if f.dir.media {
+		if typeRetention, found := j.retention[mediaType(f.path)]; found {
+			retention, ok = typeRetention, true
+		}
+	}
+	return ok && now.Sub(f.modTime) > max(retention, gracePeriod)
+}
+
+// directoryRetention is the configured retention of a directory, media cached only for streaming has one by default
+func (j *Janitor) directoryRetention(dir directory) (time.Duration, bool) {
+	if retention, ok := j.retention[dir.name]; ok {
+		return retention, true
+	}
+	if dir.name == "media-cache" && !config.WhatsappMediaCache {
+		return mediaCacheStreamRetention, true
+	}
+	return 0, false
+}
+
+func (j *Janitor) remove(f file, report *domainApp.StorageCleanupResponse) bool {
+	if err := removeFile(f); err != nil {
+		logrus.Errorf("[JANITOR] Error removing %s: %v", f.path, err)
+		return false
+	}
+
+	// Messages stop pointing to removed media, it is downloaded again when requested
+	if f.dir.media {
+		if err := j.chatStorageRepo.ClearMediaPath(f.path); err != nil {
+			logrus.Errorf("[JANITOR] Error forgetting media path %s: %v", f.path, err)
+		}
+	}
+
+	report.RemovedFiles++
+	report.RemovedSize += f.size
+	return true
+}
+
+func (j *Janitor) referencedMedia() (map[string]bool, error) {
+	paths, err := j.chatStorageRepo.GetMediaPaths()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list media paths: %w", err)
+	}
+
+	referenced := make(map[string]bool, len(paths))
+	for _, mediaPath := range paths {
+		referenced[filepath.Clean(mediaPath)] = true
+	}
+	return referenced, nil
+}
+
+// isOrphan reports downloaded media that no message or status refers to
+func isOrphan(f file, referenced map[string]bool, now time.Time) bool {
+	return f.dir.media && !referenced[filepath.Clean(f.path)] && now.Sub(f.modTime) > gracePeriod
+}
+
+// listFiles returns the files of a directory, hidden files are partial uploads and are left alone.
+// Media directories are walked recursively, older versions kept downloads in per chat and per date folders.
+func listFiles(dir directory) ([]file, error) {
+	var files []file
+	err := filepath.WalkDir(dir.path, func(path string, entry fs.DirEntry, err error) error {
+		switch {
+		case err != nil && path == dir.path && errors.Is(err, fs.ErrNotExist):
+			return fs.SkipAll
+		case err != nil && errors.Is(err, fs.ErrNotExist):
+			// Removed since the parent directory was read
+			return nil
+		case err != nil:
+			return err
+		case entry.IsDir():
+			if path != dir.path && (!dir.media || strings.HasPrefix(entry.Name(), ".")) {
+				return fs.SkipDir
+			}
+			return nil
+		case !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), "."):
+			return nil
+		}
+		if matched, _ := filepath.Match(dir.pattern, entry.Name()); !matched {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			// Removed since the directory was read
+			return nil
+		}
+		files = append(files, file{
+			dir:     dir,
+			path:    path,
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+		return nil
+	})
+	return files, err
+}
+
+// removeEmptyDirs removes the subdirectories a cleanup left empty, the directory itself is kept.
+// Media is written straight into the directory, so nothing creates subdirectories anymore.
+func removeEmptyDirs(root string) {
+	var dirs []string
+	_ = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.IsDir() && path != root {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+
+	// Deepest first, so a parent is empty once its children are gone
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if entries, err := os.ReadDir(dirs[i]); err == nil && len(entries) == 0 {
+			_ = os.Remove(dirs[i])
+		}
+	}
+}
+
+// removeFile removes a file, downloaded media goes through the media store so its MIME type goes with it
+func removeFile(f file) error {
+	if f.dir.media {
+		return mediastore.NewLocalStore(filepath.Dir(f.path)).Delete(context.Background(), filepath.Base(f.path))
+	}
+	if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// mediaType tells the WhatsApp media type of a downloaded file from the MIME type the media store keeps for it,
+// stickers are WebP images
+func mediaType(path string) string {
+	var mimeType string
+	if object, err := mediastore.NewLocalStore(filepath.Dir(path)).Stat(context.Background(), filepath.Base(path)); err == nil {
+		mimeType = object.MimeType
+	}
+	switch {
+	case strings.HasPrefix(mimeType, "image/webp"):
+		return "sticker"
+	case strings.HasPrefix(mimeType, "image/"):
+		return "image"
+	case strings.HasPrefix(mimeType, "video/"):
+		return "video"
+	case strings.HasPrefix(mimeType, "audio/"):
+		return "audio"
+	}
+	return "document"
+}
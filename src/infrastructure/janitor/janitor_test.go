@@ -0,0 +1,382 @@
+package janitor_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/janitor"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/mediastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// mediaPathRepository is a chat storage that only knows which media messages refer to
+type mediaPathRepository struct {
+	domainChatStorage.IChatStorageRepository
+	paths   []string
+	cleared []string
+}
+
+func (repo *mediaPathRepository) GetMediaPaths() ([]string, error) {
+	return repo.paths, nil
+}
+
+func (repo *mediaPathRepository) ClearMediaPath(mediaPath string) error {
+	repo.cleared = append(repo.cleared, mediaPath)
+	return nil
+}
+
+// testFile is written relative to the temp root, age is how long ago it was modified
+type testFile struct {
+	path string
+	size int
+	age  time.Duration
+}
+
+type JanitorTestSuite struct {
+	suite.Suite
+	root string
+
+	origPaths         []string
+	origRetention     []string
+	origQuota         int64
+	origRemoveOrphans bool
+	origMediaCache    bool
+}
+
+func (suite *JanitorTestSuite) SetupTest() {
+	suite.origPaths = []string{config.PathMedia, config.PathStatuses, config.PathSendItems, config.PathMediaCache, config.PathStorages, config.PathQrCode}
+	suite.origRetention = config.StorageRetention
+	suite.origQuota = config.StorageQuota
+	suite.origRemoveOrphans = config.StorageRemoveOrphans
+	suite.origMediaCache = config.WhatsappMediaCache
+}
+
+func (suite *JanitorTestSuite) TearDownTest() {
+	config.PathMedia, config.PathStatuses, config.PathSendItems = suite.origPaths[0], suite.origPaths[1], suite.origPaths[2]
+	config.PathMediaCache, config.PathStorages, config.PathQrCode = suite.origPaths[3], suite.origPaths[4], suite.origPaths[5]
+	config.StorageRetention = suite.origRetention
+	config.StorageQuota = suite.origQuota
+	config.StorageRemoveOrphans = suite.origRemoveOrphans
+	config.WhatsappMediaCache = suite.origMediaCache
+}
+
+// useTempDirs points every managed directory into a fresh temp dir
+func (suite *JanitorTestSuite) useTempDirs(t *testing.T) {
+	suite.root = t.TempDir()
+	config.PathMedia = filepath.Join(suite.root, "statics/media")
+	config.PathStatuses = filepath.Join(suite.root, "statics/statuses")
+	config.PathSendItems = filepath.Join(suite.root, "statics/senditems")
+	config.PathStorages = filepath.Join(suite.root, "storages")
+	config.PathMediaCache = filepath.Join(suite.root, "storages/media-cache")
+	config.PathQrCode = filepath.Join(suite.root, "statics/qrcode")
+}
+
+func (suite *JanitorTestSuite) writeFiles(t *testing.T, files []testFile) {
+	now := time.Now()
+	for _, f := range files {
+		path := filepath.Join(suite.root, f.path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, make([]byte, f.size), 0644))
+		modTime := now.Add(-f.age)
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+}
+
+// remaining lists the files left under the temp root, relative to it
+func (suite *JanitorTestSuite) remaining(t *testing.T) []string {
+	var files []string
+	err := filepath.Walk(suite.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(suite.root, path)
+		files = append(files, filepath.ToSlash(rel))
+		return err
+	})
+	require.NoError(t, err)
+	sort.Strings(files)
+	return files
+}
+
+func (suite *JanitorTestSuite) TestCleanup() {
+	const day = 24 * time.Hour
+
+	tests := []struct {
+		name          string
+		retention     []string
+		quota         int64
+		removeOrphans bool
+		mediaCache    bool
+		files         []testFile
+		referenced    []string
+		wantRemaining []string
+		wantCleared   []string
+	}{
+		{
+			name:      "should remove files past the retention of their directory",
+			retention: []string{"senditems=24h", "history=48h"},
+			files: []testFile{
+				{path: "statics/senditems/old.jpg", size: 10, age: 2 * day},
+				{path: "statics/senditems/new.jpg", size: 10, age: time.Hour},
+				{path: "storages/history-1.json", size: 10, age: 3 * day},
+				{path: "storages/history-2.json", size: 10, age: day},
+				{path: "storages/chatstorage.db", size: 10, age: 30 * day},
+			},
+			wantRemaining: []string{
+				"statics/senditems/new.jpg",
+				"storages/chatstorage.db",
+				"storages/history-2.json",
+			},
+		},
+		{
+			name:      "should let the media type retention win over the directory",
+			retention: []string{"media=720h", "video=24h"},
+			files: []testFile{
+				{path: "statics/media/clip.mp4", size: 10, age: 2 * day},
+				{path: "statics/media/photo.jpg", size: 10, age: 2 * day},
+				{path: "statics/media/scan.pdf", size: 10, age: 31 * day},
+			},
+			referenced:    []string{"statics/media/clip.mp4", "statics/media/photo.jpg", "statics/media/scan.pdf"},
+			wantRemaining: []string{"statics/media/photo.jpg"},
+			wantCleared:   []string{"statics/media/clip.mp4", "statics/media/scan.pdf"},
+		},
+		{
+			name:      "should keep files within the grace period whatever the retention",
+			retention: []string{"senditems=1m"},
+			files: []testFile{
+				{path: "statics/senditems/sending.jpg", size: 10, age: time.Minute},
+			},
+			wantRemaining: []string{"statics/senditems/sending.jpg"},
+		},
+		{
+			name: "should only keep media cached for streaming for a while without the media cache",
+			files: []testFile{
+				{path: "storages/media-cache/played", size: 10, age: 2 * time.Hour},
+				{path: "storages/media-cache/playing", size: 10, age: 20 * time.Minute},
+				{path: "storages/media-cache/.media-123", size: 10, age: 2 * time.Hour},
+			},
+			wantRemaining: []string{
+				"storages/media-cache/.media-123",
+				"storages/media-cache/playing",
+			},
+		},
+		{
+			name:       "should keep cached media with the media cache",
+			mediaCache: true,
+			files: []testFile{
+				{path: "storages/media-cache/played", size: 10, age: 30 * day},
+			},
+			wantRemaining: []string{"storages/media-cache/played"},
+		},
+		{
+			name:          "should remove orphaned media and keep referenced media",
+			removeOrphans: true,
+			files: []testFile{
+				{path: "statics/media/kept.jpg", size: 10, age: 10 * day},
+				{path: "statics/media/orphan.jpg", size: 10, age: 10 * day},
+				{path: "statics/media/downloading.jpg", size: 10, age: time.Minute},
+				{path: "statics/statuses/story.mp4", size: 10, age: 10 * day},
+				{path: "statics/senditems/upload.jpg", size: 10, age: 10 * day},
+			},
+			referenced: []string{"statics/media/kept.jpg"},
+			wantRemaining: []string{
+				"statics/media/downloading.jpg",
+				"statics/media/kept.jpg",
+				"statics/senditems/upload.jpg",
+			},
+			wantCleared: []string{"statics/media/orphan.jpg", "statics/statuses/story.mp4"},
+		},
+		{
+			name: "should keep orphaned media when orphan removal is off",
+			files: []testFile{
+				{path: "statics/media/orphan.jpg", size: 10, age: 10 * day},
+			},
+			wantRemaining: []string{"statics/media/orphan.jpg"},
+		},
+		{
+			name:          "should clean legacy media kept in per chat and per date folders",
+			removeOrphans: true,
+			files: []testFile{
+				{path: "statics/media/6281234567890/2024-01-02/orphan.jpg", size: 10, age: 10 * day},
+				{path: "statics/media/6281234567890/2024-01-03/kept.jpg", size: 10, age: 10 * day},
+				{path: "statics/media/6289876543210/2024-01-02/orphan.pdf", size: 10, age: 10 * day},
+			},
+			referenced:    []string{"statics/media/6281234567890/2024-01-03/kept.jpg"},
+			wantRemaining: []string{"statics/media/6281234567890/2024-01-03/kept.jpg"},
+			wantCleared: []string{
+				"statics/media/6281234567890/2024-01-02/orphan.jpg",
+				"statics/media/6289876543210/2024-01-02/orphan.pdf",
+			},
+		},
+		{
+			name:  "should evict the oldest modified files until the rest fits in the quota",
+			quota: 250,
+			files: []testFile{
+				{path: "statics/media/oldest.jpg", size: 100, age: 3 * day},
+				{path: "statics/senditems/older.jpg", size: 100, age: 2 * day},
+				{path: "statics/media/newer.jpg", size: 100, age: day},
+				{path: "storages/media-cache/newest", size: 100, age: 20 * time.Minute},
+			},
+			referenced: []string{"statics/media/oldest.jpg", "statics/media/newer.jpg"},
+			wantRemaining: []string{
+				"statics/media/newer.jpg",
+				"storages/media-cache/newest",
+			},
+			wantCleared: []string{"statics/media/oldest.jpg"},
+		},
+		{
+			name:  "should not evict files within the grace period to fit in the quota",
+			quota: 100,
+			files: []testFile{
+				{path: "statics/media/old.jpg", size: 100, age: day},
+				{path: "statics/media/recent.jpg", size: 100, age: time.Minute},
+				{path: "statics/media/current.jpg", size: 100, age: 0},
+			},
+			referenced: []string{"statics/media/old.jpg", "statics/media/recent.jpg", "statics/media/current.jpg"},
+			wantRemaining: []string{
+				"statics/media/current.jpg",
+				"statics/media/recent.jpg",
+			},
+			wantCleared: []string{"statics/media/old.jpg"},
+		},
+		{
+			name:      "should leave hidden partial uploads and unmatched files alone",
+			retention: []string{"media=24h", "qrcode=24h"},
+			files: []testFile{
+				{path: "statics/media/.upload-123", size: 10, age: 10 * day},
+				{path: "statics/qrcode/scan-old.png", size: 10, age: 10 * day},
+				{path: "statics/qrcode/logo.png", size: 10, age: 10 * day},
+			},
+			wantRemaining: []string{
+				"statics/media/.upload-123",
+				"statics/qrcode/logo.png",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			suite.useTempDirs(t)
+			config.StorageRetention = tt.retention
+			config.StorageQuota = tt.quota
+			config.StorageRemoveOrphans = tt.removeOrphans
+			config.WhatsappMediaCache = tt.mediaCache
+			suite.writeFiles(t, tt.files)
+
+			repo := &mediaPathRepository{}
+			for _, path := range tt.referenced {
+				repo.paths = append(repo.paths, filepath.Join(suite.root, path))
+			}
+
+			j, err := janitor.New(repo)
+			require.NoError(t, err)
+			report, err := j.Cleanup(context.Background())
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantRemaining, suite.remaining(t))
+			assert.Equal(t, len(tt.files)-len(tt.wantRemaining), report.RemovedFiles)
+
+			var cleared []string
+			for _, path := range repo.cleared {
+				cleared = append(cleared, filepath.ToSlash(strings.TrimPrefix(path, suite.root+string(filepath.Separator))))
+			}
+			sort.Strings(cleared)
+			assert.Equal(t, tt.wantCleared, cleared)
+		})
+	}
+}
+
+func (suite *JanitorTestSuite) TestCleanupRemovesEmptiedFolders() {
+	suite.useTempDirs(suite.T())
+	config.StorageRemoveOrphans = true
+	suite.writeFiles(suite.T(), []testFile{
+		{path: "statics/media/6281234567890/2024-01-02/orphan.jpg", size: 10, age: 10 * 24 * time.Hour},
+	})
+
+	j, err := janitor.New(&mediaPathRepository{})
+	require.NoError(suite.T(), err)
+	_, err = j.Cleanup(context.Background())
+	require.NoError(suite.T(), err)
+
+	assert.NoDirExists(suite.T(), filepath.Join(config.PathMedia, "6281234567890"))
+	assert.DirExists(suite.T(), config.PathMedia)
+}
+
+func (suite *JanitorTestSuite) TestCleanupMediaKeyedByHash() {
+	suite.useTempDirs(suite.T())
+	config.StorageRetention = []string{"video=24h"}
+	store := mediastore.NewLocalStore(config.PathMedia)
+	old := time.Now().Add(-2 * 24 * time.Hour)
+	for key, mimeType := range map[string]string{"c1ip": "video/mp4", "ph0t0": "image/jpeg"} {
+		require.NoError(suite.T(), store.Put(context.Background(), key, strings.NewReader("media"), 5, mimeType))
+		require.NoError(suite.T(), os.Chtimes(filepath.Join(config.PathMedia, key), old, old))
+	}
+
+	j, err := janitor.New(&mediaPathRepository{paths: []string{
+		filepath.Join(config.PathMedia, "c1ip"), filepath.Join(config.PathMedia, "ph0t0"),
+	}})
+	require.NoError(suite.T(), err)
+	_, err = j.Cleanup(context.Background())
+	require.NoError(suite.T(), err)
+
+	// The media type comes from the MIME type kept by the store, which is removed with the media
+	assert.Equal(suite.T(), []string{"statics/media/.ph0t0.mime", "statics/media/ph0t0"}, suite.remaining(suite.T()))
+}
+
+func (suite *JanitorTestSuite) TestUsage() {
+	suite.useTempDirs(suite.T())
+	config.StorageQuota = 1000
+	suite.writeFiles(suite.T(), []testFile{
+		{path: "statics/media/kept.jpg", size: 100, age: 24 * time.Hour},
+		{path: "statics/media/legacy/orphan.jpg", size: 50, age: 24 * time.Hour},
+		{path: "statics/senditems/upload.jpg", size: 30, age: time.Hour},
+	})
+
+	j, err := janitor.New(&mediaPathRepository{paths: []string{filepath.Join(config.PathMedia, "kept.jpg")}})
+	require.NoError(suite.T(), err)
+	usage, err := j.Usage(context.Background())
+	require.NoError(suite.T(), err)
+
+	assert.EqualValues(suite.T(), 1000, usage.Quota)
+	assert.Equal(suite.T(), 3, usage.TotalFiles)
+	assert.EqualValues(suite.T(), 180, usage.TotalSize)
+	assert.Equal(suite.T(), 1, usage.OrphanFiles)
+	assert.EqualValues(suite.T(), 50, usage.OrphanSize)
+	// Usage never removes anything
+	assert.Len(suite.T(), suite.remaining(suite.T()), 3)
+}
+
+func (suite *JanitorTestSuite) TestNewRejectsInvalidSettings() {
+	tests := []struct {
+		name      string
+		retention []string
+		quota     int64
+	}{
+		{name: "should reject an unknown directory", retention: []string{"downloads=24h"}},
+		{name: "should reject a missing duration", retention: []string{"media"}},
+		{name: "should reject a negative duration", retention: []string{"media=-1h"}},
+		{name: "should reject a negative quota", quota: -1},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			config.StorageRetention = tt.retention
+			config.StorageQuota = tt.quota
+			_, err := janitor.New(&mediaPathRepository{})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestJanitorTestSuite(t *testing.T) {
+	suite.Run(t, new(JanitorTestSuite))
+}
@@ -0,0 +1,130 @@
+package mediastore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	domainMediaStore "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/mediastore"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+)
+
+// localStore keeps media as files in a directory, the MIME type of each file is kept in a hidden file next to it
+type localStore struct {
+	dir string
+}
+
+// NewLocalStore keeps media in dir on local disk
+func NewLocalStore(dir string) domainMediaStore.IMediaStore {
+	return &localStore{dir: dir}
+}
+
+func (store *localStore) path(key string) string {
+	return filepath.Join(store.dir, filepath.FromSlash(key))
+}
+
+// mimeTypePath is the hidden file keeping the MIME type of a key, the janitor leaves hidden files alone
+func (store *localStore) mimeTypePath(key string) string {
+	target := store.path(key)
+	return filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+".mime")
+}
+
+// Put writes into a temporary file that is renamed once complete, readers never see partial media.
+// The MIME type is written first, so media that can be read always has it.
+func (store *localStore) Put(_ context.Context, key string, content io.Reader, _ int64, mimeType string) error {
+	target := store.path(key)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if mimeType != "" {
+		if err := writeFile(store.mimeTypePath(key), strings.NewReader(mimeType)); err != nil {
+			return err
+		}
+	}
+	return writeFile(target, content)
+}
+
+func writeFile(target string, content io.Reader) error {
+	file, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(file, content); err == nil {
+		err = file.Chmod(0600)
+	}
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), target)
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
+
+func (store *localStore) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	file, err := os.Open(store.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, domainMediaStore.ErrNotFound
+	}
+	return file, err
+}
+
+func (store *localStore) Stat(_ context.Context, key string) (*domainMediaStore.Object, error) {
+	info, err := os.Stat(store.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, domainMediaStore.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &domainMediaStore.Object{
+		Key:      key,
+		Size:     info.Size(),
+		MimeType: store.mimeType(key),
+		Location: store.path(key),
+		ModTime:  info.ModTime(),
+	}, nil
+}
+
+// mimeType reads the MIME type written with the media. Older versions named media with an extension instead,
+// media with neither is sniffed.
+func (store *localStore) mimeType(key string) string {
+	if mimeType, err := os.ReadFile(store.mimeTypePath(key)); err == nil {
+		return string(mimeType)
+	}
+	if mimeType := mime.TypeByExtension(filepath.Ext(key)); mimeType != "" {
+		return mimeType
+	}
+
+	file, err := os.Open(store.path(key))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	mimeType, _ := utils.DetectReaderContentType(file)
+	return mimeType
+}
+
+func (store *localStore) Delete(_ context.Context, key string) error {
+	for _, target := range []string{store.path(key), store.mimeTypePath(key)} {
+		if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// URL is empty, local media is streamed by this server through signed media URLs
+func (store *localStore) URL(_ context.Context, _ string, _ time.Duration) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,73 @@
+package mediastore
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainMediaStore "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/mediastore"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+)
+
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+)
+
+// s3Prefix holds all media in a bucket, media is keyed by its hash so each file is kept once
+const s3Prefix = "media"
+
+var (
+	s3Client   = &http.Client{Timeout: 5 * time.Minute}
+	s3Endpoint *url.URL
+)
+
+// Init checks the media storage configuration, it runs once at startup before any media is stored
+func Init() error {
+	switch strings.ToLower(config.MediaStorage) {
+	case StorageLocal:
+		s3Endpoint = nil
+		return nil
+	case StorageS3:
+		if config.MediaS3Bucket == "" || config.MediaS3AccessKey == "" || config.MediaS3SecretKey == "" {
+			return fmt.Errorf("s3 media storage needs a bucket, access key and secret key")
+		}
+
+		endpoint := config.MediaS3Endpoint
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", config.MediaS3Region)
+		}
+		parsed, err := url.Parse(strings.TrimRight(endpoint, "/"))
+		if err != nil || parsed.Host == "" {
+			return fmt.Errorf("invalid s3 endpoint %q", endpoint)
+		}
+		s3Endpoint = parsed
+		return nil
+	default:
+		return fmt.Errorf("unknown media storage %q, use %s or %s", config.MediaStorage, StorageLocal, StorageS3)
+	}
+}
+
+// For returns the store of media kept in dir. Buckets keep the media of every directory under one prefix.
+func For(dir string) domainMediaStore.IMediaStore {
+	if s3Endpoint == nil {
+		return NewLocalStore(dir)
+	}
+
+	return &s3Store{
+		client:    s3Client,
+		endpoint:  s3Endpoint,
+		bucket:    config.MediaS3Bucket,
+		prefix:    s3Prefix,
+		pathStyle: config.MediaS3PathStyle,
+		credentials: utils.AWSCredentials{
+			AccessKey: config.MediaS3AccessKey,
+			SecretKey: config.MediaS3SecretKey,
+			Region:    config.MediaS3Region,
+			Service:   "s3",
+		},
+	}
+}
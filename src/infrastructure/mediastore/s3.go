@@ -0,0 +1,128 @@
+package mediastore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	domainMediaStore "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/mediastore"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+)
+
+// maxPresignExpiry is the longest validity S3 accepts for presigned URLs
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+// s3Store keeps media as objects in an S3 compatible bucket, the MIME type is kept as the Content-Type of the object
+type s3Store struct {
+	client      *http.Client
+	endpoint    *url.URL
+	bucket      string
+	prefix      string
+	pathStyle   bool
+	credentials utils.AWSCredentials
+}
+
+func (store *s3Store) objectKey(key string) string {
+	return path.Join(store.prefix, key)
+}
+
+func (store *s3Store) objectURL(key string) *url.URL {
+	target := *store.endpoint
+	if store.pathStyle {
+		target.Path = "/" + store.bucket + "/" + store.objectKey(key)
+	} else {
+		target.Host = store.bucket + "." + target.Host
+		target.Path = "/" + store.objectKey(key)
+	}
+	target.RawPath = utils.AWSURIEncode(target.Path, false)
+	return &target
+}
+
+func (store *s3Store) do(ctx context.Context, method, key string, body io.Reader, size int64, mimeType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, store.objectURL(key).String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	payloadHash := utils.AWSEmptyPayloadHash
+	if body != nil {
+		req.ContentLength = size
+		req.Header.Set("Content-Type", mimeType)
+		payloadHash = utils.AWSUnsignedPayload
+	}
+	utils.SignAWSRequest(req, store.credentials, payloadHash, time.Now())
+
+	resp, err := store.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		_ = resp.Body.Close()
+		return nil, domainMediaStore.ErrNotFound
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("s3 %s %s failed with status %d: %s", method, store.objectKey(key), resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return resp, nil
+}
+
+func (store *s3Store) Put(ctx context.Context, key string, content io.Reader, size int64, mimeType string) error {
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	resp, err := store.do(ctx, http.MethodPut, key, content, size, mimeType)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (store *s3Store) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := store.do(ctx, http.MethodGet, key, nil, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (store *s3Store) Stat(ctx context.Context, key string) (*domainMediaStore.Object, error) {
+	resp, err := store.do(ctx, http.MethodHead, key, nil, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return &domainMediaStore.Object{
+		Key:      key,
+		Size:     resp.ContentLength,
+		MimeType: resp.Header.Get("Content-Type"),
+		Location: fmt.Sprintf("s3://%s/%s", store.bucket, store.objectKey(key)),
+		ModTime:  modTime,
+	}, nil
+}
+
+func (store *s3Store) Delete(ctx context.Context, key string) error {
+	resp, err := store.do(ctx, http.MethodDelete, key, nil, 0, "")
+	if errors.Is(err, domainMediaStore.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (store *s3Store) URL(_ context.Context, key string, expiresIn time.Duration) (string, error) {
+	if expiresIn > maxPresignExpiry {
+		expiresIn = maxPresignExpiry
+	}
+	return utils.PresignAWSURL(http.MethodGet, store.objectURL(key), store.credentials, time.Now(), expiresIn), nil
+}
@@ -0,0 +1,294 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/ui/websocket"
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow/appstate"
+	"go.mau.fi/whatsmeow/proto/waSyncAction"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// appStateUpdate is a change of chat state, a contact or a label that is forwarded to webhooks and websocket clients
+type appStateUpdate struct {
+	event     string // Webhook event name
+	code      string // Websocket message code
+	target    string // Chat JID, contact JID or label ID the update is about
+	payload   map[string]any
+	timestamp time.Time
+}
+
+// pendingChatActions counts the chat actions this device is sending by action and chat. whatsmeow fetches the patch
+// back and emits it as an event before SendAppState returns, the sender updates storage itself once the patch succeeded
+var pendingChatActions = struct {
+	sync.Mutex
+	count map[string]int
+}{count: make(map[string]int)}
+
+// SendChatAction sends a patch changing the state of chats without its echo touching chat storage or being forwarded
+// as a change made on another device, storing the change is left to the caller once the patch succeeded
+func SendChatAction(ctx context.Context, patch appstate.PatchInfo) error {
+	done := trackChatActions(patch)
+	defer done()
+	return GetClient().SendAppState(ctx, patch)
+}
+
+// trackChatActions marks the chat actions of a patch as sent by this device until done is called
+func trackChatActions(patch appstate.PatchInfo) (done func()) {
+	keys := make([]string, 0, len(patch.Mutations))
+	for _, mutation := range patch.Mutations {
+		// Chat action indexes start with the action and the chat
+		if len(mutation.Index) >= 2 {
+			keys = append(keys, chatActionKey(mutation.Index[0], mutation.Index[1]))
+		}
+	}
+
+	pendingChatActions.Lock()
+	for _, key := range keys {
+		pendingChatActions.count[key]++
+	}
+	pendingChatActions.Unlock()
+
+	return func() {
+		pendingChatActions.Lock()
+		defer pendingChatActions.Unlock()
+		for _, key := range keys {
+			if pendingChatActions.count[key]--; pendingChatActions.count[key] <= 0 {
+				delete(pendingChatActions.count, key)
+			}
+		}
+	}
+}
+
+func chatActionKey(action, jid string) string {
+	return action + "|" + jid
+}
+
+// isLocalChatAction reports whether a chat action event is the echo of a patch this device is sending
+func isLocalChatAction(rawEvt any) bool {
+	var action string
+	var jid types.JID
+	switch evt := rawEvt.(type) {
+	case *events.Pin:
+		action, jid = appstate.IndexPin, evt.JID
+	case *events.Archive:
+		action, jid = appstate.IndexArchive, evt.JID
+	case *events.Mute:
+		action, jid = appstate.IndexMute, evt.JID
+	case *events.MarkChatAsRead:
+		action, jid = appstate.IndexMarkChatAsRead, evt.JID
+	case *events.ClearChat:
+		action, jid = appstate.IndexClearChat, evt.JID
+	case *events.DeleteChat:
+		action, jid = appstate.IndexDeleteChat, evt.JID
+	default:
+		return false
+	}
+
+	pendingChatActions.Lock()
+	defer pendingChatActions.Unlock()
+	return pendingChatActions.count[chatActionKey(action, jid.String())] > 0
+}
+
+// handleChatStateChange mirrors chat actions made on other devices, or replayed by an app state sync, into chat storage
+func handleChatStateChange(ctx context.Context, rawEvt any, chatStorageRepo domainChatStorage.IChatStorageRepository) {
+	var (
+		update       *appStateUpdate
+		fromFullSync bool
+		err          error
+	)
+
+	// Changes made through this API are stored by their sender, only changes of other devices are mirrored and forwarded
+	if isLocalChatAction(rawEvt) {
+		log.Debugf("Ignoring %T echoed back from this device", rawEvt)
+		return
+	}
+
+	switch evt := rawEvt.(type) {
+	case *events.Pin:
+		fromFullSync = evt.FromFullSync
+		update = newChatUpdate(evt.JID, evt.Timestamp, "pin", map[string]any{"pinned": evt.Action.GetPinned()})
+		err = chatStorageRepo.SetChatPinned(update.target, evt.Action.GetPinned())
+	case *events.Archive:
+		fromFullSync = evt.FromFullSync
+		update = newChatUpdate(evt.JID, evt.Timestamp, "archive", map[string]any{"archived": evt.Action.GetArchived()})
+		err = chatStorageRepo.SetChatArchived(update.target, evt.Action.GetArchived())
+	case *events.Mute:
+		fromFullSync = evt.FromFullSync
+		mutedUntil := muteEndTime(evt.Action)
+		fields := map[string]any{"muted": evt.Action.GetMuted()}
+		if evt.Action.GetMuted() && mutedUntil != nil {
+			fields["muted_until"] = mutedUntil.Format(time.RFC3339)
+		}
+		update = newChatUpdate(evt.JID, evt.Timestamp, "mute", fields)
+		err = chatStorageRepo.SetChatMuted(update.target, evt.Action.GetMuted(), mutedUntil)
+	case *events.MarkChatAsRead:
+		fromFullSync = evt.FromFullSync
+		update = newChatUpdate(evt.JID, evt.Timestamp, "read", map[string]any{"read": evt.Action.GetRead()})
+		err = chatStorageRepo.SetChatMarkedUnread(update.target, !evt.Action.GetRead())
+	case *events.ClearChat:
+		fromFullSync = evt.FromFullSync
+		until := messageRangeEnd(evt.Action.GetMessageRange(), evt.Timestamp)
+		update = newChatUpdate(evt.JID, evt.Timestamp, "clear", map[string]any{"until": until.Format(time.RFC3339)})
+		err = chatStorageRepo.ClearChatMessages(update.target, until)
+	case *events.DeleteChat:
+		fromFullSync = evt.FromFullSync
+		until := messageRangeEnd(evt.Action.GetMessageRange(), evt.Timestamp)
+		update = newChatUpdate(evt.JID, evt.Timestamp, "delete", map[string]any{"until": until.Format(time.RFC3339)})
+		err = deleteChatUntil(chatStorageRepo, update.target, until)
+	case *events.LabelAssociationChat:
+		fromFullSync = evt.FromFullSync
+		update = newChatUpdate(evt.JID, evt.Timestamp, "label", map[string]any{
+			"label_id": evt.LabelID,
+			"labeled":  evt.Action.GetLabeled(),
+		})
+		err = chatStorageRepo.SetChatLabel(update.target, evt.LabelID, evt.Action.GetLabeled())
+	case *events.Contact:
+		fromFullSync = evt.FromFullSync
+		contact := &domainChatStorage.Contact{
+			JID:       evt.JID.String(),
+			FullName:  evt.Action.GetFullName(),
+			FirstName: evt.Action.GetFirstName(),
+		}
+		update = &appStateUpdate{
+			event:  "contact.update",
+			code:   "CONTACT_UPDATE",
+			target: contact.JID,
+			payload: map[string]any{
+				"jid":        contact.JID,
+				"full_name":  contact.FullName,
+				"first_name": contact.FirstName,
+			},
+			timestamp: evt.Timestamp,
+		}
+		err = chatStorageRepo.StoreContact(contact)
+	case *events.LabelEdit:
+		fromFullSync = evt.FromFullSync
+		label := &domainChatStorage.Label{
+			ID:    evt.LabelID,
+			Name:  evt.Action.GetName(),
+			Color: evt.Action.GetColor(),
+		}
+		update = &appStateUpdate{
+			event:  "label.update",
+			code:   "LABEL_UPDATE",
+			target: label.ID,
+			payload: map[string]any{
+				"label_id": label.ID,
+				"name":     label.Name,
+				"color":    label.Color,
+				"deleted":  evt.Action.GetDeleted(),
+			},
+			timestamp: evt.Timestamp,
+		}
+		if evt.Action.GetDeleted() {
+			err = chatStorageRepo.DeleteLabel(label.ID)
+		} else {
+			err = chatStorageRepo.StoreLabel(label)
+		}
+	default:
+		return
+	}
+
+	if err != nil {
+		log.Errorf("Failed to store %T for %s: %v", rawEvt, update.target, err)
+		return
+	}
+	log.Debugf("Stored %T for %s", rawEvt, update.target)
+
+	// A full sync replays the whole state, only changes made while connected are forwarded
+	if fromFullSync {
+		return
+	}
+
+	go func() {
+		websocket.Broadcast <- websocket.BroadcastMessage{
+			Code:    update.code,
+			Message: fmt.Sprintf("%s changed on another device", update.target),
+			Result:  update.payload,
+		}
+	}()
+
+	if len(config.WhatsappWebhook) > 0 {
+		go func() {
+			if err := forwardAppStateUpdateToWebhook(ctx, update); err != nil {
+				logrus.Error("Failed forward app state update to webhook: ", err)
+			}
+		}()
+	}
+}
+
+// newChatUpdate creates a chat.update event of the given type with its type specific fields
+func newChatUpdate(jid types.JID, timestamp time.Time, actionType string, fields map[string]any) *appStateUpdate {
+	payload := map[string]any{
+		"chat_id": jid.String(),
+		"type":    actionType,
+	}
+	for key, value := range fields {
+		payload[key] = value
+	}
+
+	return &appStateUpdate{
+		event:     "chat.update",
+		code:      "CHAT_UPDATE",
+		target:    jid.String(),
+		payload:   payload,
+		timestamp: timestamp,
+	}
+}
+
+// forwardAppStateUpdateToWebhook forwards chat, contact and label updates to the configured webhook URLs
+func forwardAppStateUpdateToWebhook(ctx context.Context, update *appStateUpdate) error {
+	logrus.Infof("Forwarding %s event to %d configured webhook(s)", update.event, len(config.WhatsappWebhook))
+
+	body := map[string]any{
+		"event":     update.event,
+		"payload":   update.payload,
+		"timestamp": update.timestamp.Format(time.RFC3339),
+	}
+
+	for _, url := range config.WhatsappWebhook {
+		if err := submitWebhook(ctx, body, url); err != nil {
+			return err
+		}
+	}
+
+	logrus.Infof("%s event forwarded to webhook", update.event)
+	return nil
+}
+
+// deleteChatUntil deletes a chat, unless messages arrived after the deletion which are kept like the phone does
+func deleteChatUntil(chatStorageRepo domainChatStorage.IChatStorageRepository, jid string, until time.Time) error {
+	chat, err := chatStorageRepo.GetChat(jid)
+	if err != nil || chat == nil {
+		return err
+	}
+	if chat.LastMessageTime.After(until) {
+		return chatStorageRepo.ClearChatMessages(jid, until)
+	}
+	return chatStorageRepo.DeleteChat(jid)
+}
+
+// muteEndTime converts the mute end of an app state action, nil means muted forever
+func muteEndTime(action *waSyncAction.MuteAction) *time.Time {
+	if action.GetMuteEndTimestamp() <= 0 {
+		return nil
+	}
+	mutedUntil := time.UnixMilli(action.GetMuteEndTimestamp())
+	return &mutedUntil
+}
+
+// messageRangeEnd is the time of the last message covered by a clear or delete action
+func messageRangeEnd(messageRange *waSyncAction.SyncActionMessageRange, fallback time.Time) time.Time {
+	if timestamp := messageRange.GetLastMessageTimestamp(); timestamp > 0 {
+		return time.Unix(timestamp, 0)
+	}
+	return fallback
+}
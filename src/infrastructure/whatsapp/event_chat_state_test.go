@@ -0,0 +1,125 @@
+package whatsapp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/ui/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mau.fi/whatsmeow/appstate"
+	"go.mau.fi/whatsmeow/proto/waSyncAction"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeChatStateRepository records the chat state changes mirrored into storage
+type fakeChatStateRepository struct {
+	domainChatStorage.IChatStorageRepository
+	pinned   map[string]bool
+	archived map[string]bool
+}
+
+func (repo *fakeChatStateRepository) SetChatPinned(jid string, pinned bool) error {
+	repo.pinned[jid] = pinned
+	return nil
+}
+
+func (repo *fakeChatStateRepository) SetChatArchived(jid string, archived bool) error {
+	repo.archived[jid] = archived
+	return nil
+}
+
+type ChatStateTestSuite struct {
+	suite.Suite
+	repo *fakeChatStateRepository
+	chat types.JID
+}
+
+func (suite *ChatStateTestSuite) SetupSuite() {
+	if log == nil {
+		log = waLog.Noop
+	}
+}
+
+func (suite *ChatStateTestSuite) SetupTest() {
+	suite.repo = &fakeChatStateRepository{pinned: make(map[string]bool), archived: make(map[string]bool)}
+	suite.chat = types.NewJID("6289685028129", types.DefaultUserServer)
+}
+
+func (suite *ChatStateTestSuite) pinEvent() *events.Pin {
+	return &events.Pin{JID: suite.chat, Timestamp: time.Now(), Action: &waSyncAction.PinAction{Pinned: proto.Bool(true)}}
+}
+
+// broadcast returns the websocket message sent for a change, nil when none was sent
+func (suite *ChatStateTestSuite) broadcast() *websocket.BroadcastMessage {
+	select {
+	case message := <-websocket.Broadcast:
+		return &message
+	case <-time.After(100 * time.Millisecond):
+		return nil
+	}
+}
+
+func (suite *ChatStateTestSuite) TestRemoteChangeIsMirrored() {
+	handleChatStateChange(context.Background(), suite.pinEvent(), suite.repo)
+
+	assert.True(suite.T(), suite.repo.pinned[suite.chat.String()])
+	message := suite.broadcast()
+	if assert.NotNil(suite.T(), message) {
+		assert.Equal(suite.T(), "CHAT_UPDATE", message.Code)
+		assert.Contains(suite.T(), message.Message, "changed on another device")
+	}
+}
+
+func (suite *ChatStateTestSuite) TestEchoedLocalPatchIsIgnored() {
+	// Archiving also unpins, both echoes of the patch are ours
+	done := trackChatActions(appstate.BuildArchive(suite.chat, true, time.Time{}, nil))
+
+	handleChatStateChange(context.Background(), &events.Archive{
+		JID: suite.chat, Timestamp: time.Now(), Action: &waSyncAction.ArchiveChatAction{Archived: proto.Bool(true)},
+	}, suite.repo)
+	handleChatStateChange(context.Background(), &events.Pin{
+		JID: suite.chat, Timestamp: time.Now(), Action: &waSyncAction.PinAction{Pinned: proto.Bool(false)},
+	}, suite.repo)
+
+	assert.Empty(suite.T(), suite.repo.archived, "the sender stores its own change")
+	assert.Empty(suite.T(), suite.repo.pinned)
+	assert.Nil(suite.T(), suite.broadcast(), "our own change is not reported as made on another device")
+
+	// Another chat changed meanwhile is still mirrored
+	other := types.NewJID("6281234567890", types.DefaultUserServer)
+	handleChatStateChange(context.Background(), &events.Pin{
+		JID: other, Timestamp: time.Now(), Action: &waSyncAction.PinAction{Pinned: proto.Bool(true)},
+	}, suite.repo)
+	assert.True(suite.T(), suite.repo.pinned[other.String()])
+	assert.NotNil(suite.T(), suite.broadcast())
+
+	// Once the patch is done the same change comes from another device
+	done()
+	handleChatStateChange(context.Background(), suite.pinEvent(), suite.repo)
+	assert.True(suite.T(), suite.repo.pinned[suite.chat.String()])
+	assert.NotNil(suite.T(), suite.broadcast())
+}
+
+func (suite *ChatStateTestSuite) TestOverlappingPatches() {
+	first := trackChatActions(appstate.BuildPin(suite.chat, true))
+	second := trackChatActions(appstate.BuildPin(suite.chat, true))
+
+	first()
+	handleChatStateChange(context.Background(), suite.pinEvent(), suite.repo)
+	assert.Empty(suite.T(), suite.repo.pinned, "the second patch is still being sent")
+
+	second()
+	handleChatStateChange(context.Background(), suite.pinEvent(), suite.repo)
+	assert.True(suite.T(), suite.repo.pinned[suite.chat.String()])
+	assert.NotNil(suite.T(), suite.broadcast())
+}
+
+func TestChatStateTestSuite(t *testing.T) {
+	suite.Run(t, new(ChatStateTestSuite))
+}
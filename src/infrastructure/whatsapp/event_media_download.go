@@ -0,0 +1,76 @@
+package whatsapp
+
+import (
+	"context"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/mediastore"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+)
+
+// handleAutoDownload stores the media of an incoming message when the auto-download policy allows it
+// and records where it went on the message. Webhooks of the same message reuse the stored file.
+func handleAutoDownload(ctx context.Context, evt *events.Message, chatStorageRepo domainChatStorage.IChatStorageRepository) {
+	media := messageMedia(evt.Message)
+	if media == nil {
+		return
+	}
+	mediaType, fileLength := mediaDetails(media)
+	if !utils.AutoDownloadMedia(evt.Info.Chat, mediaType, fileLength) {
+		log.Debugf("Skipping auto-download of %s in message %s", mediaType, evt.Info.ID)
+		return
+	}
+
+	// Large media would hold up every following event
+	go func() {
+		extracted, err := utils.ExtractMedia(ctx, cli, mediastore.For(config.PathMedia), media)
+		if err != nil {
+			log.Errorf("Failed to download %s of message %s: %v", mediaType, evt.Info.ID, err)
+			return
+		}
+		if err = chatStorageRepo.UpdateMessageMediaPath(evt.Info.ID, evt.Info.Chat.String(), extracted.MediaPath); err != nil {
+			log.Errorf("Failed to record media path of message %s: %v", evt.Info.ID, err)
+			return
+		}
+		log.Infof("Downloaded %s of message %s to %s", mediaType, evt.Info.ID, extracted.MediaPath)
+	}()
+}
+
+// messageMedia returns the downloadable media of a message, nil for messages without media
+func messageMedia(msg *waE2E.Message) whatsmeow.DownloadableMessage {
+	switch {
+	case msg.GetImageMessage() != nil:
+		return msg.GetImageMessage()
+	case msg.GetVideoMessage() != nil:
+		return msg.GetVideoMessage()
+	case msg.GetAudioMessage() != nil:
+		return msg.GetAudioMessage()
+	case msg.GetDocumentMessage() != nil:
+		return msg.GetDocumentMessage()
+	case msg.GetStickerMessage() != nil:
+		return msg.GetStickerMessage()
+	}
+	return nil
+}
+
+// mediaDetails returns the media type the auto-download policy is configured with and the announced size
+func mediaDetails(media whatsmeow.DownloadableMessage) (mediaType string, fileLength uint64) {
+	switch media := media.(type) {
+	case *waE2E.ImageMessage:
+		return "image", media.GetFileLength()
+	case *waE2E.VideoMessage:
+		return "video", media.GetFileLength()
+	case *waE2E.AudioMessage:
+		return "audio", media.GetFileLength()
+	case *waE2E.DocumentMessage:
+		return "document", media.GetFileLength()
+	case *waE2E.StickerMessage:
+		return "sticker", media.GetFileLength()
+	}
+	return "", 0
+}
@@ -0,0 +1,99 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waMmsRetry"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+var (
+	mediaRetryMu      sync.Mutex
+	mediaRetryWaiters = make(map[types.MessageID][]chan *events.MediaRetry)
+)
+
+// RequestMediaReupload asks the phone to upload the expired media of a message again and returns its new direct path
+func RequestMediaReupload(ctx context.Context, info *types.MessageInfo, mediaKey []byte) (string, error) {
+	if cli == nil {
+		return "", pkgError.ErrWaCLI
+	}
+	return awaitMediaReupload(ctx, info.ID, mediaKey, config.WhatsappMediaRetryTimeout, func() error {
+		return cli.SendMediaRetryReceipt(info, mediaKey)
+	})
+}
+
+// awaitMediaReupload sends the upload request and waits for the phone's answer, which is decrypted with the media key
+func awaitMediaReupload(ctx context.Context, messageID types.MessageID, mediaKey []byte, timeout time.Duration, request func() error) (string, error) {
+	waiter := make(chan *events.MediaRetry, 1)
+	mediaRetryMu.Lock()
+	mediaRetryWaiters[messageID] = append(mediaRetryWaiters[messageID], waiter)
+	mediaRetryMu.Unlock()
+	defer removeMediaRetryWaiter(messageID, waiter)
+
+	if err := request(); err != nil {
+		return "", pkgError.WaMediaRetryError(fmt.Sprintf("failed to ask the phone to upload media again: %v", err))
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case evt := <-waiter:
+		notification, err := whatsmeow.DecryptMediaRetryNotification(evt, mediaKey)
+		if errors.Is(err, whatsmeow.ErrMediaNotAvailableOnPhone) {
+			return "", pkgError.WaMediaRetryError("media is no longer available on the phone")
+		} else if err != nil {
+			return "", pkgError.WaMediaRetryError(fmt.Sprintf("failed to read media upload from the phone: %v", err))
+		}
+		if notification.GetResult() != waMmsRetry.MediaRetryNotification_SUCCESS || notification.GetDirectPath() == "" {
+			return "", pkgError.WaMediaRetryError(fmt.Sprintf("phone could not upload media again: %s", notification.GetResult()))
+		}
+		return notification.GetDirectPath(), nil
+	case <-timer.C:
+		return "", pkgError.WaMediaRetryError(fmt.Sprintf("phone did not upload media again within %s, make sure it is online and connected", timeout))
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// handleMediaRetry hands the phone's answer to a media upload request to everyone waiting for it
+func handleMediaRetry(evt *events.MediaRetry) {
+	mediaRetryMu.Lock()
+	waiters := mediaRetryWaiters[evt.MessageID]
+	delete(mediaRetryWaiters, evt.MessageID)
+	mediaRetryMu.Unlock()
+
+	if len(waiters) == 0 {
+		log.Debugf("Ignoring media retry for %s, no download is waiting for it", evt.MessageID)
+		return
+	}
+	for _, waiter := range waiters {
+		waiter <- evt
+	}
+}
+
+func removeMediaRetryWaiter(messageID types.MessageID, waiter chan *events.MediaRetry) {
+	mediaRetryMu.Lock()
+	defer mediaRetryMu.Unlock()
+
+	waiters := mediaRetryWaiters[messageID]
+	for i, pending := range waiters {
+		if pending == waiter {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(mediaRetryWaiters, messageID)
+	} else {
+		mediaRetryWaiters[messageID] = waiters
+	}
+}
@@ -0,0 +1,169 @@
+package whatsapp
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"go.mau.fi/whatsmeow/proto/waMmsRetry"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+type MediaRetryTestSuite struct {
+	suite.Suite
+	mediaKey []byte
+}
+
+func (suite *MediaRetryTestSuite) SetupTest() {
+	suite.mediaKey = []byte("0123456789abcdef0123456789abcdef")
+}
+
+// encryptNotification encrypts an answer of the phone the way it does, with a key derived from the media key
+func (suite *MediaRetryTestSuite) encryptNotification(messageID types.MessageID, mediaKey []byte, notification *waMmsRetry.MediaRetryNotification) *events.MediaRetry {
+	plaintext, err := proto.Marshal(notification)
+	require.NoError(suite.T(), err)
+	key, err := hkdf.Key(sha256.New, mediaKey, nil, "WhatsApp Media Retry Notification", 32)
+	require.NoError(suite.T(), err)
+	block, err := aes.NewCipher(key)
+	require.NoError(suite.T(), err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(suite.T(), err)
+
+	iv := make([]byte, gcm.NonceSize())
+	return &events.MediaRetry{
+		MessageID:  messageID,
+		IV:         iv,
+		Ciphertext: gcm.Seal(nil, iv, plaintext, []byte(messageID)),
+	}
+}
+
+func (suite *MediaRetryTestSuite) TestAwaitMediaReupload() {
+	tests := []struct {
+		name      string
+		answer    func(messageID types.MessageID) *events.MediaRetry // nil leaves the request unanswered
+		request   error
+		want      string
+		wantError string
+	}{
+		{
+			name: "should return the direct path the phone uploaded the media to",
+			answer: func(messageID types.MessageID) *events.MediaRetry {
+				return suite.encryptNotification(messageID, suite.mediaKey, &waMmsRetry.MediaRetryNotification{
+					StanzaID:   proto.String(messageID),
+					DirectPath: proto.String("/v/t62.7118-24/new"),
+					Result:     waMmsRetry.MediaRetryNotification_SUCCESS.Enum(),
+				})
+			},
+			want: "/v/t62.7118-24/new",
+		},
+		{
+			name: "should fail when the phone no longer has the media",
+			answer: func(messageID types.MessageID) *events.MediaRetry {
+				return &events.MediaRetry{MessageID: messageID, Error: &events.MediaRetryError{Code: 2}}
+			},
+			wantError: "no longer available on the phone",
+		},
+		{
+			name: "should fail when the phone could not upload the media",
+			answer: func(messageID types.MessageID) *events.MediaRetry {
+				return suite.encryptNotification(messageID, suite.mediaKey, &waMmsRetry.MediaRetryNotification{
+					StanzaID: proto.String(messageID),
+					Result:   waMmsRetry.MediaRetryNotification_NOT_FOUND.Enum(),
+				})
+			},
+			wantError: "NOT_FOUND",
+		},
+		{
+			name: "should fail on an answer encrypted with another media key",
+			answer: func(messageID types.MessageID) *events.MediaRetry {
+				return suite.encryptNotification(messageID, []byte("another media key of 32 bytes..."), &waMmsRetry.MediaRetryNotification{
+					DirectPath: proto.String("/v/t62.7118-24/new"),
+					Result:     waMmsRetry.MediaRetryNotification_SUCCESS.Enum(),
+				})
+			},
+			wantError: "failed to read media upload from the phone",
+		},
+		{
+			name:      "should give up when the phone does not answer in time",
+			wantError: "did not upload media again within 50ms",
+		},
+		{
+			name:      "should fail when the request cannot be sent",
+			request:   errors.New("not connected"),
+			wantError: "not connected",
+		},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			messageID := types.MessageID("3EB0" + time.Now().Format("150405.000000"))
+			directPath, err := awaitMediaReupload(context.Background(), messageID, suite.mediaKey, 50*time.Millisecond, func() error {
+				if tt.answer != nil {
+					go handleMediaRetry(tt.answer(messageID))
+				}
+				return tt.request
+			})
+
+			if tt.wantError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantError)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, directPath)
+			}
+
+			mediaRetryMu.Lock()
+			defer mediaRetryMu.Unlock()
+			assert.NotContains(t, mediaRetryWaiters, messageID, "the waiter is removed once the request is over")
+		})
+	}
+}
+
+func (suite *MediaRetryTestSuite) TestConcurrentDownloadsShareTheAnswer() {
+	messageID := types.MessageID("3EB0SHARED")
+	answer := suite.encryptNotification(messageID, suite.mediaKey, &waMmsRetry.MediaRetryNotification{
+		DirectPath: proto.String("/v/t62.7118-24/shared"),
+		Result:     waMmsRetry.MediaRetryNotification_SUCCESS.Enum(),
+	})
+
+	requested := make(chan struct{}, 2)
+	results := make(chan string, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			directPath, err := awaitMediaReupload(context.Background(), messageID, suite.mediaKey, time.Second, func() error {
+				requested <- struct{}{}
+				return nil
+			})
+			assert.NoError(suite.T(), err)
+			results <- directPath
+		}()
+	}
+	<-requested
+	<-requested
+
+	handleMediaRetry(answer)
+	assert.Equal(suite.T(), "/v/t62.7118-24/shared", <-results)
+	assert.Equal(suite.T(), "/v/t62.7118-24/shared", <-results)
+}
+
+func (suite *MediaRetryTestSuite) TestCanceledRequest() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := awaitMediaReupload(ctx, "3EB0CANCELED", suite.mediaKey, time.Second, func() error { return nil })
+	assert.ErrorIs(suite.T(), err, context.Canceled)
+}
+
+func TestMediaRetryTestSuite(t *testing.T) {
+	suite.Run(t, new(MediaRetryTestSuite))
+}
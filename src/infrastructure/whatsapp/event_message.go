@@ -10,9 +10,11 @@ import (
 	"go.mau.fi/whatsmeow/types"
 
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/mediastore"
 	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
 	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow"
 	"go.mau.fi/whatsmeow/types/events"
 )
 
@@ -138,7 +140,7 @@ func createMessagePayload(ctx context.Context, evt *events.Message) (map[string]
 	}
 
 	if audioMedia := evt.Message.GetAudioMessage(); audioMedia != nil {
-		path, err := utils.ExtractMedia(ctx, cli, config.PathMedia, audioMedia)
+		path, err := extractWebhookMedia(ctx, evt, audioMedia)
 		if err != nil {
 			logrus.Errorf("Failed to download audio from %s: %v", evt.Info.SourceString(), err)
 			return nil, pkgError.WebhookError(fmt.Sprintf("Failed to download audio: %v", err))
@@ -151,7 +153,7 @@ func createMessagePayload(ctx context.Context, evt *events.Message) (map[string]
 	}
 
 	if documentMedia := evt.Message.GetDocumentMessage(); documentMedia != nil {
-		path, err := utils.ExtractMedia(ctx, cli, config.PathMedia, documentMedia)
+		path, err := extractWebhookMedia(ctx, evt, documentMedia)
 		if err != nil {
 			logrus.Errorf("Failed to download document from %s: %v", evt.Info.SourceString(), err)
 			return nil, pkgError.WebhookError(fmt.Sprintf("Failed to download document: %v", err))
@@ -160,7 +162,7 @@ func createMessagePayload(ctx context.Context, evt *events.Message) (map[string]
 	}
 
 	if imageMedia := evt.Message.GetImageMessage(); imageMedia != nil {
-		path, err := utils.ExtractMedia(ctx, cli, config.PathMedia, imageMedia)
+		path, err := extractWebhookMedia(ctx, evt, imageMedia)
 		if err != nil {
 			logrus.Errorf("Failed to download image from %s: %v", evt.Info.SourceString(), err)
 			return nil, pkgError.WebhookError(fmt.Sprintf("Failed to download image: %v", err))
@@ -185,7 +187,7 @@ func createMessagePayload(ctx context.Context, evt *events.Message) (map[string]
 	}
 
 	if stickerMedia := evt.Message.GetStickerMessage(); stickerMedia != nil {
-		path, err := utils.ExtractMedia(ctx, cli, config.PathMedia, stickerMedia)
+		path, err := extractWebhookMedia(ctx, evt, stickerMedia)
 		if err != nil {
 			logrus.Errorf("Failed to download sticker from %s: %v", evt.Info.SourceString(), err)
 			return nil, pkgError.WebhookError(fmt.Sprintf("Failed to download sticker: %v", err))
@@ -194,7 +196,7 @@ func createMessagePayload(ctx context.Context, evt *events.Message) (map[string]
 	}
 
 	if videoMedia := evt.Message.GetVideoMessage(); videoMedia != nil {
-		path, err := utils.ExtractMedia(ctx, cli, config.PathMedia, videoMedia)
+		path, err := extractWebhookMedia(ctx, evt, videoMedia)
 		if err != nil {
 			logrus.Errorf("Failed to download video from %s: %v", evt.Info.SourceString(), err)
 			return nil, pkgError.WebhookError(fmt.Sprintf("Failed to download video: %v", err))
@@ -204,3 +206,30 @@ func createMessagePayload(ctx context.Context, evt *events.Message) (map[string]
 
 	return body, nil
 }
+
+// extractWebhookMedia links the media of a message for webhook receivers. Media the auto-download policy
+// allows is taken from the media store, where it is downloaded once for storage and webhooks. The stored copy
+// is only linked once the downloaded content matched its hash, a message naming another file's hash gets no link.
+func extractWebhookMedia(ctx context.Context, evt *events.Message, media whatsmeow.DownloadableMessage) (utils.ExtractedMedia, error) {
+	extracted := utils.DescribeMedia(media)
+	if mediaType, fileLength := mediaDetails(media); utils.AutoDownloadMedia(evt.Info.Chat, mediaType, fileLength) {
+		var err error
+		if extracted, err = utils.ExtractMedia(ctx, cli, mediastore.For(config.PathMedia), media); err != nil {
+			return extracted, err
+		}
+	}
+
+	// Buckets hand out presigned URLs, media on local disk is streamed by this server
+	if extracted.MediaURL == "" {
+		extracted.MediaURL = signedMediaURL(evt)
+	}
+	return extracted, nil
+}
+
+// signedMediaURL lets webhook receivers fetch the media without access to this server's disk
+func signedMediaURL(evt *events.Message) string {
+	if config.AppPublicURL == "" {
+		return ""
+	}
+	return utils.SignMediaURL(config.AppPublicURL, evt.Info.ID, evt.Info.Chat.String(), time.Now().Add(config.AppMediaURLTTL))
+}
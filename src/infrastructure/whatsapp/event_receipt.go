@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
 	"github.com/sirupsen/logrus"
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
@@ -82,3 +83,42 @@ func forwardReceiptToWebhook(ctx context.Context, evt *events.Receipt) error {
 	logrus.Info("Message ack event forwarded to webhook")
 	return nil
 }
+
+// storeReceipt persists delivered, read and played receipts that recipients send for our outgoing messages,
+// the chat storage drops receipts of messages it does not have as sent by this device
+func storeReceipt(ctx context.Context, evt *events.Receipt, chatStorageRepo domainChatStorage.IChatStorageRepository) error {
+	if evt.IsFromMe || len(evt.MessageIDs) == 0 {
+		return nil
+	}
+
+	var receiptType string
+	switch evt.Type {
+	case types.ReceiptTypeDelivered:
+		receiptType = domainChatStorage.ReceiptDelivered
+	case types.ReceiptTypeRead:
+		receiptType = domainChatStorage.ReceiptRead
+	case types.ReceiptTypePlayed:
+		receiptType = domainChatStorage.ReceiptPlayed
+	default:
+		return nil
+	}
+
+	// Sent messages are stored under the phone number JID, so receipts addressed by LID are mapped back to it
+	chatJID := resolveReceiptJID(ctx, evt.Chat)
+	recipientJID := resolveReceiptJID(ctx, evt.Sender)
+
+	return chatStorageRepo.StoreMessageReceipts(chatJID.String(), recipientJID.String(), evt.MessageIDs, receiptType, evt.Timestamp)
+}
+
+// resolveReceiptJID strips the device part of a JID and maps a LID to its phone number when known
+func resolveReceiptJID(ctx context.Context, jid types.JID) types.JID {
+	jid = jid.ToNonAD()
+	if jid.Server != types.HiddenUserServer || cli == nil {
+		return jid
+	}
+
+	if pn, err := cli.Store.LIDs.GetPNForLID(ctx, jid); err == nil && !pn.IsEmpty() {
+		return pn.ToNonAD()
+	}
+	return jid
+}
@@ -0,0 +1,46 @@
+package whatsapp
+
+import (
+	"context"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// handleMessageRevision records message edits and revokes in the revision history of the message they refer to
+func handleMessageRevision(_ context.Context, evt *events.Message, chatStorageRepo domainChatStorage.IChatStorageRepository) {
+	protocolMessage := evt.Message.GetProtocolMessage()
+	if protocolMessage == nil {
+		return
+	}
+
+	revision := &domainChatStorage.MessageRevision{
+		MessageID: protocolMessage.GetKey().GetID(),
+		ChatJID:   evt.Info.Chat.String(),
+		EditorJID: evt.Info.Sender.ToNonAD().String(),
+		Timestamp: evt.Info.Timestamp,
+	}
+	if timestamp := protocolMessage.GetTimestampMS(); timestamp > 0 {
+		revision.Timestamp = time.UnixMilli(timestamp)
+	}
+
+	var err error
+	switch protocolMessage.GetType() {
+	case waE2E.ProtocolMessage_MESSAGE_EDIT:
+		revision.Content = utils.ExtractMessageTextFromProto(protocolMessage.GetEditedMessage())
+		revision.RawMessage, _ = utils.RawMessageFields(protocolMessage.GetEditedMessage(), nil)
+		err = chatStorageRepo.StoreMessageEdit(revision)
+	case waE2E.ProtocolMessage_REVOKE:
+		err = chatStorageRepo.StoreMessageRevoke(revision, config.WhatsappRevokeKeepContent)
+	default:
+		return
+	}
+
+	if err != nil {
+		log.Errorf("Failed to store %s of message %s: %v", protocolMessage.GetType(), revision.MessageID, err)
+	}
+}
@@ -0,0 +1,214 @@
+package whatsapp
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/mediastore"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// statusLifetime is how long a status update stays visible on WhatsApp
+const statusLifetime = 24 * time.Hour
+
+// isStatusMessage reports whether the message was posted to status@broadcast
+func isStatusMessage(evt *events.Message) bool {
+	return evt.Info.Chat == types.StatusBroadcastJID
+}
+
+// handleStatusMessage stores, optionally downloads and views, and forwards a status update
+func handleStatusMessage(ctx context.Context, evt *events.Message, chatStorageRepo domainChatStorage.IChatStorageRepository) {
+	sender := evt.Info.Sender.String()
+
+	// A revoke on status@broadcast means the poster deleted the status
+	if protocolMessage := evt.Message.GetProtocolMessage(); protocolMessage != nil {
+		if protocolMessage.GetType() == waE2E.ProtocolMessage_REVOKE {
+			statusID := protocolMessage.GetKey().GetID()
+			if err := chatStorageRepo.DeleteStatus(statusID, sender); err != nil {
+				log.Errorf("Failed to delete revoked status %s: %v", statusID, err)
+			}
+		}
+		return
+	}
+
+	// Our own status updates are already known to us
+	if evt.Info.IsFromMe {
+		return
+	}
+
+	status := buildStatus(evt)
+	if status.Content == "" && status.MediaType == "" {
+		log.Debugf("Skipping status %s from %s - no content or media", evt.Info.ID, sender)
+		return
+	}
+
+	if config.WhatsappStatusAutoDownload {
+		if media := getStatusDownloadable(evt.Message); media != nil {
+			extracted, err := utils.ExtractMedia(ctx, cli, mediastore.For(config.PathStatuses), media)
+			if err != nil {
+				log.Errorf("Failed to download status media %s from %s: %v", evt.Info.ID, sender, err)
+			} else {
+				status.MediaPath = extracted.MediaPath
+			}
+		}
+	}
+
+	if config.WhatsappStatusAutoMarkViewed {
+		if err := cli.MarkRead([]types.MessageID{evt.Info.ID}, time.Now(), evt.Info.Chat, evt.Info.Sender); err != nil {
+			log.Warnf("Failed to mark status %s as viewed: %v", evt.Info.ID, err)
+		} else {
+			status.Viewed = true
+		}
+	}
+
+	if err := chatStorageRepo.StoreStatus(status); err != nil {
+		log.Errorf("Failed to store status %s from %s: %v", evt.Info.ID, sender, err)
+	}
+
+	if len(config.WhatsappWebhook) > 0 {
+		go func(status *domainChatStorage.Status) {
+			if err := forwardStatusToWebhook(ctx, status); err != nil {
+				logrus.Error("Failed forward status to webhook: ", err)
+			}
+		}(status)
+	}
+}
+
+// buildStatus maps an incoming status message to its storage representation
+func buildStatus(evt *events.Message) *domainChatStorage.Status {
+	mediaType, filename, url, _, mediaKey, fileSHA256, fileEncSHA256, fileLength := utils.ExtractMediaInfo(evt.Message)
+
+	return &domainChatStorage.Status{
+		ID:            evt.Info.ID,
+		Sender:        evt.Info.Sender.String(),
+		PushName:      evt.Info.PushName,
+		Content:       utils.ExtractMessageTextFromProto(evt.Message),
+		MediaType:     mediaType,
+		MimeType:      getStatusMimeType(evt.Message),
+		Filename:      filename,
+		URL:           url,
+		MediaKey:      mediaKey,
+		FileSHA256:    fileSHA256,
+		FileEncSHA256: fileEncSHA256,
+		FileLength:    fileLength,
+		Timestamp:     evt.Info.Timestamp,
+		ExpiresAt:     evt.Info.Timestamp.Add(statusLifetime),
+	}
+}
+
+// getStatusDownloadable returns the media attached to a status update, if any
+func getStatusDownloadable(msg *waE2E.Message) whatsmeow.DownloadableMessage {
+	if img := msg.GetImageMessage(); img != nil {
+		return img
+	}
+	if vid := msg.GetVideoMessage(); vid != nil {
+		return vid
+	}
+	if aud := msg.GetAudioMessage(); aud != nil {
+		return aud
+	}
+	return nil
+}
+
+// getStatusMimeType returns the MIME type of the media attached to a status update
+func getStatusMimeType(msg *waE2E.Message) string {
+	if img := msg.GetImageMessage(); img != nil {
+		return img.GetMimetype()
+	}
+	if vid := msg.GetVideoMessage(); vid != nil {
+		return vid.GetMimetype()
+	}
+	if aud := msg.GetAudioMessage(); aud != nil {
+		return aud.GetMimetype()
+	}
+	return ""
+}
+
+// StatusSenderJIDs returns the JIDs the statuses of a contact may be stored under, its phone number and its LID when the mapping is known
+func StatusSenderJIDs(ctx context.Context, sender string) []string {
+	senderJID, err := types.ParseJID(sender)
+	if err != nil || cli == nil {
+		return []string{sender}
+	}
+
+	senderJID = senderJID.ToNonAD()
+	senders := []string{senderJID.String()}
+
+	var alternate types.JID
+	switch senderJID.Server {
+	case types.HiddenUserServer:
+		alternate, err = cli.Store.LIDs.GetPNForLID(ctx, senderJID)
+	case types.DefaultUserServer:
+		alternate, err = cli.Store.LIDs.GetLIDForPN(ctx, senderJID)
+	}
+	if err == nil && !alternate.IsEmpty() {
+		senders = append(senders, alternate.ToNonAD().String())
+	}
+	return senders
+}
+
+// createStatusPayload creates a webhook payload for status update events
+func createStatusPayload(ctx context.Context, status *domainChatStorage.Status) map[string]any {
+	body := make(map[string]any)
+	payload := make(map[string]any)
+
+	payload["id"] = status.ID
+	payload["sender_id"] = status.Sender
+	if senderJID, err := types.ParseJID(status.Sender); err == nil && senderJID.Server == types.HiddenUserServer {
+		if pn, err := cli.Store.LIDs.GetPNForLID(ctx, senderJID); err == nil && !pn.IsEmpty() {
+			payload["sender_pn"] = pn.String()
+		}
+	}
+	if status.PushName != "" {
+		payload["pushname"] = status.PushName
+	}
+	if status.Content != "" {
+		payload["content"] = status.Content
+	}
+	if status.MediaType != "" {
+		payload["media_type"] = status.MediaType
+		payload["mime_type"] = status.MimeType
+		payload["file_length"] = status.FileLength
+	}
+	if status.MediaPath != "" {
+		payload["media_path"] = status.MediaPath
+		// Buckets get a presigned URL, fresh for every webhook
+		mediaURL, err := mediastore.For(config.PathStatuses).URL(ctx, path.Base(status.MediaPath), config.AppMediaURLTTL)
+		if err != nil {
+			logrus.Warnf("Failed to presign status media %s: %v", status.MediaPath, err)
+		} else if mediaURL != "" {
+			payload["media_url"] = mediaURL
+		}
+	}
+	payload["viewed"] = status.Viewed
+	payload["expires_at"] = status.ExpiresAt.Format(time.RFC3339)
+
+	body["payload"] = payload
+	body["event"] = "status"
+	body["timestamp"] = status.Timestamp.Format(time.RFC3339)
+
+	return body
+}
+
+// forwardStatusToWebhook forwards status update events to the configured webhook URLs
+func forwardStatusToWebhook(ctx context.Context, status *domainChatStorage.Status) error {
+	logrus.Infof("Forwarding status event to %d configured webhook(s)", len(config.WhatsappWebhook))
+	payload := createStatusPayload(ctx, status)
+
+	for _, url := range config.WhatsappWebhook {
+		if err := submitWebhook(ctx, payload, url); err != nil {
+			return err
+		}
+	}
+
+	logrus.Info("Status event forwarded to webhook")
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"go.mau.fi/whatsmeow/proto/waHistorySync"
+	"go.mau.fi/whatsmeow/proto/waWeb"
 
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
 	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
@@ -361,15 +362,20 @@ func handler(ctx context.Context, rawEvt any, chatStorageRepo domainChatStorage.
 	case *events.Message:
 		handleMessage(ctx, evt, chatStorageRepo)
 	case *events.Receipt:
-		handleReceipt(ctx, evt)
+		handleReceipt(ctx, evt, chatStorageRepo)
+	case *events.MediaRetry:
+		handleMediaRetry(evt)
 	case *events.Presence:
 		handlePresence(ctx, evt)
 	case *events.HistorySync:
 		handleHistorySync(ctx, evt, chatStorageRepo)
 	case *events.AppState:
 		handleAppState(ctx, evt)
+	case *events.Pin, *events.Archive, *events.Mute, *events.MarkChatAsRead, *events.ClearChat, *events.DeleteChat,
+		*events.Contact, *events.LabelEdit, *events.LabelAssociationChat:
+		handleChatStateChange(ctx, evt, chatStorageRepo)
 	case *events.GroupInfo:
-		handleGroupInfo(ctx, evt)
+		handleGroupInfo(ctx, evt, chatStorageRepo)
 	}
 }
 
@@ -467,13 +473,22 @@ func handleMessage(ctx context.Context, evt *events.Message, chatStorageRepo dom
 		evt.Message,
 	)
 
+	// Status updates have their own storage and webhook event
+	if isStatusMessage(evt) {
+		handleStatusMessage(ctx, evt, chatStorageRepo)
+		return
+	}
+
 	if err := chatStorageRepo.CreateMessage(ctx, evt); err != nil {
 		// Log storage errors to avoid silent failures that could lead to data loss
 		log.Errorf("Failed to store incoming message %s: %v", evt.Info.ID, err)
 	}
 
-	// Handle image message if present
-	handleImageMessage(ctx, evt)
+	// Edits and revokes are applied to the message they refer to and kept in its history
+	handleMessageRevision(ctx, evt, chatStorageRepo)
+
+	// Download media the auto-download policy allows
+	handleAutoDownload(ctx, evt, chatStorageRepo)
 
 	// Auto-mark message as read if configured
 	handleAutoMarkRead(ctx, evt)
@@ -502,16 +517,6 @@ func buildMessageMetaParts(evt *events.Message) []string {
 	return metaParts
 }
 
-func handleImageMessage(ctx context.Context, evt *events.Message) {
-	if img := evt.Message.GetImageMessage(); img != nil {
-		if path, err := utils.ExtractMedia(ctx, cli, config.PathStorages, img); err != nil {
-			log.Errorf("Failed to download image: %v", err)
-		} else {
-			log.Infof("Image downloaded to %s", path)
-		}
-	}
-}
-
 func handleAutoMarkRead(_ context.Context, evt *events.Message) {
 	// Only mark read if auto-mark read is enabled and message is incoming
 	if !config.WhatsappAutoMarkRead || evt.Info.IsFromMe {
@@ -601,10 +606,11 @@ func handleAutoReply(ctx context.Context, evt *events.Message, chatStorageRepo d
 	recipientJID := utils.FormatJID(evt.Info.Sender.String())
 
 	// Send the auto-reply message
+	autoReply := &waE2E.Message{Conversation: proto.String(config.WhatsappAutoReplyMessage)}
 	response, err := cli.SendMessage(
 		ctx,
 		recipientJID,
-		&waE2E.Message{Conversation: proto.String(config.WhatsappAutoReplyMessage)},
+		autoReply,
 	)
 
 	if err != nil {
@@ -628,6 +634,7 @@ func handleAutoReply(ctx context.Context, evt *events.Message, chatStorageRepo d
 			recipientJID.String(),           // Recipient JID
 			config.WhatsappAutoReplyMessage, // Auto-reply content
 			response.Timestamp,              // Timestamp from response
+			autoReply,                       // Sent message
 		); err != nil {
 			// Log storage error but don't fail the auto-reply
 			log.Errorf("Failed to store auto-reply message in chat storage: %v", err)
@@ -658,7 +665,11 @@ func handleWebhookForward(ctx context.Context, evt *events.Message) {
 	}
 }
 
-func handleReceipt(ctx context.Context, evt *events.Receipt) {
+func handleReceipt(ctx context.Context, evt *events.Receipt, chatStorageRepo domainChatStorage.IChatStorageRepository) {
+	if err := storeReceipt(ctx, evt, chatStorageRepo); err != nil {
+		log.Errorf("Failed to store receipt for %v: %v", evt.MessageIDs, err)
+	}
+
 	sendReceipt := false
 	switch evt.Type {
 	case types.ReceiptTypeRead, types.ReceiptTypeReadSelf:
@@ -804,12 +815,15 @@ func processConversationMessages(_ context.Context, data *waHistorySync.HistoryS
 				continue
 			}
 
+			// Reactions to the message come along with it
+			storeHistoryReactions(chatStorageRepo, jid, messageID, msg.GetReactions())
+
 			// Extract message content and media info
 			content := utils.ExtractMessageTextFromProto(msg.GetMessage())
-			mediaType, filename, url, mediaKey, fileSHA256, fileEncSHA256, fileLength := utils.ExtractMediaInfo(msg.GetMessage())
+			mediaType, filename, url, directPath, mediaKey, fileSHA256, fileEncSHA256, fileLength := utils.ExtractMediaInfo(msg.GetMessage())
 
-			// Skip if there's no content and no media
-			if content == "" && mediaType == "" {
+			// Skip if there's no content and no media, unless it is kept with its raw message
+			if content == "" && mediaType == "" && !utils.HasStandaloneContent(msg.GetMessage()) {
 				continue
 			}
 
@@ -866,12 +880,20 @@ func processConversationMessages(_ context.Context, data *waHistorySync.HistoryS
 				MediaType:     mediaType,
 				Filename:      filename,
 				URL:           url,
+				DirectPath:    directPath,
 				MediaKey:      mediaKey,
 				FileSHA256:    fileSHA256,
 				FileEncSHA256: fileEncSHA256,
 				FileLength:    fileLength,
 			}
 
+			// Keep the raw message with the info it is parsed to, like for messages received live
+			var info *types.MessageInfo
+			if parsed, err := cli.ParseWebMessage(jid, msg); err == nil {
+				info = &parsed.Info
+			}
+			message.RawMessage, message.RawInfo = utils.RawMessageFields(msg.GetMessage(), info)
+
 			messageBatch = append(messageBatch, message)
 		}
 
@@ -902,6 +924,37 @@ func processConversationMessages(_ context.Context, data *waHistorySync.HistoryS
 	return nil
 }
 
+// storeHistoryReactions stores the reactions attached to a message from history sync
+func storeHistoryReactions(chatStorageRepo domainChatStorage.IChatStorageRepository, chatJID types.JID, messageID string, reactions []*waWeb.Reaction) {
+	for _, reaction := range reactions {
+		// The key is the one of the reaction, its participant is the reactor in groups
+		reactor := chatJID
+		if reaction.GetKey().GetFromMe() {
+			if cli.Store.ID == nil {
+				continue
+			}
+			reactor = *cli.Store.ID
+		} else if participant := reaction.GetKey().GetParticipant(); participant != "" {
+			participantJID, err := types.ParseJID(participant)
+			if err != nil {
+				continue
+			}
+			reactor = participantJID
+		}
+
+		err := chatStorageRepo.StoreReaction(&domainChatStorage.Reaction{
+			MessageID:  messageID,
+			ChatJID:    chatJID.String(),
+			ReactorJID: reactor.ToNonAD().String(),
+			Emoji:      reaction.GetText(),
+			Timestamp:  time.UnixMilli(reaction.GetSenderTimestampMS()),
+		})
+		if err != nil {
+			log.Warnf("Failed to store reaction to message %s: %v", messageID, err)
+		}
+	}
+}
+
 // processPushNames processes push names from history sync to update chat names
 func processPushNames(_ context.Context, data *waHistorySync.HistorySync, chatStorageRepo domainChatStorage.IChatStorageRepository) error {
 	pushnames := data.GetPushnames()
@@ -936,7 +989,20 @@ func processPushNames(_ context.Context, data *waHistorySync.HistorySync, chatSt
 	return nil
 }
 
-func handleGroupInfo(ctx context.Context, evt *events.GroupInfo) {
+func handleGroupInfo(ctx context.Context, evt *events.GroupInfo, chatStorageRepo domainChatStorage.IChatStorageRepository) {
+	// Keep the stored disappearing timer in sync so outgoing messages use the current value
+	if evt.Ephemeral != nil {
+		expiration := uint32(0)
+		if evt.Ephemeral.IsEphemeral {
+			expiration = evt.Ephemeral.DisappearingTimer
+		}
+		if err := chatStorageRepo.SetChatEphemeralExpiration(evt.JID.String(), expiration, evt.Timestamp); err != nil {
+			log.Errorf("Failed to update disappearing timer of group %s: %v", evt.JID, err)
+		} else {
+			log.Infof("Group %s: disappearing timer set to %d seconds", evt.JID, expiration)
+		}
+	}
+
 	// Only process events that have actual changes
 	hasChanges := len(evt.Join) > 0 || len(evt.Leave) > 0 || len(evt.Promote) > 0 || len(evt.Demote) > 0 ||
 		evt.Name != nil || evt.Topic != nil || evt.Locked != nil || evt.Announce != nil
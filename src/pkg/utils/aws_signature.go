@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	awsSigningAlgorithm = "AWS4-HMAC-SHA256"
+	awsTimeFormat       = "20060102T150405Z"
+	awsDateFormat       = "20060102"
+
+	// AWSUnsignedPayload skips hashing the body, S3 accepts it for requests over TLS and for presigned URLs
+	AWSUnsignedPayload = "UNSIGNED-PAYLOAD"
+	// AWSEmptyPayloadHash is the SHA-256 of an empty body
+	AWSEmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// AWSCredentials is an access key with the region and service it signs requests for
+type AWSCredentials struct {
+	AccessKey string
+	SecretKey string
+	Region    string
+	Service   string
+}
+
+// SignAWSRequest adds AWS Signature Version 4 headers to a request, payloadHash is the hex SHA-256 of the body
+func SignAWSRequest(req *http.Request, credentials AWSCredentials, payloadHash string, now time.Time) {
+	now = now.UTC()
+	req.Header.Set("X-Amz-Date", now.Format(awsTimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": awsHost(req)}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || name == "content-md5" || name == "range" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+
+	signedHeaders, canonicalHeaders := awsCanonicalHeaders(headers)
+	scope := awsScope(credentials, now)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsCanonicalURI(req.URL),
+		awsCanonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	signature := awsSignature(credentials, now, scope, canonicalRequest)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsSigningAlgorithm, credentials.AccessKey, scope, signedHeaders, signature))
+}
+
+// PresignAWSURL returns the URL with AWS Signature Version 4 query authentication valid for expiresIn
+func PresignAWSURL(method string, target *url.URL, credentials AWSCredentials, now time.Time, expiresIn time.Duration) string {
+	now = now.UTC()
+	scope := awsScope(credentials, now)
+
+	query := target.Query()
+	query.Set("X-Amz-Algorithm", awsSigningAlgorithm)
+	query.Set("X-Amz-Credential", credentials.AccessKey+"/"+scope)
+	query.Set("X-Amz-Date", now.Format(awsTimeFormat))
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expiresIn/time.Second), 10))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalQuery := awsCanonicalQuery(query)
+	canonicalRequest := strings.Join([]string{
+		method,
+		awsCanonicalURI(target),
+		canonicalQuery,
+		"host:" + target.Host + "\n",
+		"host",
+		AWSUnsignedPayload,
+	}, "\n")
+
+	signature := awsSignature(credentials, now, scope, canonicalRequest)
+	return fmt.Sprintf("%s://%s%s?%s&X-Amz-Signature=%s", target.Scheme, target.Host, awsCanonicalURI(target), canonicalQuery, signature)
+}
+
+// AWSURIEncode escapes a string like AWS expects, slashes are kept when encodeSlash is false
+func AWSURIEncode(value string, encodeSlash bool) string {
+	var builder strings.Builder
+	for _, b := range []byte(value) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '.', b == '_', b == '~':
+			builder.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			builder.WriteByte(b)
+		default:
+			fmt.Fprintf(&builder, "%%%02X", b)
+		}
+	}
+	return builder.String()
+}
+
+func awsHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+func awsCanonicalURI(target *url.URL) string {
+	if target.Path == "" {
+		return "/"
+	}
+	return AWSURIEncode(target.Path, false)
+}
+
+func awsCanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, AWSURIEncode(key, true)+"="+AWSURIEncode(value, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func awsCanonicalHeaders(headers map[string]string) (signedHeaders, canonicalHeaders string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var builder strings.Builder
+	for _, name := range names {
+		builder.WriteString(name + ":" + headers[name] + "\n")
+	}
+	return strings.Join(names, ";"), builder.String()
+}
+
+func awsScope(credentials AWSCredentials, now time.Time) string {
+	return strings.Join([]string{now.Format(awsDateFormat), credentials.Region, credentials.Service, "aws4_request"}, "/")
+}
+
+func awsSignature(credentials AWSCredentials, now time.Time, scope, canonicalRequest string) string {
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		awsSigningAlgorithm,
+		now.Format(awsTimeFormat),
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := awsHMAC([]byte("AWS4"+credentials.SecretKey), now.Format(awsDateFormat))
+	key = awsHMAC(key, credentials.Region)
+	key = awsHMAC(key, credentials.Service)
+	key = awsHMAC(key, "aws4_request")
+	return hex.EncodeToString(awsHMAC(key, stringToSign))
+}
+
+func awsHMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"image"
+	"image/color"
 	"image/jpeg"
 	"io"
 	"mime/multipart"
@@ -16,8 +17,25 @@ const (
 	MaxGroupPhotoSize      = 100 * 1024 // 100KB
 	GroupPhotoQuality      = 80         // JPEG quality
 	MaxGroupPhotoDimension = 640        // Max width/height in pixels
+
+	// WhatsApp chat image constraints
+	MaxCompressedImageSize      = 300 * 1024 // 300KB, close to what the WhatsApp apps send
+	MaxCompressedImageDimension = 1600       // Max width/height in pixels after compression
+	CompressedImageQuality      = 80         // Starting JPEG quality of the compression search
+	OriginalImageQuality        = 92         // JPEG quality when re-encoding without compression
+	ImageThumbnailWidth         = 100        // Width of the inline JPEG preview
+	ImageThumbnailQuality       = 70         // JPEG quality of the inline preview
 )
 
+// ProcessedImage is an image ready to be uploaded to WhatsApp
+type ProcessedImage struct {
+	Data      []byte
+	MimeType  string
+	Width     int
+	Height    int
+	Thumbnail []byte // always JPEG
+}
+
 // ProcessGroupPhoto processes an image for WhatsApp group photo requirements:
 // - Converts to JPEG format
 // - Crops to 1:1 aspect ratio (square)
@@ -57,6 +75,84 @@ func ProcessGroupPhoto(file *multipart.FileHeader) (*bytes.Buffer, error) {
 	return compressToJPEG(img, GroupPhotoQuality)
 }
 
+// ProcessImage prepares an image for sending without touching disk:
+// - Applies the EXIF orientation so phone photos are not sent sideways
+// - Re-encodes the image, which strips EXIF and other metadata
+// - Keeps PNG as PNG for transparency and converts every other format to JPEG
+// - When compress is set, fits it within MaxCompressedImageDimension and MaxCompressedImageSize as JPEG
+// - Generates the inline JPEG thumbnail
+func ProcessImage(data []byte, compress bool) (*ProcessedImage, error) {
+	return ProcessImageReader(bytes.NewReader(data), compress)
+}
+
+// ProcessImageReader is ProcessImage for an image read from a file, like an upload spooled to disk
+func ProcessImageReader(reader io.ReadSeeker, compress bool) (*ProcessedImage, error) {
+	img, format, err := decodeOriented(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	var buf *bytes.Buffer
+	mimeType := "image/jpeg"
+	switch {
+	case compress:
+		if img.Bounds().Dx() > MaxCompressedImageDimension || img.Bounds().Dy() > MaxCompressedImageDimension {
+			img = imaging.Fit(img, MaxCompressedImageDimension, MaxCompressedImageDimension, imaging.Lanczos)
+		}
+		buf, err = compressToJPEGWithLimit(flattenImage(img), CompressedImageQuality, MaxCompressedImageSize, 0)
+	case format == "png":
+		buf = new(bytes.Buffer)
+		err = imaging.Encode(buf, img, imaging.PNG)
+		mimeType = "image/png"
+	default:
+		buf = new(bytes.Buffer)
+		err = jpeg.Encode(buf, flattenImage(img), &jpeg.Options{Quality: OriginalImageQuality})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	var thumbnail bytes.Buffer
+	thumbnailImage := imaging.Resize(flattenImage(img), ImageThumbnailWidth, 0, imaging.Lanczos)
+	if err = jpeg.Encode(&thumbnail, thumbnailImage, &jpeg.Options{Quality: ImageThumbnailQuality}); err != nil {
+		return nil, fmt.Errorf("failed to create thumbnail: %w", err)
+	}
+
+	return &ProcessedImage{
+		Data:      buf.Bytes(),
+		MimeType:  mimeType,
+		Width:     img.Bounds().Dx(),
+		Height:    img.Bounds().Dy(),
+		Thumbnail: thumbnail.Bytes(),
+	}, nil
+}
+
+// decodeOriented decodes an image and rotates it according to its EXIF orientation
+func decodeOriented(reader io.ReadSeeker) (image.Image, string, error) {
+	_, format, err := image.DecodeConfig(reader)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err = reader.Seek(0, io.SeekStart); err != nil {
+		return nil, "", err
+	}
+	img, err := imaging.Decode(reader, imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, "", err
+	}
+	return img, format, nil
+}
+
+// flattenImage draws transparent images on white, JPEG has no alpha channel and would render them black
+func flattenImage(img image.Image) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+	bounds := img.Bounds()
+	background := imaging.New(bounds.Dx(), bounds.Dy(), color.White)
+	return imaging.Overlay(background, img, image.Pt(0, 0), 1)
+}
+
 // cropToSquare crops an image to a 1:1 aspect ratio, keeping the center
 func cropToSquare(img image.Image) image.Image {
 	bounds := img.Bounds()
@@ -86,10 +182,11 @@ func cropToSquare(img image.Image) image.Image {
 // compressToJPEG converts an image to JPEG format with specified quality
 // and tries to compress it to stay under the file size limit
 func compressToJPEG(img image.Image, quality int) (*bytes.Buffer, error) {
-	return compressToJPEGWithDepth(img, quality, 0)
+	return compressToJPEGWithLimit(img, quality, MaxGroupPhotoSize, 0)
 }
 
-func compressToJPEGWithDepth(img image.Image, quality int, depth int) (*bytes.Buffer, error) {
+// compressToJPEGWithLimit lowers the quality, then the dimensions, until the JPEG fits in maxSize bytes
+func compressToJPEGWithLimit(img image.Image, quality int, maxSize int, depth int) (*bytes.Buffer, error) {
 	const maxDepth = 10 // Prevent infinite recursion
 	if depth > maxDepth {
 		return nil, fmt.Errorf("exceeded maximum compression attempts")
@@ -104,20 +201,21 @@ func compressToJPEGWithDepth(img image.Image, quality int, depth int) (*bytes.Bu
 	}
 
 	// If file is too large, reduce quality and try again
-	if buf.Len() > MaxGroupPhotoSize && quality > 10 {
-		return compressToJPEGWithDepth(img, quality-10, depth+1)
+	if buf.Len() > maxSize && quality > 10 {
+		return compressToJPEGWithLimit(img, quality-10, maxSize, depth+1)
 	}
 
 	// If still too large even at low quality, resize the image smaller
-	if buf.Len() > MaxGroupPhotoSize {
+	if buf.Len() > maxSize {
 		bounds := img.Bounds()
-		newSize := int(float64(bounds.Dx()) * 0.8) // Reduce by 20%
-		if newSize < 100 {
-			return nil, fmt.Errorf("image cannot be compressed enough to meet WhatsApp requirements (max %d bytes)", MaxGroupPhotoSize)
+		newWidth := int(float64(bounds.Dx()) * 0.8) // Reduce by 20%
+		if newWidth < 100 {
+			return nil, fmt.Errorf("image cannot be compressed enough to meet WhatsApp requirements (max %d bytes)", maxSize)
 		}
 
-		resized := imaging.Resize(img, newSize, newSize, imaging.Lanczos)
-		return compressToJPEGWithDepth(resized, quality, depth+1)
+		// Keep the aspect ratio, group photos are already square at this point
+		resized := imaging.Resize(img, newWidth, 0, imaging.Lanczos)
+		return compressToJPEGWithLimit(resized, quality, maxSize, depth+1)
 	}
 
 	return &buf, nil
@@ -0,0 +1,191 @@
+package utils_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type ImageUtilsTestSuite struct {
+	suite.Suite
+}
+
+// landscapeImage is wider than tall with a red left half, so rotations are easy to verify
+func landscapeImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, color.NRGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.NRGBA{B: 255, A: 255})
+			}
+		}
+	}
+	return img
+}
+
+// noisyImage does not compress well, to exercise the quality search
+func noisyImage(width, height int) *image.NRGBA {
+	random := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	_, _ = random.Read(img.Pix)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+	return img
+}
+
+// jpegWithOrientation encodes a JPEG carrying an EXIF APP1 segment with the given orientation
+func jpegWithOrientation(img image.Image, orientation uint16) []byte {
+	var encoded bytes.Buffer
+	_ = jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 90})
+
+	// TIFF header followed by a single IFD entry: Orientation (0x0112), SHORT, count 1
+	var tiff bytes.Buffer
+	tiff.WriteString("II*\x00")
+	_ = binary.Write(&tiff, binary.LittleEndian, uint32(8))
+	_ = binary.Write(&tiff, binary.LittleEndian, uint16(1))
+	_ = binary.Write(&tiff, binary.LittleEndian, []uint16{0x0112, 3})
+	_ = binary.Write(&tiff, binary.LittleEndian, uint32(1))
+	_ = binary.Write(&tiff, binary.LittleEndian, []uint16{orientation, 0})
+	_ = binary.Write(&tiff, binary.LittleEndian, uint32(0))
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	data := encoded.Bytes()
+	result := append([]byte{}, data[:2]...) // SOI
+	result = append(result, segment...)
+	return append(result, data[2:]...)
+}
+
+func decodeImage(t *testing.T, data []byte) image.Image {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	return img
+}
+
+func (suite *ImageUtilsTestSuite) TestProcessImageOrientation() {
+	tests := []struct {
+		name        string
+		orientation uint16
+		wantWidth   int
+		wantHeight  int
+		redAtTop    bool
+	}{
+		{
+			name:        "should keep normal orientation",
+			orientation: 1,
+			wantWidth:   80,
+			wantHeight:  40,
+		},
+		{
+			name:        "should rotate 90 degrees clockwise",
+			orientation: 6,
+			wantWidth:   40,
+			wantHeight:  80,
+			redAtTop:    true,
+		},
+		{
+			name:        "should rotate 90 degrees counter clockwise",
+			orientation: 8,
+			wantWidth:   40,
+			wantHeight:  80,
+		},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			processed, err := utils.ProcessImage(jpegWithOrientation(landscapeImage(80, 40), tt.orientation), false)
+			require.NoError(t, err)
+
+			assert.Equal(t, "image/jpeg", processed.MimeType)
+			assert.Equal(t, tt.wantWidth, processed.Width)
+			assert.Equal(t, tt.wantHeight, processed.Height)
+
+			img := decodeImage(t, processed.Data)
+			assert.Equal(t, tt.wantWidth, img.Bounds().Dx())
+			assert.Equal(t, tt.wantHeight, img.Bounds().Dy())
+
+			if tt.wantHeight > tt.wantWidth {
+				r, _, b, _ := img.At(tt.wantWidth/2, 5).RGBA()
+				assert.Equal(t, tt.redAtTop, r > b)
+			}
+		})
+	}
+}
+
+func (suite *ImageUtilsTestSuite) TestProcessImageStripsMetadata() {
+	source := jpegWithOrientation(landscapeImage(80, 40), 1)
+	require.True(suite.T(), bytes.Contains(source, []byte("Exif")))
+
+	processed, err := utils.ProcessImage(source, false)
+	require.NoError(suite.T(), err)
+	assert.False(suite.T(), bytes.Contains(processed.Data, []byte("Exif")))
+}
+
+func (suite *ImageUtilsTestSuite) TestProcessImageKeepsPNG() {
+	img := landscapeImage(60, 30)
+	img.Set(0, 0, color.NRGBA{})
+	var encoded bytes.Buffer
+	require.NoError(suite.T(), png.Encode(&encoded, img))
+
+	processed, err := utils.ProcessImage(encoded.Bytes(), false)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "image/png", processed.MimeType)
+	assert.Equal(suite.T(), "image/png", http.DetectContentType(processed.Data))
+
+	// Transparency survives when the image is not compressed
+	_, _, _, alpha := decodeImage(suite.T(), processed.Data).At(0, 0).RGBA()
+	assert.Equal(suite.T(), uint32(0), alpha)
+}
+
+func (suite *ImageUtilsTestSuite) TestProcessImageCompress() {
+	var encoded bytes.Buffer
+	require.NoError(suite.T(), png.Encode(&encoded, noisyImage(2400, 1200)))
+
+	processed, err := utils.ProcessImage(encoded.Bytes(), true)
+	require.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), "image/jpeg", processed.MimeType)
+	assert.LessOrEqual(suite.T(), len(processed.Data), utils.MaxCompressedImageSize)
+	assert.LessOrEqual(suite.T(), processed.Width, utils.MaxCompressedImageDimension)
+
+	// The aspect ratio is kept while shrinking
+	img := decodeImage(suite.T(), processed.Data)
+	assert.Equal(suite.T(), processed.Width, img.Bounds().Dx())
+	assert.InDelta(suite.T(), 2.0, float64(img.Bounds().Dx())/float64(img.Bounds().Dy()), 0.02)
+}
+
+func (suite *ImageUtilsTestSuite) TestProcessImageThumbnail() {
+	processed, err := utils.ProcessImage(jpegWithOrientation(landscapeImage(400, 200), 6), false)
+	require.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), "image/jpeg", http.DetectContentType(processed.Thumbnail))
+	thumbnail := decodeImage(suite.T(), processed.Thumbnail)
+	assert.Equal(suite.T(), utils.ImageThumbnailWidth, thumbnail.Bounds().Dx())
+	assert.Equal(suite.T(), 2*utils.ImageThumbnailWidth, thumbnail.Bounds().Dy())
+}
+
+func (suite *ImageUtilsTestSuite) TestProcessImageInvalid() {
+	_, err := utils.ProcessImage([]byte("not an image"), false)
+	assert.Error(suite.T(), err)
+}
+
+func TestImageUtilsTestSuite(t *testing.T) {
+	suite.Run(t, new(ImageUtilsTestSuite))
+}
@@ -0,0 +1,328 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Formats a message text can be converted from or to
+const (
+	MessageFormatMarkdown = "markdown"
+	MessageFormatHTML     = "html"
+)
+
+var (
+	mdFencePattern         = regexp.MustCompile("^\\s*(```|~~~)")
+	mdRulePattern          = regexp.MustCompile(`^\s{0,3}(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,}|(?:=\s*){3,})$`)
+	mdHeadingPattern       = regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.*?)(?:\s+#+)?\s*$`)
+	mdQuotePattern         = regexp.MustCompile(`^\s{0,3}>\s?(.*)$`)
+	mdUnorderedPattern     = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	mdOrderedPattern       = regexp.MustCompile(`^(\s*)(\d{1,9})[.)]\s+(.*)$`)
+	mdTaskPattern          = regexp.MustCompile(`^\[([ xX])\]\s+`)
+	mdTableDividerPattern  = regexp.MustCompile(`^\s*\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)*\|?\s*$`)
+	mdTableRowPattern      = regexp.MustCompile(`^\s*\|(.*)\|\s*$`)
+	mdLinkDefinition       = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s+\S+.*$`)
+	mdCodeSpanPattern      = regexp.MustCompile("(`+)(.+?)(`+)")
+	mdEscapePattern        = regexp.MustCompile("\\\\([\\\\`*_{}\\[\\]()#+\\-.!~|>])")
+	mdImagePattern         = regexp.MustCompile(`!\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	mdLinkPattern          = regexp.MustCompile(`\[([^\]]+)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	mdReferenceLinkPattern = regexp.MustCompile(`\[([^\]]+)\]\[[^\]]*\]`)
+	mdAutolinkPattern      = regexp.MustCompile(`<((?:https?|ftp)://[^>\s]+|mailto:[^>\s]+)>`)
+	mdLineBreakTagPattern  = regexp.MustCompile(`(?i)<br\s*/?>`)
+	mdHTMLTagPattern       = regexp.MustCompile(`</?[a-zA-Z][a-zA-Z0-9-]*(?:\s[^<>]*)?/?>`)
+	mdBoldItalicPattern    = regexp.MustCompile(`\*\*\*(\S(?:.*?\S)?)\*\*\*|___(\S(?:.*?\S)?)___`)
+	mdBoldPattern          = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*|__(\S(?:.*?\S)?)__`)
+	mdStrikePattern        = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	mdItalicPattern        = regexp.MustCompile(`\*([^\s*](?:[^*]*[^\s*])?)\*`)
+
+	waCodeBlockPattern = regexp.MustCompile("(?s)```(.+?)```")
+	waCodeSpanPattern  = regexp.MustCompile("`([^`\n]+)`")
+	waURLPattern       = regexp.MustCompile(`(?:https?://|www\.)[^\s<>]+[^\s<>.,;:!?'")\]]`)
+	waPlaceholder      = regexp.MustCompile("\x00(\\d+)\x00")
+
+	// controlCharRemover drops the characters used as internal markers from the input
+	controlCharRemover = strings.NewReplacer("\x00", "", "\x01", "")
+)
+
+// boldMarker temporarily stands for WhatsApp bold so the italic pass does not pick it up
+const boldMarker = "\x01"
+
+// placeholders keeps fragments that must not be touched by later formatting passes
+type placeholders []string
+
+func (p *placeholders) add(value string) string {
+	*p = append(*p, value)
+	return fmt.Sprintf("\x00%d\x00", len(*p)-1)
+}
+
+func (p placeholders) restore(text string) string {
+	return waPlaceholder.ReplaceAllStringFunc(text, func(match string) string {
+		index, _ := strconv.Atoi(strings.Trim(match, "\x00"))
+		if index >= len(p) {
+			return ""
+		}
+		return p[index]
+	})
+}
+
+// MarkdownToWhatsApp converts Markdown into WhatsApp formatting: bold, italic, strikethrough, monospace,
+// lists and quotes are mapped to their WhatsApp counterpart, links become their text followed by the URL
+// and constructs WhatsApp cannot render (headings, rules, images, tables, HTML) are flattened or stripped.
+func MarkdownToWhatsApp(markdown string) string {
+	lines := strings.Split(strings.ReplaceAll(controlCharRemover.Replace(markdown), "\r\n", "\n"), "\n")
+	result := make([]string, 0, len(lines))
+
+	inFence := false
+	fence := ""
+	for _, line := range lines {
+		if match := mdFencePattern.FindStringSubmatch(line); match != nil && (!inFence || match[1] == fence) {
+			// The language of a fenced block has no WhatsApp equivalent and is dropped
+			inFence = !inFence
+			fence = match[1]
+			result = append(result, "```")
+			continue
+		}
+		if inFence {
+			result = append(result, line)
+			continue
+		}
+
+		if mdLinkDefinition.MatchString(line) || mdTableDividerPattern.MatchString(line) && strings.Contains(line, "|") {
+			continue
+		}
+
+		result = append(result, convertMarkdownLine(line))
+	}
+
+	if inFence {
+		// Close an unterminated fence so the rest of the message does not render as code
+		result = append(result, "```")
+	}
+
+	return strings.TrimSpace(collapseBlankLines(strings.Join(result, "\n")))
+}
+
+// convertMarkdownLine converts the block level construct of a single line and its inline content
+func convertMarkdownLine(line string) string {
+	line = strings.TrimRight(line, " \t")
+
+	switch {
+	case mdRulePattern.MatchString(line):
+		return ""
+	case mdHeadingPattern.MatchString(line):
+		heading := convertMarkdownInline(mdHeadingPattern.FindStringSubmatch(line)[1])
+		if heading == "" {
+			return ""
+		}
+		return "*" + strings.Trim(heading, "*") + "*"
+	case mdQuotePattern.MatchString(line):
+		return "> " + convertMarkdownLine(mdQuotePattern.FindStringSubmatch(line)[1])
+	case mdUnorderedPattern.MatchString(line):
+		match := mdUnorderedPattern.FindStringSubmatch(line)
+		item := match[2]
+		if task := mdTaskPattern.FindStringSubmatch(item); task != nil {
+			checkbox := "☐ "
+			if task[1] != " " {
+				checkbox = "☑ "
+			}
+			item = checkbox + item[len(task[0]):]
+		}
+		return match[1] + "- " + convertMarkdownInline(item)
+	case mdOrderedPattern.MatchString(line):
+		match := mdOrderedPattern.FindStringSubmatch(line)
+		return match[1] + match[2] + ". " + convertMarkdownInline(match[3])
+	case mdTableRowPattern.MatchString(line):
+		cells := strings.Split(mdTableRowPattern.FindStringSubmatch(line)[1], "|")
+		for i, cell := range cells {
+			cells[i] = convertMarkdownInline(strings.TrimSpace(cell))
+		}
+		return strings.Join(cells, " | ")
+	default:
+		return convertMarkdownInline(line)
+	}
+}
+
+// convertMarkdownInline converts emphasis, code spans, links and images within a line
+func convertMarkdownInline(text string) string {
+	var kept placeholders
+
+	text = mdCodeSpanPattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := mdCodeSpanPattern.FindStringSubmatch(match)
+		if parts[1] != parts[3] {
+			return match
+		}
+		return kept.add("```" + strings.TrimSpace(parts[2]) + "```")
+	})
+	text = mdEscapePattern.ReplaceAllStringFunc(text, func(match string) string {
+		return kept.add(match[1:])
+	})
+
+	text = mdImagePattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := mdImagePattern.FindStringSubmatch(match)
+		return formatMarkdownLink(parts[1], parts[2], &kept)
+	})
+	text = mdLinkPattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := mdLinkPattern.FindStringSubmatch(match)
+		return formatMarkdownLink(parts[1], parts[2], &kept)
+	})
+	text = mdReferenceLinkPattern.ReplaceAllString(text, "$1")
+	text = mdAutolinkPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return kept.add(strings.TrimPrefix(mdAutolinkPattern.FindStringSubmatch(match)[1], "mailto:"))
+	})
+	text = waURLPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return kept.add(match)
+	})
+
+	text = mdLineBreakTagPattern.ReplaceAllString(text, "\n")
+	text = mdHTMLTagPattern.ReplaceAllString(text, "")
+
+	text = mdBoldItalicPattern.ReplaceAllString(text, boldMarker+"_${1}${2}_"+boldMarker)
+	text = mdBoldPattern.ReplaceAllString(text, boldMarker+"${1}${2}"+boldMarker)
+	text = mdStrikePattern.ReplaceAllString(text, "~$1~")
+	text = mdItalicPattern.ReplaceAllString(text, "_${1}_")
+	text = strings.ReplaceAll(text, boldMarker, "*")
+
+	// Entities are decoded before restoring so code spans keep their literal content
+	return kept.restore(html.UnescapeString(text))
+}
+
+// formatMarkdownLink renders a link as its text followed by the URL, or only the URL when the text adds nothing
+func formatMarkdownLink(text, url string, kept *placeholders) string {
+	text = strings.TrimSpace(text)
+	if text == "" || text == url || strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://") == text {
+		return kept.add(url)
+	}
+	return text + " (" + kept.add(url) + ")"
+}
+
+// collapseBlankLines keeps at most one empty line between paragraphs, stripped constructs often leave several
+func collapseBlankLines(text string) string {
+	lines := strings.Split(text, "\n")
+	result := make([]string, 0, len(lines))
+	blank := false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			if blank {
+				continue
+			}
+			blank = true
+			result = append(result, "")
+			continue
+		}
+		blank = false
+		result = append(result, line)
+	}
+	return strings.Join(result, "\n")
+}
+
+// WhatsAppToMarkdown converts WhatsApp formatting of a received message into Markdown
+func WhatsAppToMarkdown(text string) string {
+	var kept placeholders
+
+	text = controlCharRemover.Replace(text)
+	text = waCodeBlockPattern.ReplaceAllStringFunc(text, func(match string) string {
+		code := waCodeBlockPattern.FindStringSubmatch(match)[1]
+		if strings.Contains(code, "\n") {
+			return kept.add("```\n" + strings.Trim(code, "\n") + "\n```")
+		}
+		return kept.add("`" + code + "`")
+	})
+	text = waCodeSpanPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return kept.add(match)
+	})
+	text = waURLPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return kept.add(match)
+	})
+
+	text = replaceWhatsAppStyle(text, '~', "~~", "~~")
+	text = replaceWhatsAppStyle(text, '*', "**", "**")
+	text = replaceWhatsAppStyle(text, '_', "_", "_")
+
+	return kept.restore(text)
+}
+
+// WhatsAppToHTML converts WhatsApp formatting of a received message into an HTML fragment
+func WhatsAppToHTML(text string) string {
+	var kept placeholders
+
+	text = html.EscapeString(controlCharRemover.Replace(text))
+	text = waCodeBlockPattern.ReplaceAllStringFunc(text, func(match string) string {
+		code := waCodeBlockPattern.FindStringSubmatch(match)[1]
+		if strings.Contains(code, "\n") {
+			return kept.add("<pre>" + strings.Trim(code, "\n") + "</pre>")
+		}
+		return kept.add("<code>" + code + "</code>")
+	})
+	text = waCodeSpanPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return kept.add("<code>" + strings.Trim(match, "`") + "</code>")
+	})
+	text = waURLPattern.ReplaceAllStringFunc(text, func(match string) string {
+		href := match
+		if strings.HasPrefix(href, "www.") {
+			href = "http://" + href
+		}
+		return kept.add(`<a href="` + href + `">` + match + `</a>`)
+	})
+
+	text = replaceWhatsAppStyle(text, '~', "<del>", "</del>")
+	text = replaceWhatsAppStyle(text, '*', "<strong>", "</strong>")
+	text = replaceWhatsAppStyle(text, '_', "<em>", "</em>")
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if quote, ok := strings.CutPrefix(line, "&gt; "); ok {
+			lines[i] = "<blockquote>" + quote + "</blockquote>"
+		}
+	}
+	text = strings.Join(lines, "<br>")
+	text = strings.ReplaceAll(text, "</blockquote><br>", "</blockquote>")
+
+	return kept.restore(text)
+}
+
+// replaceWhatsAppStyle replaces text wrapped in a WhatsApp style marker. Like WhatsApp, a marker only opens
+// at a word start and closes at a word end within the same line, so snake_case or 2*3*4 are left untouched.
+func replaceWhatsAppStyle(text string, marker rune, open, close string) string {
+	runes := []rune(text)
+	var builder strings.Builder
+	builder.Grow(len(text))
+
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != marker || !isStyleBoundary(runes, i-1) || i+1 >= len(runes) || unicode.IsSpace(runes[i+1]) || runes[i+1] == marker {
+			builder.WriteRune(runes[i])
+			continue
+		}
+
+		end := -1
+		for j := i + 1; j < len(runes) && runes[j] != '\n'; j++ {
+			if runes[j] == marker && !unicode.IsSpace(runes[j-1]) && isStyleBoundary(runes, j+1) {
+				end = j
+				break
+			}
+		}
+		if end == -1 {
+			builder.WriteRune(runes[i])
+			continue
+		}
+
+		builder.WriteString(open)
+		builder.WriteString(string(runes[i+1 : end]))
+		builder.WriteString(close)
+		i = end
+	}
+
+	return builder.String()
+}
+
+// isStyleBoundary reports whether the rune at index does not belong to a word, the text edges count as boundaries
+func isStyleBoundary(runes []rune, index int) bool {
+	if index < 0 || index >= len(runes) {
+		return true
+	}
+	r := runes[index]
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
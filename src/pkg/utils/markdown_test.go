@@ -0,0 +1,373 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MarkdownTestSuite struct {
+	suite.Suite
+}
+
+func (suite *MarkdownTestSuite) TestMarkdownToWhatsApp() {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{
+			name:     "should keep plain text",
+			markdown: "Hello, how are you?",
+			want:     "Hello, how are you?",
+		},
+		{
+			name:     "should convert double asterisk bold",
+			markdown: "This is **bold** text",
+			want:     "This is *bold* text",
+		},
+		{
+			name:     "should convert double underscore bold",
+			markdown: "This is __bold__ text",
+			want:     "This is *bold* text",
+		},
+		{
+			name:     "should convert asterisk italic",
+			markdown: "This is *italic* text",
+			want:     "This is _italic_ text",
+		},
+		{
+			name:     "should keep underscore italic",
+			markdown: "This is _italic_ text",
+			want:     "This is _italic_ text",
+		},
+		{
+			name:     "should convert bold italic",
+			markdown: "This is ***important***",
+			want:     "This is *_important_*",
+		},
+		{
+			name:     "should convert bold and italic in the same line",
+			markdown: "**Total:** *due today*",
+			want:     "*Total:* _due today_",
+		},
+		{
+			name:     "should convert strikethrough",
+			markdown: "Price ~~100~~ 80",
+			want:     "Price ~100~ 80",
+		},
+		{
+			name:     "should convert inline code to monospace",
+			markdown: "Run `go build` first",
+			want:     "Run ```go build``` first",
+		},
+		{
+			name:     "should not format inside inline code",
+			markdown: "Use `**kwargs` in python",
+			want:     "Use ```**kwargs``` in python",
+		},
+		{
+			name:     "should convert fenced code block and drop the language",
+			markdown: "```go\nfmt.Println(\"**hi**\")\n```",
+			want:     "```\nfmt.Println(\"**hi**\")\n```",
+		},
+		{
+			name:     "should close an unterminated code block",
+			markdown: "```\ncode",
+			want:     "```\ncode\n```",
+		},
+		{
+			name:     "should convert link to text and url",
+			markdown: "Read the [invoice](https://example.com/invoice/1) now",
+			want:     "Read the invoice (https://example.com/invoice/1) now",
+		},
+		{
+			name:     "should convert link with title",
+			markdown: `[docs](https://example.com "Documentation")`,
+			want:     "docs (https://example.com)",
+		},
+		{
+			name:     "should keep only the url when the text is the url",
+			markdown: "[https://example.com](https://example.com)",
+			want:     "https://example.com",
+		},
+		{
+			name:     "should keep formatting of link text",
+			markdown: "[**Pay now**](https://example.com/pay)",
+			want:     "*Pay now* (https://example.com/pay)",
+		},
+		{
+			name:     "should not italicize underscores in link urls",
+			markdown: "[report](https://example.com/monthly_report_2024)",
+			want:     "report (https://example.com/monthly_report_2024)",
+		},
+		{
+			name:     "should not italicize underscores in bare urls",
+			markdown: "see https://example.com/_private_/file",
+			want:     "see https://example.com/_private_/file",
+		},
+		{
+			name:     "should convert autolink",
+			markdown: "Visit <https://example.com>",
+			want:     "Visit https://example.com",
+		},
+		{
+			name:     "should convert mail autolink",
+			markdown: "Mail <mailto:hello@example.com>",
+			want:     "Mail hello@example.com",
+		},
+		{
+			name:     "should convert image to alt text and url",
+			markdown: "![Logo](https://example.com/logo.png)",
+			want:     "Logo (https://example.com/logo.png)",
+		},
+		{
+			name:     "should convert reference link and strip its definition",
+			markdown: "See [the docs][1]\n\n[1]: https://example.com/docs",
+			want:     "See the docs",
+		},
+		{
+			name:     "should convert heading to bold",
+			markdown: "# Monthly report",
+			want:     "*Monthly report*",
+		},
+		{
+			name:     "should not double bold a bold heading",
+			markdown: "## **Summary** ##",
+			want:     "*Summary*",
+		},
+		{
+			name:     "should convert unordered list markers",
+			markdown: "* first\n+ second\n- third",
+			want:     "- first\n- second\n- third",
+		},
+		{
+			name:     "should keep nested list indentation",
+			markdown: "- parent\n  * child",
+			want:     "- parent\n  - child",
+		},
+		{
+			name:     "should format list items",
+			markdown: "- **bold** item",
+			want:     "- *bold* item",
+		},
+		{
+			name:     "should convert ordered list",
+			markdown: "1) first\n2. second",
+			want:     "1. first\n2. second",
+		},
+		{
+			name:     "should convert task list",
+			markdown: "- [ ] todo\n- [x] done",
+			want:     "- ☐ todo\n- ☑ done",
+		},
+		{
+			name:     "should keep quotes",
+			markdown: "> **Note** this is quoted",
+			want:     "> *Note* this is quoted",
+		},
+		{
+			name:     "should strip horizontal rules",
+			markdown: "above\n\n---\n\nbelow",
+			want:     "above\n\nbelow",
+		},
+		{
+			name:     "should strip html tags",
+			markdown: "<div>Hello <b>world</b></div>",
+			want:     "Hello world",
+		},
+		{
+			name:     "should convert html line breaks",
+			markdown: "line one<br>line two",
+			want:     "line one\nline two",
+		},
+		{
+			name:     "should decode html entities",
+			markdown: "Tom &amp; Jerry",
+			want:     "Tom & Jerry",
+		},
+		{
+			name:     "should flatten tables",
+			markdown: "| Item | Price |\n|------|------:|\n| Tea | **2** |",
+			want:     "Item | Price\nTea | *2*",
+		},
+		{
+			name:     "should keep escaped characters literal",
+			markdown: `\*not italic\* and 5 \* 3`,
+			want:     "*not italic* and 5 * 3",
+		},
+		{
+			name:     "should keep mentions",
+			markdown: "Hello @6289685028129, **welcome**",
+			want:     "Hello @6289685028129, *welcome*",
+		},
+		{
+			name:     "should collapse blank lines and trim",
+			markdown: "\n\nfirst\n\n\n\nsecond\n\n",
+			want:     "first\n\nsecond",
+		},
+		{
+			name:     "should normalize windows line endings",
+			markdown: "**a**\r\n*b*",
+			want:     "*a*\n_b_",
+		},
+		{
+			name:     "should drop internal marker characters from input",
+			markdown: "a\x000\x00b\x01",
+			want:     "a0b",
+		},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, utils.MarkdownToWhatsApp(tt.markdown))
+		})
+	}
+}
+
+func (suite *MarkdownTestSuite) TestWhatsAppToMarkdown() {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "should keep plain text",
+			text: "Hello there",
+			want: "Hello there",
+		},
+		{
+			name: "should convert bold",
+			text: "This is *bold*",
+			want: "This is **bold**",
+		},
+		{
+			name: "should keep italic",
+			text: "This is _italic_",
+			want: "This is _italic_",
+		},
+		{
+			name: "should convert strikethrough",
+			text: "This is ~gone~",
+			want: "This is ~~gone~~",
+		},
+		{
+			name: "should convert nested styles",
+			text: "*_both_*",
+			want: "**_both_**",
+		},
+		{
+			name: "should convert adjacent styled words",
+			text: "*one* *two*",
+			want: "**one** **two**",
+		},
+		{
+			name: "should convert single line monospace to inline code",
+			text: "Run ```make``` now",
+			want: "Run `make` now",
+		},
+		{
+			name: "should convert multi line monospace to code block",
+			text: "```\nline *one*\nline two\n```",
+			want: "```\nline *one*\nline two\n```",
+		},
+		{
+			name: "should keep inline code",
+			text: "Use `*ptr`",
+			want: "Use `*ptr`",
+		},
+		{
+			name: "should ignore markers inside words",
+			text: "snake_case_name and 2*3*4",
+			want: "snake_case_name and 2*3*4",
+		},
+		{
+			name: "should ignore markers followed by a space",
+			text: "5 * 3 = 15 * 1",
+			want: "5 * 3 = 15 * 1",
+		},
+		{
+			name: "should not style across lines",
+			text: "*start\nend*",
+			want: "*start\nend*",
+		},
+		{
+			name: "should not style urls",
+			text: "https://example.com/_a_/b",
+			want: "https://example.com/_a_/b",
+		},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, utils.WhatsAppToMarkdown(tt.text))
+		})
+	}
+}
+
+func (suite *MarkdownTestSuite) TestWhatsAppToHTML() {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "should convert styles",
+			text: "*bold* _italic_ ~strike~",
+			want: "<strong>bold</strong> <em>italic</em> <del>strike</del>",
+		},
+		{
+			name: "should escape html",
+			text: "<script>alert(1)</script> & *safe*",
+			want: "&lt;script&gt;alert(1)&lt;/script&gt; &amp; <strong>safe</strong>",
+		},
+		{
+			name: "should convert line breaks",
+			text: "one\ntwo",
+			want: "one<br>two",
+		},
+		{
+			name: "should convert monospace",
+			text: "```code``` and `inline`",
+			want: "<code>code</code> and <code>inline</code>",
+		},
+		{
+			name: "should convert multi line monospace to pre",
+			text: "```\na *b*\nc\n```",
+			want: "<pre>a *b*\nc</pre>",
+		},
+		{
+			name: "should convert quotes",
+			text: "> quoted\nreply",
+			want: "<blockquote>quoted</blockquote>reply",
+		},
+		{
+			name: "should link urls",
+			text: "see https://example.com/a_b_c.",
+			want: `see <a href="https://example.com/a_b_c">https://example.com/a_b_c</a>.`,
+		},
+		{
+			name: "should link www urls",
+			text: "www.example.com",
+			want: `<a href="http://www.example.com">www.example.com</a>`,
+		},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, utils.WhatsAppToHTML(tt.text))
+		})
+	}
+}
+
+func (suite *MarkdownTestSuite) TestRoundTrip() {
+	// Formatting converted to WhatsApp and back keeps its meaning
+	markdown := "**Invoice** is _due_ ~~today~~ `now`"
+	assert.Equal(suite.T(), "**Invoice** is _due_ ~~today~~ `now`", utils.WhatsAppToMarkdown(utils.MarkdownToWhatsApp(markdown)))
+}
+
+func TestMarkdownTestSuite(t *testing.T) {
+	suite.Run(t, new(MarkdownTestSuite))
+}
@@ -0,0 +1,121 @@
+package utils_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainMediaStore "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/mediastore"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/mediastore"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeDownloader delivers the content each media key decrypts to, whatever hash the message announces
+type fakeDownloader struct {
+	content   map[string][]byte
+	downloads atomic.Int32
+}
+
+func (d *fakeDownloader) DownloadToFile(_ context.Context, msg whatsmeow.DownloadableMessage, file whatsmeow.File) error {
+	d.downloads.Add(1)
+	_, err := file.Write(d.content[string(msg.GetMediaKey())])
+	return err
+}
+
+type MediaExtractTestSuite struct {
+	suite.Suite
+	origCache  string
+	downloader *fakeDownloader
+	store      domainMediaStore.IMediaStore
+	dir        string
+}
+
+func (suite *MediaExtractTestSuite) SetupTest() {
+	suite.origCache = config.PathMediaCache
+	config.PathMediaCache = suite.T().TempDir()
+	suite.dir = suite.T().TempDir()
+	suite.store = mediastore.NewLocalStore(suite.dir)
+	suite.downloader = &fakeDownloader{content: map[string][]byte{
+		"original": []byte("original image"),
+		"forged":   []byte("forged image"),
+		"copy":     []byte("original image"),
+	}}
+}
+
+func (suite *MediaExtractTestSuite) TearDownTest() {
+	config.PathMediaCache = suite.origCache
+}
+
+// imageMessage announces the hash of the given content, the media key decides what is actually delivered
+func imageMessage(mediaKey string, announced []byte) *waE2E.ImageMessage {
+	fileSHA256 := sha256.Sum256(announced)
+	return &waE2E.ImageMessage{
+		Mimetype:   proto.String("image/jpeg"),
+		MediaKey:   []byte(mediaKey),
+		FileSHA256: fileSHA256[:],
+		DirectPath: proto.String("/v/" + mediaKey),
+	}
+}
+
+func (suite *MediaExtractTestSuite) storedContent(mediaPath string) string {
+	content, err := os.ReadFile(mediaPath)
+	require.NoError(suite.T(), err)
+	return string(content)
+}
+
+func (suite *MediaExtractTestSuite) TestStoredUnderCheckedHash() {
+	original := []byte("original image")
+	extracted, err := utils.ExtractMedia(context.Background(), suite.downloader, suite.store, imageMessage("original", original))
+	require.NoError(suite.T(), err)
+
+	hash := sha256.Sum256(original)
+	assert.Equal(suite.T(), filepath.Join(suite.dir, hex.EncodeToString(hash[:])), extracted.MediaPath)
+	assert.Equal(suite.T(), "original image", suite.storedContent(extracted.MediaPath))
+	assert.Equal(suite.T(), "image/jpeg", extracted.MimeType)
+}
+
+func (suite *MediaExtractTestSuite) TestClaimedHashWithOtherContent() {
+	original := []byte("original image")
+	stored, err := utils.ExtractMedia(context.Background(), suite.downloader, suite.store, imageMessage("original", original))
+	require.NoError(suite.T(), err)
+
+	// A second message names the stored hash but delivers something else
+	forged, err := utils.ExtractMedia(context.Background(), suite.downloader, suite.store, imageMessage("forged", original))
+	assert.ErrorIs(suite.T(), err, whatsmeow.ErrInvalidMediaSHA256)
+	assert.Empty(suite.T(), forged.MediaPath, "the stored media is not linked to the forged message")
+	assert.Empty(suite.T(), forged.MediaURL)
+	assert.Equal(suite.T(), int32(2), suite.downloader.downloads.Load(), "a stored hash does not skip the download")
+	assert.Equal(suite.T(), "original image", suite.storedContent(stored.MediaPath))
+
+	entries, err := os.ReadDir(suite.dir)
+	require.NoError(suite.T(), err)
+	for _, entry := range entries {
+		assert.NotContains(suite.T(), suite.storedContent(filepath.Join(suite.dir, entry.Name())), "forged", "forged content is not stored")
+	}
+}
+
+func (suite *MediaExtractTestSuite) TestSameContentStoredOnce() {
+	original := []byte("original image")
+	first, err := utils.ExtractMedia(context.Background(), suite.downloader, suite.store, imageMessage("original", original))
+	require.NoError(suite.T(), err)
+	second, err := utils.ExtractMedia(context.Background(), suite.downloader, suite.store, imageMessage("copy", original))
+	require.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), first.MediaPath, second.MediaPath)
+	assert.Equal(suite.T(), int32(2), suite.downloader.downloads.Load(), "each message proves its content")
+}
+
+func TestMediaExtractTestSuite(t *testing.T) {
+	suite.Run(t, new(MediaExtractTestSuite))
+}
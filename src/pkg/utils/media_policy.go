@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"strings"
+
+	"go.mau.fi/whatsmeow/types"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+)
+
+// AutoDownloadMedia reports whether the media of an incoming message is downloaded as it arrives.
+// Chats listed in WhatsappAutoDownloadChats are downloaded even when group downloads are off.
+func AutoDownloadMedia(chat types.JID, mediaType string, fileLength uint64) bool {
+	if !config.WhatsappAutoDownload || mediaType == "" {
+		return false
+	}
+	if !containsFold(config.WhatsappAutoDownloadTypes, mediaType) {
+		return false
+	}
+	if config.WhatsappAutoDownloadMaxSize > 0 && fileLength > uint64(config.WhatsappAutoDownloadMaxSize) {
+		return false
+	}
+	if chatListed(config.WhatsappAutoDownloadExcludeChats, chat) {
+		return false
+	}
+	if len(config.WhatsappAutoDownloadChats) > 0 {
+		return chatListed(config.WhatsappAutoDownloadChats, chat)
+	}
+	return config.WhatsappAutoDownloadGroups || chat.Server != types.GroupServer
+}
+
+// chatListed matches a chat against full JIDs and bare phone numbers or group IDs
+func chatListed(chats []string, chat types.JID) bool {
+	for _, entry := range chats {
+		entry = strings.TrimSpace(entry)
+		if entry != "" && (entry == chat.String() || entry == chat.User) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+	return false
+}
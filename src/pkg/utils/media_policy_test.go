@@ -0,0 +1,199 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mau.fi/whatsmeow/types"
+)
+
+type MediaPolicyTestSuite struct {
+	suite.Suite
+	origEnabled      bool
+	origTypes        []string
+	origMaxSize      int64
+	origGroups       bool
+	origChats        []string
+	origExcludeChats []string
+}
+
+func (suite *MediaPolicyTestSuite) SetupTest() {
+	suite.origEnabled = config.WhatsappAutoDownload
+	suite.origTypes = config.WhatsappAutoDownloadTypes
+	suite.origMaxSize = config.WhatsappAutoDownloadMaxSize
+	suite.origGroups = config.WhatsappAutoDownloadGroups
+	suite.origChats = config.WhatsappAutoDownloadChats
+	suite.origExcludeChats = config.WhatsappAutoDownloadExcludeChats
+}
+
+func (suite *MediaPolicyTestSuite) TearDownTest() {
+	config.WhatsappAutoDownload = suite.origEnabled
+	config.WhatsappAutoDownloadTypes = suite.origTypes
+	config.WhatsappAutoDownloadMaxSize = suite.origMaxSize
+	config.WhatsappAutoDownloadGroups = suite.origGroups
+	config.WhatsappAutoDownloadChats = suite.origChats
+	config.WhatsappAutoDownloadExcludeChats = suite.origExcludeChats
+}
+
+func (suite *MediaPolicyTestSuite) TestAutoDownloadMedia() {
+	user := types.NewJID("6281234567890", types.DefaultUserServer)
+	group := types.NewJID("120363025246125486", types.GroupServer)
+
+	tests := []struct {
+		name         string
+		enabled      bool
+		types        []string
+		maxSize      int64
+		groups       bool
+		chats        []string
+		excludeChats []string
+		chat         types.JID
+		mediaType    string
+		fileLength   uint64
+		want         bool
+	}{
+		{
+			name:       "should download with the defaults",
+			enabled:    true,
+			types:      []string{"image", "video"},
+			groups:     true,
+			chat:       user,
+			mediaType:  "image",
+			fileLength: 1024,
+			want:       true,
+		},
+		{
+			name:      "should skip when disabled",
+			types:     []string{"image"},
+			groups:    true,
+			chat:      user,
+			mediaType: "image",
+		},
+		{
+			name:      "should skip types that are not listed",
+			enabled:   true,
+			types:     []string{"image"},
+			groups:    true,
+			chat:      user,
+			mediaType: "video",
+		},
+		{
+			name:      "should match types regardless of case and spaces",
+			enabled:   true,
+			types:     []string{" Image", "VIDEO "},
+			groups:    true,
+			chat:      user,
+			mediaType: "video",
+			want:      true,
+		},
+		{
+			name:       "should skip media over the maximum size",
+			enabled:    true,
+			types:      []string{"video"},
+			maxSize:    1000,
+			groups:     true,
+			chat:       user,
+			mediaType:  "video",
+			fileLength: 1001,
+		},
+		{
+			name:       "should download media at the maximum size",
+			enabled:    true,
+			types:      []string{"video"},
+			maxSize:    1000,
+			groups:     true,
+			chat:       user,
+			mediaType:  "video",
+			fileLength: 1000,
+			want:       true,
+		},
+		{
+			name:      "should skip groups when group downloads are off",
+			enabled:   true,
+			types:     []string{"image"},
+			chat:      group,
+			mediaType: "image",
+		},
+		{
+			name:      "should download direct chats when group downloads are off",
+			enabled:   true,
+			types:     []string{"image"},
+			chat:      user,
+			mediaType: "image",
+			want:      true,
+		},
+		{
+			name:      "should download listed groups when group downloads are off",
+			enabled:   true,
+			types:     []string{"image"},
+			chats:     []string{"120363025246125486@g.us"},
+			chat:      group,
+			mediaType: "image",
+			want:      true,
+		},
+		{
+			name:      "should skip chats that are not listed",
+			enabled:   true,
+			types:     []string{"image"},
+			groups:    true,
+			chats:     []string{"6289876543210"},
+			chat:      user,
+			mediaType: "image",
+		},
+		{
+			name:      "should match listed phone numbers",
+			enabled:   true,
+			types:     []string{"image"},
+			groups:    true,
+			chats:     []string{"6281234567890"},
+			chat:      user,
+			mediaType: "image",
+			want:      true,
+		},
+		{
+			name:         "should skip excluded chats",
+			enabled:      true,
+			types:        []string{"image"},
+			groups:       true,
+			chats:        []string{"6281234567890"},
+			excludeChats: []string{"6281234567890@s.whatsapp.net"},
+			chat:         user,
+			mediaType:    "image",
+		},
+		{
+			name:    "should skip messages without media",
+			enabled: true,
+			types:   []string{""},
+			groups:  true,
+			chat:    user,
+		},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			config.WhatsappAutoDownload = tt.enabled
+			config.WhatsappAutoDownloadTypes = tt.types
+			config.WhatsappAutoDownloadMaxSize = tt.maxSize
+			config.WhatsappAutoDownloadGroups = tt.groups
+			config.WhatsappAutoDownloadChats = tt.chats
+			config.WhatsappAutoDownloadExcludeChats = tt.excludeChats
+
+			assert.Equal(t, tt.want, utils.AutoDownloadMedia(tt.chat, tt.mediaType, tt.fileLength))
+		})
+	}
+}
+
+func (suite *MediaPolicyTestSuite) TestDefaultsOnlyDownloadImages() {
+	user := types.NewJID("6281234567890", types.DefaultUserServer)
+	for _, mediaType := range []string{"audio", "document", "sticker", "video"} {
+		assert.False(suite.T(), utils.AutoDownloadMedia(user, mediaType, 1024), mediaType)
+	}
+	assert.True(suite.T(), utils.AutoDownloadMedia(user, "image", 1024))
+}
+
+func TestMediaPolicyTestSuite(t *testing.T) {
+	suite.Run(t, new(MediaPolicyTestSuite))
+}
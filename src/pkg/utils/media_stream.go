@@ -0,0 +1,257 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+)
+
+// sniffLength is the number of leading bytes http.DetectContentType looks at
+const sniffLength = 512
+
+// ErrMediaTooLarge is returned while streaming media that grows past its size limit
+var ErrMediaTooLarge = errors.New("media exceeds maximum allowed size")
+
+// allowedAudioMimes aligns audio URL downloads with the MIME types accepted for uploaded files
+var allowedAudioMimes = map[string]bool{
+	"audio/aac":      true,
+	"audio/amr":      true,
+	"audio/flac":     true,
+	"audio/m4a":      true,
+	"audio/m4r":      true,
+	"audio/mp3":      true,
+	"audio/mpeg":     true,
+	"audio/ogg":      true,
+	"audio/wma":      true,
+	"audio/x-ms-wma": true,
+	"audio/wav":      true,
+	"audio/vnd.wav":  true,
+	"audio/vnd.wave": true,
+	"audio/wave":     true,
+	"audio/x-pn-wav": true,
+	"audio/x-wav":    true,
+}
+
+var allowedVideoMimes = map[string]bool{
+	"video/mp4":        true,
+	"video/x-matroska": true, // mkv
+	"video/avi":        true,
+	"video/x-msvideo":  true,
+}
+
+// MediaSource is media opened for streaming, it is read once from start to end and must be closed.
+// MimeType is sniffed from the content, Size is -1 when the source did not announce it.
+type MediaSource struct {
+	Reader   io.Reader
+	FileName string
+	MimeType string
+	Size     int64
+	closer   io.Closer
+}
+
+func (source *MediaSource) Read(p []byte) (int, error) {
+	return source.Reader.Read(p)
+}
+
+func (source *MediaSource) Close() error {
+	if source.closer == nil {
+		return nil
+	}
+	return source.closer.Close()
+}
+
+// SaveTo streams the media into a file, for tools such as ffmpeg that need a path to work on
+func (source *MediaSource) SaveTo(path string) (int64, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+
+	written, err := io.Copy(file, source)
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return written, err
+	}
+	return written, nil
+}
+
+// DetectReaderContentType sniffs the MIME type of a seekable stream and rewinds it
+func DetectReaderContentType(reader io.ReadSeeker) (string, error) {
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	if _, err = reader.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+// OpenMultipartMedia opens an uploaded file without copying it, large uploads are already spooled to disk by the server
+func OpenMultipartMedia(fileHeader *multipart.FileHeader) (*MediaSource, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType, err := DetectReaderContentType(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &MediaSource{
+		Reader:   file,
+		FileName: fileHeader.Filename,
+		MimeType: mimeType,
+		Size:     fileHeader.Size,
+		closer:   file,
+	}, nil
+}
+
+// OpenAudioFromURL starts downloading an audio file and returns it as a stream limited to WhatsappSettingMaxDownloadSize
+func OpenAudioFromURL(audioURL string) (*MediaSource, error) {
+	source, err := openMediaFromURL(audioURL, "audio", allowedAudioMimes, config.WhatsappSettingMaxDownloadSize)
+	if err != nil {
+		return nil, err
+	}
+	if source.FileName == "" {
+		source.FileName = fmt.Sprintf("audio_%d", time.Now().Unix())
+	}
+	return source, nil
+}
+
+// OpenVideoFromURL starts downloading a video file and returns it as a stream limited to WhatsappSettingMaxDownloadSize
+func OpenVideoFromURL(videoURL string) (*MediaSource, error) {
+	source, err := openMediaFromURL(videoURL, "video", allowedVideoMimes, config.WhatsappSettingMaxDownloadSize)
+	if err != nil {
+		return nil, err
+	}
+	if source.FileName == "" {
+		source.FileName = fmt.Sprintf("video_%d.mp4", time.Now().Unix())
+	}
+	return source, nil
+}
+
+// OpenGIFFromURL starts downloading a GIF, or a video to play as GIF, limited to WhatsappSettingMaxDownloadSize
+func OpenGIFFromURL(gifURL string) (*MediaSource, error) {
+	allowedMimes := map[string]bool{"image/gif": true}
+	for mime := range allowedVideoMimes {
+		allowedMimes[mime] = true
+	}
+
+	source, err := openMediaFromURL(gifURL, "gif", allowedMimes, config.WhatsappSettingMaxDownloadSize)
+	if err != nil {
+		return nil, err
+	}
+	if source.FileName == "" {
+		source.FileName = fmt.Sprintf("gif_%d.gif", time.Now().Unix())
+	}
+	return source, nil
+}
+
+func openMediaFromURL(mediaURL, kind string, allowedMimes map[string]bool, maxSize int64) (*MediaSource, error) {
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("too many redirects")
+			}
+			return nil
+		},
+	}
+
+	resp, err := client.Get(mediaURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("HTTP request failed with status: %s", resp.Status)
+	}
+
+	// Extract only the MIME type portion (ignore parameters like charset)
+	contentType := strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0])
+	if !allowedMimes[contentType] {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("invalid content type: %s", contentType)
+	}
+
+	// Reject early when the server announces the size, otherwise the limit is enforced while streaming
+	if resp.ContentLength > maxSize {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("%s size %d exceeds maximum allowed size %d", kind, resp.ContentLength, maxSize)
+	}
+
+	// Sniff the real MIME type from the first bytes without consuming them
+	buffered := bufio.NewReaderSize(resp.Body, sniffLength)
+	head, err := buffered.Peek(sniffLength)
+	if err != nil && !errors.Is(err, io.EOF) {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+
+	// Derive filename from URL path (strip query parameters if present)
+	segments := strings.Split(mediaURL, "/")
+	fileName := strings.Split(segments[len(segments)-1], "?")[0]
+
+	return &MediaSource{
+		Reader:   NewMaxSizeReader(buffered, maxSize),
+		FileName: fileName,
+		MimeType: http.DetectContentType(head),
+		Size:     resp.ContentLength,
+		closer:   resp.Body,
+	}, nil
+}
+
+type maxSizeReader struct {
+	reader  io.Reader
+	maxSize int64
+	read    int64
+}
+
+// NewMaxSizeReader wraps a reader that fails with ErrMediaTooLarge once more than maxSize bytes went through it
+func NewMaxSizeReader(reader io.Reader, maxSize int64) io.Reader {
+	return &maxSizeReader{reader: reader, maxSize: maxSize}
+}
+
+func (r *maxSizeReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.read > r.maxSize {
+		return n, fmt.Errorf("%w of %d bytes", ErrMediaTooLarge, r.maxSize)
+	}
+	return n, err
+}
+
+// SpoolToTempFile copies a stream into a temporary file and rewinds it, for consumers that need to seek.
+// The caller closes and removes the file.
+func SpoolToTempFile(reader io.Reader, pattern string) (*os.File, error) {
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = io.Copy(file, reader); err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return nil, err
+	}
+	return file, nil
+}
@@ -0,0 +1,275 @@
+package utils_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	// streamedMediaSize is large enough that buffering it would blow the allocation budget
+	streamedMediaSize = 64 << 20
+	// streamingAllocBudget is the most a streaming pipeline may allocate, whatever the media size
+	streamingAllocBudget = 8 << 20
+)
+
+// mp4Header makes the generated media sniff as a video
+var mp4Header = []byte{0x00, 0x00, 0x00, 0x18, 'f', 't', 'y', 'p', 'm', 'p', '4', '2', 0x00, 0x00, 0x00, 0x00, 'm', 'p', '4', '2', 'i', 's', 'o', 'm'}
+
+// generatedMedia produces size bytes of deterministic content without holding them in memory
+type generatedMedia struct {
+	size int64
+	read int64
+}
+
+func (g *generatedMedia) Read(p []byte) (int, error) {
+	if g.read >= g.size {
+		return 0, io.EOF
+	}
+	n := int64(len(p))
+	if remaining := g.size - g.read; n > remaining {
+		n = remaining
+	}
+	for i := int64(0); i < n; i++ {
+		offset := g.read + i
+		if offset < int64(len(mp4Header)) {
+			p[i] = mp4Header[offset]
+		} else {
+			p[i] = byte(offset % 251)
+		}
+	}
+	g.read += n
+	return int(n), nil
+}
+
+func generatedMediaHash(size int64) []byte {
+	hash := sha256.New()
+	_, _ = io.Copy(hash, &generatedMedia{size: size})
+	return hash.Sum(nil)
+}
+
+// allocatedDuring reports how many bytes the heap allocated while running fn
+func allocatedDuring(fn func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	fn()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+type MediaStreamTestSuite struct {
+	suite.Suite
+	origMaxSize int64
+}
+
+func (suite *MediaStreamTestSuite) SetupTest() {
+	suite.origMaxSize = config.WhatsappSettingMaxDownloadSize
+	config.WhatsappSettingMaxDownloadSize = 1 << 30
+}
+
+func (suite *MediaStreamTestSuite) TearDownTest() {
+	config.WhatsappSettingMaxDownloadSize = suite.origMaxSize
+}
+
+func (suite *MediaStreamTestSuite) TestDetectReaderContentType() {
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{
+			name:    "should detect png",
+			content: []byte("\x89PNG\r\n\x1a\n0000"),
+			want:    "image/png",
+		},
+		{
+			name:    "should detect mp4",
+			content: mp4Header,
+			want:    "video/mp4",
+		},
+		{
+			name:    "should detect short text",
+			content: []byte("hi"),
+			want:    "text/plain; charset=utf-8",
+		},
+		{
+			name:    "should handle empty content",
+			content: []byte{},
+			want:    "text/plain; charset=utf-8",
+		},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			reader := bytes.NewReader(tt.content)
+			got, err := utils.DetectReaderContentType(reader)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			// The reader is rewound so the whole content is still available
+			rest, err := io.ReadAll(reader)
+			assert.NoError(t, err)
+			assert.Equal(t, len(tt.content), len(rest))
+		})
+	}
+}
+
+func (suite *MediaStreamTestSuite) TestOpenVideoFromURLStreamsToFile() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "video/mp4")
+		_, _ = io.Copy(w, &generatedMedia{size: streamedMediaSize})
+	}))
+	defer server.Close()
+
+	path := filepath.Join(suite.T().TempDir(), "video.mp4")
+	var (
+		source  *utils.MediaSource
+		written int64
+		err     error
+	)
+	allocated := allocatedDuring(func() {
+		source, err = utils.OpenVideoFromURL(server.URL + "/clip.mp4?token=1")
+		if err != nil {
+			return
+		}
+		defer source.Close()
+		written, err = source.SaveTo(path)
+	})
+	require.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), "clip.mp4", source.FileName)
+	assert.Equal(suite.T(), "video/mp4", source.MimeType)
+	assert.Equal(suite.T(), int64(streamedMediaSize), written)
+	assert.Less(suite.T(), allocated, uint64(streamingAllocBudget), "download must not be buffered in memory")
+
+	file, err := os.Open(path)
+	require.NoError(suite.T(), err)
+	defer file.Close()
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), generatedMediaHash(streamedMediaSize), hash.Sum(nil))
+}
+
+func (suite *MediaStreamTestSuite) TestOpenAudioFromURLEnforcesLimitWhileStreaming() {
+	config.WhatsappSettingMaxDownloadSize = 1 << 20
+
+	// Without Content-Length the limit can only be enforced while reading
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.(http.Flusher).Flush()
+		_, _ = io.Copy(w, &generatedMedia{size: streamedMediaSize})
+	}))
+	defer server.Close()
+
+	source, err := utils.OpenAudioFromURL(server.URL + "/song.mp3")
+	require.NoError(suite.T(), err)
+	defer source.Close()
+	assert.Equal(suite.T(), int64(-1), source.Size)
+
+	read, err := io.Copy(io.Discard, source)
+	assert.True(suite.T(), errors.Is(err, utils.ErrMediaTooLarge))
+	assert.LessOrEqual(suite.T(), read, int64(2<<20), "reading must stop soon after the limit")
+
+	_, _, err = utils.DownloadAudioFromURL(server.URL + "/song.mp3")
+	assert.ErrorIs(suite.T(), err, utils.ErrMediaTooLarge)
+}
+
+func (suite *MediaStreamTestSuite) TestOpenMultipartMediaStreamsSpooledUpload() {
+	// Build a large upload the way the HTTP server receives it, the parser spools the file to disk
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		part, err := form.CreateFormFile("video", "upload.mp4")
+		if err == nil {
+			_, err = io.Copy(part, &generatedMedia{size: streamedMediaSize})
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		_ = writer.CloseWithError(err)
+	}()
+
+	parsed, err := multipart.NewReader(body, form.Boundary()).ReadForm(1 << 20)
+	require.NoError(suite.T(), err)
+	defer func() { _ = parsed.RemoveAll() }()
+	fileHeader := parsed.File["video"][0]
+
+	var (
+		source *utils.MediaSource
+		hashed []byte
+	)
+	allocated := allocatedDuring(func() {
+		source, err = utils.OpenMultipartMedia(fileHeader)
+		if err != nil {
+			return
+		}
+		defer source.Close()
+		hash := sha256.New()
+		if _, err = io.Copy(hash, source); err == nil {
+			hashed = hash.Sum(nil)
+		}
+	})
+	require.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), "upload.mp4", source.FileName)
+	assert.Equal(suite.T(), "video/mp4", source.MimeType)
+	assert.Equal(suite.T(), int64(streamedMediaSize), source.Size)
+	assert.Equal(suite.T(), generatedMediaHash(streamedMediaSize), hashed)
+	assert.Less(suite.T(), allocated, uint64(streamingAllocBudget), "upload must not be buffered in memory")
+}
+
+func (suite *MediaStreamTestSuite) TestSpoolToTempFile() {
+	var (
+		file *os.File
+		err  error
+	)
+	allocated := allocatedDuring(func() {
+		file, err = utils.SpoolToTempFile(&generatedMedia{size: streamedMediaSize}, "spool-test-*")
+	})
+	require.NoError(suite.T(), err)
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+	assert.Less(suite.T(), allocated, uint64(streamingAllocBudget), "spooling must not be buffered in memory")
+
+	// The file is rewound and ready to be read from the start
+	head := make([]byte, len(mp4Header))
+	_, err = io.ReadFull(file, head)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), mp4Header, head)
+
+	info, err := file.Stat()
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(streamedMediaSize), info.Size())
+}
+
+func (suite *MediaStreamTestSuite) TestSpoolToTempFileRemovesFileOnError() {
+	pattern := "spool-error-test-*"
+	_, err := utils.SpoolToTempFile(utils.NewMaxSizeReader(strings.NewReader("too long"), 3), pattern)
+	assert.ErrorIs(suite.T(), err, utils.ErrMediaTooLarge)
+
+	leftovers, _ := filepath.Glob(filepath.Join(os.TempDir(), pattern))
+	assert.Empty(suite.T(), leftovers)
+}
+
+func TestMediaStreamTestSuite(t *testing.T) {
+	suite.Run(t, new(MediaStreamTestSuite))
+}
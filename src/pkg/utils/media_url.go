@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrMediaURLExpired is returned for a signed media URL past its expiry
+	ErrMediaURLExpired = errors.New("media url has expired")
+	// ErrMediaURLSignature is returned for a media URL that was not signed by this server
+	ErrMediaURLSignature = errors.New("media url signature is invalid")
+
+	mediaURLKeyOnce sync.Once
+	mediaURLKey     []byte
+)
+
+// mediaURLKeyFile keeps the generated signing key in PathStorages, so signed URLs survive restarts without a secret
+const mediaURLKeyFile = "media-url.key"
+
+// MediaURLPath is the path of the endpoint streaming the media of a message
+func MediaURLPath(messageID string) string {
+	return fmt.Sprintf("%s/message/%s/media", config.AppBasePath, url.PathEscape(messageID))
+}
+
+// SignMediaURL builds a URL streaming the media of a message without credentials until expiresAt
+func SignMediaURL(baseURL, messageID, chatJID string, expiresAt time.Time) string {
+	expires := expiresAt.Unix()
+
+	query := url.Values{}
+	query.Set("phone", chatJID)
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", mediaURLSignature(messageID, chatJID, expires))
+
+	return strings.TrimRight(baseURL, "/") + MediaURLPath(messageID) + "?" + query.Encode()
+}
+
+// VerifyMediaURL checks the expires and signature query values of a signed media URL
+func VerifyMediaURL(messageID, chatJID, expires, signature string) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || signature == "" {
+		return ErrMediaURLSignature
+	}
+
+	expected := mediaURLSignature(messageID, chatJID, expiresAt)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrMediaURLSignature
+	}
+	if time.Now().Unix() > expiresAt {
+		return ErrMediaURLExpired
+	}
+	return nil
+}
+
+func mediaURLSignature(messageID, chatJID string, expires int64) string {
+	mac := hmac.New(sha256.New, mediaURLSigningKey())
+	_, _ = fmt.Fprintf(mac, "%s\n%s\n%d", messageID, chatJID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// InitMediaURLSigningKey loads the key signing media URLs when no secret is configured, generating and saving it
+// on the first start. Replicas each generate their own key, they need AppMediaURLSecret to accept each other's URLs.
+func InitMediaURLSigningKey() error {
+	if config.AppMediaURLSecret != "" {
+		return nil
+	}
+
+	keyPath := filepath.Join(config.PathStorages, mediaURLKeyFile)
+	key, err := os.ReadFile(keyPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read media url signing key: %w", err)
+	}
+	if len(key) < 32 {
+		key = make([]byte, 32)
+		if _, err = rand.Read(key); err != nil {
+			return fmt.Errorf("failed to generate media url signing key: %w", err)
+		}
+		if err = os.WriteFile(keyPath, key, 0600); err != nil {
+			return fmt.Errorf("failed to save media url signing key: %w", err)
+		}
+		logrus.Warnf("No media URL secret is set, signing media URLs with a key generated in %s", keyPath)
+	}
+
+	mediaURLKeyOnce.Do(func() {})
+	mediaURLKey = key
+	return nil
+}
+
+// mediaURLSigningKey uses the configured secret, or else the key loaded by InitMediaURLSigningKey.
+// A process that never loaded one signs with a random key valid until it exits.
+func mediaURLSigningKey() []byte {
+	if config.AppMediaURLSecret != "" {
+		return []byte(config.AppMediaURLSecret)
+	}
+
+	mediaURLKeyOnce.Do(func() {
+		mediaURLKey = make([]byte, 32)
+		if _, err := rand.Read(mediaURLKey); err != nil {
+			panic(fmt.Sprintf("failed to generate media url signing key: %v", err))
+		}
+	})
+	return mediaURLKey
+}
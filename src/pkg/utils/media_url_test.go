@@ -0,0 +1,170 @@
+package utils_test
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type MediaURLTestSuite struct {
+	suite.Suite
+	origSecret   string
+	origBasePath string
+	origStorages string
+}
+
+func (suite *MediaURLTestSuite) SetupTest() {
+	suite.origSecret = config.AppMediaURLSecret
+	suite.origBasePath = config.AppBasePath
+	suite.origStorages = config.PathStorages
+	config.AppMediaURLSecret = "test-secret"
+	config.AppBasePath = ""
+}
+
+func (suite *MediaURLTestSuite) TearDownTest() {
+	config.AppMediaURLSecret = suite.origSecret
+	config.AppBasePath = suite.origBasePath
+	config.PathStorages = suite.origStorages
+}
+
+func (suite *MediaURLTestSuite) TestSignMediaURL() {
+	config.AppBasePath = "/gowa"
+	signed := utils.SignMediaURL("https://wa.example.com/", "3EB0ABC", "6281234567890@s.whatsapp.net", time.Unix(1700000000, 0))
+
+	parsed, err := url.Parse(signed)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "wa.example.com", parsed.Host)
+	assert.Equal(suite.T(), "/gowa/message/3EB0ABC/media", parsed.Path)
+	assert.Equal(suite.T(), "6281234567890@s.whatsapp.net", parsed.Query().Get("phone"))
+	assert.Equal(suite.T(), "1700000000", parsed.Query().Get("expires"))
+	assert.Len(suite.T(), parsed.Query().Get("signature"), 64)
+}
+
+func (suite *MediaURLTestSuite) TestVerifyMediaURL() {
+	const (
+		messageID = "3EB0ABC"
+		chatJID   = "6281234567890@s.whatsapp.net"
+	)
+
+	sign := func(expiresAt time.Time) url.Values {
+		parsed, err := url.Parse(utils.SignMediaURL("http://localhost:3000", messageID, chatJID, expiresAt))
+		require.NoError(suite.T(), err)
+		return parsed.Query()
+	}
+	valid := sign(time.Now().Add(time.Minute))
+	expired := sign(time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name      string
+		messageID string
+		chatJID   string
+		expires   string
+		signature string
+		err       error
+	}{
+		{
+			name:      "should accept a signed url",
+			messageID: messageID,
+			chatJID:   chatJID,
+			expires:   valid.Get("expires"),
+			signature: valid.Get("signature"),
+		},
+		{
+			name:      "should reject an expired url",
+			messageID: messageID,
+			chatJID:   chatJID,
+			expires:   expired.Get("expires"),
+			signature: expired.Get("signature"),
+			err:       utils.ErrMediaURLExpired,
+		},
+		{
+			name:      "should reject another message",
+			messageID: "3EB0DEF",
+			chatJID:   chatJID,
+			expires:   valid.Get("expires"),
+			signature: valid.Get("signature"),
+			err:       utils.ErrMediaURLSignature,
+		},
+		{
+			name:      "should reject another chat",
+			messageID: messageID,
+			chatJID:   "6289876543210@s.whatsapp.net",
+			expires:   valid.Get("expires"),
+			signature: valid.Get("signature"),
+			err:       utils.ErrMediaURLSignature,
+		},
+		{
+			name:      "should reject an extended expiry",
+			messageID: messageID,
+			chatJID:   chatJID,
+			expires:   strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10),
+			signature: valid.Get("signature"),
+			err:       utils.ErrMediaURLSignature,
+		},
+		{
+			name:      "should reject a missing signature",
+			messageID: messageID,
+			chatJID:   chatJID,
+			expires:   valid.Get("expires"),
+			err:       utils.ErrMediaURLSignature,
+		},
+		{
+			name:      "should reject a malformed expiry",
+			messageID: messageID,
+			chatJID:   chatJID,
+			expires:   "tomorrow",
+			signature: valid.Get("signature"),
+			err:       utils.ErrMediaURLSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			err := utils.VerifyMediaURL(tt.messageID, tt.chatJID, tt.expires, tt.signature)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func (suite *MediaURLTestSuite) TestVerifyMediaURLWithAnotherSecret() {
+	parsed, err := url.Parse(utils.SignMediaURL("http://localhost:3000", "3EB0ABC", "6281234567890@s.whatsapp.net", time.Now().Add(time.Minute)))
+	require.NoError(suite.T(), err)
+
+	config.AppMediaURLSecret = "rotated-secret"
+	err = utils.VerifyMediaURL("3EB0ABC", "6281234567890@s.whatsapp.net", parsed.Query().Get("expires"), parsed.Query().Get("signature"))
+	assert.Equal(suite.T(), utils.ErrMediaURLSignature, err)
+}
+
+func (suite *MediaURLTestSuite) TestGeneratedSigningKeySurvivesRestarts() {
+	config.AppMediaURLSecret = ""
+	config.PathStorages = suite.T().TempDir()
+	expiresAt := time.Now().Add(time.Minute)
+
+	require.NoError(suite.T(), utils.InitMediaURLSigningKey())
+	signed := utils.SignMediaURL("http://localhost:3000", "3EB0ABC", "6281234567890@s.whatsapp.net", expiresAt)
+
+	info, err := os.Stat(filepath.Join(config.PathStorages, "media-url.key"))
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), os.FileMode(0600), info.Mode().Perm())
+
+	// A restart loads the saved key instead of generating another one
+	require.NoError(suite.T(), utils.InitMediaURLSigningKey())
+	assert.Equal(suite.T(), signed, utils.SignMediaURL("http://localhost:3000", "3EB0ABC", "6281234567890@s.whatsapp.net", expiresAt))
+
+	// A configured secret wins over the generated key
+	config.AppMediaURLSecret = "test-secret"
+	assert.NotEqual(suite.T(), signed, utils.SignMediaURL("http://localhost:3000", "3EB0ABC", "6281234567890@s.whatsapp.net", expiresAt))
+}
+
+func TestMediaURLTestSuite(t *testing.T) {
+	suite.Run(t, new(MediaURLTestSuite))
+}
@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+)
+
+// ErrViewOnceMessage rejects quoting and forwarding view once messages, either would show their content again
+var ErrViewOnceMessage = pkgError.ValidationError("view once messages can not be quoted or forwarded")
+
+// gzipMagic starts gzip data, a marshaled message never does as 0x1f is not a valid protobuf tag
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// RawMessageFields returns the raw message and the JSON of its info to store with a message,
+// both are empty when ChatStorageRawMessages is off or the message can not be encoded
+func RawMessageFields(msg *waE2E.Message, info *types.MessageInfo) (rawMessage []byte, rawInfo string) {
+	if !config.ChatStorageRawMessages || msg == nil {
+		return nil, ""
+	}
+
+	rawMessage, err := EncodeRawMessage(msg)
+	if err != nil {
+		logrus.Warnf("Failed to encode raw message: %v", err)
+		return nil, ""
+	}
+	if info != nil {
+		if encodedInfo, err := json.Marshal(info); err != nil {
+			logrus.Warnf("Failed to encode info of message %s: %v", info.ID, err)
+		} else {
+			rawInfo = string(encodedInfo)
+		}
+	}
+	return rawMessage, rawInfo
+}
+
+// EncodeRawMessage marshals a message for chat storage. With ChatStorageCompressRaw the bytes are
+// gzip compressed when that makes them smaller, DecodeRawMessage reads both.
+func EncodeRawMessage(msg *waE2E.Message) ([]byte, error) {
+	if msg == nil {
+		return nil, nil
+	}
+
+	data, err := proto.Marshal(msg)
+	if err != nil || !config.ChatStorageCompressRaw {
+		return data, err
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err = writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+	if compressed.Len() >= len(data) {
+		return data, nil
+	}
+	return compressed.Bytes(), nil
+}
+
+// DecodeRawMessage unmarshals a message stored by EncodeRawMessage, nil when nothing was stored
+func DecodeRawMessage(data []byte) (*waE2E.Message, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	if bytes.HasPrefix(data, gzipMagic) {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		if data, err = io.ReadAll(reader); err != nil {
+			return nil, err
+		}
+	}
+
+	msg := &waE2E.Message{}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// HasStandaloneContent reports messages without text or media that are kept anyway, like locations,
+// contact cards and polls. They are only worth storing with their raw message.
+func HasStandaloneContent(msg *waE2E.Message) bool {
+	return msg.GetLocationMessage() != nil ||
+		msg.GetLiveLocationMessage() != nil ||
+		msg.GetContactMessage() != nil ||
+		msg.GetContactsArrayMessage() != nil ||
+		msg.GetPollCreationMessage() != nil ||
+		msg.GetPollCreationMessageV2() != nil ||
+		msg.GetPollCreationMessageV3() != nil
+}
+
+// QuotedMessage returns a stored message as it is quoted in a reply, without the wrappers
+// of ephemeral and own device messages and without the context of the message itself.
+// View once messages fail with ErrViewOnceMessage.
+func QuotedMessage(raw *waE2E.Message) (*waE2E.Message, error) {
+	msg, err := unwrapRawMessage(raw)
+	if err != nil {
+		return nil, err
+	}
+	clearContextInfo(msg)
+	return msg, nil
+}
+
+// ForwardedMessage returns a stored message to send again as a forward. The forwarding score counts
+// how often the message was forwarded, WhatsApp shows "Forwarded many times" from 5 on.
+// View once messages fail with ErrViewOnceMessage, a forward would send them as ordinary media.
+func ForwardedMessage(raw *waE2E.Message) (*waE2E.Message, error) {
+	msg, err := unwrapRawMessage(raw)
+	if err != nil {
+		return nil, err
+	}
+	score := forwardingScore(msg) + 1
+	clearContextInfo(msg)
+	msg.MessageContextInfo = nil
+
+	// Plain text has no context, it is forwarded as extended text
+	if text := msg.GetConversation(); text != "" {
+		msg.Conversation = nil
+		msg.ExtendedTextMessage = &waE2E.ExtendedTextMessage{Text: proto.String(text)}
+	}
+
+	forwarded := &waE2E.ContextInfo{IsForwarded: proto.Bool(true), ForwardingScore: proto.Uint32(score)}
+	eachContextInfo(msg, func(content protoreflect.Message, field protoreflect.FieldDescriptor) {
+		content.Set(field, protoreflect.ValueOfMessage(proto.Clone(forwarded).ProtoReflect()))
+	})
+	return msg, nil
+}
+
+// unwrapRawMessage copies the content out of its wrappers, failing for the view once wrappers
+// and for media flagged as view once without them
+func unwrapRawMessage(raw *waE2E.Message) (*waE2E.Message, error) {
+	evt := (&events.Message{RawMessage: proto.Clone(raw).(*waE2E.Message)}).UnwrapRaw()
+	msg := evt.Message
+	if evt.IsViewOnce || msg.GetImageMessage().GetViewOnce() || msg.GetVideoMessage().GetViewOnce() || msg.GetAudioMessage().GetViewOnce() {
+		return nil, ErrViewOnceMessage
+	}
+	return msg, nil
+}
+
+func forwardingScore(msg *waE2E.Message) (score uint32) {
+	eachContextInfo(msg, func(content protoreflect.Message, field protoreflect.FieldDescriptor) {
+		if content.Has(field) {
+			score = max(score, content.Get(field).Message().Interface().(*waE2E.ContextInfo).GetForwardingScore())
+		}
+	})
+	return score
+}
+
+func clearContextInfo(msg *waE2E.Message) {
+	eachContextInfo(msg, func(content protoreflect.Message, field protoreflect.FieldDescriptor) {
+		content.Clear(field)
+	})
+}
+
+// eachContextInfo calls fn with every content of a message that can carry a context, like its text, image or location
+func eachContextInfo(msg *waE2E.Message, fn func(content protoreflect.Message, field protoreflect.FieldDescriptor)) {
+	msg.ProtoReflect().Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		if field.Kind() != protoreflect.MessageKind || field.IsList() || field.IsMap() {
+			return true
+		}
+		content := value.Message()
+		if contextField := content.Descriptor().Fields().ByName("contextInfo"); contextField != nil {
+			fn(content, contextField)
+		}
+		return true
+	})
+}
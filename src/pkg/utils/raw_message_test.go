@@ -0,0 +1,204 @@
+package utils_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+type RawMessageTestSuite struct {
+	suite.Suite
+	origRawMessages bool
+	origCompressRaw bool
+}
+
+func (suite *RawMessageTestSuite) SetupTest() {
+	suite.origRawMessages = config.ChatStorageRawMessages
+	suite.origCompressRaw = config.ChatStorageCompressRaw
+}
+
+func (suite *RawMessageTestSuite) TearDownTest() {
+	config.ChatStorageRawMessages = suite.origRawMessages
+	config.ChatStorageCompressRaw = suite.origCompressRaw
+}
+
+func (suite *RawMessageTestSuite) TestEncodeAndDecodeRawMessage() {
+	tests := []struct {
+		name       string
+		compress   bool
+		text       string
+		compressed bool
+	}{
+		{
+			name: "should keep plain bytes when compression is off",
+			text: strings.Repeat("hello ", 100),
+		},
+		{
+			name:       "should compress repetitive messages",
+			compress:   true,
+			text:       strings.Repeat("hello ", 100),
+			compressed: true,
+		},
+		{
+			name:     "should keep plain bytes when compression does not help",
+			compress: true,
+			text:     "hi",
+		},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			config.ChatStorageCompressRaw = tt.compress
+			msg := &waE2E.Message{Conversation: proto.String(tt.text)}
+
+			data, err := utils.EncodeRawMessage(msg)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.compressed, len(data) > 1 && data[0] == 0x1f && data[1] == 0x8b)
+
+			decoded, err := utils.DecodeRawMessage(data)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.text, decoded.GetConversation())
+		})
+	}
+
+	decoded, err := utils.DecodeRawMessage(nil)
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), decoded)
+}
+
+func (suite *RawMessageTestSuite) TestRawMessageFields() {
+	msg := &waE2E.Message{Conversation: proto.String("hello")}
+	info := &types.MessageInfo{ID: "3EB0789ABC123456"}
+
+	config.ChatStorageRawMessages = false
+	rawMessage, rawInfo := utils.RawMessageFields(msg, info)
+	assert.Nil(suite.T(), rawMessage)
+	assert.Empty(suite.T(), rawInfo)
+
+	config.ChatStorageRawMessages = true
+	rawMessage, rawInfo = utils.RawMessageFields(msg, info)
+	assert.NotEmpty(suite.T(), rawMessage)
+	assert.Contains(suite.T(), rawInfo, `"ID":"3EB0789ABC123456"`)
+}
+
+func (suite *RawMessageTestSuite) TestQuotedMessage() {
+	raw := &waE2E.Message{EphemeralMessage: &waE2E.FutureProofMessage{Message: &waE2E.Message{
+		ImageMessage: &waE2E.ImageMessage{
+			Caption:     proto.String("look"),
+			ContextInfo: &waE2E.ContextInfo{StanzaID: proto.String("earlier")},
+		},
+	}}}
+
+	quoted, err := utils.QuotedMessage(raw)
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), quoted.GetEphemeralMessage())
+	assert.Equal(suite.T(), "look", quoted.GetImageMessage().GetCaption())
+	assert.Nil(suite.T(), quoted.GetImageMessage().GetContextInfo())
+	// The stored message is left as it was
+	assert.Equal(suite.T(), "earlier", raw.GetEphemeralMessage().GetMessage().GetImageMessage().GetContextInfo().GetStanzaID())
+}
+
+func (suite *RawMessageTestSuite) TestForwardedMessage() {
+	tests := []struct {
+		name      string
+		raw       *waE2E.Message
+		wantText  string
+		wantScore uint32
+	}{
+		{
+			name:      "should forward plain text as extended text",
+			raw:       &waE2E.Message{Conversation: proto.String("hello")},
+			wantText:  "hello",
+			wantScore: 1,
+		},
+		{
+			name: "should count forwards of a forwarded message",
+			raw: &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+				Text:        proto.String("again"),
+				ContextInfo: &waE2E.ContextInfo{IsForwarded: proto.Bool(true), ForwardingScore: proto.Uint32(4), StanzaID: proto.String("quoted")},
+			}},
+			wantText:  "again",
+			wantScore: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			forwarded, err := utils.ForwardedMessage(tt.raw)
+			assert.NoError(t, err)
+			assert.Empty(t, forwarded.GetConversation())
+			assert.Equal(t, tt.wantText, forwarded.GetExtendedTextMessage().GetText())
+
+			contextInfo := forwarded.GetExtendedTextMessage().GetContextInfo()
+			assert.True(t, contextInfo.GetIsForwarded())
+			assert.Equal(t, tt.wantScore, contextInfo.GetForwardingScore())
+			assert.Empty(t, contextInfo.GetStanzaID())
+		})
+	}
+
+	location, err := utils.ForwardedMessage(&waE2E.Message{LocationMessage: &waE2E.LocationMessage{DegreesLatitude: proto.Float64(-6.2)}})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), -6.2, location.GetLocationMessage().GetDegreesLatitude())
+	assert.True(suite.T(), location.GetLocationMessage().GetContextInfo().GetIsForwarded())
+}
+
+func (suite *RawMessageTestSuite) TestViewOnceMessagesAreRejected() {
+	image := &waE2E.Message{ImageMessage: &waE2E.ImageMessage{Caption: proto.String("secret")}}
+	tests := []struct {
+		name string
+		raw  *waE2E.Message
+	}{
+		{
+			name: "should reject view once",
+			raw:  &waE2E.Message{ViewOnceMessage: &waE2E.FutureProofMessage{Message: image}},
+		},
+		{
+			name: "should reject view once v2",
+			raw:  &waE2E.Message{ViewOnceMessageV2: &waE2E.FutureProofMessage{Message: image}},
+		},
+		{
+			name: "should reject view once v2 extension",
+			raw:  &waE2E.Message{ViewOnceMessageV2Extension: &waE2E.FutureProofMessage{Message: image}},
+		},
+		{
+			name: "should reject view once inside an ephemeral message",
+			raw: &waE2E.Message{EphemeralMessage: &waE2E.FutureProofMessage{Message: &waE2E.Message{
+				ViewOnceMessageV2: &waE2E.FutureProofMessage{Message: image},
+			}}},
+		},
+		{
+			name: "should reject media flagged as view once",
+			raw:  &waE2E.Message{VideoMessage: &waE2E.VideoMessage{ViewOnce: proto.Bool(true)}},
+		},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			forwarded, err := utils.ForwardedMessage(tt.raw)
+			assert.ErrorIs(t, err, utils.ErrViewOnceMessage)
+			assert.Nil(t, forwarded)
+
+			quoted, err := utils.QuotedMessage(tt.raw)
+			assert.ErrorIs(t, err, utils.ErrViewOnceMessage)
+			assert.Nil(t, quoted)
+		})
+	}
+}
+
+func (suite *RawMessageTestSuite) TestHasStandaloneContent() {
+	assert.True(suite.T(), utils.HasStandaloneContent(&waE2E.Message{LocationMessage: &waE2E.LocationMessage{}}))
+	assert.True(suite.T(), utils.HasStandaloneContent(&waE2E.Message{PollCreationMessageV3: &waE2E.PollCreationMessage{}}))
+	assert.False(suite.T(), utils.HasStandaloneContent(&waE2E.Message{Conversation: proto.String("hello")}))
+	assert.False(suite.T(), utils.HasStandaloneContent(nil))
+}
+
+func TestRawMessageTestSuite(t *testing.T) {
+	suite.Run(t, new(RawMessageTestSuite))
+}
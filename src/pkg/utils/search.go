@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// BuildFullTextQuery turns user search input into a safe FTS5 match expression.
+// Words must all match, "quoted text" matches a phrase, a trailing * matches a prefix
+// and an uppercase OR between terms matches either of them. Every other FTS5 operator
+// is taken literally. An empty string is returned when nothing searchable is left.
+func BuildFullTextQuery(input string) string {
+	var terms []string
+	pendingOr := false
+
+	for input = strings.TrimSpace(input); input != ""; input = strings.TrimLeftFunc(input, unicode.IsSpace) {
+		var text string
+		if input[0] == '"' {
+			// Phrase up to the closing quote, an unclosed quote runs to the end
+			end := strings.IndexByte(input[1:], '"')
+			if end < 0 {
+				text, input = input[1:], ""
+			} else {
+				text, input = input[1:end+1], input[end+2:]
+			}
+		} else {
+			end := strings.IndexFunc(input, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+			if end < 0 {
+				end = len(input)
+			}
+			text, input = input[:end], input[end:]
+
+			if text == "OR" {
+				pendingOr = len(terms) > 0
+				continue
+			}
+		}
+
+		// A * right after the term asks for a prefix match
+		prefix := strings.HasSuffix(text, "*")
+		if strings.HasPrefix(input, "*") {
+			prefix, input = true, input[1:]
+		}
+		text = strings.TrimRight(text, "*")
+
+		if !strings.ContainsFunc(text, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }) {
+			continue
+		}
+
+		term := `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+		if pendingOr {
+			term = "OR " + term
+			pendingOr = false
+		}
+		terms = append(terms, term)
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// FullTextQueryToTSQuery converts a match expression of BuildFullTextQuery to PostgreSQL tsquery input.
+// Like in FTS5 an OR binds tighter than the implicit AND, "a OR b c" is "(a | b) & c".
+func FullTextQueryToTSQuery(match string) string {
+	lexeme := strings.NewReplacer(`\`, `\\`, `'`, `''`)
+
+	var groups [][]string
+	pendingOr := false
+	for match = strings.TrimSpace(match); match != ""; match = strings.TrimLeft(match, " ") {
+		if strings.HasPrefix(match, "OR ") {
+			pendingOr, match = true, match[3:]
+			continue
+		}
+		if match[0] != '"' {
+			// Not produced by BuildFullTextQuery
+			break
+		}
+
+		// The term ends at the first quote that is not doubled
+		var text strings.Builder
+		end := 1
+		for ; end < len(match); end++ {
+			if match[end] == '"' {
+				if !strings.HasPrefix(match[end+1:], `"`) {
+					break
+				}
+				end++
+			}
+			text.WriteByte(match[end])
+		}
+		match = match[min(end+1, len(match)):]
+
+		// A quoted lexeme with spaces is a phrase in tsquery as well
+		term := "'" + lexeme.Replace(text.String()) + "'"
+		if strings.HasPrefix(match, "*") {
+			term, match = term+":*", match[1:]
+		}
+
+		if pendingOr && len(groups) > 0 {
+			groups[len(groups)-1] = append(groups[len(groups)-1], term)
+		} else {
+			groups = append(groups, []string{term})
+		}
+		pendingOr = false
+	}
+
+	parts := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if len(group) > 1 {
+			parts = append(parts, "("+strings.Join(group, " | ")+")")
+		} else {
+			parts = append(parts, group[0])
+		}
+	}
+	return strings.Join(parts, " & ")
+}
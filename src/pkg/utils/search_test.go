@@ -0,0 +1,157 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SearchTestSuite struct {
+	suite.Suite
+}
+
+func (suite *SearchTestSuite) TestBuildFullTextQuery() {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "should match all words",
+			input: "meeting  tomorrow",
+			want:  `"meeting" "tomorrow"`,
+		},
+		{
+			name:  "should keep phrases",
+			input: `"see you" soon`,
+			want:  `"see you" "soon"`,
+		},
+		{
+			name:  "should keep prefix on words",
+			input: "invoi*",
+			want:  `"invoi"*`,
+		},
+		{
+			name:  "should keep prefix on phrases",
+			input: `"happy birth"*`,
+			want:  `"happy birth"*`,
+		},
+		{
+			name:  "should keep OR between terms",
+			input: "cat OR dog",
+			want:  `"cat" OR "dog"`,
+		},
+		{
+			name:  "should drop dangling OR",
+			input: "OR cat OR",
+			want:  `"cat"`,
+		},
+		{
+			name:  "should treat other operators literally",
+			input: "NOT cat AND dog NEAR(x)",
+			want:  `"NOT" "cat" "AND" "dog" "NEAR(x)"`,
+		},
+		{
+			name:  "should treat lowercase or as a word",
+			input: "this or that",
+			want:  `"this" "or" "that"`,
+		},
+		{
+			name:  "should escape quotes inside words",
+			input: `it's 5'9`,
+			want:  `"it's" "5'9"`,
+		},
+		{
+			name:  "should close unbalanced quote",
+			input: `"open phrase`,
+			want:  `"open phrase"`,
+		},
+		{
+			name:  "should split words at quotes",
+			input: `say"hi"`,
+			want:  `"say" "hi"`,
+		},
+		{
+			name:  "should keep column filters literal",
+			input: "content:secret",
+			want:  `"content:secret"`,
+		},
+		{
+			name:  "should skip terms without letters or digits",
+			input: `- * "" ()`,
+			want:  "",
+		},
+		{
+			name:  "should keep unicode words",
+			input: "café ação",
+			want:  `"café" "ação"`,
+		},
+		{
+			name:  "should return empty for blank input",
+			input: "   ",
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, utils.BuildFullTextQuery(tt.input))
+		})
+	}
+}
+
+func (suite *SearchTestSuite) TestFullTextQueryToTSQuery() {
+	tests := []struct {
+		name  string
+		match string
+		want  string
+	}{
+		{
+			name:  "should match all words",
+			match: `"meeting" "tomorrow"`,
+			want:  `'meeting' & 'tomorrow'`,
+		},
+		{
+			name:  "should keep phrases",
+			match: `"see you" "soon"`,
+			want:  `'see you' & 'soon'`,
+		},
+		{
+			name:  "should keep prefixes",
+			match: `"invoi"* "happy birth"*`,
+			want:  `'invoi':* & 'happy birth':*`,
+		},
+		{
+			name:  "should group OR before AND",
+			match: `"cat" OR "dog" "food" OR "toy"*`,
+			want:  `('cat' | 'dog') & ('food' | 'toy':*)`,
+		},
+		{
+			name:  "should escape quotes and backslashes",
+			match: `"say ""hi""" "it's" "a\b"`,
+			want:  `'say "hi"' & 'it''s' & 'a\\b'`,
+		},
+		{
+			name:  "should treat operators inside terms literally",
+			match: `"NOT" "a&b" "!x"`,
+			want:  `'NOT' & 'a&b' & '!x'`,
+		},
+		{
+			name:  "should return empty for an empty match",
+			match: "",
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, utils.FullTextQueryToTSQuery(tt.match))
+		})
+	}
+}
+
+func TestSearchTestSuite(t *testing.T) {
+	suite.Run(t, new(SearchTestSuite))
+}
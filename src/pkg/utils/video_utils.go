@@ -0,0 +1,359 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	// WhatsApp video constraints
+	VideoAudioBitrate      = 128_000 // AAC bitrate in bits per second
+	VideoMinAudioBitrate   = 64_000  // AAC bitrate when the size budget is tight
+	VideoMinBitrate        = 150_000 // Below this the video is not worth watching
+	VideoSizeSafetyMargin  = 0.95    // Room for container overhead and bitrate variance
+	VideoQualityCRF        = 23      // x264 constant quality when the size budget allows it
+	CompressedVideoCRF     = 28      // x264 constant quality when compression is requested
+	MaxCompressedVideoSide = 720     // Max width in pixels when compression is requested
+)
+
+// mp4Brands are the major brands of ISO MP4 files. ffprobe reports MOV, 3GP and MP4 as the same format,
+// QuickTime files carry the "qt" brand and some phones do not play them.
+var mp4Brands = map[string]bool{
+	"isom": true, "iso2": true, "iso4": true, "iso5": true, "iso6": true,
+	"mp41": true, "mp42": true, "avc1": true, "m4v": true, "dash": true, "mmp4": true,
+}
+
+// VideoInfo is what ffprobe reports about a video file
+type VideoInfo struct {
+	FormatName  string
+	MajorBrand  string // brand of MP4 and MOV files, tells them apart
+	FastStart   bool   // the index of an MP4 comes before the media, phones play it while downloading
+	VideoCodec  string
+	PixelFormat string
+	AudioCodec  string  // empty when the video has no audio track
+	Duration    float64 // seconds
+	Width       int     // display width, after rotation
+	Height      int     // display height, after rotation
+	Rotation    int
+	Size        int64
+}
+
+// VideoTranscodeOptions are the constraints a video must meet before sending
+type VideoTranscodeOptions struct {
+	MaxSize  int64
+	Compress bool
+	Silent   bool // drop the audio track, GIF playback is always muted
+}
+
+// VideoTranscodePlan describes how a video has to be re-encoded to play on phones
+type VideoTranscodePlan struct {
+	Reasons      []string
+	Remux        bool // only the container has to change, the streams are copied as they are
+	CRF          int
+	VideoBitrate int64 // cap in bits per second, 0 when the size budget does not constrain it
+	AudioBitrate int64
+	MaxWidth     int // 0 keeps the original width
+	HasAudio     bool
+}
+
+// Needed reports whether the video must be transcoded before sending
+func (plan *VideoTranscodePlan) Needed() bool {
+	return len(plan.Reasons) > 0
+}
+
+// IsMP4 reports an ISO MP4 file, not a QuickTime or 3GP file that ffprobe reports as the same format
+func (info *VideoInfo) IsMP4() bool {
+	return strings.Contains(info.FormatName, "mp4") && mp4Brands[strings.ToLower(strings.TrimSpace(info.MajorBrand))]
+}
+
+func (info *VideoInfo) container() string {
+	if brand := strings.TrimSpace(info.MajorBrand); brand != "" {
+		return fmt.Sprintf("%s (brand %s)", info.FormatName, brand)
+	}
+	return info.FormatName
+}
+
+// ThumbnailOffset picks the moment of the preview frame, one second in or the middle of short clips
+func (info *VideoInfo) ThumbnailOffset() float64 {
+	if info.Duration > 0 && info.Duration < 2 {
+		return info.Duration / 2
+	}
+	return 1
+}
+
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType    string            `json:"codec_type"`
+		CodecName    string            `json:"codec_name"`
+		PixelFormat  string            `json:"pix_fmt"`
+		Width        int               `json:"width"`
+		Height       int               `json:"height"`
+		Tags         map[string]string `json:"tags"`
+		SideDataList []struct {
+			Rotation float64 `json:"rotation"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+	Format struct {
+		FormatName string            `json:"format_name"`
+		Duration   string            `json:"duration"`
+		Size       string            `json:"size"`
+		Tags       map[string]string `json:"tags"`
+	} `json:"format"`
+}
+
+// ProbeVideo runs ffprobe on a video file
+func ProbeVideo(ctx context.Context, path string) (*VideoInfo, error) {
+	cmd := exec.CommandContext(ctx, "ffprobe", "-v", "error", "-print_format", "json", "-show_format", "-show_streams", path)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	info, err := ParseVideoProbe(output)
+	if err != nil || !info.IsMP4() {
+		return info, err
+	}
+	if info.FastStart, err = MP4FastStart(path); err != nil {
+		return nil, fmt.Errorf("failed to read mp4 boxes: %w", err)
+	}
+	return info, nil
+}
+
+// MP4FastStart reports whether the moov box, the index of an MP4, comes before the mdat box holding the media
+func MP4FastStart(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	header := make([]byte, 16)
+	for {
+		if _, err = io.ReadFull(file, header[:8]); errors.Is(err, io.EOF) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+
+		switch string(header[4:8]) {
+		case "moov":
+			return true, nil
+		case "mdat":
+			return false, nil
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			// The box runs to the end of the file
+			return false, nil
+		case 1:
+			if _, err = io.ReadFull(file, header[8:16]); err != nil {
+				return false, err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if size < headerSize {
+			return false, fmt.Errorf("invalid size %d of box %q", size, header[4:8])
+		}
+		if _, err = file.Seek(size-headerSize, io.SeekCurrent); err != nil {
+			return false, err
+		}
+	}
+}
+
+// ParseVideoProbe reads the JSON output of ffprobe -show_format -show_streams
+func ParseVideoProbe(output []byte) (*VideoInfo, error) {
+	var probe ffprobeOutput
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil, fmt.Errorf("invalid ffprobe output: %w", err)
+	}
+
+	info := &VideoInfo{FormatName: probe.Format.FormatName, MajorBrand: probe.Format.Tags["major_brand"]}
+	info.Duration, _ = strconv.ParseFloat(probe.Format.Duration, 64)
+	info.Size, _ = strconv.ParseInt(probe.Format.Size, 10, 64)
+
+	for _, stream := range probe.Streams {
+		switch stream.CodecType {
+		case "video":
+			// Cover art is exposed as a video stream too, only the first real one counts
+			if info.VideoCodec != "" || stream.CodecName == "mjpeg" || stream.CodecName == "png" {
+				continue
+			}
+			info.VideoCodec = stream.CodecName
+			info.PixelFormat = stream.PixelFormat
+			info.Width = stream.Width
+			info.Height = stream.Height
+
+			// Phones record in landscape and store the rotation next to the stream
+			if rotate, err := strconv.Atoi(stream.Tags["rotate"]); err == nil {
+				info.Rotation = rotate
+			}
+			for _, sideData := range stream.SideDataList {
+				if sideData.Rotation != 0 {
+					info.Rotation = int(sideData.Rotation)
+				}
+			}
+		case "audio":
+			if info.AudioCodec == "" {
+				info.AudioCodec = stream.CodecName
+			}
+		}
+	}
+
+	if info.VideoCodec == "" {
+		return nil, fmt.Errorf("no video stream found")
+	}
+	if rotation := ((info.Rotation % 360) + 360) % 360; rotation == 90 || rotation == 270 {
+		info.Width, info.Height = info.Height, info.Width
+	}
+	return info, nil
+}
+
+// PlanVideoTranscode decides whether a video has to be transcoded to H.264/AAC MP4 and picks
+// a bitrate cap so the result stays under the maximum size. H.264/AAC in another container, or in an MP4
+// whose index is at the end, is only remuxed.
+func PlanVideoTranscode(info *VideoInfo, options VideoTranscodeOptions) (*VideoTranscodePlan, error) {
+	maxSize := options.MaxSize
+	plan := &VideoTranscodePlan{
+		CRF:          VideoQualityCRF,
+		AudioBitrate: VideoAudioBitrate,
+		HasAudio:     info.AudioCodec != "" && !options.Silent,
+	}
+
+	if !info.IsMP4() {
+		plan.Reasons = append(plan.Reasons, fmt.Sprintf("container %s is not MP4", info.container()))
+	} else if !info.FastStart {
+		plan.Reasons = append(plan.Reasons, "MP4 index is at the end of the file")
+	}
+	containerReasons := len(plan.Reasons)
+	if info.VideoCodec != "h264" {
+		plan.Reasons = append(plan.Reasons, fmt.Sprintf("video codec %s is not H.264", info.VideoCodec))
+	} else if info.PixelFormat != "" && info.PixelFormat != "yuv420p" && info.PixelFormat != "yuvj420p" {
+		plan.Reasons = append(plan.Reasons, fmt.Sprintf("pixel format %s is not supported by phones", info.PixelFormat))
+	}
+	if plan.HasAudio && info.AudioCodec != "aac" {
+		plan.Reasons = append(plan.Reasons, fmt.Sprintf("audio codec %s is not AAC", info.AudioCodec))
+	}
+	if options.Silent && info.AudioCodec != "" {
+		plan.Reasons = append(plan.Reasons, "audio track removed")
+	}
+	if info.Size > maxSize {
+		plan.Reasons = append(plan.Reasons, fmt.Sprintf("size %d exceeds maximum allowed size %d", info.Size, maxSize))
+	}
+	if options.Compress {
+		plan.Reasons = append(plan.Reasons, "compression requested")
+		plan.CRF = CompressedVideoCRF
+		plan.MaxWidth = MaxCompressedVideoSide
+	}
+
+	// The streams play on phones as they are, they are copied into an MP4 with the index first
+	if plan.Needed() && len(plan.Reasons) == containerReasons {
+		plan.Remux = true
+		return plan, nil
+	}
+
+	// Without a duration the output size cannot be predicted, rely on the constant quality
+	if !plan.Needed() || info.Duration <= 0 {
+		return plan, nil
+	}
+
+	budget := int64(float64(maxSize) * 8 * VideoSizeSafetyMargin / info.Duration)
+	if !plan.HasAudio {
+		plan.AudioBitrate = 0
+	} else if budget-plan.AudioBitrate < VideoMinBitrate {
+		plan.AudioBitrate = VideoMinAudioBitrate
+	}
+	plan.VideoBitrate = budget - plan.AudioBitrate
+	if plan.VideoBitrate < VideoMinBitrate {
+		return nil, fmt.Errorf("video of %.0f seconds is too long to fit in %d bytes", math.Ceil(info.Duration), maxSize)
+	}
+	return plan, nil
+}
+
+// TranscodeVideoArgs builds the ffmpeg arguments that turn input into a WhatsApp compatible MP4
+func TranscodeVideoArgs(input, output string, plan *VideoTranscodePlan) []string {
+	if plan.Remux {
+		return []string{"-y", "-i", input, "-map", "0:v:0", "-map", "0:a:0?", "-c", "copy", "-movflags", "+faststart", output}
+	}
+
+	// H.264 in 4:2:0 needs even dimensions
+	scale := "scale=trunc(iw/2)*2:trunc(ih/2)*2"
+	if plan.MaxWidth > 0 {
+		scale = fmt.Sprintf("scale='min(%d,iw)':-2", plan.MaxWidth)
+	}
+
+	args := []string{"-y", "-i", input,
+		"-map", "0:v:0", "-map", "0:a:0?",
+		"-c:v", "libx264",
+		"-preset", "fast",
+		"-profile:v", "main",
+		"-pix_fmt", "yuv420p",
+		"-crf", strconv.Itoa(plan.CRF),
+		"-vf", scale,
+	}
+	if plan.VideoBitrate > 0 {
+		args = append(args, "-maxrate", strconv.FormatInt(plan.VideoBitrate, 10), "-bufsize", strconv.FormatInt(plan.VideoBitrate*2, 10))
+	}
+	if plan.HasAudio {
+		args = append(args, "-c:a", "aac", "-b:a", strconv.FormatInt(plan.AudioBitrate, 10), "-ac", "2")
+	} else {
+		args = append(args, "-an")
+	}
+	return append(args, "-movflags", "+faststart", output)
+}
+
+// TranscodeVideo runs ffmpeg with the given plan
+func TranscodeVideo(ctx context.Context, input, output string, plan *VideoTranscodePlan) error {
+	cmd := exec.CommandContext(ctx, "ffmpeg", TranscodeVideoArgs(input, output, plan)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg transcoding failed: %v: %s", err, lastLines(string(out), 5))
+	}
+	return nil
+}
+
+// ExtractVideoThumbnail grabs a frame at the given offset in seconds as a small JPEG, without writing it to disk
+func ExtractVideoThumbnail(ctx context.Context, path string, offset float64) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-ss", strconv.FormatFloat(offset, 'f', 3, 64),
+		"-i", path,
+		"-frames:v", "1",
+		"-vf", fmt.Sprintf("scale=%d:-2", ImageThumbnailWidth),
+		"-f", "image2pipe",
+		"-c:v", "mjpeg",
+		"-q:v", "5",
+		"pipe:1")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	thumbnail, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg thumbnail failed: %v: %s", err, lastLines(stderr.String(), 5))
+	}
+	if len(thumbnail) == 0 {
+		return nil, fmt.Errorf("ffmpeg produced no thumbnail at %.3fs", offset)
+	}
+	return thumbnail, nil
+}
+
+// lastLines keeps the end of a tool output, where ffmpeg prints the actual error
+func lastLines(output string, count int) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > count {
+		lines = lines[len(lines)-count:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,348 @@
+package utils_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type VideoUtilsTestSuite struct {
+	suite.Suite
+}
+
+func (suite *VideoUtilsTestSuite) TestParseVideoProbe() {
+	tests := []struct {
+		name    string
+		output  string
+		want    *utils.VideoInfo
+		wantErr bool
+	}{
+		{
+			name: "should parse mp4 with h264 and aac",
+			output: `{"streams":[
+				{"codec_type":"video","codec_name":"h264","pix_fmt":"yuv420p","width":1280,"height":720},
+				{"codec_type":"audio","codec_name":"aac"}
+			],"format":{"format_name":"mov,mp4,m4a,3gp,3g2,mj2","duration":"12.480000","size":"2048000","tags":{"major_brand":"isom"}}}`,
+			want: &utils.VideoInfo{
+				FormatName: "mov,mp4,m4a,3gp,3g2,mj2", MajorBrand: "isom", VideoCodec: "h264", PixelFormat: "yuv420p", AudioCodec: "aac",
+				Duration: 12.48, Width: 1280, Height: 720, Size: 2048000,
+			},
+		},
+		{
+			name: "should swap dimensions of rotated phone video from side data",
+			output: `{"streams":[
+				{"codec_type":"video","codec_name":"hevc","pix_fmt":"yuv420p10le","width":1920,"height":1080,"side_data_list":[{"rotation":-90}]}
+			],"format":{"format_name":"mov,mp4,m4a,3gp,3g2,mj2","duration":"3.0","size":"100"}}`,
+			want: &utils.VideoInfo{
+				FormatName: "mov,mp4,m4a,3gp,3g2,mj2", VideoCodec: "hevc", PixelFormat: "yuv420p10le",
+				Duration: 3, Width: 1080, Height: 1920, Rotation: -90, Size: 100,
+			},
+		},
+		{
+			name: "should swap dimensions of rotated video from tags",
+			output: `{"streams":[
+				{"codec_type":"video","codec_name":"h264","width":640,"height":480,"tags":{"rotate":"270"}}
+			],"format":{"format_name":"mov,mp4,m4a,3gp,3g2,mj2","duration":"1","size":"1"}}`,
+			want: &utils.VideoInfo{
+				FormatName: "mov,mp4,m4a,3gp,3g2,mj2", VideoCodec: "h264",
+				Duration: 1, Width: 480, Height: 640, Rotation: 270, Size: 1,
+			},
+		},
+		{
+			name: "should skip cover art streams",
+			output: `{"streams":[
+				{"codec_type":"video","codec_name":"mjpeg","width":300,"height":300},
+				{"codec_type":"video","codec_name":"vp9","pix_fmt":"yuv420p","width":854,"height":480},
+				{"codec_type":"audio","codec_name":"opus"}
+			],"format":{"format_name":"matroska,webm","duration":"60.5","size":"5000000"}}`,
+			want: &utils.VideoInfo{
+				FormatName: "matroska,webm", VideoCodec: "vp9", PixelFormat: "yuv420p", AudioCodec: "opus",
+				Duration: 60.5, Width: 854, Height: 480, Size: 5000000,
+			},
+		},
+		{
+			name:    "should fail without video stream",
+			output:  `{"streams":[{"codec_type":"audio","codec_name":"mp3"}],"format":{"format_name":"mp3"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "should fail on invalid output",
+			output:  `not json`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			got, err := utils.ParseVideoProbe([]byte(tt.output))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func (suite *VideoUtilsTestSuite) TestPlanVideoTranscode() {
+	compatible := utils.VideoInfo{
+		FormatName: "mov,mp4,m4a,3gp,3g2,mj2", MajorBrand: "isom", FastStart: true, VideoCodec: "h264", PixelFormat: "yuv420p", AudioCodec: "aac",
+		Duration: 100, Width: 1280, Height: 720, Size: 10_000_000,
+	}
+	const maxSize = 16_000_000
+
+	tests := []struct {
+		name             string
+		modify           func(info *utils.VideoInfo)
+		compress         bool
+		silent           bool
+		wantNeeded       bool
+		wantRemux        bool
+		wantReason       string
+		wantVideoBitrate int64
+		wantAudioBitrate int64
+		wantMaxWidth     int
+		wantErr          bool
+	}{
+		{
+			name:             "should send compatible video as is",
+			modify:           func(info *utils.VideoInfo) {},
+			wantAudioBitrate: utils.VideoAudioBitrate,
+		},
+		{
+			name:             "should remux h264 and aac from matroska",
+			modify:           func(info *utils.VideoInfo) { info.FormatName = "matroska,webm"; info.MajorBrand = "" },
+			wantNeeded:       true,
+			wantRemux:        true,
+			wantReason:       "container matroska,webm is not MP4",
+			wantAudioBitrate: utils.VideoAudioBitrate,
+		},
+		{
+			name:             "should remux quicktime that ffprobe reports as mp4",
+			modify:           func(info *utils.VideoInfo) { info.MajorBrand = "qt  "; info.FastStart = false },
+			wantNeeded:       true,
+			wantRemux:        true,
+			wantReason:       "container mov,mp4,m4a,3gp,3g2,mj2 (brand qt) is not MP4",
+			wantAudioBitrate: utils.VideoAudioBitrate,
+		},
+		{
+			name:             "should remux mp4 with the index at the end",
+			modify:           func(info *utils.VideoInfo) { info.FastStart = false },
+			wantNeeded:       true,
+			wantRemux:        true,
+			wantReason:       "MP4 index is at the end of the file",
+			wantAudioBitrate: utils.VideoAudioBitrate,
+		},
+		{
+			name:             "should transcode quicktime with hevc",
+			modify:           func(info *utils.VideoInfo) { info.MajorBrand = "qt  "; info.VideoCodec = "hevc" },
+			wantNeeded:       true,
+			wantReason:       "video codec hevc is not H.264",
+			wantVideoBitrate: 1_216_000 - utils.VideoAudioBitrate,
+			wantAudioBitrate: utils.VideoAudioBitrate,
+		},
+		{
+			name:             "should transcode hevc",
+			modify:           func(info *utils.VideoInfo) { info.VideoCodec = "hevc" },
+			wantNeeded:       true,
+			wantReason:       "video codec hevc is not H.264",
+			wantVideoBitrate: 1_216_000 - utils.VideoAudioBitrate,
+			wantAudioBitrate: utils.VideoAudioBitrate,
+		},
+		{
+			name:             "should transcode 10 bit h264",
+			modify:           func(info *utils.VideoInfo) { info.PixelFormat = "yuv420p10le" },
+			wantNeeded:       true,
+			wantReason:       "pixel format yuv420p10le is not supported by phones",
+			wantVideoBitrate: 1_216_000 - utils.VideoAudioBitrate,
+			wantAudioBitrate: utils.VideoAudioBitrate,
+		},
+		{
+			name:             "should transcode non aac audio",
+			modify:           func(info *utils.VideoInfo) { info.AudioCodec = "opus" },
+			wantNeeded:       true,
+			wantReason:       "audio codec opus is not AAC",
+			wantVideoBitrate: 1_216_000 - utils.VideoAudioBitrate,
+			wantAudioBitrate: utils.VideoAudioBitrate,
+		},
+		{
+			name:             "should give the whole budget to video without audio",
+			modify:           func(info *utils.VideoInfo) { info.AudioCodec = ""; info.VideoCodec = "vp9" },
+			wantNeeded:       true,
+			wantReason:       "video codec vp9 is not H.264",
+			wantVideoBitrate: 1_216_000,
+		},
+		{
+			name:             "should reduce bitrate of too large video",
+			modify:           func(info *utils.VideoInfo) { info.Size = 20_000_000 },
+			wantNeeded:       true,
+			wantReason:       "size 20000000 exceeds maximum allowed size 16000000",
+			wantVideoBitrate: 1_216_000 - utils.VideoAudioBitrate,
+			wantAudioBitrate: utils.VideoAudioBitrate,
+		},
+		{
+			name: "should lower audio bitrate when the budget is tight",
+			modify: func(info *utils.VideoInfo) {
+				info.VideoCodec = "hevc"
+				info.Duration = 500
+			},
+			wantNeeded:       true,
+			wantReason:       "video codec hevc is not H.264",
+			wantVideoBitrate: 243_200 - utils.VideoMinAudioBitrate,
+			wantAudioBitrate: utils.VideoMinAudioBitrate,
+		},
+		{
+			name:             "should scale down when compressing",
+			modify:           func(info *utils.VideoInfo) {},
+			compress:         true,
+			wantNeeded:       true,
+			wantReason:       "compression requested",
+			wantVideoBitrate: 1_216_000 - utils.VideoAudioBitrate,
+			wantAudioBitrate: utils.VideoAudioBitrate,
+			wantMaxWidth:     utils.MaxCompressedVideoSide,
+		},
+		{
+			name: "should convert gif to silent video",
+			modify: func(info *utils.VideoInfo) {
+				info.FormatName = "gif"
+				info.VideoCodec = "gif"
+				info.PixelFormat = "bgra"
+				info.AudioCodec = ""
+			},
+			silent:           true,
+			wantNeeded:       true,
+			wantReason:       "video codec gif is not H.264",
+			wantVideoBitrate: 1_216_000,
+		},
+		{
+			name:             "should strip audio for silent playback",
+			modify:           func(info *utils.VideoInfo) {},
+			silent:           true,
+			wantNeeded:       true,
+			wantReason:       "audio track removed",
+			wantVideoBitrate: 1_216_000,
+		},
+		{
+			name:             "should not cap bitrate without duration",
+			modify:           func(info *utils.VideoInfo) { info.VideoCodec = "hevc"; info.Duration = 0 },
+			wantNeeded:       true,
+			wantReason:       "video codec hevc is not H.264",
+			wantAudioBitrate: utils.VideoAudioBitrate,
+		},
+		{
+			name:    "should reject video too long for the size limit",
+			modify:  func(info *utils.VideoInfo) { info.VideoCodec = "hevc"; info.Duration = 3600 },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			info := compatible
+			tt.modify(&info)
+
+			plan, err := utils.PlanVideoTranscode(&info, utils.VideoTranscodeOptions{MaxSize: maxSize, Compress: tt.compress, Silent: tt.silent})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNeeded, plan.Needed())
+			assert.Equal(t, tt.wantRemux, plan.Remux)
+			if tt.wantReason != "" {
+				assert.Contains(t, plan.Reasons, tt.wantReason)
+			}
+			assert.Equal(t, tt.wantVideoBitrate, plan.VideoBitrate)
+			assert.Equal(t, tt.wantAudioBitrate, plan.AudioBitrate)
+			assert.Equal(t, tt.wantMaxWidth, plan.MaxWidth)
+		})
+	}
+}
+
+func (suite *VideoUtilsTestSuite) TestTranscodeVideoArgs() {
+	plan := &utils.VideoTranscodePlan{
+		Reasons:      []string{"video codec hevc is not H.264"},
+		CRF:          utils.CompressedVideoCRF,
+		VideoBitrate: 1_000_000,
+		AudioBitrate: utils.VideoAudioBitrate,
+		MaxWidth:     utils.MaxCompressedVideoSide,
+		HasAudio:     true,
+	}
+	args := strings.Join(utils.TranscodeVideoArgs("in.mkv", "out.mp4", plan), " ")
+	assert.Contains(suite.T(), args, "-i in.mkv")
+	assert.Contains(suite.T(), args, "-c:v libx264")
+	assert.Contains(suite.T(), args, "-pix_fmt yuv420p")
+	assert.Contains(suite.T(), args, "-crf 28")
+	assert.Contains(suite.T(), args, "-vf scale='min(720,iw)':-2")
+	assert.Contains(suite.T(), args, "-maxrate 1000000 -bufsize 2000000")
+	assert.Contains(suite.T(), args, "-c:a aac -b:a 128000")
+	assert.True(suite.T(), strings.HasSuffix(args, "-movflags +faststart out.mp4"))
+
+	plan.VideoBitrate = 0
+	plan.MaxWidth = 0
+	plan.HasAudio = false
+	args = strings.Join(utils.TranscodeVideoArgs("in.mkv", "out.mp4", plan), " ")
+	assert.NotContains(suite.T(), args, "-maxrate")
+	assert.Contains(suite.T(), args, "-vf scale=trunc(iw/2)*2:trunc(ih/2)*2")
+	assert.Contains(suite.T(), args, "-an")
+
+	args = strings.Join(utils.TranscodeVideoArgs("in.mov", "out.mp4", &utils.VideoTranscodePlan{Remux: true}), " ")
+	assert.Equal(suite.T(), "-y -i in.mov -map 0:v:0 -map 0:a:0? -c copy -movflags +faststart out.mp4", args)
+}
+
+func (suite *VideoUtilsTestSuite) TestMP4FastStart() {
+	box := func(kind string, payload int) []byte {
+		data := make([]byte, 8+payload)
+		binary.BigEndian.PutUint32(data, uint32(len(data)))
+		copy(data[4:], kind)
+		return data
+	}
+	largeBox := func(kind string, payload int) []byte {
+		data := make([]byte, 16+payload)
+		binary.BigEndian.PutUint32(data, 1)
+		copy(data[4:], kind)
+		binary.BigEndian.PutUint64(data[8:], uint64(len(data)))
+		return data
+	}
+
+	tests := []struct {
+		name  string
+		boxes [][]byte
+		want  bool
+	}{
+		{name: "should find the index before the media", boxes: [][]byte{box("ftyp", 16), box("moov", 100), box("mdat", 1000)}, want: true},
+		{name: "should find the index after the media", boxes: [][]byte{box("ftyp", 16), box("mdat", 1000), box("moov", 100)}},
+		{name: "should skip boxes with a 64 bit size", boxes: [][]byte{box("ftyp", 16), largeBox("free", 50), box("moov", 10)}, want: true},
+		{name: "should not find an index that is missing", boxes: [][]byte{box("ftyp", 16)}},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "video.mp4")
+			require.NoError(t, os.WriteFile(path, bytes.Join(tt.boxes, nil), 0600))
+
+			got, err := utils.MP4FastStart(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func (suite *VideoUtilsTestSuite) TestThumbnailOffset() {
+	assert.Equal(suite.T(), 1.0, (&utils.VideoInfo{Duration: 30}).ThumbnailOffset())
+	assert.Equal(suite.T(), 0.6, (&utils.VideoInfo{Duration: 1.2}).ThumbnailOffset())
+	assert.Equal(suite.T(), 1.0, (&utils.VideoInfo{}).ThumbnailOffset())
+}
+
+func TestVideoUtilsTestSuite(t *testing.T) {
+	suite.Run(t, new(VideoUtilsTestSuite))
+}
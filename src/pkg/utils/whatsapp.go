@@ -5,11 +5,14 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"mime"
+	"io"
 	"os"
+	"path"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -19,6 +22,7 @@ import (
 	"go.mau.fi/whatsmeow/types/events"
 
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainMediaStore "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/mediastore"
 	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
 	"go.mau.fi/whatsmeow"
 )
@@ -99,9 +103,14 @@ func ExtractMessageTextFromEvent(evt *events.Message) string {
 		}
 	} else if videoMessage := evt.Message.GetVideoMessage(); videoMessage != nil {
 		messageText = videoMessage.GetCaption()
-		if messageText == "" {
+		switch {
+		case videoMessage.GetGifPlayback() && messageText == "":
+			messageText = "🎞️ GIF"
+		case videoMessage.GetGifPlayback():
+			messageText = "🎞️ " + messageText
+		case messageText == "":
 			messageText = "🎥 Video"
-		} else {
+		default:
 			messageText = "🎥 " + messageText
 		}
 	} else if liveLocationMessage := evt.Message.GetLiveLocationMessage(); liveLocationMessage != nil {
@@ -185,16 +194,16 @@ func ExtractMessageTextFromEvent(evt *events.Message) string {
 }
 
 // ExtractMediaInfo extracts media information from a WhatsApp message
-func ExtractMediaInfo(msg *waE2E.Message) (mediaType string, filename string, url string, mediaKey []byte, fileSHA256 []byte, fileEncSHA256 []byte, fileLength uint64) {
+func ExtractMediaInfo(msg *waE2E.Message) (mediaType string, filename string, url string, directPath string, mediaKey []byte, fileSHA256 []byte, fileEncSHA256 []byte, fileLength uint64) {
 	if msg == nil {
-		return "", "", "", nil, nil, nil, 0
+		return "", "", "", "", nil, nil, nil, 0
 	}
 
 	// Check for image message
 	if img := msg.GetImageMessage(); img != nil {
 		filename = GenerateMediaFilename("image", "jpg", img.GetCaption())
 		return "image", filename,
-			img.GetURL(), img.GetMediaKey(), img.GetFileSHA256(),
+			img.GetURL(), img.GetDirectPath(), img.GetMediaKey(), img.GetFileSHA256(),
 			img.GetFileEncSHA256(), img.GetFileLength()
 	}
 
@@ -202,7 +211,7 @@ func ExtractMediaInfo(msg *waE2E.Message) (mediaType string, filename string, ur
 	if vid := msg.GetVideoMessage(); vid != nil {
 		filename = GenerateMediaFilename("video", "mp4", vid.GetCaption())
 		return "video", filename,
-			vid.GetURL(), vid.GetMediaKey(), vid.GetFileSHA256(),
+			vid.GetURL(), vid.GetDirectPath(), vid.GetMediaKey(), vid.GetFileSHA256(),
 			vid.GetFileEncSHA256(), vid.GetFileLength()
 	}
 
@@ -214,7 +223,7 @@ func ExtractMediaInfo(msg *waE2E.Message) (mediaType string, filename string, ur
 		}
 		filename = GenerateMediaFilename("audio", extension, "")
 		return "audio", filename,
-			aud.GetURL(), aud.GetMediaKey(), aud.GetFileSHA256(),
+			aud.GetURL(), aud.GetDirectPath(), aud.GetMediaKey(), aud.GetFileSHA256(),
 			aud.GetFileEncSHA256(), aud.GetFileLength()
 	}
 
@@ -225,7 +234,7 @@ func ExtractMediaInfo(msg *waE2E.Message) (mediaType string, filename string, ur
 			filename = GenerateMediaFilename("document", "", doc.GetTitle())
 		}
 		return "document", filename,
-			doc.GetURL(), doc.GetMediaKey(), doc.GetFileSHA256(),
+			doc.GetURL(), doc.GetDirectPath(), doc.GetMediaKey(), doc.GetFileSHA256(),
 			doc.GetFileEncSHA256(), doc.GetFileLength()
 	}
 
@@ -233,11 +242,11 @@ func ExtractMediaInfo(msg *waE2E.Message) (mediaType string, filename string, ur
 	if sticker := msg.GetStickerMessage(); sticker != nil {
 		filename = GenerateMediaFilename("sticker", "webp", "")
 		return "sticker", filename,
-			sticker.GetURL(), sticker.GetMediaKey(), sticker.GetFileSHA256(),
+			sticker.GetURL(), sticker.GetDirectPath(), sticker.GetMediaKey(), sticker.GetFileSHA256(),
 			sticker.GetFileEncSHA256(), sticker.GetFileLength()
 	}
 
-	return "", "", "", nil, nil, nil, 0
+	return "", "", "", "", nil, nil, nil, 0
 }
 
 // ExtractEphemeralExpiration extracts ephemeral expiration from a WhatsApp message
@@ -470,26 +479,114 @@ type ExtractedMedia struct {
 	MediaPath string `json:"media_path"`
 	MimeType  string `json:"mime_type"`
 	Caption   string `json:"caption"`
+	MediaURL  string `json:"media_url,omitempty"` // Presigned bucket URL, or signed URL streaming the media when a public URL is configured
+	FileSize  int64  `json:"-"`
+}
+
+// MediaDownloader decrypts WhatsApp media into a file, *whatsmeow.Client implements it
+type MediaDownloader interface {
+	DownloadToFile(ctx context.Context, msg whatsmeow.DownloadableMessage, file whatsmeow.File) error
 }
 
-// ExtractMedia is a helper function to extract media from whatsapp
-func ExtractMedia(ctx context.Context, client *whatsmeow.Client, storageLocation string, mediaFile whatsmeow.DownloadableMessage) (extractedMedia ExtractedMedia, err error) {
+// ExtractMedia downloads media from whatsapp into the media store. Media is stored by the SHA-256 of its content
+// once the download proved it, a copy already in the store from another message is reused instead of stored again.
+func ExtractMedia(ctx context.Context, client MediaDownloader, store domainMediaStore.IMediaStore, mediaFile whatsmeow.DownloadableMessage) (extractedMedia ExtractedMedia, err error) {
 	if mediaFile == nil {
 		logrus.Info("Skip download because data is nil")
 		return extractedMedia, nil
 	}
 
-	data, err := client.Download(ctx, mediaFile)
+	extractedMedia = DescribeMedia(mediaFile)
+
+	// The same message arriving for storage and webhooks at once is downloaded by the first caller only
+	object, err := shareMediaDownload(mediaClaimKey(mediaFile), func() (*domainMediaStore.Object, error) {
+		return storeVerifiedMedia(ctx, client, store, mediaFile, extractedMedia.MimeType)
+	})
 	if err != nil {
 		return extractedMedia, err
 	}
 
-	// Validate file size before writing to disk
+	return describeStoredMedia(ctx, store, object, extractedMedia)
+}
+
+// storeVerifiedMedia decrypts media into a hidden temporary file and checks its content against the SHA-256 the message
+// announces, which the sender is free to set to any value. Only checked content is looked up in and put into the store,
+// large videos and documents are streamed from the temporary file and never held in memory.
+func storeVerifiedMedia(ctx context.Context, client MediaDownloader, store domainMediaStore.IMediaStore, mediaFile whatsmeow.DownloadableMessage, mimeType string) (*domainMediaStore.Object, error) {
 	maxFileSize := config.WhatsappSettingMaxDownloadSize
-	if int64(len(data)) > maxFileSize {
-		return extractedMedia, fmt.Errorf("file size exceeds the maximum limit of %d bytes", maxFileSize)
+	if sized, ok := mediaFile.(interface{ GetFileLength() uint64 }); ok && sized.GetFileLength() > uint64(maxFileSize) {
+		return nil, fmt.Errorf("file size exceeds the maximum limit of %d bytes", maxFileSize)
+	}
+
+	file, err := os.CreateTemp(config.PathMediaCache, ".download-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+
+	if err = client.DownloadToFile(ctx, mediaFile, file); err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	// The announced length is checked before downloading, the content is checked again as it is not signed
+	if info.Size() > maxFileSize {
+		return nil, fmt.Errorf("file size exceeds the maximum limit of %d bytes", maxFileSize)
+	}
+
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	hasher := sha256.New()
+	if _, err = io.Copy(hasher, file); err != nil {
+		return nil, err
+	}
+	fileSHA256 := hasher.Sum(nil)
+	if announced := mediaFile.GetFileSHA256(); len(announced) > 0 && !hmac.Equal(announced, fileSHA256) {
+		return nil, whatsmeow.ErrInvalidMediaSHA256
+	}
+
+	key := MediaObjectKey(fileSHA256)
+	object, err := store.Stat(ctx, key)
+	if err == nil || !errors.Is(err, domainMediaStore.ErrNotFound) {
+		return object, err
 	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err = store.Put(ctx, key, file, info.Size(), mimeType); err != nil {
+		return nil, err
+	}
+	return store.Stat(ctx, key)
+}
 
+// StoredMedia returns media that was downloaded into the media store before, without downloading it again.
+// mediaPath is the location recorded for the message, ErrNotFound is returned when it was removed since.
+func StoredMedia(ctx context.Context, store domainMediaStore.IMediaStore, mediaPath string) (ExtractedMedia, error) {
+	object, err := store.Stat(ctx, path.Base(mediaPath))
+	if err != nil {
+		return ExtractedMedia{}, err
+	}
+	return describeStoredMedia(ctx, store, object, ExtractedMedia{MimeType: object.MimeType})
+}
+
+func describeStoredMedia(ctx context.Context, store domainMediaStore.IMediaStore, object *domainMediaStore.Object, extractedMedia ExtractedMedia) (ExtractedMedia, error) {
+	var err error
+	extractedMedia.MediaPath = object.Location
+	extractedMedia.FileSize = object.Size
+	if extractedMedia.MediaURL, err = store.URL(ctx, object.Key, config.AppMediaURLTTL); err != nil {
+		return extractedMedia, err
+	}
+	return extractedMedia, nil
+}
+
+// DescribeMedia returns the MIME type and caption of media without downloading it
+func DescribeMedia(mediaFile whatsmeow.DownloadableMessage) (extractedMedia ExtractedMedia) {
 	switch media := mediaFile.(type) {
 	case *waE2E.ImageMessage:
 		extractedMedia.MimeType = media.GetMimetype()
@@ -505,20 +602,63 @@ func ExtractMedia(ctx context.Context, client *whatsmeow.Client, storageLocation
 		extractedMedia.MimeType = media.GetMimetype()
 		extractedMedia.Caption = media.GetCaption()
 	}
+	return extractedMedia
+}
+
+// mediaDownload is a download in progress, callers asking for the same media meanwhile wait for its outcome
+type mediaDownload struct {
+	done   chan struct{}
+	object *domainMediaStore.Object
+	err    error
+}
+
+// mediaDownloads holds the downloads in progress by the claim of the media they download
+var mediaDownloads = struct {
+	sync.Mutex
+	inflight map[string]*mediaDownload
+}{inflight: make(map[string]*mediaDownload)}
+
+// shareMediaDownload runs download once for the callers asking for the same media at the same time.
+// Downloads are only merged within this process, replicas sharing a media store may each download the same file,
+// the last upload of the same content replaces the others.
+func shareMediaDownload(claim string, download func() (*domainMediaStore.Object, error)) (*domainMediaStore.Object, error) {
+	mediaDownloads.Lock()
+	if running, busy := mediaDownloads.inflight[claim]; busy {
+		mediaDownloads.Unlock()
+		<-running.done
+		return running.object, running.err
+	}
+	running := &mediaDownload{done: make(chan struct{})}
+	mediaDownloads.inflight[claim] = running
+	mediaDownloads.Unlock()
+
+	running.object, running.err = download()
+
+	mediaDownloads.Lock()
+	delete(mediaDownloads.inflight, claim)
+	mediaDownloads.Unlock()
+	close(running.done)
+	return running.object, running.err
+}
 
-	var extension string
-	if ext, err := mime.ExtensionsByType(extractedMedia.MimeType); err == nil && len(ext) > 0 {
-		extension = ext[0]
-	} else if parts := strings.Split(extractedMedia.MimeType, "/"); len(parts) > 1 {
-		extension = "." + parts[len(parts)-1]
+// mediaClaimKey identifies the media of one message by everything it announces, the media key included which only
+// the recipients of the message know. Messages merely naming the same hash do not share a download.
+func mediaClaimKey(mediaFile whatsmeow.DownloadableMessage) string {
+	hasher := sha256.New()
+	for _, part := range [][]byte{mediaFile.GetMediaKey(), mediaFile.GetFileEncSHA256(), mediaFile.GetFileSHA256(), []byte(mediaFile.GetDirectPath())} {
+		hasher.Write(part)
+		hasher.Write([]byte{0})
 	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
 
-	extractedMedia.MediaPath = fmt.Sprintf("%s/%d-%s%s", storageLocation, time.Now().Unix(), uuid.NewString(), extension)
-	err = os.WriteFile(extractedMedia.MediaPath, data, 0600)
-	if err != nil {
-		return extractedMedia, err
+// MediaObjectKey names media in the media store by the SHA-256 of its content, computed after downloading it, so each
+// file is kept once. Media without a hash gets a unique name. The MIME type is kept by the store, not in the key.
+func MediaObjectKey(fileSHA256 []byte) string {
+	if len(fileSHA256) == 0 {
+		return fmt.Sprintf("%d-%s", time.Now().Unix(), uuid.NewString())
 	}
-	return extractedMedia, nil
+	return hex.EncodeToString(fileSHA256)
 }
 
 // SanitizePhone sanitizes phone number by adding appropriate WhatsApp suffix
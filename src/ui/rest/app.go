@@ -22,6 +22,8 @@ func InitRestApp(app fiber.Router, service domainApp.IAppUsecase) App {
 	app.Get("/app/reconnect", rest.Reconnect)
 	app.Get("/app/devices", rest.Devices)
 	app.Get("/app/status", rest.ConnectionStatus)
+	app.Get("/app/storage", rest.StorageUsage)
+	app.Post("/app/storage", rest.CleanupStorage)
 
 	return App{Service: service}
 }
@@ -105,3 +107,27 @@ func (handler *App) ConnectionStatus(c *fiber.Ctx) error {
 		},
 	})
 }
+
+func (handler *App) StorageUsage(c *fiber.Ctx) error {
+	response, err := handler.Service.StorageUsage(c.UserContext())
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: "Storage usage retrieved",
+		Results: response,
+	})
+}
+
+func (handler *App) CleanupStorage(c *fiber.Ctx) error {
+	response, err := handler.Service.CleanupStorage(c.UserContext())
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: fmt.Sprintf("Storage cleanup removed %d files", response.RemovedFiles),
+		Results: response,
+	})
+}
@@ -15,8 +15,15 @@ func InitRestChat(app fiber.Router, service domainChat.IChatUsecase) Chat {
 
 	// Chat endpoints
 	app.Get("/chats", rest.ListChats)
+	app.Get("/chats/search", rest.SearchMessages)
 	app.Get("/chat/:chat_jid/messages", rest.GetChatMessages)
 	app.Post("/chat/:chat_jid/pin", rest.PinChat)
+	app.Post("/chat/:chat_jid/archive", rest.ArchiveChat)
+	app.Post("/chat/:chat_jid/mute", rest.MuteChat)
+	app.Post("/chat/:chat_jid/read", rest.MarkChatAsRead)
+	app.Post("/chat/:chat_jid/clear", rest.ClearChat)
+	app.Post("/chat/:chat_jid/delete", rest.DeleteChat)
+	app.Post("/chat/:chat_jid/disappearing-timer", rest.SetDisappearingTimer)
 
 	return rest
 }
@@ -30,6 +37,24 @@ func (controller *Chat) ListChats(c *fiber.Ctx) error {
 	request.Search = c.Query("search", "")
 	request.HasMedia = c.QueryBool("has_media", false)
 
+	// Parse chat state filters
+	if c.Query("archived") != "" {
+		archived := c.QueryBool("archived")
+		request.Archived = &archived
+	}
+	if c.Query("muted") != "" {
+		muted := c.QueryBool("muted")
+		request.Muted = &muted
+	}
+	if c.Query("marked_unread") != "" {
+		markedUnread := c.QueryBool("marked_unread")
+		request.MarkedUnread = &markedUnread
+	}
+	if c.Query("pinned") != "" {
+		pinned := c.QueryBool("pinned")
+		request.Pinned = &pinned
+	}
+
 	response, err := controller.Service.ListChats(c.UserContext(), request)
 	utils.PanicIfNeeded(err)
 
@@ -52,6 +77,8 @@ func (controller *Chat) GetChatMessages(c *fiber.Ctx) error {
 	request.Offset = c.QueryInt("offset", 0)
 	request.MediaOnly = c.QueryBool("media_only", false)
 	request.Search = c.Query("search", "")
+	request.Format = c.Query("format", "")
+	request.IncludeRevisions = c.QueryBool("include_revisions", false)
 
 	// Parse time filters
 	if startTime := c.Query("start_time"); startTime != "" {
@@ -78,6 +105,42 @@ func (controller *Chat) GetChatMessages(c *fiber.Ctx) error {
 	})
 }
 
+func (controller *Chat) SearchMessages(c *fiber.Ctx) error {
+	var request domainChat.SearchMessagesRequest
+
+	// Parse query parameters
+	request.Query = c.Query("q", "")
+	request.ChatJID = c.Query("chat_jid", "")
+	request.Sender = c.Query("sender", "")
+	request.MediaType = c.Query("media_type", "")
+	request.Limit = c.QueryInt("limit", 20)
+	request.Cursor = c.Query("cursor", "")
+
+	// Parse time filters
+	if startTime := c.Query("start_time"); startTime != "" {
+		request.StartTime = &startTime
+	}
+	if endTime := c.Query("end_time"); endTime != "" {
+		request.EndTime = &endTime
+	}
+
+	// Parse is_from_me filter
+	if isFromMeStr := c.Query("is_from_me"); isFromMeStr != "" {
+		isFromMe := c.QueryBool("is_from_me")
+		request.IsFromMe = &isFromMe
+	}
+
+	response, err := controller.Service.SearchMessages(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: "Success search messages",
+		Results: response,
+	})
+}
+
 func (controller *Chat) PinChat(c *fiber.Ctx) error {
 	var request domainChat.PinChatRequest
 
@@ -104,3 +167,145 @@ func (controller *Chat) PinChat(c *fiber.Ctx) error {
 		Results: response,
 	})
 }
+
+func (controller *Chat) ArchiveChat(c *fiber.Ctx) error {
+	var request domainChat.ArchiveChatRequest
+
+	// Parse path parameter
+	request.ChatJID = c.Params("chat_jid")
+
+	// Parse JSON body
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(400).JSON(utils.ResponseData{
+			Status:  400,
+			Code:    "BAD_REQUEST",
+			Message: "Invalid request body",
+			Results: nil,
+		})
+	}
+
+	response, err := controller.Service.ArchiveChat(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: response.Message,
+		Results: response,
+	})
+}
+
+func (controller *Chat) MuteChat(c *fiber.Ctx) error {
+	var request domainChat.MuteChatRequest
+
+	// Parse path parameter
+	request.ChatJID = c.Params("chat_jid")
+
+	// Parse JSON body
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(400).JSON(utils.ResponseData{
+			Status:  400,
+			Code:    "BAD_REQUEST",
+			Message: "Invalid request body",
+			Results: nil,
+		})
+	}
+
+	response, err := controller.Service.MuteChat(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: response.Message,
+		Results: response,
+	})
+}
+
+func (controller *Chat) MarkChatAsRead(c *fiber.Ctx) error {
+	var request domainChat.MarkChatAsReadRequest
+
+	// Parse path parameter
+	request.ChatJID = c.Params("chat_jid")
+
+	// Parse JSON body
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(400).JSON(utils.ResponseData{
+			Status:  400,
+			Code:    "BAD_REQUEST",
+			Message: "Invalid request body",
+			Results: nil,
+		})
+	}
+
+	response, err := controller.Service.MarkChatAsRead(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: response.Message,
+		Results: response,
+	})
+}
+
+func (controller *Chat) ClearChat(c *fiber.Ctx) error {
+	var request domainChat.ChatActionRequest
+
+	// Parse path parameter
+	request.ChatJID = c.Params("chat_jid")
+
+	response, err := controller.Service.ClearChat(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: response.Message,
+		Results: response,
+	})
+}
+
+func (controller *Chat) DeleteChat(c *fiber.Ctx) error {
+	var request domainChat.ChatActionRequest
+
+	// Parse path parameter
+	request.ChatJID = c.Params("chat_jid")
+
+	response, err := controller.Service.DeleteChat(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: response.Message,
+		Results: response,
+	})
+}
+
+func (controller *Chat) SetDisappearingTimer(c *fiber.Ctx) error {
+	var request domainChat.SetDisappearingTimerRequest
+
+	// Parse path parameter
+	request.ChatJID = c.Params("chat_jid")
+
+	// Parse JSON body
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(400).JSON(utils.ResponseData{
+			Status:  400,
+			Code:    "BAD_REQUEST",
+			Message: "Invalid request body",
+			Results: nil,
+		})
+	}
+
+	response, err := controller.Service.SetDisappearingTimer(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: response.Message,
+		Results: response,
+	})
+}
@@ -2,7 +2,6 @@ package helpers
 
 import (
 	"context"
-	"mime/multipart"
 	"time"
 
 	domainApp "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/app"
@@ -25,13 +24,3 @@ func SetAutoReconnectChecking(cli *whatsmeow.Client) {
 		}
 	}()
 }
-
-func MultipartFormFileHeaderToBytes(fileHeader *multipart.FileHeader) []byte {
-	file, _ := fileHeader.Open()
-	defer file.Close()
-
-	fileBytes := make([]byte, fileHeader.Size)
-	_, _ = file.Read(fileBytes)
-
-	return fileBytes
-}
@@ -1,9 +1,14 @@
 package rest
 
 import (
+	"fmt"
+	"io"
+	"mime"
+
 	domainMessage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/message"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
 	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
 )
 
 type Message struct {
@@ -21,7 +26,14 @@ func InitRestMessage(app fiber.Router, service domainMessage.IMessageUsecase) Me
 	app.Post("/message/:message_id/read", rest.MarkAsRead)
 	app.Post("/message/:message_id/star", rest.StarMessage)
 	app.Post("/message/:message_id/unstar", rest.UnstarMessage)
+	app.Post("/message/:message_id/forward", rest.ForwardMessage)
 	app.Get("/message/:message_id/download", rest.DownloadMedia)
+	app.Get("/message/:message_id/media", rest.StreamMedia)
+	app.Get("/message/:message_id/media/url", rest.GetMediaURL)
+	app.Get("/message/:message_id/status", rest.GetMessageStatus)
+	app.Get("/message/:message_id/reactions", rest.GetMessageReactions)
+	app.Get("/message/:message_id/revisions", rest.GetMessageRevisions)
+	app.Get("/message/:message_id", rest.GetFullMessage)
 	return rest
 }
 
@@ -176,3 +188,166 @@ func (controller *Message) DownloadMedia(c *fiber.Ctx) error {
 		Results: response,
 	})
 }
+
+func (controller *Message) StreamMedia(c *fiber.Ctx) error {
+	var request domainMessage.StreamMediaRequest
+
+	request.MessageID = c.Params("message_id")
+	request.Phone = c.Query("phone")
+	utils.SanitizePhone(&request.Phone)
+
+	response, err := controller.Service.StreamMedia(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return sendMedia(c, response)
+}
+
+func (controller *Message) GetMediaURL(c *fiber.Ctx) error {
+	var request domainMessage.MediaURLRequest
+
+	request.MessageID = c.Params("message_id")
+	request.Phone = c.Query("phone")
+	request.ExpiresIn = c.QueryInt("expires_in", 0)
+	utils.SanitizePhone(&request.Phone)
+
+	response, err := controller.Service.GetMediaURL(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: fmt.Sprintf("Media URL of message %s is valid until %s", response.MessageID, response.ExpiresAt),
+		Results: response,
+	})
+}
+
+func (controller *Message) GetMessageStatus(c *fiber.Ctx) error {
+	var request domainMessage.MessageStatusRequest
+
+	request.MessageID = c.Params("message_id")
+	request.Phone = c.Query("phone")
+	utils.SanitizePhone(&request.Phone)
+
+	response, err := controller.Service.GetMessageStatus(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: fmt.Sprintf("Status of message %s is %s", response.MessageID, response.Status),
+		Results: response,
+	})
+}
+
+func (controller *Message) GetMessageReactions(c *fiber.Ctx) error {
+	var request domainMessage.MessageReactionsRequest
+
+	request.MessageID = c.Params("message_id")
+	request.Phone = c.Query("phone")
+	utils.SanitizePhone(&request.Phone)
+
+	response, err := controller.Service.GetMessageReactions(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: fmt.Sprintf("Message %s has %d reactions", response.MessageID, len(response.Reactions)),
+		Results: response,
+	})
+}
+
+func (controller *Message) GetMessageRevisions(c *fiber.Ctx) error {
+	var request domainMessage.MessageRevisionsRequest
+
+	request.MessageID = c.Params("message_id")
+	request.Phone = c.Query("phone")
+	utils.SanitizePhone(&request.Phone)
+
+	response, err := controller.Service.GetMessageRevisions(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: fmt.Sprintf("Message %s has %d revisions", response.MessageID, len(response.Revisions)),
+		Results: response,
+	})
+}
+
+func (controller *Message) ForwardMessage(c *fiber.Ctx) error {
+	var request domainMessage.ForwardMessageRequest
+	err := c.BodyParser(&request)
+	utils.PanicIfNeeded(err)
+
+	request.MessageID = c.Params("message_id")
+	utils.SanitizePhone(&request.Phone)
+
+	response, err := controller.Service.ForwardMessage(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: response.Status,
+		Results: response,
+	})
+}
+
+func (controller *Message) GetFullMessage(c *fiber.Ctx) error {
+	var request domainMessage.FullMessageRequest
+
+	request.MessageID = c.Params("message_id")
+	request.Phone = c.Query("phone")
+	utils.SanitizePhone(&request.Phone)
+
+	response, err := controller.Service.GetFullMessage(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: fmt.Sprintf("Message %s", response.MessageID),
+		Results: response,
+	})
+}
+
+// sendMedia streams decrypted media, honouring a single byte range so players can seek
+func sendMedia(c *fiber.Ctx, media domainMessage.StreamMediaResponse) error {
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+
+	start, end := int64(0), media.FileSize-1
+	if byteRange := c.Get(fiber.HeaderRange); byteRange != "" {
+		rangeStart, rangeEnd, err := fasthttp.ParseByteRange([]byte(byteRange), int(media.FileSize))
+		if err != nil {
+			_ = media.Content.Close()
+			c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", media.FileSize))
+			return c.SendStatus(fiber.StatusRequestedRangeNotSatisfiable)
+		}
+
+		start, end = int64(rangeStart), int64(rangeEnd)
+		if _, err = media.Content.Seek(start, io.SeekStart); err != nil {
+			_ = media.Content.Close()
+			return err
+		}
+		c.Status(fiber.StatusPartialContent)
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, end, media.FileSize))
+	}
+
+	disposition := mime.FormatMediaType("inline", map[string]string{"filename": media.Filename})
+	if disposition == "" {
+		disposition = "inline"
+	}
+	c.Set(fiber.HeaderContentType, media.MimeType)
+	c.Set(fiber.HeaderContentDisposition, disposition)
+
+	// The response body is closed by fasthttp once it has been written
+	length := end - start + 1
+	c.Response().SetBodyStream(&mediaBody{Reader: io.LimitReader(media.Content, length), Closer: media.Content}, int(length))
+	return nil
+}
+
+type mediaBody struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// BodyLimit limits the size of uploads, the multipart form bodies of the routes taking files. Bodies are
+// streamed by the server, so its own limit only decides when streaming starts. Bodies announcing a larger
+// Content-Length are rejected before they are read, chunked bodies are counted while they are read into
+// a temporary file the handler then reads from. Other bodies, like JSON requests, are left alone.
+func BodyLimit(limit int64) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !strings.HasPrefix(string(c.Request().Header.ContentType()), fiber.MIMEMultipartForm) {
+			return c.Next()
+		}
+
+		contentLength := c.Request().Header.ContentLength()
+		if int64(contentLength) > limit {
+			return bodyTooLarge(c, limit)
+		}
+		stream := c.Request().BodyStream()
+		if contentLength != -1 || stream == nil {
+			return c.Next()
+		}
+
+		body, size, err := spoolBody(stream, limit)
+		if errors.Is(err, errBodyTooLarge) {
+			return bodyTooLarge(c, limit)
+		}
+		if err != nil {
+			c.Context().SetConnectionClose()
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ResponseData{
+				Status:  fiber.StatusBadRequest,
+				Code:    "BAD_REQUEST",
+				Message: fmt.Sprintf("failed to read request body: %v", err),
+			})
+		}
+		// The original stream is fully read, the request is reset to the spooled copy
+		c.Request().SetBodyStream(body, int(size))
+		return c.Next()
+	}
+}
+
+func bodyTooLarge(c *fiber.Ctx, limit int64) error {
+	// The body is left unread, the connection can not serve another request
+	c.Context().SetConnectionClose()
+	return c.Status(fiber.StatusRequestEntityTooLarge).JSON(utils.ResponseData{
+		Status:  fiber.StatusRequestEntityTooLarge,
+		Code:    "REQUEST_TOO_LARGE",
+		Message: fmt.Sprintf("request body exceeds the limit of %d bytes", limit),
+	})
+}
+
+// spooledBody is a request body copied to a temporary file, removed when the request is released
+type spooledBody struct {
+	*os.File
+}
+
+func (body spooledBody) Close() error {
+	err := body.File.Close()
+	_ = os.Remove(body.Name())
+	return err
+}
+
+// spoolBody copies at most limit bytes of a streamed body to a temporary file
+func spoolBody(stream io.Reader, limit int64) (spooledBody, int64, error) {
+	file, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		return spooledBody{}, 0, err
+	}
+	body := spooledBody{File: file}
+
+	size, err := io.Copy(file, io.LimitReader(stream, limit+1))
+	if err == nil && size > limit {
+		err = errBodyTooLarge
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		_ = body.Close()
+		return spooledBody{}, 0, err
+	}
+	return body, size, nil
+}
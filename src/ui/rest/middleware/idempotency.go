@@ -0,0 +1,279 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+
+	// idempotencyReservationTTL frees the key of a request that never completed, like one cut off by a crash
+	idempotencyReservationTTL = 10 * time.Minute
+)
+
+// Idempotency replays the stored response of a request whose idempotency key was already seen,
+// so clients can safely retry without the message being sent twice.
+// Keys are scoped to the authenticated user and the route, clients only see responses to their own requests.
+// Requests sharing a key are serialized on this instance, a request whose key is held by another instance
+// sharing the chat storage is rejected as in flight, and a key reused with another request body is rejected.
+func Idempotency(chatStorageRepo domainChatStorage.IChatStorageRepository) fiber.Handler {
+	locks := newKeyedMutex()
+
+	return func(c *fiber.Ctx) error {
+		key := getIdempotencyKey(c)
+		if key == "" {
+			return c.Next()
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ResponseData{
+				Status:  fiber.StatusBadRequest,
+				Code:    "INVALID_IDEMPOTENCY_KEY",
+				Message: "Idempotency key must not be longer than 255 characters",
+			})
+		}
+
+		requestHash, err := fingerprintRequest(c)
+		utils.PanicIfNeeded(err)
+
+		scopedKey := scopeIdempotencyKey(c, key)
+		unlock := locks.Lock(scopedKey)
+		defer unlock()
+
+		record, err := chatStorageRepo.GetIdempotencyRecord(scopedKey)
+		utils.PanicIfNeeded(err)
+		if record != nil {
+			return replayIdempotencyRecord(c, record, requestHash)
+		}
+
+		reserved, err := chatStorageRepo.ReserveIdempotencyKey(&domainChatStorage.IdempotencyRecord{
+			Key:         scopedKey,
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().Add(idempotencyReservationTTL),
+		})
+		utils.PanicIfNeeded(err)
+		if !reserved {
+			// Another instance took the key since it was looked up
+			record, err = chatStorageRepo.GetIdempotencyRecord(scopedKey)
+			utils.PanicIfNeeded(err)
+			if record == nil {
+				record = &domainChatStorage.IdempotencyRecord{RequestHash: requestHash}
+			}
+			return replayIdempotencyRecord(c, record, requestHash)
+		}
+
+		// The reservation is released unless a response is stored, handlers report errors by panicking
+		stored := false
+		defer func() {
+			if stored {
+				return
+			}
+			if err := chatStorageRepo.ReleaseIdempotencyKey(scopedKey); err != nil {
+				logrus.Warnf("Failed to release idempotency key %s: %v", key, err)
+			}
+		}()
+
+		if err = c.Next(); err != nil {
+			return err
+		}
+
+		// Only successful responses are kept, failed sends may be retried with the same key
+		statusCode := c.Response().StatusCode()
+		if statusCode < fiber.StatusOK || statusCode >= fiber.StatusMultipleChoices {
+			return nil
+		}
+
+		if err = chatStorageRepo.StoreIdempotencyRecord(&domainChatStorage.IdempotencyRecord{
+			Key:          scopedKey,
+			RequestHash:  requestHash,
+			StatusCode:   statusCode,
+			ResponseBody: append([]byte(nil), c.Response().Body()...),
+			ExpiresAt:    time.Now().Add(config.AppIdempotencyTTL),
+		}); err != nil {
+			logrus.Warnf("Failed to store response for idempotency key %s: %v", key, err)
+			return nil
+		}
+
+		stored = true
+		return nil
+	}
+}
+
+// replayIdempotencyRecord answers a request whose key was already taken with the response stored for it
+func replayIdempotencyRecord(c *fiber.Ctx, record *domainChatStorage.IdempotencyRecord, requestHash string) error {
+	if record.RequestHash != requestHash {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(utils.ResponseData{
+			Status:  fiber.StatusUnprocessableEntity,
+			Code:    "IDEMPOTENCY_KEY_REUSED",
+			Message: "Idempotency key was already used for a different request",
+		})
+	}
+
+	if record.InFlight() {
+		c.Set(fiber.HeaderRetryAfter, "1")
+		return c.Status(fiber.StatusConflict).JSON(utils.ResponseData{
+			Status:  fiber.StatusConflict,
+			Code:    "IDEMPOTENCY_KEY_IN_FLIGHT",
+			Message: "A request with this idempotency key is still being processed, retry later",
+		})
+	}
+
+	c.Set(IdempotencyReplayedHeader, "true")
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	return c.Status(record.StatusCode).Send(record.ResponseBody)
+}
+
+// scopeIdempotencyKey derives the stored key from the basic auth user, the method and path of the request
+// and the key the client sent, so users and routes that pick the same key do not share it
+func scopeIdempotencyKey(c *fiber.Ctx, key string) string {
+	user, _ := c.Locals("username").(string)
+	hash := sha256.Sum256([]byte(user + "\n" + c.Method() + " " + c.Path() + "\n" + key))
+	return hex.EncodeToString(hash[:])
+}
+
+// getIdempotencyKey reads the key from the Idempotency-Key header, falling back to the idempotency_key field
+func getIdempotencyKey(c *fiber.Ctx) string {
+	if key := strings.TrimSpace(c.Get(IdempotencyKeyHeader)); key != "" {
+		return key
+	}
+
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) {
+		var body struct {
+			IdempotencyKey string `json:"idempotency_key"`
+		}
+		if err := json.Unmarshal(c.Body(), &body); err != nil {
+			return ""
+		}
+		return strings.TrimSpace(body.IdempotencyKey)
+	}
+
+	return strings.TrimSpace(c.FormValue("idempotency_key"))
+}
+
+// fingerprintRequest hashes the route and the request content. JSON bodies are hashed in a canonical form,
+// so retries that order the fields or space them differently match. Multipart forms are hashed by
+// their values and file contents, since the boundary changes between retries of the same request.
+func fingerprintRequest(c *fiber.Ctx) (string, error) {
+	hash := sha256.New()
+	hash.Write([]byte(c.Method() + " " + c.Path() + "\n"))
+
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) {
+		hash.Write(canonicalJSON(c.Body()))
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		// Not a multipart request, the raw body is stable across retries
+		hash.Write(c.Body())
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+
+	fields := make([]string, 0, len(form.Value))
+	for field := range form.Value {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		for _, value := range form.Value[field] {
+			hash.Write([]byte(field + "=" + value + "\n"))
+		}
+	}
+
+	fileFields := make([]string, 0, len(form.File))
+	for field := range form.File {
+		fileFields = append(fileFields, field)
+	}
+	sort.Strings(fileFields)
+	for _, field := range fileFields {
+		for _, fileHeader := range form.File[field] {
+			hash.Write([]byte(field + "=" + fileHeader.Filename + "\n"))
+
+			file, err := fileHeader.Open()
+			if err != nil {
+				return "", err
+			}
+			_, err = io.Copy(hash, file)
+			file.Close()
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// canonicalJSON re-encodes a JSON body with sorted object keys and no whitespace, numbers are kept as written.
+// A body that is not valid JSON is returned as it is.
+func canonicalJSON(body []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return body
+	}
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+	return canonical
+}
+
+// keyedMutex serializes requests sharing the same idempotency key
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	refs int
+}
+
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{locks: make(map[string]*keyedLock)}
+}
+
+// Lock blocks until the key is free and returns the function releasing it
+func (k *keyedMutex) Lock(key string) func() {
+	k.mu.Lock()
+	lock, ok := k.locks[key]
+	if !ok {
+		lock = &keyedLock{}
+		k.locks[key] = lock
+	}
+	lock.refs++
+	k.mu.Unlock()
+
+	lock.Lock()
+
+	return func() {
+		lock.Unlock()
+
+		k.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
@@ -0,0 +1,295 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/ui/rest/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/basicauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// idempotencyRepository keeps idempotency records in memory the way the chat storage does
+type idempotencyRepository struct {
+	domainChatStorage.IChatStorageRepository
+	mu      sync.Mutex
+	records map[string]domainChatStorage.IdempotencyRecord
+}
+
+func (repo *idempotencyRepository) GetIdempotencyRecord(key string) (*domainChatStorage.IdempotencyRecord, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	record, ok := repo.records[key]
+	if !ok || !record.ExpiresAt.After(time.Now()) {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+func (repo *idempotencyRepository) ReserveIdempotencyKey(record *domainChatStorage.IdempotencyRecord) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if existing, ok := repo.records[record.Key]; ok && existing.ExpiresAt.After(time.Now()) {
+		return false, nil
+	}
+	repo.records[record.Key] = *record
+	return true, nil
+}
+
+func (repo *idempotencyRepository) StoreIdempotencyRecord(record *domainChatStorage.IdempotencyRecord) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.records[record.Key] = *record
+	return nil
+}
+
+func (repo *idempotencyRepository) ReleaseIdempotencyKey(key string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if record := repo.records[key]; record.InFlight() {
+		delete(repo.records, key)
+	}
+	return nil
+}
+
+type IdempotencyTestSuite struct {
+	suite.Suite
+	origTTL time.Duration
+	repo    *idempotencyRepository
+	app     *fiber.App
+	sends   atomic.Int32
+	release chan struct{} // Holds the send handler while set
+}
+
+func (suite *IdempotencyTestSuite) SetupTest() {
+	suite.origTTL = config.AppIdempotencyTTL
+	config.AppIdempotencyTTL = time.Hour
+	suite.reset()
+}
+
+func (suite *IdempotencyTestSuite) TearDownTest() {
+	config.AppIdempotencyTTL = suite.origTTL
+}
+
+// reset starts over with an empty store and a send handler that counts its calls
+func (suite *IdempotencyTestSuite) reset() {
+	suite.repo = &idempotencyRepository{records: make(map[string]domainChatStorage.IdempotencyRecord)}
+	suite.sends.Store(0)
+	suite.release = nil
+
+	send := func(c *fiber.Ctx) error {
+		if suite.release != nil {
+			<-suite.release
+		}
+		sent := suite.sends.Add(1)
+		if strings.Contains(string(c.Body()), "fail") {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"code": "INTERNAL_SERVER_ERROR"})
+		}
+		return c.JSON(fiber.Map{"code": "SUCCESS", "send": sent})
+	}
+
+	suite.app = fiber.New()
+	suite.app.Use(middleware.Recovery())
+	suite.app.Use(basicauth.New(basicauth.Config{Users: map[string]string{"alice": "secret", "bob": "secret"}}))
+	suite.app.Use("/send", middleware.Idempotency(suite.repo))
+	suite.app.Post("/send/message", send)
+	suite.app.Post("/send/link", send)
+}
+
+func (suite *IdempotencyTestSuite) send(t *testing.T, key, body string) (*http.Response, string) {
+	return suite.sendAs(t, "alice", "/send/message", key, body)
+}
+
+func (suite *IdempotencyTestSuite) sendAs(t *testing.T, user, path, key, body string) (*http.Response, string) {
+	request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	request.SetBasicAuth(user, "secret")
+	if key != "" {
+		request.Header.Set(middleware.IdempotencyKeyHeader, key)
+	}
+
+	response, err := suite.app.Test(request, -1)
+	require.NoError(t, err)
+	responseBody, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	return response, string(responseBody)
+}
+
+func (suite *IdempotencyTestSuite) TestRetries() {
+	tests := []struct {
+		name         string
+		first        string
+		retry        string
+		key          string
+		wantStatus   int
+		wantCode     string
+		wantReplayed bool
+		wantSends    int32
+	}{
+		{
+			name:         "should replay the response of the same request",
+			key:          "key-1",
+			first:        `{"phone":"6281234567890","message":"hello"}`,
+			retry:        `{"phone":"6281234567890","message":"hello"}`,
+			wantStatus:   fiber.StatusOK,
+			wantCode:     `"send":1`,
+			wantReplayed: true,
+			wantSends:    1,
+		},
+		{
+			name:         "should replay a retry that orders and spaces the fields differently",
+			key:          "key-1",
+			first:        `{"phone":"6281234567890","message":"hello"}`,
+			retry:        "{\n  \"message\": \"hello\",\n  \"phone\": \"6281234567890\"\n}",
+			wantStatus:   fiber.StatusOK,
+			wantCode:     `"send":1`,
+			wantReplayed: true,
+			wantSends:    1,
+		},
+		{
+			name:       "should reject the key reused with another body",
+			key:        "key-1",
+			first:      `{"phone":"6281234567890","message":"hello"}`,
+			retry:      `{"phone":"6281234567890","message":"bye"}`,
+			wantStatus: fiber.StatusUnprocessableEntity,
+			wantCode:   "IDEMPOTENCY_KEY_REUSED",
+			wantSends:  1,
+		},
+		{
+			name:       "should send again after a failed request",
+			key:        "key-1",
+			first:      `{"message":"fail"}`,
+			retry:      `{"message":"fail"}`,
+			wantStatus: fiber.StatusInternalServerError,
+			wantCode:   "INTERNAL_SERVER_ERROR",
+			wantSends:  2,
+		},
+		{
+			name:       "should send every request without a key",
+			first:      `{"phone":"6281234567890","message":"hello"}`,
+			retry:      `{"phone":"6281234567890","message":"hello"}`,
+			wantStatus: fiber.StatusOK,
+			wantCode:   `"send":2`,
+			wantSends:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			suite.reset()
+			suite.send(t, tt.key, tt.first)
+			response, body := suite.send(t, tt.key, tt.retry)
+
+			assert.Equal(t, tt.wantStatus, response.StatusCode)
+			assert.Contains(t, body, tt.wantCode)
+			assert.Equal(t, tt.wantReplayed, response.Header.Get(middleware.IdempotencyReplayedHeader) == "true")
+			assert.Equal(t, tt.wantSends, suite.sends.Load())
+		})
+	}
+}
+
+func (suite *IdempotencyTestSuite) TestExpiredRecordIsNotReplayed() {
+	body := `{"phone":"6281234567890","message":"hello"}`
+	suite.send(suite.T(), "key-1", body)
+
+	for key, record := range suite.repo.records {
+		record.ExpiresAt = time.Now().Add(-time.Second)
+		suite.repo.records[key] = record
+	}
+
+	response, responseBody := suite.send(suite.T(), "key-1", body)
+	assert.Equal(suite.T(), fiber.StatusOK, response.StatusCode)
+	assert.Empty(suite.T(), response.Header.Get(middleware.IdempotencyReplayedHeader))
+	assert.Contains(suite.T(), responseBody, `"send":2`)
+}
+
+func (suite *IdempotencyTestSuite) TestConcurrentRequestsSendOnce() {
+	suite.release = make(chan struct{})
+	body := `{"phone":"6281234567890","message":"hello"}`
+
+	const requests = 5
+	responses := make(chan *http.Response, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, _ := suite.send(suite.T(), "key-1", body)
+			responses <- response
+		}()
+	}
+
+	// Let the first request hold the key while the others line up behind it
+	time.Sleep(100 * time.Millisecond)
+	close(suite.release)
+	wg.Wait()
+	close(responses)
+
+	var replayed int
+	for response := range responses {
+		assert.Equal(suite.T(), fiber.StatusOK, response.StatusCode)
+		if response.Header.Get(middleware.IdempotencyReplayedHeader) == "true" {
+			replayed++
+		}
+	}
+	assert.EqualValues(suite.T(), 1, suite.sends.Load())
+	assert.Equal(suite.T(), requests-1, replayed)
+}
+
+func (suite *IdempotencyTestSuite) TestKeyHeldByAnotherInstance() {
+	body := `{"phone":"6281234567890","message":"hello"}`
+
+	// The stored response is turned back into a reservation still held by another instance
+	suite.send(suite.T(), "key-1", body)
+	for key, record := range suite.repo.records {
+		record.StatusCode, record.ResponseBody = 0, nil
+		suite.repo.records[key] = record
+	}
+
+	response, responseBody := suite.send(suite.T(), "key-1", body)
+	assert.Equal(suite.T(), fiber.StatusConflict, response.StatusCode)
+	assert.Contains(suite.T(), responseBody, "IDEMPOTENCY_KEY_IN_FLIGHT")
+	assert.NotEmpty(suite.T(), response.Header.Get(fiber.HeaderRetryAfter))
+	assert.EqualValues(suite.T(), 1, suite.sends.Load())
+}
+
+func (suite *IdempotencyTestSuite) TestKeysAreScopedToUserAndRoute() {
+	body := `{"phone":"6281234567890","message":"hello"}`
+	suite.sendAs(suite.T(), "alice", "/send/message", "key-1", body)
+
+	tests := []struct {
+		name         string
+		user         string
+		path         string
+		wantReplayed bool
+	}{
+		{name: "should not replay the response of another user", user: "bob", path: "/send/message"},
+		{name: "should not replay the response of another route", user: "alice", path: "/send/link"},
+		{name: "should replay the response of the same user and route", user: "alice", path: "/send/message", wantReplayed: true},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			response, responseBody := suite.sendAs(t, tt.user, tt.path, "key-1", body)
+			assert.Equal(t, fiber.StatusOK, response.StatusCode)
+			assert.Equal(t, tt.wantReplayed, response.Header.Get(middleware.IdempotencyReplayedHeader) == "true")
+			assert.Contains(t, responseBody, `"send":`)
+		})
+	}
+	assert.EqualValues(suite.T(), 3, suite.sends.Load())
+}
+
+func TestIdempotencyTestSuite(t *testing.T) {
+	suite.Run(t, new(IdempotencyTestSuite))
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SignedMediaRequest reports whether the request streams media with a valid signed URL,
+// such requests skip basic auth as the signature already grants access to that one message
+func SignedMediaRequest(c *fiber.Ctx) bool {
+	if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+		return false
+	}
+	if c.Query("signature") == "" {
+		return false
+	}
+
+	path := strings.TrimPrefix(c.Path(), config.AppBasePath)
+	if !strings.HasPrefix(path, "/message/") || !strings.HasSuffix(path, "/media") {
+		return false
+	}
+	messageID := strings.TrimSuffix(strings.TrimPrefix(path, "/message/"), "/media")
+	if messageID == "" || strings.Contains(messageID, "/") {
+		return false
+	}
+
+	return utils.VerifyMediaURL(messageID, c.Query("phone"), c.Query("expires"), c.Query("signature")) == nil
+}
@@ -12,7 +12,17 @@ type Newsletter struct {
 
 func InitRestNewsletter(app fiber.Router, service domainNewsletter.INewsletterUsecase) Newsletter {
 	rest := Newsletter{Service: service}
+	app.Post("/newsletter", rest.Create)
+	app.Post("/newsletter/follow", rest.Follow)
 	app.Post("/newsletter/unfollow", rest.Unfollow)
+	app.Get("/newsletter/info-from-invite", rest.InfoFromInvite)
+	app.Post("/newsletter/:newsletter_id/update", rest.Update)
+	app.Post("/newsletter/:newsletter_id/mute", rest.Mute)
+	app.Post("/newsletter/:newsletter_id/send", rest.SendMessage)
+	app.Get("/newsletter/:newsletter_id/messages", rest.ListMessages)
+	app.Get("/newsletter/:newsletter_id/updates", rest.ListUpdates)
+	app.Post("/newsletter/:newsletter_id/messages/:server_id/reaction", rest.React)
+	app.Post("/newsletter/:newsletter_id/viewed", rest.MarkViewed)
 	return rest
 }
 
@@ -30,3 +40,201 @@ func (controller *Newsletter) Unfollow(c *fiber.Ctx) error {
 		Message: "Success unfollow newsletter",
 	})
 }
+
+func (controller *Newsletter) SendMessage(c *fiber.Ctx) error {
+	var request domainNewsletter.SendMessageRequest
+	err := c.BodyParser(&request)
+	utils.PanicIfNeeded(err)
+
+	request.NewsletterID = c.Params("newsletter_id")
+
+	// Try to get files but ignore error if not provided
+	if imageFile, errFile := c.FormFile("image"); errFile == nil {
+		request.Image = imageFile
+	}
+	if videoFile, errFile := c.FormFile("video"); errFile == nil {
+		request.Video = videoFile
+	}
+
+	response, err := controller.Service.SendMessage(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: response.Status,
+		Results: response,
+	})
+}
+
+func (controller *Newsletter) ListMessages(c *fiber.Ctx) error {
+	var request domainNewsletter.ListMessagesRequest
+	request.NewsletterID = c.Params("newsletter_id")
+	request.Count = c.QueryInt("count", 25)
+	request.Before = c.QueryInt("before", 0)
+
+	response, err := controller.Service.ListMessages(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: "Success get newsletter messages",
+		Results: response,
+	})
+}
+
+func (controller *Newsletter) Create(c *fiber.Ctx) error {
+	var request domainNewsletter.CreateRequest
+	err := c.BodyParser(&request)
+	utils.PanicIfNeeded(err)
+
+	if pictureFile, errFile := c.FormFile("picture"); errFile == nil {
+		request.Picture = pictureFile
+	}
+
+	response, err := controller.Service.Create(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: "Success create newsletter",
+		Results: response.Data,
+	})
+}
+
+func (controller *Newsletter) Update(c *fiber.Ctx) error {
+	var request domainNewsletter.UpdateRequest
+	err := c.BodyParser(&request)
+	utils.PanicIfNeeded(err)
+
+	request.NewsletterID = c.Params("newsletter_id")
+
+	if pictureFile, errFile := c.FormFile("picture"); errFile == nil {
+		request.Picture = pictureFile
+	}
+
+	response, err := controller.Service.Update(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: "Success update newsletter",
+		Results: response.Data,
+	})
+}
+
+func (controller *Newsletter) Follow(c *fiber.Ctx) error {
+	var request domainNewsletter.FollowRequest
+	err := c.BodyParser(&request)
+	utils.PanicIfNeeded(err)
+
+	response, err := controller.Service.Follow(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: "Success follow newsletter",
+		Results: response.Data,
+	})
+}
+
+func (controller *Newsletter) Mute(c *fiber.Ctx) error {
+	var request domainNewsletter.MuteRequest
+	err := c.BodyParser(&request)
+	utils.PanicIfNeeded(err)
+
+	request.NewsletterID = c.Params("newsletter_id")
+
+	err = controller.Service.Mute(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	message := "Success unmute newsletter"
+	if request.Mute {
+		message = "Success mute newsletter"
+	}
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: message,
+	})
+}
+
+func (controller *Newsletter) InfoFromInvite(c *fiber.Ctx) error {
+	var request domainNewsletter.InfoFromInviteRequest
+	err := c.QueryParser(&request)
+	utils.PanicIfNeeded(err)
+
+	response, err := controller.Service.InfoFromInvite(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: "Success get newsletter info",
+		Results: response.Data,
+	})
+}
+
+func (controller *Newsletter) ListUpdates(c *fiber.Ctx) error {
+	var request domainNewsletter.ListUpdatesRequest
+	request.NewsletterID = c.Params("newsletter_id")
+	request.Count = c.QueryInt("count", 25)
+	request.Since = c.Query("since")
+	request.After = c.QueryInt("after", 0)
+
+	response, err := controller.Service.ListUpdates(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: "Success get newsletter updates",
+		Results: response,
+	})
+}
+
+func (controller *Newsletter) React(c *fiber.Ctx) error {
+	var request domainNewsletter.ReactRequest
+	err := c.BodyParser(&request)
+	utils.PanicIfNeeded(err)
+
+	request.NewsletterID = c.Params("newsletter_id")
+	// A non numeric server id is left as zero and rejected by the validation
+	request.ServerID, _ = c.ParamsInt("server_id")
+
+	err = controller.Service.React(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	message := "Success react to newsletter message"
+	if request.Reaction == "" {
+		message = "Success remove reaction from newsletter message"
+	}
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: message,
+	})
+}
+
+func (controller *Newsletter) MarkViewed(c *fiber.Ctx) error {
+	var request domainNewsletter.MarkViewedRequest
+	err := c.BodyParser(&request)
+	utils.PanicIfNeeded(err)
+
+	request.NewsletterID = c.Params("newsletter_id")
+
+	err = controller.Service.MarkViewed(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: "Success mark newsletter messages as viewed",
+	})
+}
@@ -0,0 +1,164 @@
+package rest_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	domainSend "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/send"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/ui/rest"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/ui/rest/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const testBodyLimit = 4 << 20
+
+// uploadRecorder is a send usecase that only records how the uploaded file reached it
+type uploadRecorder struct {
+	domainSend.ISendUsecase
+	onDisk bool
+	size   int64
+}
+
+func (recorder *uploadRecorder) SendText(_ context.Context, request domainSend.MessageRequest) (domainSend.GenericResponse, error) {
+	recorder.size = int64(len(request.Message))
+	return domainSend.GenericResponse{MessageID: "3EB0789ABC123456", Status: "sent"}, nil
+}
+
+func (recorder *uploadRecorder) SendFile(_ context.Context, request domainSend.FileRequest) (domainSend.GenericResponse, error) {
+	file, err := request.File.Open()
+	if err != nil {
+		return domainSend.GenericResponse{}, err
+	}
+	defer file.Close()
+
+	// Parts kept in memory are opened as section readers, spooled parts as files
+	_, recorder.onDisk = file.(*os.File)
+	recorder.size, err = io.Copy(io.Discard, file)
+	return domainSend.GenericResponse{MessageID: "3EB0789ABC123456", Status: "sent"}, err
+}
+
+type SendTestSuite struct {
+	suite.Suite
+	app      *fiber.App
+	recorder *uploadRecorder
+}
+
+func (suite *SendTestSuite) SetupTest() {
+	suite.recorder = &uploadRecorder{}
+	suite.app = fiber.New(rest.ServerConfig(nil, testBodyLimit))
+	suite.app.Use(middleware.Recovery())
+	suite.app.Use(middleware.BodyLimit(testBodyLimit))
+	rest.InitRestSend(suite.app, suite.recorder)
+}
+
+func fileUpload(t *testing.T, size int) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	require.NoError(t, writer.WriteField("phone", "6281234567890@s.whatsapp.net"))
+	part, err := writer.CreateFormFile("file", "report.pdf")
+	require.NoError(t, err)
+	_, err = part.Write(bytes.Repeat([]byte("a"), size))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return body, writer.FormDataContentType()
+}
+
+func (suite *SendTestSuite) TestSendFileIsSpooledToDisk() {
+	body, contentType := fileUpload(suite.T(), 1<<20)
+	request := httptest.NewRequest(http.MethodPost, "/send/file", body)
+	request.Header.Set(fiber.HeaderContentType, contentType)
+
+	response, err := suite.app.Test(request, -1)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), fiber.StatusOK, response.StatusCode)
+	assert.True(suite.T(), suite.recorder.onDisk)
+	assert.EqualValues(suite.T(), 1<<20, suite.recorder.size)
+}
+
+func (suite *SendTestSuite) TestBodyLimit() {
+	tests := []struct {
+		name       string
+		path       string
+		body       func() (io.Reader, string, int64)
+		wantStatus int
+		wantCode   string
+		wantSize   int64
+	}{
+		{
+			name: "should reject an upload over the limit",
+			path: "/send/file",
+			body: func() (io.Reader, string, int64) {
+				body, contentType := fileUpload(suite.T(), testBodyLimit)
+				return body, contentType, int64(body.Len())
+			},
+			wantStatus: fiber.StatusRequestEntityTooLarge,
+			wantCode:   "REQUEST_TOO_LARGE",
+		},
+		{
+			name: "should reject a chunked upload over the limit",
+			path: "/send/file",
+			body: func() (io.Reader, string, int64) {
+				body, contentType := fileUpload(suite.T(), testBodyLimit)
+				return body, contentType, -1
+			},
+			wantStatus: fiber.StatusRequestEntityTooLarge,
+			wantCode:   "REQUEST_TOO_LARGE",
+		},
+		{
+			name: "should accept a chunked upload within the limit",
+			path: "/send/file",
+			body: func() (io.Reader, string, int64) {
+				body, contentType := fileUpload(suite.T(), 1<<20)
+				return body, contentType, -1
+			},
+			wantStatus: fiber.StatusOK,
+			wantCode:   "SUCCESS",
+			wantSize:   1 << 20,
+		},
+		{
+			name: "should not limit a chunked JSON body",
+			path: "/send/message",
+			body: func() (io.Reader, string, int64) {
+				message := strings.Repeat("a", testBodyLimit)
+				return strings.NewReader(`{"phone":"6281234567890@s.whatsapp.net","message":"` + message + `"}`), fiber.MIMEApplicationJSON, -1
+			},
+			wantStatus: fiber.StatusOK,
+			wantCode:   "SUCCESS",
+			wantSize:   testBodyLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			suite.recorder.size = 0
+			body, contentType, contentLength := tt.body()
+			request := httptest.NewRequest(http.MethodPost, tt.path, body)
+			request.Header.Set(fiber.HeaderContentType, contentType)
+			request.ContentLength = contentLength
+			if contentLength < 0 {
+				request.TransferEncoding = []string{"chunked"}
+			}
+
+			response, err := suite.app.Test(request, -1)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, response.StatusCode)
+			responseBody, _ := io.ReadAll(response.Body)
+			assert.Contains(t, string(responseBody), tt.wantCode)
+			assert.Equal(t, tt.wantSize, suite.recorder.size)
+		})
+	}
+}
+
+func TestSendTestSuite(t *testing.T) {
+	suite.Run(t, new(SendTestSuite))
+}
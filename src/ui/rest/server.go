@@ -0,0 +1,18 @@
+package rest
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// ServerConfig is the fiber configuration of the REST server. Request bodies are streamed and multipart
+// forms are parsed by the handlers, which spools uploaded files to temp files instead of holding them in
+// memory. middleware.BodyLimit enforces the upload size limit since a streamed body is not limited by fiber.
+func ServerConfig(views fiber.Views, bodyLimit int) fiber.Config {
+	return fiber.Config{
+		Views:                        views,
+		BodyLimit:                    bodyLimit,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+		Network:                      "tcp",
+	}
+}
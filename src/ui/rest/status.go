@@ -0,0 +1,40 @@
+package rest
+
+import (
+	domainStatus "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/status"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Status struct {
+	Service domainStatus.IStatusUsecase
+}
+
+func InitRestStatus(app fiber.Router, service domainStatus.IStatusUsecase) Status {
+	rest := Status{Service: service}
+
+	app.Get("/status/feed", rest.GetFeed)
+
+	return rest
+}
+
+func (controller *Status) GetFeed(c *fiber.Ctx) error {
+	var request domainStatus.FeedRequest
+
+	// Parse query parameters
+	request.Sender = c.Query("sender", "")
+	request.MediaOnly = c.QueryBool("media_only", false)
+	request.IncludeExpired = c.QueryBool("include_expired", false)
+	request.Limit = c.QueryInt("limit", 25)
+	request.Offset = c.QueryInt("offset", 0)
+
+	response, err := controller.Service.GetFeed(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: "Success get status feed",
+		Results: response,
+	})
+}
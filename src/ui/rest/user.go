@@ -16,6 +16,7 @@ func InitRestUser(app fiber.Router, service domainUser.IUserUsecase) User {
 	app.Get("/user/avatar", rest.UserAvatar)
 	app.Post("/user/avatar", rest.UserChangeAvatar)
 	app.Post("/user/pushname", rest.UserChangePushName)
+	app.Post("/user/disappearing-timer", rest.UserSetDefaultDisappearingTimer)
 	app.Get("/user/my/privacy", rest.UserMyPrivacySetting)
 	app.Get("/user/my/groups", rest.UserMyListGroups)
 	app.Get("/user/my/newsletters", rest.UserMyListNewsletter)
@@ -143,6 +144,21 @@ func (controller *User) UserChangePushName(c *fiber.Ctx) error {
 	})
 }
 
+func (controller *User) UserSetDefaultDisappearingTimer(c *fiber.Ctx) error {
+	var request domainUser.SetDefaultDisappearingTimerRequest
+	err := c.BodyParser(&request)
+	utils.PanicIfNeeded(err)
+
+	err = controller.Service.SetDefaultDisappearingTimer(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: "Success set default disappearing timer",
+	})
+}
+
 func (controller *User) UserCheck(c *fiber.Ctx) error {
 	var request domainUser.CheckRequest
 	err := c.QueryParser(&request)
@@ -10,6 +10,7 @@ import (
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
 	domainApp "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/app"
 	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/janitor"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/whatsapp"
 	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/validations"
@@ -23,11 +24,13 @@ import (
 
 type serviceApp struct {
 	chatStorageRepo domainChatStorage.IChatStorageRepository
+	storageJanitor  *janitor.Janitor
 }
 
-func NewAppService(chatStorageRepo domainChatStorage.IChatStorageRepository) domainApp.IAppUsecase {
+func NewAppService(chatStorageRepo domainChatStorage.IChatStorageRepository, storageJanitor *janitor.Janitor) domainApp.IAppUsecase {
 	return &serviceApp{
 		chatStorageRepo: chatStorageRepo,
+		storageJanitor:  storageJanitor,
 	}
 }
 
@@ -270,3 +273,13 @@ func (service *serviceApp) FetchDevices(ctx context.Context) (response []domainA
 
 	return response, nil
 }
+
+// StorageUsage reports the disk usage of downloaded media, send items and history files
+func (service *serviceApp) StorageUsage(ctx context.Context) (response domainApp.StorageUsageResponse, err error) {
+	return service.storageJanitor.Usage(ctx)
+}
+
+// CleanupStorage applies the retention, orphan and quota settings right away instead of waiting for the next run
+func (service *serviceApp) CleanupStorage(ctx context.Context) (response domainApp.StorageCleanupResponse, err error) {
+	return service.storageJanitor.Cleanup(ctx)
+}
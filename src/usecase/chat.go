@@ -2,16 +2,26 @@ package usecase
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	domainChat "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chat"
 	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/whatsapp"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/validations"
 	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow"
 	"go.mau.fi/whatsmeow/appstate"
+	"go.mau.fi/whatsmeow/proto/waCommon"
+	"go.mau.fi/whatsmeow/proto/waSyncAction"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
 )
 
 type serviceChat struct {
@@ -31,10 +41,14 @@ func (service serviceChat) ListChats(ctx context.Context, request domainChat.Lis
 
 	// Create filter from request
 	filter := &domainChatStorage.ChatFilter{
-		Limit:      request.Limit,
-		Offset:     request.Offset,
-		SearchName: request.Search,
-		HasMedia:   request.HasMedia,
+		Limit:        request.Limit,
+		Offset:       request.Offset,
+		SearchName:   request.Search,
+		HasMedia:     request.HasMedia,
+		Archived:     request.Archived,
+		Muted:        request.Muted,
+		MarkedUnread: request.MarkedUnread,
+		Pinned:       request.Pinned,
 	}
 
 	// Get chats from storage
@@ -55,15 +69,7 @@ func (service serviceChat) ListChats(ctx context.Context, request domainChat.Lis
 	// Convert entities to domain objects
 	chatInfos := make([]domainChat.ChatInfo, 0, len(chats))
 	for _, chat := range chats {
-		chatInfo := domainChat.ChatInfo{
-			JID:                 chat.JID,
-			Name:                chat.Name,
-			LastMessageTime:     chat.LastMessageTime.Format(time.RFC3339),
-			EphemeralExpiration: chat.EphemeralExpiration,
-			CreatedAt:           chat.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:           chat.UpdatedAt.Format(time.RFC3339),
-		}
-		chatInfos = append(chatInfos, chatInfo)
+		chatInfos = append(chatInfos, buildChatInfo(chat))
 	}
 
 	// Create pagination response
@@ -152,6 +158,47 @@ func (service serviceChat) GetChatMessages(ctx context.Context, request domainCh
 		totalCount = 0
 	}
 
+	// Collect receipts of outgoing messages to report their delivery status
+	var outgoingIDs []string
+	for _, message := range messages {
+		if message.IsFromMe {
+			outgoingIDs = append(outgoingIDs, message.ID)
+		}
+	}
+	receiptsByMessage := make(map[string][]*domainChatStorage.MessageReceipt)
+	receipts, err := service.chatStorageRepo.GetMessageReceipts(request.ChatJID, outgoingIDs)
+	if err != nil {
+		logrus.WithError(err).WithField("chat_jid", request.ChatJID).Error("Failed to get message receipts")
+		// Continue without delivery status
+	}
+	for _, receipt := range receipts {
+		receiptsByMessage[receipt.MessageID] = append(receiptsByMessage[receipt.MessageID], receipt)
+	}
+
+	messageIDs := make([]string, 0, len(messages))
+	for _, message := range messages {
+		messageIDs = append(messageIDs, message.ID)
+	}
+	reactionsByMessage := make(map[string][]*domainChatStorage.Reaction)
+	reactions, err := service.chatStorageRepo.GetMessageReactions(request.ChatJID, messageIDs)
+	if err != nil {
+		logrus.WithError(err).WithField("chat_jid", request.ChatJID).Error("Failed to get message reactions")
+		// Continue without reactions
+	}
+	for _, reaction := range reactions {
+		reactionsByMessage[reaction.MessageID] = append(reactionsByMessage[reaction.MessageID], reaction)
+	}
+
+	revisionsByMessage := make(map[string][]*domainChatStorage.MessageRevision)
+	revisions, err := service.chatStorageRepo.GetMessageRevisions(request.ChatJID, messageIDs)
+	if err != nil {
+		logrus.WithError(err).WithField("chat_jid", request.ChatJID).Error("Failed to get message revisions")
+		// Continue without edit history
+	}
+	for _, revision := range revisions {
+		revisionsByMessage[revision.MessageID] = append(revisionsByMessage[revision.MessageID], revision)
+	}
+
 	// Convert entities to domain objects
 	messageInfos := make([]domainChat.MessageInfo, 0, len(messages))
 	for _, message := range messages {
@@ -159,28 +206,29 @@ func (service serviceChat) GetChatMessages(ctx context.Context, request domainCh
 			ID:         message.ID,
 			ChatJID:    message.ChatJID,
 			SenderJID:  message.Sender,
-			Content:    message.Content,
+			Content:    formatMessageContent(message.Content, request.Format),
 			Timestamp:  message.Timestamp.Format(time.RFC3339),
 			IsFromMe:   message.IsFromMe,
 			MediaType:  message.MediaType,
 			Filename:   message.Filename,
 			URL:        message.URL,
 			FileLength: message.FileLength,
+			MediaPath:  message.MediaPath,
 			CreatedAt:  message.CreatedAt.Format(time.RFC3339),
 			UpdatedAt:  message.UpdatedAt.Format(time.RFC3339),
 		}
+		if message.IsFromMe {
+			messageInfo.DeliveryStatus = summarizeDeliveryStatus(receiptsByMessage[message.ID])
+		}
+		if messageReactions := reactionsByMessage[message.ID]; len(messageReactions) > 0 {
+			messageInfo.ReactionCounts = countReactions(messageReactions)
+		}
+		applyMessageRevisions(&messageInfo, revisionsByMessage[message.ID], request.IncludeRevisions)
 		messageInfos = append(messageInfos, messageInfo)
 	}
 
 	// Create chat info for response
-	chatInfo := domainChat.ChatInfo{
-		JID:                 chat.JID,
-		Name:                chat.Name,
-		LastMessageTime:     chat.LastMessageTime.Format(time.RFC3339),
-		EphemeralExpiration: chat.EphemeralExpiration,
-		CreatedAt:           chat.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:           chat.UpdatedAt.Format(time.RFC3339),
-	}
+	chatInfo := buildChatInfo(chat)
 
 	// Create pagination response
 	pagination := domainChat.PaginationResponse{
@@ -203,6 +251,95 @@ func (service serviceChat) GetChatMessages(ctx context.Context, request domainCh
 	return response, nil
 }
 
+func (service serviceChat) SearchMessages(ctx context.Context, request domainChat.SearchMessagesRequest) (response domainChat.SearchMessagesResponse, err error) {
+	if err = validations.ValidateSearchMessages(ctx, &request); err != nil {
+		return response, err
+	}
+
+	// Fetch one extra result to know whether another page follows
+	filter := &domainChatStorage.MessageSearchFilter{
+		Query:     utils.BuildFullTextQuery(request.Query),
+		ChatJID:   request.ChatJID,
+		MediaType: request.MediaType,
+		IsFromMe:  request.IsFromMe,
+		Limit:     request.Limit + 1,
+	}
+
+	if request.Sender != "" {
+		sender, err := utils.ParseJID(request.Sender)
+		if err != nil {
+			return response, pkgError.ValidationError(fmt.Sprintf("sender: %v", err))
+		}
+		filter.Sender = sender.ToNonAD().String()
+	}
+
+	// Parse time filters if provided
+	if request.StartTime != nil && *request.StartTime != "" {
+		startTime, err := time.Parse(time.RFC3339, *request.StartTime)
+		if err != nil {
+			return response, fmt.Errorf("invalid start_time format: %v", err)
+		}
+		filter.StartTime = &startTime
+	}
+
+	if request.EndTime != nil && *request.EndTime != "" {
+		endTime, err := time.Parse(time.RFC3339, *request.EndTime)
+		if err != nil {
+			return response, fmt.Errorf("invalid end_time format: %v", err)
+		}
+		filter.EndTime = &endTime
+	}
+
+	if request.Cursor != "" {
+		if filter.After, err = decodeSearchCursor(request.Cursor); err != nil {
+			return response, pkgError.ValidationError("cursor: must be the next_cursor of a previous search.")
+		}
+	}
+
+	results, err := service.chatStorageRepo.SearchAllMessages(filter)
+	if err != nil {
+		logrus.WithError(err).WithField("query", filter.Query).Error("Failed to search messages")
+		return response, err
+	}
+
+	if len(results) > request.Limit {
+		results = results[:request.Limit]
+		response.NextCursor = encodeSearchCursor(results[len(results)-1].Cursor)
+	}
+
+	response.Data = make([]domainChat.SearchMessageResult, 0, len(results))
+	for _, result := range results {
+		message := result.Message
+		response.Data = append(response.Data, domainChat.SearchMessageResult{
+			MessageInfo: domainChat.MessageInfo{
+				ID:         message.ID,
+				ChatJID:    message.ChatJID,
+				SenderJID:  message.Sender,
+				Content:    message.Content,
+				Timestamp:  message.Timestamp.Format(time.RFC3339),
+				IsFromMe:   message.IsFromMe,
+				MediaType:  message.MediaType,
+				Filename:   message.Filename,
+				URL:        message.URL,
+				FileLength: message.FileLength,
+				MediaPath:  message.MediaPath,
+				CreatedAt:  message.CreatedAt.Format(time.RFC3339),
+				UpdatedAt:  message.UpdatedAt.Format(time.RFC3339),
+			},
+			Snippet: result.Snippet,
+			Score:   result.Score,
+		})
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"query":   filter.Query,
+		"results": len(response.Data),
+		"limit":   request.Limit,
+	}).Info("Searched messages successfully")
+
+	return response, nil
+}
+
 func (service serviceChat) PinChat(ctx context.Context, request domainChat.PinChatRequest) (response domainChat.PinChatResponse, err error) {
 	if err = validations.ValidatePinChat(ctx, &request); err != nil {
 		return response, err
@@ -218,7 +355,7 @@ func (service serviceChat) PinChat(ctx context.Context, request domainChat.PinCh
 	patchInfo := appstate.BuildPin(targetJID, request.Pinned)
 
 	// Send app state update
-	if err = whatsapp.GetClient().SendAppState(ctx, patchInfo); err != nil {
+	if err = whatsapp.SendChatAction(ctx, patchInfo); err != nil {
 		logrus.WithError(err).WithFields(logrus.Fields{
 			"chat_jid": request.ChatJID,
 			"pinned":   request.Pinned,
@@ -226,6 +363,11 @@ func (service serviceChat) PinChat(ctx context.Context, request domainChat.PinCh
 		return response, err
 	}
 
+	// Storage only changes once the patch is confirmed, its echo while sending is ignored
+	if err = service.chatStorageRepo.SetChatPinned(targetJID.String(), request.Pinned); err != nil {
+		logrus.WithError(err).WithField("chat_jid", targetJID.String()).Warn("Failed to store pinned chat")
+	}
+
 	// Build response
 	response.Status = "success"
 	response.ChatJID = request.ChatJID
@@ -244,3 +386,449 @@ func (service serviceChat) PinChat(ctx context.Context, request domainChat.PinCh
 
 	return response, nil
 }
+
+func (service serviceChat) ArchiveChat(ctx context.Context, request domainChat.ArchiveChatRequest) (response domainChat.ArchiveChatResponse, err error) {
+	if err = validations.ValidateArchiveChat(ctx, &request); err != nil {
+		return response, err
+	}
+
+	// Validate JID and ensure connection
+	targetJID, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.ChatJID)
+	if err != nil {
+		return response, err
+	}
+
+	lastMessageTime, lastMessageKey := service.lastMessageKey(targetJID)
+	patchInfo := appstate.BuildArchive(targetJID, request.Archived, lastMessageTime, lastMessageKey)
+
+	if err = whatsapp.SendChatAction(ctx, patchInfo); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"chat_jid": request.ChatJID,
+			"archived": request.Archived,
+		}).Error("Failed to send archive chat app state")
+		return response, err
+	}
+
+	// Storage only changes once the patch is confirmed, its echo while sending is ignored
+	if err = service.chatStorageRepo.SetChatArchived(targetJID.String(), request.Archived); err != nil {
+		logrus.WithError(err).WithField("chat_jid", targetJID.String()).Warn("Failed to store archived chat")
+	}
+	if request.Archived {
+		// The archive patch also unpins the chat
+		if err = service.chatStorageRepo.SetChatPinned(targetJID.String(), false); err != nil {
+			logrus.WithError(err).WithField("chat_jid", targetJID.String()).Warn("Failed to store unpinned chat")
+		}
+	}
+
+	response.Status = "success"
+	response.ChatJID = targetJID.String()
+	response.Archived = request.Archived
+
+	if request.Archived {
+		response.Message = "Chat archived successfully"
+	} else {
+		response.Message = "Chat unarchived successfully"
+	}
+
+	return response, nil
+}
+
+func (service serviceChat) MuteChat(ctx context.Context, request domainChat.MuteChatRequest) (response domainChat.MuteChatResponse, err error) {
+	if err = validations.ValidateMuteChat(ctx, &request); err != nil {
+		return response, err
+	}
+
+	// Validate JID and ensure connection
+	targetJID, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.ChatJID)
+	if err != nil {
+		return response, err
+	}
+
+	duration := chatMuteDurations[request.Duration]
+	patchInfo := appstate.BuildMute(targetJID, request.Muted, duration)
+
+	if err = whatsapp.SendChatAction(ctx, patchInfo); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"chat_jid": request.ChatJID,
+			"muted":    request.Muted,
+		}).Error("Failed to send mute chat app state")
+		return response, err
+	}
+
+	var mutedUntil *time.Time
+	if request.Muted && duration > 0 {
+		until := time.Now().Add(duration)
+		mutedUntil = &until
+		response.MutedUntil = until.Format(time.RFC3339)
+	}
+
+	// Storage only changes once the patch is confirmed, its echo while sending is ignored
+	if err = service.chatStorageRepo.SetChatMuted(targetJID.String(), request.Muted, mutedUntil); err != nil {
+		logrus.WithError(err).WithField("chat_jid", targetJID.String()).Warn("Failed to store muted chat")
+	}
+
+	response.Status = "success"
+	response.ChatJID = targetJID.String()
+	response.Muted = request.Muted
+
+	switch {
+	case !request.Muted:
+		response.Message = "Chat unmuted successfully"
+	case mutedUntil == nil:
+		response.Message = "Chat muted forever"
+	default:
+		response.Message = fmt.Sprintf("Chat muted for %s", request.Duration)
+	}
+
+	return response, nil
+}
+
+func (service serviceChat) MarkChatAsRead(ctx context.Context, request domainChat.MarkChatAsReadRequest) (response domainChat.MarkChatAsReadResponse, err error) {
+	if err = validations.ValidateMarkChatAsRead(ctx, &request); err != nil {
+		return response, err
+	}
+
+	// Validate JID and ensure connection
+	targetJID, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.ChatJID)
+	if err != nil {
+		return response, err
+	}
+
+	patchInfo := buildMarkChatAsRead(targetJID, request.Read, chatMessageRange(service.lastMessageKey(targetJID)))
+
+	if err = whatsapp.SendChatAction(ctx, patchInfo); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"chat_jid": request.ChatJID,
+			"read":     request.Read,
+		}).Error("Failed to send mark chat as read app state")
+		return response, err
+	}
+
+	// Storage only changes once the patch is confirmed, its echo while sending is ignored
+	if err = service.chatStorageRepo.SetChatMarkedUnread(targetJID.String(), !request.Read); err != nil {
+		logrus.WithError(err).WithField("chat_jid", targetJID.String()).Warn("Failed to store unread chat")
+	}
+
+	response.Status = "success"
+	response.ChatJID = targetJID.String()
+	response.Read = request.Read
+
+	if request.Read {
+		response.Message = "Chat marked as read"
+	} else {
+		response.Message = "Chat marked as unread"
+	}
+
+	return response, nil
+}
+
+func (service serviceChat) ClearChat(ctx context.Context, request domainChat.ChatActionRequest) (response domainChat.ChatActionResponse, err error) {
+	if err = validations.ValidateChatAction(ctx, &request); err != nil {
+		return response, err
+	}
+
+	// Validate JID and ensure connection
+	targetJID, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.ChatJID)
+	if err != nil {
+		return response, err
+	}
+
+	messageRange := chatMessageRange(service.lastMessageKey(targetJID))
+	if err = whatsapp.SendChatAction(ctx, buildClearChat(targetJID, messageRange)); err != nil {
+		logrus.WithError(err).WithField("chat_jid", request.ChatJID).Error("Failed to send clear chat app state")
+		return response, err
+	}
+
+	// Storage only changes once the patch is confirmed, its echo while sending is ignored
+	until := time.Unix(messageRange.GetLastMessageTimestamp(), 0)
+	if err = service.chatStorageRepo.ClearChatMessages(targetJID.String(), until); err != nil {
+		logrus.WithError(err).WithField("chat_jid", targetJID.String()).Warn("Failed to clear stored chat messages")
+	}
+
+	response.Status = "success"
+	response.ChatJID = targetJID.String()
+	response.Message = "Chat cleared successfully"
+
+	return response, nil
+}
+
+func (service serviceChat) DeleteChat(ctx context.Context, request domainChat.ChatActionRequest) (response domainChat.ChatActionResponse, err error) {
+	if err = validations.ValidateChatAction(ctx, &request); err != nil {
+		return response, err
+	}
+
+	// Validate JID and ensure connection
+	targetJID, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.ChatJID)
+	if err != nil {
+		return response, err
+	}
+
+	messageRange := chatMessageRange(service.lastMessageKey(targetJID))
+	if err = whatsapp.SendChatAction(ctx, buildDeleteChat(targetJID, messageRange)); err != nil {
+		logrus.WithError(err).WithField("chat_jid", request.ChatJID).Error("Failed to send delete chat app state")
+		return response, err
+	}
+
+	// Storage only changes once the patch is confirmed, its echo while sending is ignored
+	if err = service.chatStorageRepo.DeleteChat(targetJID.String()); err != nil {
+		logrus.WithError(err).WithField("chat_jid", targetJID.String()).Warn("Failed to delete stored chat")
+	}
+
+	response.Status = "success"
+	response.ChatJID = targetJID.String()
+	response.Message = "Chat deleted successfully"
+
+	return response, nil
+}
+
+func (service serviceChat) SetDisappearingTimer(ctx context.Context, request domainChat.SetDisappearingTimerRequest) (response domainChat.SetDisappearingTimerResponse, err error) {
+	if err = validations.ValidateSetDisappearingTimer(ctx, &request); err != nil {
+		return response, err
+	}
+
+	// Validate JID and ensure connection
+	targetJID, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.ChatJID)
+	if err != nil {
+		return response, err
+	}
+
+	// Already validated against the supported timers
+	timer, _ := whatsmeow.ParseDisappearingTimerString(request.Timer)
+
+	if err = whatsapp.GetClient().SetDisappearingTimer(targetJID, timer, time.Now()); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"chat_jid": request.ChatJID,
+			"timer":    request.Timer,
+		}).Error("Failed to set disappearing timer")
+		return response, err
+	}
+
+	// Update storage right away, our own setting change is not echoed back for private chats
+	expiration := uint32(timer.Seconds())
+	if err = service.chatStorageRepo.SetChatEphemeralExpiration(targetJID.String(), expiration, time.Now()); err != nil {
+		logrus.WithError(err).WithField("chat_jid", targetJID.String()).Warn("Failed to store disappearing timer")
+	}
+
+	response.Status = "success"
+	response.ChatJID = targetJID.String()
+	response.Timer = request.Timer
+	response.EphemeralExpiration = expiration
+
+	if timer == whatsmeow.DisappearingTimerOff {
+		response.Message = "Disappearing messages turned off"
+	} else {
+		response.Message = fmt.Sprintf("Disappearing messages set to %s", request.Timer)
+	}
+
+	return response, nil
+}
+
+// summarizeDeliveryStatus counts how many recipients reached each stage of an outgoing message
+func summarizeDeliveryStatus(receipts []*domainChatStorage.MessageReceipt) *domainChat.MessageDeliveryStatus {
+	status := &domainChat.MessageDeliveryStatus{
+		Status:     aggregateReceiptStage(receipts),
+		Recipients: len(receipts),
+	}
+	for _, receipt := range receipts {
+		if receipt.DeliveredAt != nil {
+			status.Delivered++
+		}
+		if receipt.ReadAt != nil {
+			status.Read++
+		}
+		if receipt.PlayedAt != nil {
+			status.Played++
+		}
+	}
+	return status
+}
+
+// applyMessageRevisions sets when a message was last edited or revoked, and its history when requested
+func applyMessageRevisions(messageInfo *domainChat.MessageInfo, revisions []*domainChatStorage.MessageRevision, includeRevisions bool) {
+	for _, revision := range revisions {
+		// Revisions are ordered oldest first
+		switch revision.Type {
+		case domainChatStorage.RevisionEdit:
+			messageInfo.EditedAt = revision.Timestamp.Format(time.RFC3339)
+		case domainChatStorage.RevisionRevoke:
+			messageInfo.RevokedAt = revision.Timestamp.Format(time.RFC3339)
+		}
+
+		if includeRevisions {
+			messageInfo.Revisions = append(messageInfo.Revisions, domainChat.MessageRevision{
+				Type:            revision.Type,
+				EditorJID:       revision.EditorJID,
+				Content:         revision.Content,
+				PreviousContent: revision.PreviousContent,
+				Timestamp:       revision.Timestamp.Format(time.RFC3339),
+			})
+		}
+	}
+}
+
+// formatMessageContent converts the WhatsApp formatting of a stored message into the requested format
+func formatMessageContent(content, format string) string {
+	switch format {
+	case utils.MessageFormatMarkdown:
+		return utils.WhatsAppToMarkdown(content)
+	case utils.MessageFormatHTML:
+		return utils.WhatsAppToHTML(content)
+	default:
+		return content
+	}
+}
+
+// encodeSearchCursor makes the position of a search result opaque to clients, the score is kept exactly
+func encodeSearchCursor(cursor domainChatStorage.MessageSearchCursor) string {
+	position := strconv.FormatFloat(cursor.Score, 'g', -1, 64) + ":" + strconv.FormatInt(cursor.RowID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(position))
+}
+
+func decodeSearchCursor(cursor string) (*domainChatStorage.MessageSearchCursor, error) {
+	position, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	score, rowID, found := strings.Cut(string(position), ":")
+	if !found {
+		return nil, fmt.Errorf("invalid search cursor")
+	}
+
+	decoded := &domainChatStorage.MessageSearchCursor{}
+	if decoded.Score, err = strconv.ParseFloat(score, 64); err != nil || math.IsNaN(decoded.Score) || math.IsInf(decoded.Score, 0) {
+		return nil, fmt.Errorf("invalid search cursor")
+	}
+	if decoded.RowID, err = strconv.ParseInt(rowID, 10, 64); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// chatMuteDurations are the accepted mute durations, zero mutes forever
+var chatMuteDurations = map[string]time.Duration{
+	"8h":      8 * time.Hour,
+	"1w":      7 * 24 * time.Hour,
+	"forever": 0,
+}
+
+// buildChatInfo converts a stored chat into its API representation
+func buildChatInfo(chat *domainChatStorage.Chat) domainChat.ChatInfo {
+	chatInfo := domainChat.ChatInfo{
+		JID:                 chat.JID,
+		Name:                chat.Name,
+		LastMessageTime:     chat.LastMessageTime.Format(time.RFC3339),
+		EphemeralExpiration: chat.EphemeralExpiration,
+		Archived:            chat.Archived,
+		Muted:               chat.IsMuted(time.Now()),
+		MarkedUnread:        chat.MarkedUnread,
+		Pinned:              chat.Pinned,
+		Labels:              chat.Labels,
+		CreatedAt:           chat.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:           chat.UpdatedAt.Format(time.RFC3339),
+	}
+	if chatInfo.Muted && chat.MutedUntil != nil {
+		chatInfo.MutedUntil = chat.MutedUntil.Format(time.RFC3339)
+	}
+	if chatInfo.Labels == nil {
+		chatInfo.Labels = []string{} // Empty array instead of null for consistent JSON
+	}
+	return chatInfo
+}
+
+// lastMessageKey finds the latest stored message of a chat, app state chat actions reference it
+func (service serviceChat) lastMessageKey(chatJID types.JID) (time.Time, *waCommon.MessageKey) {
+	messages, err := service.chatStorageRepo.GetMessages(&domainChatStorage.MessageFilter{ChatJID: chatJID.String(), Limit: 1})
+	if err != nil || len(messages) == 0 {
+		return time.Time{}, nil
+	}
+
+	message := messages[0]
+	key := &waCommon.MessageKey{
+		RemoteJID: proto.String(chatJID.String()),
+		FromMe:    proto.Bool(message.IsFromMe),
+		ID:        proto.String(message.ID),
+	}
+	if chatJID.Server == types.GroupServer && !message.IsFromMe {
+		key.Participant = proto.String(message.Sender)
+	}
+	return message.Timestamp, key
+}
+
+// chatMessageRange describes the messages covered by a chat action, up to now when no message is stored
+func chatMessageRange(lastMessageTime time.Time, lastMessageKey *waCommon.MessageKey) *waSyncAction.SyncActionMessageRange {
+	if lastMessageTime.IsZero() {
+		lastMessageTime = time.Now()
+	}
+
+	messageRange := &waSyncAction.SyncActionMessageRange{
+		LastMessageTimestamp: proto.Int64(lastMessageTime.Unix()),
+	}
+	if lastMessageKey != nil {
+		messageRange.Messages = []*waSyncAction.SyncActionMessage{{
+			Key:       lastMessageKey,
+			Timestamp: proto.Int64(lastMessageTime.Unix()),
+		}}
+	}
+	return messageRange
+}
+
+// Mutation versions and index flags of the chat actions whatsmeow has no builder for, alongside its appstate.Index* names
+const (
+	markChatAsReadVersion = 3
+	clearChatVersion      = 6
+	deleteChatVersion     = 6
+
+	indexFlagFalse = "0"
+	indexFlagTrue  = "1"
+)
+
+// buildMarkChatAsRead builds the app state patch marking a chat as read or unread, whatsmeow has no builder for it
+func buildMarkChatAsRead(target types.JID, read bool, messageRange *waSyncAction.SyncActionMessageRange) appstate.PatchInfo {
+	return appstate.PatchInfo{
+		Type: appstate.WAPatchRegularLow,
+		Mutations: []appstate.MutationInfo{{
+			Index:   []string{appstate.IndexMarkChatAsRead, target.String()},
+			Version: markChatAsReadVersion,
+			Value: &waSyncAction.SyncActionValue{
+				MarkChatAsReadAction: &waSyncAction.MarkChatAsReadAction{
+					Read:         proto.Bool(read),
+					MessageRange: messageRange,
+				},
+			},
+		}},
+	}
+}
+
+// buildClearChat builds the app state patch clearing the messages of a chat, starred messages included
+func buildClearChat(target types.JID, messageRange *waSyncAction.SyncActionMessageRange) appstate.PatchInfo {
+	const deleteStarred, deleteMedia = indexFlagTrue, indexFlagFalse
+	return appstate.PatchInfo{
+		Type: appstate.WAPatchRegularHigh,
+		Mutations: []appstate.MutationInfo{{
+			Index:   []string{appstate.IndexClearChat, target.String(), deleteStarred, deleteMedia},
+			Version: clearChatVersion,
+			Value: &waSyncAction.SyncActionValue{
+				ClearChatAction: &waSyncAction.ClearChatAction{
+					MessageRange: messageRange,
+				},
+			},
+		}},
+	}
+}
+
+// buildDeleteChat builds the app state patch deleting a chat with its media
+func buildDeleteChat(target types.JID, messageRange *waSyncAction.SyncActionMessageRange) appstate.PatchInfo {
+	const deleteMedia = indexFlagTrue
+	return appstate.PatchInfo{
+		Type: appstate.WAPatchRegularHigh,
+		Mutations: []appstate.MutationInfo{{
+			Index:   []string{appstate.IndexDeleteChat, target.String(), deleteMedia},
+			Version: deleteChatVersion,
+			Value: &waSyncAction.SyncActionValue{
+				DeleteChatAction: &waSyncAction.DeleteChatAction{
+					MessageRange: messageRange,
+				},
+			},
+		}},
+	}
+}
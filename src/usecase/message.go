@@ -2,14 +2,22 @@ package usecase
 
 import (
 	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
 	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
 	domainMessage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/message"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/mediastore"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/whatsapp"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/validations"
@@ -20,6 +28,7 @@ import (
 	"go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/proto/waSyncAction"
 	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -84,6 +93,18 @@ func (service serviceMessage) ReactMessage(ctx context.Context, request domainMe
 		return response, err
 	}
 
+	// Our own reactions are not echoed back as message events
+	err = service.chatStorageRepo.StoreReaction(&domainChatStorage.Reaction{
+		MessageID:  request.MessageID,
+		ChatJID:    dataWaRecipient.String(),
+		ReactorJID: whatsapp.GetClient().Store.ID.ToNonAD().String(),
+		Emoji:      request.Emoji,
+		Timestamp:  time.UnixMilli(msg.GetReactionMessage().GetSenderTimestampMS()),
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("message_id", request.MessageID).Warn("Failed to store sent reaction")
+	}
+
 	response.MessageID = ts.ID
 	response.Status = fmt.Sprintf("Reaction sent to %s (server timestamp: %s)", request.Phone, ts.Timestamp)
 	return response, nil
@@ -103,6 +124,17 @@ func (service serviceMessage) RevokeMessage(ctx context.Context, request domainM
 		return response, err
 	}
 
+	// Our own revokes are not echoed back as message events
+	err = service.chatStorageRepo.StoreMessageRevoke(&domainChatStorage.MessageRevision{
+		MessageID: request.MessageID,
+		ChatJID:   dataWaRecipient.String(),
+		EditorJID: whatsapp.GetClient().Store.ID.ToNonAD().String(),
+		Timestamp: ts.Timestamp,
+	}, config.WhatsappRevokeKeepContent)
+	if err != nil {
+		logrus.WithError(err).WithField("message_id", request.MessageID).Warn("Failed to store message revoke")
+	}
+
 	response.MessageID = ts.ID
 	response.Status = fmt.Sprintf("Revoke success %s (server timestamp: %s)", request.Phone, ts.Timestamp)
 	return response, nil
@@ -158,11 +190,65 @@ func (service serviceMessage) UpdateMessage(ctx context.Context, request domainM
 		return response, err
 	}
 
+	// Our own edits are not echoed back as message events
+	rawMessage, _ := utils.RawMessageFields(msg, nil)
+	err = service.chatStorageRepo.StoreMessageEdit(&domainChatStorage.MessageRevision{
+		MessageID:  request.MessageID,
+		ChatJID:    dataWaRecipient.String(),
+		EditorJID:  whatsapp.GetClient().Store.ID.ToNonAD().String(),
+		Content:    request.Message,
+		Timestamp:  ts.Timestamp,
+		RawMessage: rawMessage,
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("message_id", request.MessageID).Warn("Failed to store message edit")
+	}
+
 	response.MessageID = ts.ID
 	response.Status = fmt.Sprintf("Update message success %s (server timestamp: %s)", request.Phone, ts.Timestamp)
 	return response, nil
 }
 
+// ForwardMessage sends a stored message to another chat as a forward. It is rebuilt from its raw message,
+// so media, locations and polls are forwarded as they were received.
+func (service serviceMessage) ForwardMessage(ctx context.Context, request domainMessage.ForwardMessageRequest) (response domainMessage.GenericResponse, err error) {
+	if err = validations.ValidateForwardMessage(ctx, request); err != nil {
+		return response, err
+	}
+
+	dataWaRecipient, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.Phone)
+	if err != nil {
+		return response, err
+	}
+
+	message, err := service.chatStorageRepo.GetFullMessage(request.MessageID, "")
+	if err != nil {
+		return response, fmt.Errorf("message not found: %v", err)
+	}
+	if message == nil {
+		return response, fmt.Errorf("message with ID %s not found", request.MessageID)
+	}
+
+	msg, err := forwardedMessage(message)
+	if err != nil {
+		return response, err
+	}
+
+	ts, err := whatsapp.GetClient().SendMessage(ctx, dataWaRecipient, msg)
+	if err != nil {
+		return response, err
+	}
+
+	err = service.chatStorageRepo.StoreSentMessageWithContext(ctx, ts.ID, whatsapp.GetClient().Store.ID.String(), dataWaRecipient.String(), message.Content, ts.Timestamp, msg)
+	if err != nil {
+		logrus.WithError(err).WithField("message_id", ts.ID).Warn("Failed to store forwarded message")
+	}
+
+	response.MessageID = ts.ID
+	response.Status = fmt.Sprintf("Message %s forwarded to %s (server timestamp: %s)", request.MessageID, request.Phone, ts.Timestamp)
+	return response, nil
+}
+
 // StarMessage implements message.IMessageService.
 func (service serviceMessage) StarMessage(ctx context.Context, request domainMessage.StarRequest) (err error) {
 	if err = validations.ValidateStarMessage(ctx, request); err != nil {
@@ -193,119 +279,639 @@ func (service serviceMessage) DownloadMedia(ctx context.Context, request domainM
 		return response, err
 	}
 
-	dataWaRecipient, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.Phone)
+	message, downloadableMsg, err := service.getMediaMessage(request.Phone, request.MessageID)
+	if err != nil {
+		return response, err
+	}
+
+	// Media the auto-download stored already is reused, otherwise it is downloaded into the media store
+	store := mediastore.For(config.PathMedia)
+	var extractedMedia utils.ExtractedMedia
+	if message.MediaPath != "" {
+		extractedMedia, err = utils.StoredMedia(ctx, store, message.MediaPath)
+	}
+	if message.MediaPath == "" || err != nil {
+		err = service.downloadWithReupload(ctx, message, downloadableMsg, func(media whatsmeow.DownloadableMessage) (err error) {
+			extractedMedia, err = utils.ExtractMedia(ctx, whatsapp.GetClient(), store, media)
+			return err
+		})
+		if err != nil {
+			return response, fmt.Errorf("failed to download media: %v", err)
+		}
+		if err = service.chatStorageRepo.UpdateMessageMediaPath(message.ID, message.ChatJID, extractedMedia.MediaPath); err != nil {
+			logrus.Warnf("Failed to record media path of message %s: %v", message.ID, err)
+		}
+	}
+
+	// Build response
+	response.MessageID = request.MessageID
+	response.Status = fmt.Sprintf("Media downloaded successfully to %s", extractedMedia.MediaPath)
+	response.MediaType = message.MediaType
+	response.Filename = path.Base(extractedMedia.MediaPath)
+	response.FilePath = extractedMedia.MediaPath
+	response.MediaURL = extractedMedia.MediaURL
+	response.FileSize = extractedMedia.FileSize
+
+	logrus.Info(map[string]any{
+		"message_id": request.MessageID,
+		"phone":      request.Phone,
+		"chat":       message.ChatJID,
+		"media_type": response.MediaType,
+		"file_path":  response.FilePath,
+		"file_size":  response.FileSize,
+	})
+
+	return response, nil
+}
+
+// StreamMedia streams the decrypted media of a message to the client, from disk when it was downloaded before
+func (service serviceMessage) StreamMedia(ctx context.Context, request domainMessage.StreamMediaRequest) (response domainMessage.StreamMediaResponse, err error) {
+	if err = validations.ValidateStreamMedia(ctx, request); err != nil {
+		return response, err
+	}
+
+	message, downloadableMsg, err := service.getMediaMessage(request.Phone, request.MessageID)
 	if err != nil {
 		return response, err
 	}
 
+	if maxSize := config.WhatsappSettingMaxDownloadSize; message.FileLength > uint64(maxSize) {
+		return response, fmt.Errorf("file size exceeds the maximum limit of %d bytes", maxSize)
+	}
+
+	file, err := service.openDecryptedMedia(ctx, message, downloadableMsg)
+	if err != nil {
+		return response, fmt.Errorf("failed to download media: %v", err)
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return response, err
+	}
+
+	// Stored messages do not keep the MIME type, the file name usually tells it and the content does otherwise
+	mimeType := mime.TypeByExtension(filepath.Ext(message.Filename))
+	if mimeType == "" {
+		if mimeType, err = utils.DetectReaderContentType(file); err != nil {
+			_ = file.Close()
+			return response, err
+		}
+	}
+
+	filename := message.Filename
+	if filename == "" {
+		filename = message.ID
+		if extensions, _ := mime.ExtensionsByType(mimeType); len(extensions) > 0 {
+			filename += extensions[0]
+		}
+	}
+
+	response.MessageID = message.ID
+	response.MediaType = message.MediaType
+	response.MimeType = mimeType
+	response.Filename = filename
+	response.FileSize = fileInfo.Size()
+	response.Content = file
+
+	return response, nil
+}
+
+// GetMediaURL signs a short lived URL streaming the media of a message, for services that have no API credentials
+func (service serviceMessage) GetMediaURL(ctx context.Context, request domainMessage.MediaURLRequest) (response domainMessage.MediaURLResponse, err error) {
+	if err = validations.ValidateMediaURL(ctx, request); err != nil {
+		return response, err
+	}
+
+	message, _, err := service.getMediaMessage(request.Phone, request.MessageID)
+	if err != nil {
+		return response, err
+	}
+
+	ttl := config.AppMediaURLTTL
+	if request.ExpiresIn > 0 {
+		ttl = time.Duration(request.ExpiresIn) * time.Second
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	response.MessageID = message.ID
+	response.URL = utils.SignMediaURL(config.AppPublicURL, message.ID, message.ChatJID, expiresAt)
+	response.ExpiresAt = expiresAt.Format(time.RFC3339)
+
+	return response, nil
+}
+
+// getMediaMessage looks up a stored message of the chat and rebuilds its media so it can be downloaded again
+func (service serviceMessage) getMediaMessage(phone, messageID string) (*domainChatStorage.Message, whatsmeow.DownloadableMessage, error) {
+	dataWaRecipient, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), phone)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Query the message from chat storage
-	message, err := service.chatStorageRepo.GetMessageByID(request.MessageID)
+	message, err := service.chatStorageRepo.GetMessageByID(messageID)
 	if err != nil {
-		return response, fmt.Errorf("message not found: %v", err)
+		return nil, nil, fmt.Errorf("message not found: %v", err)
 	}
 
 	if message == nil {
-		return response, fmt.Errorf("message with ID %s not found", request.MessageID)
+		return nil, nil, fmt.Errorf("message with ID %s not found", messageID)
 	}
 
 	// Check if message has media
-	if message.MediaType == "" || message.URL == "" {
-		return response, fmt.Errorf("message %s does not contain downloadable media", request.MessageID)
+	if message.MediaType == "" || (message.URL == "" && message.DirectPath == "") {
+		return nil, nil, fmt.Errorf("message %s does not contain downloadable media", messageID)
 	}
 
 	// Verify the message is from the specified chat
 	if message.ChatJID != dataWaRecipient.String() {
-		return response, fmt.Errorf("message %s does not belong to chat %s", request.MessageID, dataWaRecipient.String())
+		return nil, nil, fmt.Errorf("message %s does not belong to chat %s", messageID, dataWaRecipient.String())
 	}
 
-	// Create directory structure for organized storage
-	chatDir := filepath.Join(config.PathMedia, utils.ExtractPhoneNumber(message.ChatJID))
-	dateDir := filepath.Join(chatDir, message.Timestamp.Format("2006-01-02"))
-
-	err = os.MkdirAll(dateDir, 0755)
+	downloadableMsg, err := newDownloadableMedia(message)
 	if err != nil {
-		return response, fmt.Errorf("failed to create directory: %v", err)
+		return nil, nil, err
 	}
+	return message, downloadableMsg, nil
+}
 
-	// Create a downloadable message interface based on media type
-	var downloadableMsg interface{}
+// newDownloadableMedia creates a downloadable message interface based on media type
+func newDownloadableMedia(message *domainChatStorage.Message) (whatsmeow.DownloadableMessage, error) {
+	// The MIME type is not stored with the message, the file name tells it so the store keeps it with the media
+	var mimeType *string
+	if byExtension := mime.TypeByExtension(filepath.Ext(message.Filename)); byExtension != "" {
+		mimeType = proto.String(byExtension)
+	}
 
 	switch message.MediaType {
 	case "image":
-		downloadableMsg = &waE2E.ImageMessage{
+		return &waE2E.ImageMessage{
 			URL:           proto.String(message.URL),
+			DirectPath:    proto.String(message.DirectPath),
 			MediaKey:      message.MediaKey,
 			FileSHA256:    message.FileSHA256,
 			FileEncSHA256: message.FileEncSHA256,
 			FileLength:    proto.Uint64(message.FileLength),
-		}
+			Mimetype:      mimeType,
+		}, nil
 	case "video":
-		downloadableMsg = &waE2E.VideoMessage{
+		return &waE2E.VideoMessage{
 			URL:           proto.String(message.URL),
+			DirectPath:    proto.String(message.DirectPath),
 			MediaKey:      message.MediaKey,
 			FileSHA256:    message.FileSHA256,
 			FileEncSHA256: message.FileEncSHA256,
 			FileLength:    proto.Uint64(message.FileLength),
-		}
+			Mimetype:      mimeType,
+		}, nil
 	case "audio":
-		downloadableMsg = &waE2E.AudioMessage{
+		return &waE2E.AudioMessage{
 			URL:           proto.String(message.URL),
+			DirectPath:    proto.String(message.DirectPath),
 			MediaKey:      message.MediaKey,
 			FileSHA256:    message.FileSHA256,
 			FileEncSHA256: message.FileEncSHA256,
 			FileLength:    proto.Uint64(message.FileLength),
-		}
+			Mimetype:      mimeType,
+		}, nil
 	case "document":
-		downloadableMsg = &waE2E.DocumentMessage{
+		return &waE2E.DocumentMessage{
 			URL:           proto.String(message.URL),
+			DirectPath:    proto.String(message.DirectPath),
 			MediaKey:      message.MediaKey,
 			FileSHA256:    message.FileSHA256,
 			FileEncSHA256: message.FileEncSHA256,
 			FileLength:    proto.Uint64(message.FileLength),
+			Mimetype:      mimeType,
 			FileName:      proto.String(message.Filename),
-		}
+		}, nil
 	case "sticker":
-		downloadableMsg = &waE2E.StickerMessage{
+		return &waE2E.StickerMessage{
 			URL:           proto.String(message.URL),
+			DirectPath:    proto.String(message.DirectPath),
 			MediaKey:      message.MediaKey,
 			FileSHA256:    message.FileSHA256,
 			FileEncSHA256: message.FileEncSHA256,
 			FileLength:    proto.Uint64(message.FileLength),
+			Mimetype:      mimeType,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported media type: %s", message.MediaType)
+	}
+}
+
+// downloadWithReupload runs a download of stored media. Media older than a few weeks is removed from the WhatsApp
+// servers, like the official clients the phone is then asked to upload it again and the download is retried from there.
+func (service serviceMessage) downloadWithReupload(ctx context.Context, message *domainChatStorage.Message, downloadableMsg whatsmeow.DownloadableMessage, download func(whatsmeow.DownloadableMessage) error) error {
+	err := download(downloadableMsg)
+	if !errors.Is(err, whatsmeow.ErrMediaDownloadFailedWith404) && !errors.Is(err, whatsmeow.ErrMediaDownloadFailedWith410) {
+		return err
+	}
+
+	logrus.Infof("Media of message %s has expired, asking the phone to upload it again", message.ID)
+	info, err := mediaMessageInfo(message)
+	if err != nil {
+		return err
+	}
+	directPath, err := whatsapp.RequestMediaReupload(ctx, info, message.MediaKey)
+	if err != nil {
+		return err
+	}
+
+	// The URL of the expired media is dropped, whatsmeow downloads the direct path from its current media hosts
+	message.URL, message.DirectPath = "", directPath
+	if err = service.chatStorageRepo.UpdateMessageDirectPath(message.ID, message.ChatJID, directPath); err != nil {
+		logrus.Warnf("Failed to store the new direct path of media of message %s: %v", message.ID, err)
+	}
+
+	if downloadableMsg, err = newDownloadableMedia(message); err != nil {
+		return err
+	}
+	return download(downloadableMsg)
+}
+
+// mediaMessageInfo is the message info the phone needs to find the media of a stored message
+func mediaMessageInfo(message *domainChatStorage.Message) (*types.MessageInfo, error) {
+	chatJID, err := types.ParseJID(message.ChatJID)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &types.MessageInfo{
+		ID: message.ID,
+		MessageSource: types.MessageSource{
+			Chat:     chatJID,
+			IsFromMe: message.IsFromMe,
+			IsGroup:  chatJID.Server == types.GroupServer,
+		},
+	}
+	if info.IsGroup {
+		if info.Sender, err = types.ParseJID(message.Sender); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// mediaFile is decrypted media opened for streaming
+type mediaFile interface {
+	io.ReadSeekCloser
+	Stat() (os.FileInfo, error)
+}
+
+// tempMediaFile is decrypted media that is only kept until it has been streamed
+type tempMediaFile struct {
+	*os.File
+}
+
+func (file tempMediaFile) Close() error {
+	err := file.File.Close()
+	_ = os.Remove(file.Name())
+	return err
+}
+
+// openDecryptedMedia opens media already downloaded to local disk, or downloads and decrypts it into the media cache
+// by its SHA-256 so repeated requests, like a player seeking through a video, are served from there.
+// Without the media cache setting the janitor removes cached files an hour after they were last served.
+func (service serviceMessage) openDecryptedMedia(ctx context.Context, message *domainChatStorage.Message, downloadableMsg whatsmeow.DownloadableMessage) (mediaFile, error) {
+	if message.MediaPath != "" && !strings.Contains(message.MediaPath, "://") {
+		if file, err := os.Open(message.MediaPath); err == nil {
+			return file, nil
+		}
+	}
+
+	if len(message.FileSHA256) == 0 {
+		file, err := service.downloadToTempFile(ctx, "", message, downloadableMsg)
+		if err != nil {
+			return nil, err
+		}
+		return tempMediaFile{file}, nil
+	}
+
+	cachePath := filepath.Join(config.PathMediaCache, hex.EncodeToString(message.FileSHA256))
+	if file, err := os.Open(cachePath); err == nil {
+		// Serving the file renews it, retention and the storage quota go by the modification time
+		now := time.Now()
+		_ = os.Chtimes(cachePath, now, now)
+		return file, nil
+	}
+
+	// Downloads land next to the cache and are renamed once verified, concurrent requests never see partial files
+	file, err := service.downloadToTempFile(ctx, config.PathMediaCache, message, downloadableMsg)
+	if err != nil {
+		return nil, err
+	}
+	if err = file.Close(); err == nil {
+		err = os.Rename(file.Name(), cachePath)
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return nil, err
+	}
+
+	cached, err := os.Open(cachePath)
+	if err != nil {
+		return nil, err
+	}
+	return cached, nil
+}
+
+// downloadToTempFile decrypts media into a new temporary file in dir, whatsmeow checks it against its SHA-256
+func (service serviceMessage) downloadToTempFile(ctx context.Context, dir string, message *domainChatStorage.Message, downloadableMsg whatsmeow.DownloadableMessage) (*os.File, error) {
+	// Hidden until complete, the janitor leaves hidden files alone
+	file, err := os.CreateTemp(dir, ".media-*")
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.downloadWithReupload(ctx, message, downloadableMsg, func(media whatsmeow.DownloadableMessage) error {
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		return whatsapp.GetClient().DownloadToFile(ctx, media, file)
+	})
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return nil, err
+	}
+	return file, nil
+}
+
+func (service serviceMessage) GetMessageStatus(ctx context.Context, request domainMessage.MessageStatusRequest) (response domainMessage.MessageStatusResponse, err error) {
+	if err = validations.ValidateMessageStatus(ctx, request); err != nil {
+		return response, err
+	}
+
+	message, chatJID, err := service.lookupMessageChat(request.Phone, request.MessageID)
+	if err != nil {
+		return response, err
+	}
+
+	receipts, err := service.chatStorageRepo.GetMessageReceipts(chatJID, []string{request.MessageID})
+	if err != nil {
+		return response, err
+	}
+
+	if message == nil && len(receipts) == 0 {
+		return response, fmt.Errorf("message with ID %s not found", request.MessageID)
+	}
+	if message != nil && !message.IsFromMe {
+		return response, fmt.Errorf("message %s was not sent by this device, receipts are only tracked for outgoing messages", request.MessageID)
+	}
+
+	response.MessageID = request.MessageID
+	response.ChatJID = chatJID
+	response.Status = aggregateReceiptStage(receipts)
+	response.Recipients = make([]domainMessage.RecipientStatus, 0, len(receipts))
+
+	response.DeliveredAt = formatReceiptTime(latestReceiptTime(receipts, func(r *domainChatStorage.MessageReceipt) *time.Time { return r.DeliveredAt }))
+	response.ReadAt = formatReceiptTime(latestReceiptTime(receipts, func(r *domainChatStorage.MessageReceipt) *time.Time { return r.ReadAt }))
+	response.PlayedAt = formatReceiptTime(latestReceiptTime(receipts, func(r *domainChatStorage.MessageReceipt) *time.Time { return r.PlayedAt }))
+
+	for _, receipt := range receipts {
+		response.Recipients = append(response.Recipients, domainMessage.RecipientStatus{
+			RecipientJID: receipt.RecipientJID,
+			Status:       receiptStage(receipt),
+			DeliveredAt:  formatReceiptTime(receipt.DeliveredAt),
+			ReadAt:       formatReceiptTime(receipt.ReadAt),
+			PlayedAt:     formatReceiptTime(receipt.PlayedAt),
+		})
+	}
+
+	return response, nil
+}
+
+// lookupMessageChat finds a stored message and the chat to look up its receipts, reactions or revisions in:
+// the chat of phone when given, else the chat of the message. The message is nil when it is not stored in that chat,
+// details may still be stored for it, e.g. for messages sent before the history sync window.
+func (service serviceMessage) lookupMessageChat(phone, messageID string) (*domainChatStorage.Message, string, error) {
+	message, err := service.chatStorageRepo.GetMessageByID(messageID)
+	if err != nil {
+		return nil, "", fmt.Errorf("message not found: %v", err)
+	}
+
+	chatJID := ""
+	if phone != "" {
+		dataWaRecipient, err := utils.ParseJID(phone)
+		if err != nil {
+			return nil, "", err
 		}
+		chatJID = dataWaRecipient.String()
+	} else if message != nil {
+		chatJID = message.ChatJID
+	}
+
+	// The same ID was found in another chat, only details of the requested chat are relevant
+	if message != nil && message.ChatJID != chatJID {
+		message = nil
+	}
+	return message, chatJID, nil
+}
+
+// messageStatusSent is the stage of an outgoing message that no recipient acknowledged yet
+const messageStatusSent = "sent"
+
+var receiptStageOrder = map[string]int{
+	messageStatusSent:                  0,
+	domainChatStorage.ReceiptDelivered: 1,
+	domainChatStorage.ReceiptRead:      2,
+	domainChatStorage.ReceiptPlayed:    3,
+}
+
+// receiptStage returns the furthest stage a single recipient reached
+func receiptStage(receipt *domainChatStorage.MessageReceipt) string {
+	switch {
+	case receipt.PlayedAt != nil:
+		return domainChatStorage.ReceiptPlayed
+	case receipt.ReadAt != nil:
+		return domainChatStorage.ReceiptRead
+	case receipt.DeliveredAt != nil:
+		return domainChatStorage.ReceiptDelivered
 	default:
-		return response, fmt.Errorf("unsupported media type: %s", message.MediaType)
+		return messageStatusSent
 	}
+}
 
-	// Download the media using existing utils.ExtractMedia function
-	extractedMedia, err := utils.ExtractMedia(ctx, whatsapp.GetClient(), dateDir, downloadableMsg.(whatsmeow.DownloadableMessage))
+func (service serviceMessage) GetMessageReactions(ctx context.Context, request domainMessage.MessageReactionsRequest) (response domainMessage.MessageReactionsResponse, err error) {
+	if err = validations.ValidateMessageReactions(ctx, request); err != nil {
+		return response, err
+	}
+
+	message, chatJID, err := service.lookupMessageChat(request.Phone, request.MessageID)
 	if err != nil {
-		return response, fmt.Errorf("failed to download media: %v", err)
+		return response, err
 	}
 
-	// Get file size
-	fileInfo, err := os.Stat(extractedMedia.MediaPath)
+	reactions, err := service.chatStorageRepo.GetMessageReactions(chatJID, []string{request.MessageID})
 	if err != nil {
-		logrus.Warnf("Could not get file size for %s: %v", extractedMedia.MediaPath, err)
+		return response, err
+	}
+
+	if message == nil && len(reactions) == 0 {
+		return response, fmt.Errorf("message with ID %s not found", request.MessageID)
 	}
 
-	// Build response
 	response.MessageID = request.MessageID
-	response.Status = fmt.Sprintf("Media downloaded successfully to %s", extractedMedia.MediaPath)
+	response.ChatJID = chatJID
+	response.ReactionCounts = countReactions(reactions)
+	response.Reactions = make([]domainMessage.MessageReaction, 0, len(reactions))
+	for _, reaction := range reactions {
+		response.Reactions = append(response.Reactions, domainMessage.MessageReaction{
+			ReactorJID: reaction.ReactorJID,
+			Emoji:      reaction.Emoji,
+			Timestamp:  reaction.Timestamp.Format(time.RFC3339),
+		})
+	}
+
+	return response, nil
+}
+
+func (service serviceMessage) GetMessageRevisions(ctx context.Context, request domainMessage.MessageRevisionsRequest) (response domainMessage.MessageRevisionsResponse, err error) {
+	if err = validations.ValidateMessageRevisions(ctx, request); err != nil {
+		return response, err
+	}
+
+	message, chatJID, err := service.lookupMessageChat(request.Phone, request.MessageID)
+	if err != nil {
+		return response, err
+	}
+
+	revisions, err := service.chatStorageRepo.GetMessageRevisions(chatJID, []string{request.MessageID})
+	if err != nil {
+		return response, err
+	}
+
+	if message == nil && len(revisions) == 0 {
+		return response, fmt.Errorf("message with ID %s not found", request.MessageID)
+	}
+
+	response.MessageID = request.MessageID
+	response.ChatJID = chatJID
+	response.Revisions = make([]domainMessage.MessageRevision, 0, len(revisions))
+	for _, revision := range revisions {
+		response.Revisions = append(response.Revisions, domainMessage.MessageRevision{
+			Type:            revision.Type,
+			EditorJID:       revision.EditorJID,
+			Content:         revision.Content,
+			PreviousContent: revision.PreviousContent,
+			Timestamp:       revision.Timestamp.Format(time.RFC3339),
+		})
+	}
+
+	return response, nil
+}
+
+func (service serviceMessage) GetFullMessage(ctx context.Context, request domainMessage.FullMessageRequest) (response domainMessage.FullMessageResponse, err error) {
+	if err = validations.ValidateFullMessage(ctx, request); err != nil {
+		return response, err
+	}
+
+	chatJID := ""
+	if request.Phone != "" {
+		dataWaRecipient, err := utils.ParseJID(request.Phone)
+		if err != nil {
+			return response, err
+		}
+		chatJID = dataWaRecipient.String()
+	}
+
+	message, err := service.chatStorageRepo.GetFullMessage(request.MessageID, chatJID)
+	if err != nil {
+		return response, fmt.Errorf("message not found: %v", err)
+	}
+	if message == nil {
+		return response, fmt.Errorf("message with ID %s not found", request.MessageID)
+	}
+
+	response.MessageID = message.ID
+	response.ChatJID = message.ChatJID
+	response.SenderJID = message.Sender
+	response.Content = message.Content
+	response.Timestamp = message.Timestamp.Format(time.RFC3339)
+	response.IsFromMe = message.IsFromMe
 	response.MediaType = message.MediaType
-	response.Filename = filepath.Base(extractedMedia.MediaPath)
-	response.FilePath = extractedMedia.MediaPath
-	if fileInfo != nil {
-		response.FileSize = fileInfo.Size()
+	if message.RawInfo != "" {
+		response.Info = json.RawMessage(message.RawInfo)
 	}
 
-	logrus.Info(map[string]any{
-		"message_id": request.MessageID,
-		"phone":      request.Phone,
-		"chat":       dataWaRecipient.String(),
-		"media_type": response.MediaType,
-		"file_path":  response.FilePath,
-		"file_size":  response.FileSize,
-	})
+	raw, err := utils.DecodeRawMessage(message.RawMessage)
+	if err != nil {
+		return response, fmt.Errorf("failed to decode raw message: %v", err)
+	}
+	if raw != nil {
+		if response.Message, err = protojson.Marshal(raw); err != nil {
+			return response, fmt.Errorf("failed to render raw message: %v", err)
+		}
+	}
 
 	return response, nil
 }
+
+// forwardedMessage builds the forward of a stored message. Without its raw message only text can be forwarded.
+func forwardedMessage(message *domainChatStorage.Message) (*waE2E.Message, error) {
+	raw, err := utils.DecodeRawMessage(message.RawMessage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode raw message: %v", err)
+	}
+	if raw != nil {
+		return utils.ForwardedMessage(raw)
+	}
+
+	if message.Content == "" || message.MediaType != "" {
+		return nil, fmt.Errorf("message with ID %s can not be forwarded, its raw message is not stored", message.ID)
+	}
+	return &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+		Text:        proto.String(message.Content),
+		ContextInfo: &waE2E.ContextInfo{IsForwarded: proto.Bool(true), ForwardingScore: proto.Uint32(1)},
+	}}, nil
+}
+
+// countReactions counts the reactions of a message per emoji
+func countReactions(reactions []*domainChatStorage.Reaction) map[string]int {
+	counts := make(map[string]int)
+	for _, reaction := range reactions {
+		counts[reaction.Emoji]++
+	}
+	return counts
+}
+
+// aggregateReceiptStage returns the stage every recipient that acknowledged the message reached
+func aggregateReceiptStage(receipts []*domainChatStorage.MessageReceipt) string {
+	if len(receipts) == 0 {
+		return messageStatusSent
+	}
+
+	stage := domainChatStorage.ReceiptPlayed
+	for _, receipt := range receipts {
+		if recipientStage := receiptStage(receipt); receiptStageOrder[recipientStage] < receiptStageOrder[stage] {
+			stage = recipientStage
+		}
+	}
+	return stage
+}
+
+// latestReceiptTime returns when the last recipient reached a stage, or nil while some recipient has not reached it
+func latestReceiptTime(receipts []*domainChatStorage.MessageReceipt, stageTime func(*domainChatStorage.MessageReceipt) *time.Time) *time.Time {
+	var latest *time.Time
+	for _, receipt := range receipts {
+		reachedAt := stageTime(receipt)
+		if reachedAt == nil {
+			return nil
+		}
+		if latest == nil || reachedAt.After(*latest) {
+			latest = reachedAt
+		}
+	}
+	return latest
+}
+
+func formatReceiptTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -1,12 +1,37 @@
 package usecase
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
 	domainNewsletter "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/newsletter"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/whatsapp"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/validations"
+	fiberUtils "github.com/gofiber/fiber/v2/utils"
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// Channel terms of service notice that must be accepted before creating a newsletter
+	newsletterTOSNoticeID    = "20601218"
+	newsletterTOSNoticeStage = "5"
+
+	// GraphQL mutation used by WhatsApp to update a newsletter's name, description and picture
+	mutationUpdateNewsletter = "7150902998257522"
 )
 
 type serviceNewsletter struct{}
@@ -27,3 +52,427 @@ func (service serviceNewsletter) Unfollow(ctx context.Context, request domainNew
 
 	return whatsapp.GetClient().UnfollowNewsletter(JID)
 }
+
+func (service serviceNewsletter) SendMessage(ctx context.Context, request domainNewsletter.SendMessageRequest) (response domainNewsletter.SendMessageResponse, err error) {
+	if err = validations.ValidateSendNewsletterMessage(ctx, request); err != nil {
+		return response, err
+	}
+
+	newsletterJID, err := service.parseNewsletterJID(request.NewsletterID)
+	if err != nil {
+		return response, err
+	}
+
+	// Only owners and admins are allowed to publish, fail early with a clear message
+	info, err := whatsapp.GetClient().GetNewsletterInfo(newsletterJID)
+	if err != nil {
+		return response, err
+	}
+	if info.ViewerMeta == nil ||
+		(info.ViewerMeta.Role != types.NewsletterRoleOwner && info.ViewerMeta.Role != types.NewsletterRoleAdmin) {
+		return response, pkgError.ValidationError(fmt.Sprintf("you must be an owner or admin of newsletter %s to publish", newsletterJID.String()))
+	}
+
+	var (
+		msg         *waE2E.Message
+		mediaHandle string
+	)
+
+	switch request.Type {
+	case domainNewsletter.SendMessageTypeText:
+		msg = &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+			Text: proto.String(request.Message),
+		}}
+	case domainNewsletter.SendMessageTypeImage:
+		msg, mediaHandle, err = service.buildImageMessage(ctx, request)
+	case domainNewsletter.SendMessageTypeVideo:
+		msg, mediaHandle, err = service.buildVideoMessage(ctx, request)
+	case domainNewsletter.SendMessageTypePoll:
+		msg = whatsapp.GetClient().BuildPollCreation(request.Question, request.Options, request.MaxAnswer)
+	}
+	if err != nil {
+		return response, err
+	}
+
+	// Newsletter media is sent unencrypted and referenced by the upload handle
+	ts, err := whatsapp.GetClient().SendMessage(ctx, newsletterJID, msg, whatsmeow.SendRequestExtra{MediaHandle: mediaHandle})
+	if err != nil {
+		return response, err
+	}
+
+	response.MessageID = ts.ID
+	response.ServerID = int(ts.ServerID)
+	response.Status = fmt.Sprintf("%s published to %s (server id: %d)", request.Type, newsletterJID.String(), ts.ServerID)
+	return response, nil
+}
+
+func (service serviceNewsletter) ListMessages(ctx context.Context, request domainNewsletter.ListMessagesRequest) (response domainNewsletter.ListMessagesResponse, err error) {
+	if err = validations.ValidateListNewsletterMessages(ctx, &request); err != nil {
+		return response, err
+	}
+
+	newsletterJID, err := service.parseNewsletterJID(request.NewsletterID)
+	if err != nil {
+		return response, err
+	}
+
+	messages, err := whatsapp.GetClient().GetNewsletterMessages(newsletterJID, &whatsmeow.GetNewsletterMessagesParams{
+		Count:  request.Count,
+		Before: types.MessageServerID(request.Before),
+	})
+	if err != nil {
+		return response, err
+	}
+
+	response.Data = make([]domainNewsletter.MessageInfo, 0, len(messages))
+	for _, message := range messages {
+		response.Data = append(response.Data, service.toMessageInfo(message))
+	}
+
+	return response, nil
+}
+
+func (service serviceNewsletter) Create(ctx context.Context, request domainNewsletter.CreateRequest) (response domainNewsletter.InfoResponse, err error) {
+	if err = validations.ValidateCreateNewsletter(ctx, request); err != nil {
+		return response, err
+	}
+
+	utils.MustLogin(whatsapp.GetClient())
+
+	picture, err := service.processPicture(request.Picture)
+	if err != nil {
+		return response, err
+	}
+
+	// WhatsApp refuses to create channels until the channel terms of service are accepted
+	if err = whatsapp.GetClient().AcceptTOSNotice(newsletterTOSNoticeID, newsletterTOSNoticeStage); err != nil {
+		return response, err
+	}
+
+	newsletter, err := whatsapp.GetClient().CreateNewsletter(whatsmeow.CreateNewsletterParams{
+		Name:        request.Name,
+		Description: request.Description,
+		Picture:     picture,
+	})
+	if err != nil {
+		return response, err
+	}
+
+	response.Data = newsletter
+	return response, nil
+}
+
+func (service serviceNewsletter) Update(ctx context.Context, request domainNewsletter.UpdateRequest) (response domainNewsletter.InfoResponse, err error) {
+	if err = validations.ValidateUpdateNewsletter(ctx, request); err != nil {
+		return response, err
+	}
+
+	newsletterJID, err := service.parseNewsletterJID(request.NewsletterID)
+	if err != nil {
+		return response, err
+	}
+
+	updates := map[string]any{}
+	if request.Name != "" {
+		updates["name"] = request.Name
+	}
+	if request.Description != "" {
+		updates["description"] = request.Description
+	}
+	if request.Picture != nil {
+		picture, err := service.processPicture(request.Picture)
+		if err != nil {
+			return response, err
+		}
+		updates["picture"] = picture
+	}
+
+	// whatsmeow does not expose a channel update call yet, so send the mutation ourselves
+	data, err := whatsapp.GetClient().DangerousInternals().SendMexIQ(ctx, mutationUpdateNewsletter, map[string]any{
+		"newsletter_id": newsletterJID.String(),
+		"updates":       updates,
+	})
+	if err != nil {
+		return response, err
+	}
+
+	var result struct {
+		Newsletter *types.NewsletterMetadata `json:"xwa2_newsletter_update"`
+	}
+	if err = json.Unmarshal(data, &result); err != nil {
+		return response, pkgError.InternalServerError(fmt.Sprintf("failed to parse newsletter update response: %v", err))
+	}
+
+	response.Data = result.Newsletter
+	return response, nil
+}
+
+func (service serviceNewsletter) Follow(ctx context.Context, request domainNewsletter.FollowRequest) (response domainNewsletter.InfoResponse, err error) {
+	if err = validations.ValidateFollowNewsletter(ctx, request); err != nil {
+		return response, err
+	}
+
+	var newsletterJID types.JID
+	if request.InviteLink != "" {
+		utils.MustLogin(whatsapp.GetClient())
+
+		info, err := whatsapp.GetClient().GetNewsletterInfoWithInvite(request.InviteLink)
+		if err != nil {
+			return response, err
+		}
+		newsletterJID = info.ID
+	} else {
+		newsletterJID, err = service.parseNewsletterJID(request.NewsletterID)
+		if err != nil {
+			return response, err
+		}
+	}
+
+	if err = whatsapp.GetClient().FollowNewsletter(newsletterJID); err != nil {
+		return response, err
+	}
+
+	response.Data, err = whatsapp.GetClient().GetNewsletterInfo(newsletterJID)
+	return response, err
+}
+
+func (service serviceNewsletter) Mute(ctx context.Context, request domainNewsletter.MuteRequest) (err error) {
+	if err = validations.ValidateMuteNewsletter(ctx, request); err != nil {
+		return err
+	}
+
+	newsletterJID, err := service.parseNewsletterJID(request.NewsletterID)
+	if err != nil {
+		return err
+	}
+
+	return whatsapp.GetClient().NewsletterToggleMute(newsletterJID, request.Mute)
+}
+
+func (service serviceNewsletter) InfoFromInvite(ctx context.Context, request domainNewsletter.InfoFromInviteRequest) (response domainNewsletter.InfoResponse, err error) {
+	if err = validations.ValidateNewsletterInfoFromInvite(ctx, request); err != nil {
+		return response, err
+	}
+
+	utils.MustLogin(whatsapp.GetClient())
+
+	response.Data, err = whatsapp.GetClient().GetNewsletterInfoWithInvite(request.InviteLink)
+	return response, err
+}
+
+func (service serviceNewsletter) ListUpdates(ctx context.Context, request domainNewsletter.ListUpdatesRequest) (response domainNewsletter.ListMessagesResponse, err error) {
+	if err = validations.ValidateListNewsletterUpdates(ctx, &request); err != nil {
+		return response, err
+	}
+
+	newsletterJID, err := service.parseNewsletterJID(request.NewsletterID)
+	if err != nil {
+		return response, err
+	}
+
+	params := &whatsmeow.GetNewsletterUpdatesParams{
+		Count: request.Count,
+		After: types.MessageServerID(request.After),
+	}
+	if request.Since != "" {
+		// Already validated as RFC3339
+		params.Since, _ = time.Parse(time.RFC3339, request.Since)
+	}
+
+	messages, err := whatsapp.GetClient().GetNewsletterMessageUpdates(newsletterJID, params)
+	if err != nil {
+		return response, err
+	}
+
+	response.Data = make([]domainNewsletter.MessageInfo, 0, len(messages))
+	for _, message := range messages {
+		response.Data = append(response.Data, service.toMessageInfo(message))
+	}
+
+	return response, nil
+}
+
+func (service serviceNewsletter) React(ctx context.Context, request domainNewsletter.ReactRequest) (err error) {
+	if err = validations.ValidateReactNewsletterMessage(ctx, request); err != nil {
+		return err
+	}
+
+	newsletterJID, err := service.parseNewsletterJID(request.NewsletterID)
+	if err != nil {
+		return err
+	}
+
+	return whatsapp.GetClient().NewsletterSendReaction(newsletterJID, types.MessageServerID(request.ServerID), request.Reaction, "")
+}
+
+func (service serviceNewsletter) MarkViewed(ctx context.Context, request domainNewsletter.MarkViewedRequest) (err error) {
+	if err = validations.ValidateMarkNewsletterViewed(ctx, request); err != nil {
+		return err
+	}
+
+	newsletterJID, err := service.parseNewsletterJID(request.NewsletterID)
+	if err != nil {
+		return err
+	}
+
+	serverIDs := make([]types.MessageServerID, 0, len(request.ServerIDs))
+	for _, serverID := range request.ServerIDs {
+		serverIDs = append(serverIDs, types.MessageServerID(serverID))
+	}
+
+	return whatsapp.GetClient().NewsletterMarkViewed(newsletterJID, serverIDs)
+}
+
+// processPicture converts an optional uploaded picture into the square JPEG WhatsApp expects
+func (service serviceNewsletter) processPicture(picture *multipart.FileHeader) ([]byte, error) {
+	if picture == nil {
+		return nil, nil
+	}
+
+	processed, err := utils.ProcessGroupPhoto(picture)
+	if err != nil {
+		return nil, pkgError.ValidationError(fmt.Sprintf("failed to process picture: %v", err))
+	}
+
+	return processed.Bytes(), nil
+}
+
+// parseNewsletterJID accepts either a bare newsletter ID or a full newsletter JID
+func (service serviceNewsletter) parseNewsletterJID(newsletterID string) (types.JID, error) {
+	if !strings.Contains(newsletterID, "@") {
+		newsletterID = newsletterID + "@" + types.NewsletterServer
+	}
+
+	newsletterJID, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), newsletterID)
+	if err != nil {
+		return newsletterJID, err
+	}
+	if newsletterJID.Server != types.NewsletterServer {
+		return newsletterJID, pkgError.InvalidJID(fmt.Sprintf("%s is not a newsletter JID", newsletterID))
+	}
+
+	return newsletterJID, nil
+}
+
+func (service serviceNewsletter) toMessageInfo(message *types.NewsletterMessage) domainNewsletter.MessageInfo {
+	mediaType, _, _, _, _, _, _, _ := utils.ExtractMediaInfo(message.Message)
+
+	reactionCounts := message.ReactionCounts
+	if reactionCounts == nil {
+		reactionCounts = map[string]int{}
+	}
+
+	return domainNewsletter.MessageInfo{
+		ServerID:       int(message.MessageServerID),
+		MessageID:      message.MessageID,
+		Type:           message.Type,
+		Content:        utils.ExtractMessageTextFromProto(message.Message),
+		MediaType:      mediaType,
+		Timestamp:      message.Timestamp.Format(time.RFC3339),
+		ViewsCount:     message.ViewsCount,
+		ReactionCounts: reactionCounts,
+	}
+}
+
+func (service serviceNewsletter) buildImageMessage(ctx context.Context, request domainNewsletter.SendMessageRequest) (*waE2E.Message, string, error) {
+	var image io.ReadSeeker
+	if request.ImageURL != nil && *request.ImageURL != "" {
+		downloaded, _, err := utils.DownloadImageFromURL(*request.ImageURL)
+		if err != nil {
+			return nil, "", pkgError.InternalServerError(fmt.Sprintf("failed to download image from URL %v", err))
+		}
+		image = bytes.NewReader(downloaded)
+	} else {
+		// Uploads are decoded straight from the file they were spooled to
+		file, err := request.Image.Open()
+		if err != nil {
+			return nil, "", pkgError.InternalServerError(fmt.Sprintf("failed to open image %v", err))
+		}
+		defer file.Close()
+		image = file
+	}
+
+	// WhatsApp only renders JPEG and PNG, anything else (e.g. WebP from URLs) is re-encoded as JPEG
+	processed, err := utils.ProcessImageReader(image, false)
+	if err != nil {
+		return nil, "", pkgError.InternalServerError(fmt.Sprintf("failed to process image %v", err))
+	}
+
+	uploaded, err := whatsapp.GetClient().UploadNewsletter(ctx, processed.Data, whatsmeow.MediaImage)
+	if err != nil {
+		return nil, "", pkgError.WaUploadMediaError(fmt.Sprintf("Failed to upload image: %v", err))
+	}
+
+	msg := &waE2E.Message{ImageMessage: &waE2E.ImageMessage{
+		URL:           proto.String(uploaded.URL),
+		DirectPath:    proto.String(uploaded.DirectPath),
+		Mimetype:      proto.String(processed.MimeType),
+		Caption:       proto.String(request.Caption),
+		FileSHA256:    uploaded.FileSHA256,
+		FileLength:    proto.Uint64(uploaded.FileLength),
+		Width:         proto.Uint32(uint32(processed.Width)),
+		Height:        proto.Uint32(uint32(processed.Height)),
+		JPEGThumbnail: processed.Thumbnail,
+	}}
+
+	return msg, uploaded.Handle, nil
+}
+
+func (service serviceNewsletter) buildVideoMessage(ctx context.Context, request domainNewsletter.SendMessageRequest) (*waE2E.Message, string, error) {
+	var (
+		source *utils.MediaSource
+		err    error
+	)
+	if request.VideoURL != nil && *request.VideoURL != "" {
+		source, err = utils.OpenVideoFromURL(*request.VideoURL)
+		if err != nil {
+			return nil, "", pkgError.InternalServerError(fmt.Sprintf("failed to download video from URL %v", err))
+		}
+	} else {
+		source, err = utils.OpenMultipartMedia(request.Video)
+		if err != nil {
+			return nil, "", pkgError.InternalServerError(fmt.Sprintf("failed to open video %v", err))
+		}
+	}
+	mimeType := source.MimeType
+
+	// Stream the video to disk once, it is both the seekable upload body and the ffmpeg input
+	videoPath := fmt.Sprintf("%s/%s.mp4", config.PathSendItems, fiberUtils.UUIDv4())
+	_, err = source.SaveTo(videoPath)
+	_ = source.Close()
+	if err != nil {
+		return nil, "", pkgError.InternalServerError(fmt.Sprintf("failed to store video in server %v", err))
+	}
+	defer func() {
+		go utils.RemoveFile(1, videoPath)
+	}()
+
+	video, err := os.Open(videoPath)
+	if err != nil {
+		return nil, "", pkgError.InternalServerError(fmt.Sprintf("failed to open video %v", err))
+	}
+	defer video.Close()
+
+	uploaded, err := whatsapp.GetClient().UploadNewsletterReader(ctx, video, whatsmeow.MediaVideo)
+	if err != nil {
+		return nil, "", pkgError.WaUploadMediaError(fmt.Sprintf("Failed to upload video: %v", err))
+	}
+
+	msg := &waE2E.Message{VideoMessage: &waE2E.VideoMessage{
+		URL:        proto.String(uploaded.URL),
+		DirectPath: proto.String(uploaded.DirectPath),
+		Mimetype:   proto.String(mimeType),
+		Caption:    proto.String(request.Caption),
+		FileSHA256: uploaded.FileSHA256,
+		FileLength: proto.Uint64(uploaded.FileLength),
+	}}
+
+	// The preview thumbnail is optional, publish without it when ffmpeg is unavailable
+	if thumbnail, err := utils.ExtractVideoThumbnail(ctx, videoPath, 1); err != nil {
+		logrus.Warnf("Failed to generate newsletter video thumbnail: %v, continue without thumbnail", err)
+	} else {
+		msg.VideoMessage.JPEGThumbnail = thumbnail
+	}
+
+	return msg, uploaded.Handle, nil
+}
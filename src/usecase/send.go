@@ -5,11 +5,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net/http"
+	"io"
+	"math"
+	"math/rand"
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/domains/app"
@@ -18,15 +22,14 @@ import (
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/whatsapp"
 	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
-	"github.com/aldinokemal/go-whatsapp-web-multidevice/ui/rest/helpers"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/validations"
-	"github.com/disintegration/imaging"
 	fiberUtils "github.com/gofiber/fiber/v2/utils"
 	"github.com/sirupsen/logrus"
 	"github.com/valyala/fasthttp"
 	"go.mau.fi/whatsmeow"
 	"go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -42,9 +45,63 @@ func NewSendService(appService app.IAppUsecase, chatStorageRepo domainChatStorag
 	}
 }
 
-// wrapSendMessage wraps the message sending process with message ID saving
-func (service serviceSend) wrapSendMessage(ctx context.Context, recipient types.JID, msg *waE2E.Message, content string) (whatsmeow.SendResponse, error) {
-	ts, err := whatsapp.GetClient().SendMessage(ctx, recipient, msg)
+// wrapSendMessage wraps the message sending process with message ID saving. With typing simulated the
+// message is sent in the background once the typing delay is over, the response only carries its ID.
+func (service serviceSend) wrapSendMessage(ctx context.Context, recipient types.JID, msg *waE2E.Message, content string, typing *bool) (whatsmeow.SendResponse, error) {
+	if typing == nil {
+		typing = &config.WhatsappSimulateTyping
+	}
+	if !*typing {
+		return service.sendAndStore(ctx, recipient, msg, content, whatsmeow.SendRequestExtra{})
+	}
+
+	// The request is done before the message goes out, its context must not cancel the send
+	id := whatsapp.GetClient().GenerateMessageID()
+	enqueueTypedMessage(recipient, func() {
+		service.simulateTyping(recipient, msg, content)
+		if _, err := service.sendAndStore(context.Background(), recipient, msg, content, whatsmeow.SendRequestExtra{ID: id}); err != nil {
+			logrus.Errorf("Failed to send message %s to %s after typing: %v", id, recipient.String(), err)
+		}
+	})
+	return whatsmeow.SendResponse{ID: id}, nil
+}
+
+// typedMessages holds the messages of each chat waiting for their typing delay, they are sent one after
+// another so a short message does not overtake a longer one queued before it
+var typedMessages = struct {
+	sync.Mutex
+	pending map[types.JID][]func()
+}{pending: make(map[types.JID][]func())}
+
+// enqueueTypedMessage queues a send for the chat, starting its worker when the chat has none running
+func enqueueTypedMessage(recipient types.JID, send func()) {
+	typedMessages.Lock()
+	queued, running := typedMessages.pending[recipient]
+	typedMessages.pending[recipient] = append(queued, send)
+	typedMessages.Unlock()
+	if running {
+		return
+	}
+
+	go func() {
+		for {
+			typedMessages.Lock()
+			queued := typedMessages.pending[recipient]
+			if len(queued) == 0 {
+				delete(typedMessages.pending, recipient)
+				typedMessages.Unlock()
+				return
+			}
+			typedMessages.pending[recipient] = queued[1:]
+			typedMessages.Unlock()
+
+			queued[0]()
+		}
+	}()
+}
+
+func (service serviceSend) sendAndStore(ctx context.Context, recipient types.JID, msg *waE2E.Message, content string, extra whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error) {
+	ts, err := whatsapp.GetClient().SendMessage(ctx, recipient, msg, extra)
 	if err != nil {
 		return whatsmeow.SendResponse{}, err
 	}
@@ -55,29 +112,40 @@ func (service serviceSend) wrapSendMessage(ctx context.Context, recipient types.
 		senderJID = whatsapp.GetClient().Store.ID.String()
 	}
 
-	// Store message asynchronously with timeout
-	// Use a goroutine to avoid blocking the send operation
-	go func() {
-		storeCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
+	// Stored before returning so receipts arriving right after the send find the message,
+	// receipts of messages that are not stored as sent are dropped
+	storeCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
 
-		if err := service.chatStorageRepo.StoreSentMessageWithContext(storeCtx, ts.ID, senderJID, recipient.String(), content, ts.Timestamp); err != nil {
-			if errors.Is(err, context.DeadlineExceeded) {
-				logrus.Warn("Timeout storing sent message")
-			} else {
-				logrus.Warnf("Failed to store sent message: %v", err)
-			}
+	if err := service.chatStorageRepo.StoreSentMessageWithContext(storeCtx, ts.ID, senderJID, recipient.String(), content, ts.Timestamp, msg); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			logrus.Warn("Timeout storing sent message")
+		} else {
+			logrus.Warnf("Failed to store sent message: %v", err)
 		}
-	}()
+	}
 
 	return ts, nil
 }
 
+// sentStatus completes the status of a sent message, a message still waiting for its typing delay
+// has no server timestamp yet
+func sentStatus(status string, ts whatsmeow.SendResponse) string {
+	if ts.Timestamp.IsZero() {
+		return status + " (sending after the typing delay)"
+	}
+	return fmt.Sprintf("%s (server timestamp: %s)", status, ts.Timestamp.String())
+}
+
 func (service serviceSend) SendText(ctx context.Context, request domainSend.MessageRequest) (response domainSend.GenericResponse, err error) {
 	err = validations.ValidateSendMessage(ctx, request)
 	if err != nil {
 		return response, err
 	}
+	// Converted before mentions are parsed so they see the final text
+	if request.Format == utils.MessageFormatMarkdown {
+		request.Message = utils.MarkdownToWhatsApp(request.Message)
+	}
 	dataWaRecipient, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.BaseRequest.Phone)
 	if err != nil {
 		return response, err
@@ -111,7 +179,7 @@ func (service serviceSend) SendText(ctx context.Context, request domainSend.Mess
 
 	// Reply message
 	if request.ReplyMessageID != nil && *request.ReplyMessageID != "" {
-		message, err := service.chatStorageRepo.GetMessageByID(*request.ReplyMessageID)
+		message, err := service.chatStorageRepo.GetFullMessage(*request.ReplyMessageID, "")
 		if err != nil {
 			logrus.Warnf("Error retrieving reply message ID %s: %v, continuing without reply context", *request.ReplyMessageID, err)
 		} else if message != nil { // Only set reply context if we found the message
@@ -121,13 +189,16 @@ func (service serviceSend) SendText(ctx context.Context, request domainSend.Mess
 			// the JID here to prevent corrupting valid group or special JIDs.
 			participantJID := message.Sender
 
+			quoted, err := quotedMessage(message)
+			if err != nil {
+				return response, err
+			}
+
 			// Build base ContextInfo with reply details
 			ctxInfo := &waE2E.ContextInfo{
-				StanzaID:    request.ReplyMessageID,
-				Participant: proto.String(participantJID),
-				QuotedMessage: &waE2E.Message{
-					Conversation: proto.String(message.Content),
-				},
+				StanzaID:      request.ReplyMessageID,
+				Participant:   proto.String(participantJID),
+				QuotedMessage: quoted,
 			}
 
 			// Preserve forwarding flag if set
@@ -157,13 +228,17 @@ func (service serviceSend) SendText(ctx context.Context, request domainSend.Mess
 		}
 	}
 
-	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, request.Message)
+	if request.BaseRequest.DryRun {
+		return service.dryRunResponse(dataWaRecipient, msg, 0)
+	}
+
+	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, request.Message, request.BaseRequest.SimulateTyping)
 	if err != nil {
 		return response, err
 	}
 
 	response.MessageID = ts.ID
-	response.Status = fmt.Sprintf("Message sent to %s (server timestamp: %s)", request.Phone, ts.Timestamp.String())
+	response.Status = sentStatus(fmt.Sprintf("Message sent to %s", request.Phone), ts)
 	return response, nil
 }
 
@@ -172,125 +247,57 @@ func (service serviceSend) SendImage(ctx context.Context, request domainSend.Ima
 	if err != nil {
 		return response, err
 	}
+	if request.Format == utils.MessageFormatMarkdown {
+		request.Caption = utils.MarkdownToWhatsApp(request.Caption)
+	}
 	dataWaRecipient, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.Phone)
 	if err != nil {
 		return response, err
 	}
 
-	var (
-		imagePath      string
-		imageThumbnail string
-		imageName      string
-		deletedItems   []string
-		oriImagePath   string
-	)
-
+	// Uploads are decoded straight from the file they were spooled to, only URLs are downloaded into memory
+	var image io.ReadSeeker
+	var originalSize int
 	if request.ImageURL != nil && *request.ImageURL != "" {
-		// Download image from URL
-		imageData, fileName, err := utils.DownloadImageFromURL(*request.ImageURL)
-		if err != nil {
-			return response, pkgError.InternalServerError(fmt.Sprintf("failed to download image from URL %v", err))
-		}
-
-		// Check if the downloaded image is WebP and convert to PNG if needed
-		mimeType := http.DetectContentType(imageData)
-		if mimeType == "image/webp" {
-			// Convert WebP to PNG
-			webpImage, err := imaging.Decode(bytes.NewReader(imageData))
-			if err != nil {
-				return response, pkgError.InternalServerError(fmt.Sprintf("failed to decode WebP image %v", err))
-			}
-
-			// Change file extension to PNG
-			if strings.HasSuffix(strings.ToLower(fileName), ".webp") {
-				fileName = fileName[:len(fileName)-5] + ".png"
-			} else {
-				fileName = fileName + ".png"
-			}
-
-			// Convert to PNG format
-			var pngBuffer bytes.Buffer
-			err = imaging.Encode(&pngBuffer, webpImage, imaging.PNG)
-			if err != nil {
-				return response, pkgError.InternalServerError(fmt.Sprintf("failed to convert WebP to PNG %v", err))
-			}
-			imageData = pngBuffer.Bytes()
-		}
-
-		oriImagePath = fmt.Sprintf("%s/%s", config.PathSendItems, fileName)
-		imageName = fileName
-		err = os.WriteFile(oriImagePath, imageData, 0644)
-		if err != nil {
-			return response, pkgError.InternalServerError(fmt.Sprintf("failed to save downloaded image %v", err))
+		imageData, _, errDownload := utils.DownloadImageFromURL(*request.ImageURL)
+		if errDownload != nil {
+			return response, pkgError.InternalServerError(fmt.Sprintf("failed to download image from URL %v", errDownload))
 		}
+		image, originalSize = bytes.NewReader(imageData), len(imageData)
 	} else if request.Image != nil {
-		// Save image to server
-		oriImagePath = fmt.Sprintf("%s/%s", config.PathSendItems, request.Image.Filename)
-		err = fasthttp.SaveMultipartFile(request.Image, oriImagePath)
-		if err != nil {
-			return response, err
+		file, errOpen := request.Image.Open()
+		if errOpen != nil {
+			return response, pkgError.InternalServerError(fmt.Sprintf("failed to open image %v", errOpen))
 		}
-		imageName = request.Image.Filename
+		defer file.Close()
+		image, originalSize = file, int(request.Image.Size)
 	}
-	deletedItems = append(deletedItems, oriImagePath)
 
-	/* Generate thumbnail with smalled image size */
-	srcImage, err := imaging.Open(oriImagePath)
+	// Apply EXIF orientation, strip metadata, compress if requested and build the thumbnail
+	processed, err := utils.ProcessImageReader(image, request.Compress)
 	if err != nil {
-		return response, pkgError.InternalServerError(fmt.Sprintf("Failed to open image file '%s' for thumbnail generation: %v. Possible causes: file not found, unsupported format, or permission denied.", oriImagePath, err))
-	}
-
-	// Resize Thumbnail
-	resizedImage := imaging.Resize(srcImage, 100, 0, imaging.Lanczos)
-	imageThumbnail = fmt.Sprintf("%s/thumbnails-%s", config.PathSendItems, imageName)
-	if err = imaging.Save(resizedImage, imageThumbnail); err != nil {
-		return response, pkgError.InternalServerError(fmt.Sprintf("failed to save thumbnail %v", err))
-	}
-	deletedItems = append(deletedItems, imageThumbnail)
-
-	if request.Compress {
-		// Resize image
-		openImageBuffer, err := imaging.Open(oriImagePath)
-		if err != nil {
-			return response, pkgError.InternalServerError(fmt.Sprintf("Failed to open image file '%s' for compression: %v. Possible causes: file not found, unsupported format, or permission denied.", oriImagePath, err))
-		}
-		newImage := imaging.Resize(openImageBuffer, 600, 0, imaging.Lanczos)
-		newImagePath := fmt.Sprintf("%s/new-%s", config.PathSendItems, imageName)
-		if err = imaging.Save(newImage, newImagePath); err != nil {
-			return response, pkgError.InternalServerError(fmt.Sprintf("failed to save image %v", err))
-		}
-		deletedItems = append(deletedItems, newImagePath)
-		imagePath = newImagePath
-	} else {
-		imagePath = oriImagePath
+		return response, pkgError.InternalServerError(fmt.Sprintf("failed to process image %v", err))
 	}
 
 	// Send to WA server
-	dataWaCaption := request.Caption
-	dataWaImage, err := os.ReadFile(imagePath)
-	if err != nil {
-		return response, err
-	}
-	uploadedImage, err := service.uploadMedia(ctx, whatsmeow.MediaImage, dataWaImage, dataWaRecipient)
+	uploadedImage, err := service.uploadMedia(ctx, whatsmeow.MediaImage, bytes.NewReader(processed.Data), dataWaRecipient, request.BaseRequest.DryRun)
 	if err != nil {
 		fmt.Printf("failed to upload file: %v", err)
 		return response, err
 	}
-	dataWaThumbnail, err := os.ReadFile(imageThumbnail)
-	if err != nil {
-		return response, pkgError.InternalServerError(fmt.Sprintf("failed to read thumbnail %v", err))
-	}
 
 	msg := &waE2E.Message{ImageMessage: &waE2E.ImageMessage{
-		JPEGThumbnail: dataWaThumbnail,
-		Caption:       proto.String(dataWaCaption),
+		JPEGThumbnail: processed.Thumbnail,
+		Caption:       proto.String(request.Caption),
 		URL:           proto.String(uploadedImage.URL),
 		DirectPath:    proto.String(uploadedImage.DirectPath),
 		MediaKey:      uploadedImage.MediaKey,
-		Mimetype:      proto.String(http.DetectContentType(dataWaImage)),
+		Mimetype:      proto.String(processed.MimeType),
 		FileEncSHA256: uploadedImage.FileEncSHA256,
 		FileSHA256:    uploadedImage.FileSHA256,
-		FileLength:    proto.Uint64(uint64(len(dataWaImage))),
+		FileLength:    proto.Uint64(uploadedImage.FileLength),
+		Width:         proto.Uint32(uint32(processed.Width)),
+		Height:        proto.Uint32(uint32(processed.Height)),
 		ViewOnce:      proto.Bool(request.ViewOnce),
 	}}
 
@@ -313,19 +320,17 @@ func (service serviceSend) SendImage(ctx context.Context, request domainSend.Ima
 	if request.Caption != "" {
 		caption = "🖼️ " + request.Caption
 	}
-	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, caption)
-	go func() {
-		errDelete := utils.RemoveFile(0, deletedItems...)
-		if errDelete != nil {
-			fmt.Println("error when deleting picture: ", errDelete)
-		}
-	}()
+	if request.BaseRequest.DryRun {
+		return service.dryRunResponse(dataWaRecipient, msg, originalSize)
+	}
+
+	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, caption, request.BaseRequest.SimulateTyping)
 	if err != nil {
 		return response, err
 	}
 
 	response.MessageID = ts.ID
-	response.Status = fmt.Sprintf("Message sent to %s (server timestamp: %s)", request.BaseRequest.Phone, ts.Timestamp.String())
+	response.Status = sentStatus(fmt.Sprintf("Message sent to %s", request.BaseRequest.Phone), ts)
 	return response, nil
 }
 
@@ -334,16 +339,23 @@ func (service serviceSend) SendFile(ctx context.Context, request domainSend.File
 	if err != nil {
 		return response, err
 	}
+	if request.Format == utils.MessageFormatMarkdown {
+		request.Caption = utils.MarkdownToWhatsApp(request.Caption)
+	}
 	dataWaRecipient, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.BaseRequest.Phone)
 	if err != nil {
 		return response, err
 	}
 
-	fileBytes := helpers.MultipartFormFileHeaderToBytes(request.File)
-	fileMimeType := http.DetectContentType(fileBytes)
+	file, err := utils.OpenMultipartMedia(request.File)
+	if err != nil {
+		return response, pkgError.InternalServerError(fmt.Sprintf("failed to open file %v", err))
+	}
+	defer file.Close()
+	fileMimeType := file.MimeType
 
 	// Send to WA server
-	uploadedFile, err := service.uploadMedia(ctx, whatsmeow.MediaDocument, fileBytes, dataWaRecipient)
+	uploadedFile, err := service.uploadMedia(ctx, whatsmeow.MediaDocument, file.Reader, dataWaRecipient, request.BaseRequest.DryRun)
 	if err != nil {
 		fmt.Printf("Failed to upload file: %v", err)
 		return response, err
@@ -380,13 +392,18 @@ func (service serviceSend) SendFile(ctx context.Context, request domainSend.File
 	if request.Caption != "" {
 		caption = "📄 " + request.Caption
 	}
-	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, caption)
+
+	if request.BaseRequest.DryRun {
+		return service.dryRunResponse(dataWaRecipient, msg, int(request.File.Size))
+	}
+
+	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, caption, request.BaseRequest.SimulateTyping)
 	if err != nil {
 		return response, err
 	}
 
 	response.MessageID = ts.ID
-	response.Status = fmt.Sprintf("Document sent to %s (server timestamp: %s)", request.BaseRequest.Phone, ts.Timestamp.String())
+	response.Status = sentStatus(fmt.Sprintf("Document sent to %s", request.BaseRequest.Phone), ts)
 	return response, nil
 }
 
@@ -395,15 +412,17 @@ func (service serviceSend) SendVideo(ctx context.Context, request domainSend.Vid
 	if err != nil {
 		return response, err
 	}
+	if request.Format == utils.MessageFormatMarkdown {
+		request.Caption = utils.MarkdownToWhatsApp(request.Caption)
+	}
 	dataWaRecipient, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.BaseRequest.Phone)
 	if err != nil {
 		return response, err
 	}
 
 	var (
-		videoPath      string
-		videoThumbnail string
-		deletedItems   []string
+		videoPath    string
+		deletedItems []string
 	)
 
 	// Ensure temporary files are always removed, even on early returns
@@ -416,20 +435,30 @@ func (service serviceSend) SendVideo(ctx context.Context, request domainSend.Vid
 
 	generateUUID := fiberUtils.UUIDv4()
 
-	var oriVideoPath string
+	var (
+		oriVideoPath string
+		originalSize int
+	)
 
 	// Determine source of video (URL or uploaded file)
 	if request.VideoURL != nil && *request.VideoURL != "" {
-		// Download video bytes
-		videoBytes, fileName, errDownload := utils.DownloadVideoFromURL(*request.VideoURL)
+		// Stream the download straight to disk, ffmpeg needs a file to work on
+		openSource := utils.OpenVideoFromURL
+		if request.GifPlayback {
+			openSource = utils.OpenGIFFromURL
+		}
+		source, errDownload := openSource(*request.VideoURL)
 		if errDownload != nil {
 			return response, pkgError.InternalServerError(fmt.Sprintf("failed to download video from URL %v", errDownload))
 		}
-		// Build file path to save the downloaded video temporarily
-		oriVideoPath = fmt.Sprintf("%s/%s", config.PathSendItems, generateUUID+fileName)
-		if errWrite := os.WriteFile(oriVideoPath, videoBytes, 0644); errWrite != nil {
+		oriVideoPath = fmt.Sprintf("%s/%s", config.PathSendItems, generateUUID+source.FileName)
+		written, errWrite := source.SaveTo(oriVideoPath)
+		_ = source.Close()
+		if errWrite != nil {
 			return response, pkgError.InternalServerError(fmt.Sprintf("failed to store downloaded video in server %v", errWrite))
 		}
+		deletedItems = append(deletedItems, oriVideoPath)
+		originalSize = int(written)
 	} else if request.Video != nil {
 		// Save uploaded video to server
 		oriVideoPath = fmt.Sprintf("%s/%s", config.PathSendItems, generateUUID+request.Video.Filename)
@@ -437,93 +466,99 @@ func (service serviceSend) SendVideo(ctx context.Context, request domainSend.Vid
 		if err != nil {
 			return response, pkgError.InternalServerError(fmt.Sprintf("failed to store video in server %v", err))
 		}
+		deletedItems = append(deletedItems, oriVideoPath)
+		originalSize = int(request.Video.Size)
 	} else {
 		// This should not happen due to validation, but guard anyway
 		return response, pkgError.ValidationError("either Video or VideoURL must be provided")
 	}
 
 	// Check if ffmpeg is installed
-	_, err = exec.LookPath("ffmpeg")
-	if err != nil {
+	if _, err = exec.LookPath("ffmpeg"); err != nil {
 		return response, pkgError.InternalServerError("ffmpeg not installed")
 	}
+	if _, err = exec.LookPath("ffprobe"); err != nil {
+		return response, pkgError.InternalServerError("ffprobe not installed")
+	}
 
-	// Generate thumbnail using ffmpeg
-	thumbnailVideoPath := fmt.Sprintf("%s/%s", config.PathSendItems, generateUUID+".png")
-	cmdThumbnail := exec.Command("ffmpeg", "-i", oriVideoPath, "-ss", "00:00:01.000", "-vframes", "1", thumbnailVideoPath)
-	err = cmdThumbnail.Run()
+	// Probe the input and transcode it when phones would not play it or it is too large
+	sourceInfo, err := utils.ProbeVideo(ctx, oriVideoPath)
 	if err != nil {
-		return response, pkgError.InternalServerError(fmt.Sprintf("failed to create thumbnail %v", err))
+		return response, pkgError.ValidationError(fmt.Sprintf("unable to read video: %v", err))
+	}
+	// GIF playback is muted on phones, so GIFs and videos sent as GIF become silent MP4s
+	plan, err := utils.PlanVideoTranscode(sourceInfo, utils.VideoTranscodeOptions{
+		MaxSize:  config.WhatsappSettingMaxVideoSize,
+		Compress: request.Compress,
+		Silent:   request.GifPlayback,
+	})
+	if err != nil {
+		return response, pkgError.ValidationError(err.Error())
 	}
 
-	// Resize Thumbnail
-	srcImage, err := imaging.Open(thumbnailVideoPath)
-	if err != nil {
-		return response, pkgError.InternalServerError(fmt.Sprintf("Failed to open generated video thumbnail image '%s': %v. Possible causes: file not found, unsupported format, or permission denied.", thumbnailVideoPath, err))
-	}
-	resizedImage := imaging.Resize(srcImage, 100, 0, imaging.Lanczos)
-	thumbnailResizeVideoPath := fmt.Sprintf("%s/thumbnails-%s", config.PathSendItems, generateUUID+".png")
-	if err = imaging.Save(resizedImage, thumbnailResizeVideoPath); err != nil {
-		return response, pkgError.InternalServerError(fmt.Sprintf("failed to save thumbnail %v", err))
-	}
-
-	deletedItems = append(deletedItems, thumbnailVideoPath)
-	deletedItems = append(deletedItems, thumbnailResizeVideoPath)
-	videoThumbnail = thumbnailResizeVideoPath
-
-	// Compress if requested
-	if request.Compress {
-		compresVideoPath := fmt.Sprintf("%s/%s", config.PathSendItems, generateUUID+".mp4")
-
-		// Use proper compression settings to reduce file size
-		// -crf 28: Constant Rate Factor (18-28 is good range, higher = smaller file)
-		// -preset medium: Balance between encoding speed and compression efficiency
-		// -c:v libx264: Use H.264 codec for video
-		// -c:a aac: Use AAC codec for audio
-		// -movflags +faststart: Optimize for web streaming
-		// -vf scale=720:-2: Scale video to max width 720px, maintain aspect ratio
-		cmdCompress := exec.Command("ffmpeg", "-i", oriVideoPath,
-			"-c:v", "libx264",
-			"-crf", "28",
-			"-preset", "fast",
-			"-vf", "scale=720:-2",
-			"-c:a", "aac",
-			"-b:a", "128k",
-			"-movflags", "+faststart",
-			"-y", // Overwrite output file if it exists
-			compresVideoPath)
-
-		// Capture both stdout and stderr for better error reporting
-		output, err := cmdCompress.CombinedOutput()
-		if err != nil {
-			logrus.Errorf("ffmpeg compression failed: %v, output: %s", err, string(output))
-			return response, pkgError.InternalServerError(fmt.Sprintf("failed to compress video: %v", err))
+	videoPath = oriVideoPath
+	videoInfo := sourceInfo
+	if plan.Needed() {
+		action := "Transcoding"
+		if plan.Remux {
+			action = "Remuxing"
+		}
+		logrus.Infof("%s video before sending: %s", action, strings.Join(plan.Reasons, ", "))
+		transcodedPath := fmt.Sprintf("%s/%s", config.PathSendItems, generateUUID+".mp4")
+		deletedItems = append(deletedItems, transcodedPath)
+		if err = utils.TranscodeVideo(ctx, oriVideoPath, transcodedPath, plan); err != nil {
+			logrus.Errorf("%v", err)
+			return response, pkgError.InternalServerError(fmt.Sprintf("failed to transcode video: %v", err))
 		}
 
-		videoPath = compresVideoPath
-		deletedItems = append(deletedItems, compresVideoPath)
-	} else {
-		videoPath = oriVideoPath
+		videoPath = transcodedPath
+		if videoInfo, err = utils.ProbeVideo(ctx, transcodedPath); err != nil {
+			return response, pkgError.InternalServerError(fmt.Sprintf("failed to read transcoded video %v", err))
+		}
+		if videoInfo.Size > config.WhatsappSettingMaxVideoSize {
+			return response, pkgError.ValidationError(fmt.Sprintf("transcoded video size %d still exceeds maximum allowed size %d", videoInfo.Size, config.WhatsappSettingMaxVideoSize))
+		}
+	}
+
+	// The preview is generated straight from the video, without intermediate files
+	dataWaThumbnail, err := utils.ExtractVideoThumbnail(ctx, videoPath, videoInfo.ThumbnailOffset())
+	if err != nil {
+		return response, pkgError.InternalServerError(fmt.Sprintf("failed to create thumbnail %v", err))
 	}
-	deletedItems = append(deletedItems, oriVideoPath)
 
 	//Send to WA server
-	dataWaVideo, err := os.ReadFile(videoPath)
+	dataWaVideo, err := os.Open(videoPath)
 	if err != nil {
 		return response, err
 	}
-	uploaded, err := service.uploadMedia(ctx, whatsmeow.MediaVideo, dataWaVideo, dataWaRecipient)
+	defer dataWaVideo.Close()
+	videoMimeType, err := utils.DetectReaderContentType(dataWaVideo)
 	if err != nil {
-		return response, pkgError.InternalServerError(fmt.Sprintf("Failed to upload file: %v", err))
+		return response, pkgError.InternalServerError(fmt.Sprintf("failed to read video %v", err))
 	}
-	dataWaThumbnail, err := os.ReadFile(videoThumbnail)
+	uploaded, err := service.uploadMedia(ctx, whatsmeow.MediaVideo, dataWaVideo, dataWaRecipient, request.BaseRequest.DryRun)
 	if err != nil {
-		return response, err
+		return response, pkgError.InternalServerError(fmt.Sprintf("Failed to upload file: %v", err))
+	}
+
+	transcode := &domainSend.VideoTranscodeResponse{
+		Transcoded:       plan.Needed(),
+		Remuxed:          plan.Remux,
+		Reasons:          plan.Reasons,
+		SourceFormat:     sourceInfo.FormatName,
+		SourceVideoCodec: sourceInfo.VideoCodec,
+		SourceAudioCodec: sourceInfo.AudioCodec,
+		OriginalSize:     int64(originalSize),
+		FinalSize:        int64(uploaded.FileLength),
+		VideoBitrate:     plan.VideoBitrate,
+		Duration:         uint32(math.Round(videoInfo.Duration)),
+		Width:            videoInfo.Width,
+		Height:           videoInfo.Height,
 	}
 
 	msg := &waE2E.Message{VideoMessage: &waE2E.VideoMessage{
 		URL:                 proto.String(uploaded.URL),
-		Mimetype:            proto.String(http.DetectContentType(dataWaVideo)),
+		Mimetype:            proto.String(videoMimeType),
 		Caption:             proto.String(request.Caption),
 		FileLength:          proto.Uint64(uploaded.FileLength),
 		FileSHA256:          uploaded.FileSHA256,
@@ -531,6 +566,10 @@ func (service serviceSend) SendVideo(ctx context.Context, request domainSend.Vid
 		MediaKey:            uploaded.MediaKey,
 		DirectPath:          proto.String(uploaded.DirectPath),
 		ViewOnce:            proto.Bool(request.ViewOnce),
+		GifPlayback:         proto.Bool(request.GifPlayback),
+		Seconds:             proto.Uint32(transcode.Duration),
+		Width:               proto.Uint32(uint32(transcode.Width)),
+		Height:              proto.Uint32(uint32(transcode.Height)),
 		JPEGThumbnail:       dataWaThumbnail,
 		ThumbnailEncSHA256:  dataWaThumbnail,
 		ThumbnailSHA256:     dataWaThumbnail,
@@ -555,13 +594,27 @@ func (service serviceSend) SendVideo(ctx context.Context, request domainSend.Vid
 	if request.Caption != "" {
 		caption = "🎥 " + request.Caption
 	}
-	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, caption)
+	if request.GifPlayback {
+		caption = "🎞️ GIF"
+		if request.Caption != "" {
+			caption = "🎞️ " + request.Caption
+		}
+	}
+
+	if request.BaseRequest.DryRun {
+		response, err = service.dryRunResponse(dataWaRecipient, msg, originalSize)
+		response.Transcode = transcode
+		return response, err
+	}
+
+	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, caption, request.BaseRequest.SimulateTyping)
 	if err != nil {
 		return response, err
 	}
 
 	response.MessageID = ts.ID
-	response.Status = fmt.Sprintf("Video sent to %s (server timestamp: %s)", request.BaseRequest.Phone, ts.Timestamp.String())
+	response.Status = sentStatus(fmt.Sprintf("Video sent to %s", request.BaseRequest.Phone), ts)
+	response.Transcode = transcode
 	return response, nil
 }
 
@@ -598,13 +651,17 @@ func (service serviceSend) SendContact(ctx context.Context, request domainSend.C
 
 	content := "👤 " + request.ContactName
 
-	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, content)
+	if request.BaseRequest.DryRun {
+		return service.dryRunResponse(dataWaRecipient, msg, 0)
+	}
+
+	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, content, request.BaseRequest.SimulateTyping)
 	if err != nil {
 		return response, err
 	}
 
 	response.MessageID = ts.ID
-	response.Status = fmt.Sprintf("Contact sent to %s (server timestamp: %s)", request.BaseRequest.Phone, ts.Timestamp.String())
+	response.Status = sentStatus(fmt.Sprintf("Contact sent to %s", request.BaseRequest.Phone), ts)
 	return response, nil
 }
 
@@ -613,6 +670,9 @@ func (service serviceSend) SendLink(ctx context.Context, request domainSend.Link
 	if err != nil {
 		return response, err
 	}
+	if request.Format == utils.MessageFormatMarkdown {
+		request.Caption = utils.MarkdownToWhatsApp(request.Caption)
+	}
 	dataWaRecipient, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.BaseRequest.Phone)
 	if err != nil {
 		return response, err
@@ -653,9 +713,9 @@ func (service serviceSend) SendLink(ctx context.Context, request domainSend.Link
 		msg.ExtendedTextMessage.ContextInfo.Expiration = proto.Uint32(uint32(*request.BaseRequest.Duration))
 	}
 
-	// If we have a thumbnail image, upload it to WhatsApp's servers
-	if len(metadata.ImageThumb) > 0 && metadata.Height != nil && metadata.Width != nil {
-		uploadedThumb, err := service.uploadMedia(ctx, whatsmeow.MediaLinkThumbnail, metadata.ImageThumb, dataWaRecipient)
+	// If we have a thumbnail image, upload it to WhatsApp's servers, a dry run leaves the upload fields empty
+	if !request.BaseRequest.DryRun && len(metadata.ImageThumb) > 0 && metadata.Height != nil && metadata.Width != nil {
+		uploadedThumb, err := service.uploadMedia(ctx, whatsmeow.MediaLinkThumbnail, bytes.NewReader(metadata.ImageThumb), dataWaRecipient, false)
 		if err == nil {
 			// Update the message with the uploaded thumbnail information
 			msg.ExtendedTextMessage.ThumbnailDirectPath = proto.String(uploadedThumb.DirectPath)
@@ -673,13 +733,18 @@ func (service serviceSend) SendLink(ctx context.Context, request domainSend.Link
 	if request.Caption != "" {
 		content = "🔗 " + request.Caption
 	}
-	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, content)
+
+	if request.BaseRequest.DryRun {
+		return service.dryRunResponse(dataWaRecipient, msg, 0)
+	}
+
+	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, content, request.BaseRequest.SimulateTyping)
 	if err != nil {
 		return response, err
 	}
 
 	response.MessageID = ts.ID
-	response.Status = fmt.Sprintf("Link sent to %s (server timestamp: %s)", request.BaseRequest.Phone, ts.Timestamp.String())
+	response.Status = sentStatus(fmt.Sprintf("Link sent to %s", request.BaseRequest.Phone), ts)
 	return response, nil
 }
 
@@ -717,14 +782,18 @@ func (service serviceSend) SendLocation(ctx context.Context, request domainSend.
 
 	content := "📍 " + request.Latitude + ", " + request.Longitude
 
+	if request.BaseRequest.DryRun {
+		return service.dryRunResponse(dataWaRecipient, msg, 0)
+	}
+
 	// Send WhatsApp Message Proto
-	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, content)
+	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, content, request.BaseRequest.SimulateTyping)
 	if err != nil {
 		return response, err
 	}
 
 	response.MessageID = ts.ID
-	response.Status = fmt.Sprintf("Send location success %s (server timestamp: %s)", request.BaseRequest.Phone, ts.Timestamp.String())
+	response.Status = sentStatus(fmt.Sprintf("Send location success %s", request.BaseRequest.Phone), ts)
 	return response, nil
 }
 
@@ -740,25 +809,26 @@ func (service serviceSend) SendAudio(ctx context.Context, request domainSend.Aud
 		return response, err
 	}
 
-	var (
-		audioBytes    []byte
-		audioMimeType string
-	)
-
-	// Handle audio from URL or file
+	// Handle audio from URL or file, both are streamed to the upload without buffering
+	var audio *utils.MediaSource
 	if request.AudioURL != nil && *request.AudioURL != "" {
-		audioBytes, _, err = utils.DownloadAudioFromURL(*request.AudioURL)
+		audio, err = utils.OpenAudioFromURL(*request.AudioURL)
 		if err != nil {
 			return response, pkgError.InternalServerError(fmt.Sprintf("failed to download audio from URL %v", err))
 		}
-		audioMimeType = http.DetectContentType(audioBytes)
 	} else if request.Audio != nil {
-		audioBytes = helpers.MultipartFormFileHeaderToBytes(request.Audio)
-		audioMimeType = http.DetectContentType(audioBytes)
+		audio, err = utils.OpenMultipartMedia(request.Audio)
+		if err != nil {
+			return response, pkgError.InternalServerError(fmt.Sprintf("failed to open audio %v", err))
+		}
+	} else {
+		return response, pkgError.ValidationError("either Audio or AudioURL must be provided")
 	}
+	defer audio.Close()
+	audioMimeType := audio.MimeType
 
 	// upload to WhatsApp servers
-	audioUploaded, err := service.uploadMedia(ctx, whatsmeow.MediaAudio, audioBytes, dataWaRecipient)
+	audioUploaded, err := service.uploadMedia(ctx, whatsmeow.MediaAudio, audio.Reader, dataWaRecipient, request.BaseRequest.DryRun)
 	if err != nil {
 		err = pkgError.WaUploadMediaError(fmt.Sprintf("Failed to upload audio: %v", err))
 		return response, err
@@ -792,13 +862,17 @@ func (service serviceSend) SendAudio(ctx context.Context, request domainSend.Aud
 
 	content := "🎵 Audio"
 
-	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, content)
+	if request.BaseRequest.DryRun {
+		return service.dryRunResponse(dataWaRecipient, msg, int(audioUploaded.FileLength))
+	}
+
+	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, content, request.BaseRequest.SimulateTyping)
 	if err != nil {
 		return response, err
 	}
 
 	response.MessageID = ts.ID
-	response.Status = fmt.Sprintf("Send audio success %s (server timestamp: %s)", request.BaseRequest.Phone, ts.Timestamp.String())
+	response.Status = sentStatus(fmt.Sprintf("Send audio success %s", request.BaseRequest.Phone), ts)
 	return response, nil
 }
 
@@ -823,13 +897,17 @@ func (service serviceSend) SendPoll(ctx context.Context, request domainSend.Poll
 		msg.PollCreationMessage.ContextInfo.Expiration = proto.Uint32(uint32(*request.BaseRequest.Duration))
 	}
 
-	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, content)
+	if request.BaseRequest.DryRun {
+		return service.dryRunResponse(dataWaRecipient, msg, 0)
+	}
+
+	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, content, request.BaseRequest.SimulateTyping)
 	if err != nil {
 		return response, err
 	}
 
 	response.MessageID = ts.ID
-	response.Status = fmt.Sprintf("Send poll success %s (server timestamp: %s)", request.BaseRequest.Phone, ts.Timestamp.String())
+	response.Status = sentStatus(fmt.Sprintf("Send poll success %s", request.BaseRequest.Phone), ts)
 	return response, nil
 }
 
@@ -839,6 +917,11 @@ func (service serviceSend) SendPresence(ctx context.Context, request domainSend.
 		return response, err
 	}
 
+	if request.DryRun {
+		response.Status = fmt.Sprintf("Dry run: presence %s was not sent", request.Type)
+		return response, nil
+	}
+
 	err = whatsapp.GetClient().SendPresence(types.Presence(request.Type))
 	if err != nil {
 		return response, err
@@ -877,6 +960,12 @@ func (service serviceSend) SendChatPresence(ctx context.Context, request domainS
 		return response, fmt.Errorf("invalid action: %s. Must be 'start' or 'stop'", request.Action)
 	}
 
+	if request.DryRun {
+		response.Status = fmt.Sprintf("Dry run: chat presence %s to %s was not sent", request.Action, userJid.String())
+		response.DryRun = &domainSend.DryRunResponse{RecipientJID: userJid.String()}
+		return response, nil
+	}
+
 	err = whatsapp.GetClient().SendChatPresence(userJid, presenceType, "")
 	if err != nil {
 		return response, err
@@ -898,13 +987,111 @@ func (service serviceSend) getMentionFromText(_ context.Context, messages string
 	return result
 }
 
-func (service serviceSend) uploadMedia(ctx context.Context, mediaType whatsmeow.MediaType, media []byte, recipient types.JID) (uploaded whatsmeow.UploadResponse, err error) {
-	if recipient.Server == types.NewsletterServer {
-		uploaded, err = whatsapp.GetClient().UploadNewsletter(ctx, media, mediaType)
-	} else {
-		uploaded, err = whatsapp.GetClient().Upload(ctx, media, mediaType)
+// uploadMedia streams the media through hashing and encryption to the WhatsApp servers.
+// The encrypted copy is spooled to a temp file because its hash is needed before the upload starts.
+func (service serviceSend) uploadMedia(ctx context.Context, mediaType whatsmeow.MediaType, media io.Reader, recipient types.JID, dryRun bool) (uploaded whatsmeow.UploadResponse, err error) {
+	// A dry run stops before the upload, only report what would have been uploaded
+	if dryRun {
+		size, err := io.Copy(io.Discard, media)
+		return whatsmeow.UploadResponse{FileLength: uint64(size)}, err
+	}
+
+	if recipient.Server != types.NewsletterServer {
+		return whatsapp.GetClient().UploadReader(ctx, media, nil, mediaType)
+	}
+
+	// Newsletter media is sent unencrypted but the upload needs to know its hash and length first
+	seeker, ok := media.(io.ReadSeeker)
+	if !ok {
+		spooled, err := utils.SpoolToTempFile(media, "newsletter-upload-*")
+		if err != nil {
+			return uploaded, err
+		}
+		defer func() {
+			_ = spooled.Close()
+			_ = os.Remove(spooled.Name())
+		}()
+		seeker = spooled
+	}
+	return whatsapp.GetClient().UploadNewsletterReader(ctx, seeker, mediaType)
+}
+
+// dryRunResponse describes the message that would have been sent to the recipient without sending it
+func (service serviceSend) dryRunResponse(recipient types.JID, msg *waE2E.Message, originalSize int) (response domainSend.GenericResponse, err error) {
+	renderedMessage, err := protojson.Marshal(msg)
+	if err != nil {
+		return response, pkgError.InternalServerError(fmt.Sprintf("failed to render message %v", err))
+	}
+
+	dryRun := &domainSend.DryRunResponse{
+		RecipientJID: recipient.String(),
+		OriginalSize: originalSize,
+		PayloadSize:  proto.Size(msg),
+		Message:      renderedMessage,
+	}
+
+	switch {
+	case msg.GetImageMessage() != nil:
+		dryRun.MimeType = msg.GetImageMessage().GetMimetype()
+		dryRun.FinalSize = int(msg.GetImageMessage().GetFileLength())
+		dryRun.ThumbnailSize = len(msg.GetImageMessage().GetJPEGThumbnail())
+	case msg.GetVideoMessage() != nil:
+		dryRun.MimeType = msg.GetVideoMessage().GetMimetype()
+		dryRun.FinalSize = int(msg.GetVideoMessage().GetFileLength())
+		dryRun.ThumbnailSize = len(msg.GetVideoMessage().GetJPEGThumbnail())
+	case msg.GetAudioMessage() != nil:
+		dryRun.MimeType = msg.GetAudioMessage().GetMimetype()
+		dryRun.FinalSize = int(msg.GetAudioMessage().GetFileLength())
+	case msg.GetDocumentMessage() != nil:
+		dryRun.MimeType = msg.GetDocumentMessage().GetMimetype()
+		dryRun.FinalSize = int(msg.GetDocumentMessage().GetFileLength())
+	case msg.GetExtendedTextMessage() != nil:
+		dryRun.ThumbnailSize = len(msg.GetExtendedTextMessage().GetJPEGThumbnail())
+	}
+
+	response.Status = fmt.Sprintf("Dry run: message to %s was not sent", recipient.String())
+	response.DryRun = dryRun
+	return response, nil
+}
+
+// simulateTyping shows a typing (or recording for audio) indicator in the chat for a delay proportional
+// to the content length before the message goes out
+func (service serviceSend) simulateTyping(recipient types.JID, msg *waE2E.Message, content string) {
+	media := types.ChatPresenceMediaText
+	if msg.GetAudioMessage() != nil {
+		media = types.ChatPresenceMediaAudio
+	}
+
+	client := whatsapp.GetClient()
+	if err := client.SendChatPresence(recipient, types.ChatPresenceComposing, media); err != nil {
+		// The indicator is cosmetic, the message is still sent without it
+		logrus.Warnf("Failed to send typing presence to %s: %v", recipient.String(), err)
+		return
+	}
+	time.Sleep(typingDelay(content))
+	if err := client.SendChatPresence(recipient, types.ChatPresencePaused, media); err != nil {
+		logrus.Warnf("Failed to clear typing presence for %s: %v", recipient.String(), err)
 	}
-	return uploaded, err
+}
+
+// typingDelay estimates how long a person would need to type the content, with some jitter
+// so consecutive messages do not look scripted, clamped between a minimum and the configured maximum
+func typingDelay(content string) time.Duration {
+	const (
+		typingBaseDelay = 500 * time.Millisecond
+		typingPerChar   = 50 * time.Millisecond
+		typingMinDelay  = time.Second
+	)
+
+	delay := typingBaseDelay + time.Duration(utf8.RuneCountInString(content))*typingPerChar
+	delay += time.Duration((rand.Float64()*0.5 - 0.25) * float64(delay)) // ±25% jitter
+
+	maxDelay := config.WhatsappSimulateTypingMaxDelay
+	if maxDelay < typingMinDelay {
+		maxDelay = typingMinDelay
+	}
+
+	return min(max(delay, typingMinDelay), maxDelay)
 }
 
 func (service serviceSend) getDefaultEphemeralExpiration(jid string) (expiration uint32) {
@@ -924,3 +1111,16 @@ func (service serviceSend) getDefaultEphemeralExpiration(jid string) (expiration
 
 	return expiration
 }
+
+// quotedMessage returns a stored message as quoted by a reply. Its raw message quotes media, locations
+// and polls as they were sent, otherwise only its text is quoted. View once messages can not be quoted.
+func quotedMessage(message *domainChatStorage.Message) (*waE2E.Message, error) {
+	raw, err := utils.DecodeRawMessage(message.RawMessage)
+	if err != nil {
+		logrus.Warnf("Failed to decode raw message %s, quoting its text: %v", message.ID, err)
+	}
+	if raw != nil {
+		return utils.QuotedMessage(raw)
+	}
+	return &waE2E.Message{Conversation: proto.String(message.Content)}, nil
+}
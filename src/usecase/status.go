@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	domainStatus "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/status"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/whatsapp"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/validations"
+	"github.com/sirupsen/logrus"
+)
+
+type serviceStatus struct {
+	chatStorageRepo domainChatStorage.IChatStorageRepository
+}
+
+func NewStatusService(chatStorageRepo domainChatStorage.IChatStorageRepository) domainStatus.IStatusUsecase {
+	return &serviceStatus{
+		chatStorageRepo: chatStorageRepo,
+	}
+}
+
+func (service serviceStatus) GetFeed(ctx context.Context, request domainStatus.FeedRequest) (response domainStatus.FeedResponse, err error) {
+	if err = validations.ValidateStatusFeed(ctx, &request); err != nil {
+		return response, err
+	}
+
+	filter := &domainChatStorage.StatusFilter{
+		MediaOnly:      request.MediaOnly,
+		IncludeExpired: request.IncludeExpired,
+		Limit:          request.Limit,
+		Offset:         request.Offset,
+	}
+
+	if request.Sender != "" {
+		// Accept plain phone numbers as sender filter, statuses stored under the contact's LID match too
+		utils.SanitizePhone(&request.Sender)
+		filter.Senders = whatsapp.StatusSenderJIDs(ctx, request.Sender)
+	}
+
+	statuses, err := service.chatStorageRepo.GetStatuses(filter)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get statuses from storage")
+		return response, err
+	}
+
+	totalCount, err := service.chatStorageRepo.GetStatusCount(filter)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get status count")
+		// Continue with partial data
+		totalCount = 0
+	}
+
+	statusInfos := make([]domainStatus.StatusInfo, 0, len(statuses))
+	for _, status := range statuses {
+		statusInfos = append(statusInfos, domainStatus.StatusInfo{
+			ID:         status.ID,
+			SenderJID:  status.Sender,
+			PushName:   status.PushName,
+			Content:    status.Content,
+			MediaType:  status.MediaType,
+			MimeType:   status.MimeType,
+			Filename:   status.Filename,
+			URL:        status.URL,
+			FileLength: status.FileLength,
+			MediaPath:  status.MediaPath,
+			Viewed:     status.Viewed,
+			Timestamp:  status.Timestamp.Format(time.RFC3339),
+			ExpiresAt:  status.ExpiresAt.Format(time.RFC3339),
+		})
+	}
+
+	response.Data = statusInfos
+	response.Pagination = domainStatus.PaginationResponse{
+		Limit:  request.Limit,
+		Offset: request.Offset,
+		Total:  int(totalCount),
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"total_statuses": len(statusInfos),
+		"limit":          request.Limit,
+		"offset":         request.Offset,
+	}).Info("Retrieved status feed successfully")
+
+	return response, nil
+}
@@ -242,6 +242,19 @@ func (service serviceUser) ChangePushName(ctx context.Context, request domainUse
 	return nil
 }
 
+func (service serviceUser) SetDefaultDisappearingTimer(ctx context.Context, request domainUser.SetDefaultDisappearingTimerRequest) (err error) {
+	if err = validations.ValidateSetDefaultDisappearingTimer(ctx, request); err != nil {
+		return err
+	}
+
+	utils.MustLogin(whatsapp.GetClient())
+
+	// Already validated against the supported timers
+	timer, _ := whatsmeow.ParseDisappearingTimerString(request.Timer)
+
+	return whatsapp.GetClient().SetDefaultDisappearingTimer(timer)
+}
+
 func (service serviceUser) IsOnWhatsApp(ctx context.Context, request domainUser.CheckRequest) (response domainUser.CheckResponse, err error) {
 	utils.MustLogin(whatsapp.GetClient())
 
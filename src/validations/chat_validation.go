@@ -5,6 +5,7 @@ import (
 
 	domainChat "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chat"
 	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 )
 
@@ -36,6 +37,7 @@ func ValidateGetChatMessages(ctx context.Context, request *domainChat.GetChatMes
 		validation.Field(&request.ChatJID, validation.Required),
 		validation.Field(&request.Limit, validation.Min(1), validation.Max(100)),
 		validation.Field(&request.Offset, validation.Min(0)),
+		validation.Field(&request.Format, validation.In(utils.MessageFormatMarkdown, utils.MessageFormatHTML)),
 	)
 
 	if err != nil {
@@ -45,6 +47,33 @@ func ValidateGetChatMessages(ctx context.Context, request *domainChat.GetChatMes
 	return nil
 }
 
+// searchMediaTypes are the media_type filters of a message search, text matches messages without media
+var searchMediaTypes = []any{"text", "image", "video", "audio", "document", "sticker"}
+
+func ValidateSearchMessages(ctx context.Context, request *domainChat.SearchMessagesRequest) error {
+	// Set default limit if not provided
+	if request.Limit == 0 {
+		request.Limit = 20
+	}
+
+	err := validation.ValidateStructWithContext(ctx, request,
+		validation.Field(&request.Query, validation.Required),
+		validation.Field(&request.MediaType, validation.In(searchMediaTypes...)),
+		validation.Field(&request.Limit, validation.Min(1), validation.Max(100)),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	// Operators and punctuation alone leave nothing to match
+	if utils.BuildFullTextQuery(request.Query) == "" {
+		return pkgError.ValidationError("q: must contain a word to search.")
+	}
+
+	return nil
+}
+
 func ValidatePinChat(ctx context.Context, request *domainChat.PinChatRequest) error {
 	err := validation.ValidateStructWithContext(ctx, request,
 		validation.Field(&request.ChatJID, validation.Required),
@@ -56,3 +85,76 @@ func ValidatePinChat(ctx context.Context, request *domainChat.PinChatRequest) er
 
 	return nil
 }
+
+func ValidateArchiveChat(ctx context.Context, request *domainChat.ArchiveChatRequest) error {
+	err := validation.ValidateStructWithContext(ctx, request,
+		validation.Field(&request.ChatJID, validation.Required),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
+
+// muteDurations are the mute durations offered by WhatsApp
+var muteDurations = []any{"8h", "1w", "forever"}
+
+func ValidateMuteChat(ctx context.Context, request *domainChat.MuteChatRequest) error {
+	// Mute forever unless told otherwise
+	if request.Muted && request.Duration == "" {
+		request.Duration = "forever"
+	}
+
+	err := validation.ValidateStructWithContext(ctx, request,
+		validation.Field(&request.ChatJID, validation.Required),
+		validation.Field(&request.Duration, validation.In(muteDurations...)),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
+
+func ValidateMarkChatAsRead(ctx context.Context, request *domainChat.MarkChatAsReadRequest) error {
+	err := validation.ValidateStructWithContext(ctx, request,
+		validation.Field(&request.ChatJID, validation.Required),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
+
+func ValidateChatAction(ctx context.Context, request *domainChat.ChatActionRequest) error {
+	err := validation.ValidateStructWithContext(ctx, request,
+		validation.Field(&request.ChatJID, validation.Required),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
+
+// disappearingTimers are the disappearing message durations accepted by WhatsApp
+var disappearingTimers = []any{"off", "24h", "7d", "90d"}
+
+func ValidateSetDisappearingTimer(ctx context.Context, request *domainChat.SetDisappearingTimerRequest) error {
+	err := validation.ValidateStructWithContext(ctx, request,
+		validation.Field(&request.ChatJID, validation.Required),
+		validation.Field(&request.Timer, validation.Required, validation.In(disappearingTimers...)),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
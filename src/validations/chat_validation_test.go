@@ -86,6 +86,24 @@ func TestValidateGetChatMessages(t *testing.T) {
 			}},
 			err: nil,
 		},
+		{
+			name: "should success with html format",
+			args: args{request: domainChat.GetChatMessagesRequest{
+				ChatJID: "6289685028129@s.whatsapp.net",
+				Limit:   50,
+				Format:  "html",
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with unknown format",
+			args: args{request: domainChat.GetChatMessagesRequest{
+				ChatJID: "6289685028129@s.whatsapp.net",
+				Limit:   50,
+				Format:  "rtf",
+			}},
+			err: pkgError.ValidationError("format: must be a valid value."),
+		},
 		{
 			name: "should success with zero limit (auto set to default)",
 			args: args{request: domainChat.GetChatMessagesRequest{
@@ -141,6 +159,82 @@ func TestValidateGetChatMessages(t *testing.T) {
 	}
 }
 
+func TestValidateSearchMessages(t *testing.T) {
+	type args struct {
+		request domainChat.SearchMessagesRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success with valid request",
+			args: args{request: domainChat.SearchMessagesRequest{
+				Query: "invoice",
+				Limit: 20,
+			}},
+			err: nil,
+		},
+		{
+			name: "should success with filters",
+			args: args{request: domainChat.SearchMessagesRequest{
+				Query:     `"see you" tomor*`,
+				ChatJID:   "6289685028129@s.whatsapp.net",
+				MediaType: "text",
+				Limit:     100,
+			}},
+			err: nil,
+		},
+		{
+			name: "should success with zero limit (auto set to default)",
+			args: args{request: domainChat.SearchMessagesRequest{
+				Query: "invoice",
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with empty query",
+			args: args{request: domainChat.SearchMessagesRequest{
+				Limit: 20,
+			}},
+			err: pkgError.ValidationError("q: cannot be blank."),
+		},
+		{
+			name: "should error with query without words",
+			args: args{request: domainChat.SearchMessagesRequest{
+				Query: `* "" OR`,
+				Limit: 20,
+			}},
+			err: pkgError.ValidationError("q: must contain a word to search."),
+		},
+		{
+			name: "should error with unknown media type",
+			args: args{request: domainChat.SearchMessagesRequest{
+				Query:     "invoice",
+				MediaType: "gif",
+				Limit:     20,
+			}},
+			err: pkgError.ValidationError("media_type: must be a valid value."),
+		},
+		{
+			name: "should error with limit too high",
+			args: args{request: domainChat.SearchMessagesRequest{
+				Query: "invoice",
+				Limit: 101,
+			}},
+			err: pkgError.ValidationError("limit: must be no greater than 100."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSearchMessages(context.Background(), &tt.args.request)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
 func TestValidatePinChat(t *testing.T) {
 	type args struct {
 		request domainChat.PinChatRequest
@@ -183,3 +277,217 @@ func TestValidatePinChat(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateSetDisappearingTimer(t *testing.T) {
+	type args struct {
+		request domainChat.SetDisappearingTimerRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success with 7d timer",
+			args: args{request: domainChat.SetDisappearingTimerRequest{
+				ChatJID: "6289685028129@s.whatsapp.net",
+				Timer:   "7d",
+			}},
+			err: nil,
+		},
+		{
+			name: "should success turning timer off",
+			args: args{request: domainChat.SetDisappearingTimerRequest{
+				ChatJID: "120363024512399999@g.us",
+				Timer:   "off",
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with unsupported timer",
+			args: args{request: domainChat.SetDisappearingTimerRequest{
+				ChatJID: "6289685028129@s.whatsapp.net",
+				Timer:   "30d",
+			}},
+			err: pkgError.ValidationError("timer: must be a valid value."),
+		},
+		{
+			name: "should error with empty chat_jid",
+			args: args{request: domainChat.SetDisappearingTimerRequest{
+				Timer: "24h",
+			}},
+			err: pkgError.ValidationError("chat_jid: cannot be blank."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSetDisappearingTimer(context.Background(), &tt.args.request)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestValidateArchiveChat(t *testing.T) {
+	type args struct {
+		request domainChat.ArchiveChatRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success archiving chat",
+			args: args{request: domainChat.ArchiveChatRequest{
+				ChatJID:  "6289685028129@s.whatsapp.net",
+				Archived: true,
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with empty chat_jid",
+			args: args{request: domainChat.ArchiveChatRequest{
+				Archived: true,
+			}},
+			err: pkgError.ValidationError("chat_jid: cannot be blank."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateArchiveChat(context.Background(), &tt.args.request)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestValidateMuteChat(t *testing.T) {
+	type args struct {
+		request domainChat.MuteChatRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		err          any
+		wantDuration string
+	}{
+		{
+			name: "should success muting for 8 hours",
+			args: args{request: domainChat.MuteChatRequest{
+				ChatJID:  "6289685028129@s.whatsapp.net",
+				Muted:    true,
+				Duration: "8h",
+			}},
+			err:          nil,
+			wantDuration: "8h",
+		},
+		{
+			name: "should mute forever without duration",
+			args: args{request: domainChat.MuteChatRequest{
+				ChatJID: "120363024512399999@g.us",
+				Muted:   true,
+			}},
+			err:          nil,
+			wantDuration: "forever",
+		},
+		{
+			name: "should success unmuting",
+			args: args{request: domainChat.MuteChatRequest{
+				ChatJID: "6289685028129@s.whatsapp.net",
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with unsupported duration",
+			args: args{request: domainChat.MuteChatRequest{
+				ChatJID:  "6289685028129@s.whatsapp.net",
+				Muted:    true,
+				Duration: "3d",
+			}},
+			err:          pkgError.ValidationError("duration: must be a valid value."),
+			wantDuration: "3d",
+		},
+		{
+			name: "should error with empty chat_jid",
+			args: args{request: domainChat.MuteChatRequest{
+				Muted: true,
+			}},
+			err:          pkgError.ValidationError("chat_jid: cannot be blank."),
+			wantDuration: "forever",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMuteChat(context.Background(), &tt.args.request)
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.wantDuration, tt.args.request.Duration)
+		})
+	}
+}
+
+func TestValidateMarkChatAsRead(t *testing.T) {
+	type args struct {
+		request domainChat.MarkChatAsReadRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success marking chat unread",
+			args: args{request: domainChat.MarkChatAsReadRequest{
+				ChatJID: "6289685028129@s.whatsapp.net",
+				Read:    false,
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with empty chat_jid",
+			args: args{request: domainChat.MarkChatAsReadRequest{
+				Read: true,
+			}},
+			err: pkgError.ValidationError("chat_jid: cannot be blank."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMarkChatAsRead(context.Background(), &tt.args.request)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestValidateChatAction(t *testing.T) {
+	type args struct {
+		request domainChat.ChatActionRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success with chat_jid",
+			args: args{request: domainChat.ChatActionRequest{
+				ChatJID: "120363024512399999@g.us",
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with empty chat_jid",
+			args: args{request: domainChat.ChatActionRequest{}},
+			err:  pkgError.ValidationError("chat_jid: cannot be blank."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateChatAction(context.Background(), &tt.args.request)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
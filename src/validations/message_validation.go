@@ -3,11 +3,15 @@ package validations
 import (
 	"context"
 
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
 	domainMessage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/message"
 	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 )
 
+// maxMediaURLExpiresIn keeps signed media URLs short lived, a day at most
+const maxMediaURLExpiresIn = 24 * 60 * 60
+
 func ValidateMarkAsRead(ctx context.Context, request domainMessage.MarkAsReadRequest) error {
 	err := validation.ValidateStructWithContext(ctx, &request,
 		validation.Field(&request.MessageID, validation.Required),
@@ -102,3 +106,96 @@ func ValidateDownloadMedia(ctx context.Context, request domainMessage.DownloadMe
 
 	return nil
 }
+
+func ValidateStreamMedia(ctx context.Context, request domainMessage.StreamMediaRequest) error {
+	err := validation.ValidateStructWithContext(ctx, &request,
+		validation.Field(&request.Phone, validation.Required),
+		validation.Field(&request.MessageID, validation.Required),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
+
+func ValidateMediaURL(ctx context.Context, request domainMessage.MediaURLRequest) error {
+	err := validation.ValidateStructWithContext(ctx, &request,
+		validation.Field(&request.Phone, validation.Required),
+		validation.Field(&request.MessageID, validation.Required),
+		validation.Field(&request.ExpiresIn, validation.Min(0), validation.Max(maxMediaURLExpiresIn)),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	// The address of the request comes from its Host header, a signed URL must not point wherever the client says
+	if config.AppPublicURL == "" {
+		return pkgError.ValidationError("signed media URLs need --public-url to be set")
+	}
+
+	return nil
+}
+
+func ValidateMessageStatus(ctx context.Context, request domainMessage.MessageStatusRequest) error {
+	err := validation.ValidateStructWithContext(ctx, &request,
+		validation.Field(&request.MessageID, validation.Required),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
+
+func ValidateMessageRevisions(ctx context.Context, request domainMessage.MessageRevisionsRequest) error {
+	err := validation.ValidateStructWithContext(ctx, &request,
+		validation.Field(&request.MessageID, validation.Required),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
+
+func ValidateMessageReactions(ctx context.Context, request domainMessage.MessageReactionsRequest) error {
+	err := validation.ValidateStructWithContext(ctx, &request,
+		validation.Field(&request.MessageID, validation.Required),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
+
+func ValidateForwardMessage(ctx context.Context, request domainMessage.ForwardMessageRequest) error {
+	err := validation.ValidateStructWithContext(ctx, &request,
+		validation.Field(&request.Phone, validation.Required),
+		validation.Field(&request.MessageID, validation.Required),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
+
+func ValidateFullMessage(ctx context.Context, request domainMessage.FullMessageRequest) error {
+	err := validation.ValidateStructWithContext(ctx, &request,
+		validation.Field(&request.MessageID, validation.Required),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
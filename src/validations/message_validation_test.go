@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
 	domainMessage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/message"
 	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
 	"github.com/stretchr/testify/assert"
@@ -291,3 +292,347 @@ func TestValidateStarMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateMessageStatus(t *testing.T) {
+	type args struct {
+		request domainMessage.MessageStatusRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success with message id and phone",
+			args: args{request: domainMessage.MessageStatusRequest{
+				MessageID: "3EB0789ABC123456",
+				Phone:     "6281234567890@s.whatsapp.net",
+			}},
+			err: nil,
+		},
+		{
+			name: "should success with message id only",
+			args: args{request: domainMessage.MessageStatusRequest{
+				MessageID: "3EB0789ABC123456",
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with empty message id",
+			args: args{request: domainMessage.MessageStatusRequest{
+				Phone: "6281234567890@s.whatsapp.net",
+			}},
+			err: pkgError.ValidationError("message_id: cannot be blank."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMessageStatus(context.Background(), tt.args.request)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tt.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateMessageReactions(t *testing.T) {
+	type args struct {
+		request domainMessage.MessageReactionsRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success with message id and phone",
+			args: args{request: domainMessage.MessageReactionsRequest{
+				MessageID: "3EB0789ABC123456",
+				Phone:     "120363025246125486@g.us",
+			}},
+			err: nil,
+		},
+		{
+			name: "should success with message id only",
+			args: args{request: domainMessage.MessageReactionsRequest{
+				MessageID: "3EB0789ABC123456",
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with empty message id",
+			args: args{request: domainMessage.MessageReactionsRequest{
+				Phone: "120363025246125486@g.us",
+			}},
+			err: pkgError.ValidationError("message_id: cannot be blank."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMessageReactions(context.Background(), tt.args.request)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tt.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateMessageRevisions(t *testing.T) {
+	type args struct {
+		request domainMessage.MessageRevisionsRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success with message id and phone",
+			args: args{request: domainMessage.MessageRevisionsRequest{
+				MessageID: "3EB0789ABC123456",
+				Phone:     "6281234567890@s.whatsapp.net",
+			}},
+			err: nil,
+		},
+		{
+			name: "should success with message id only",
+			args: args{request: domainMessage.MessageRevisionsRequest{
+				MessageID: "3EB0789ABC123456",
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with empty message id",
+			args: args{request: domainMessage.MessageRevisionsRequest{
+				Phone: "6281234567890@s.whatsapp.net",
+			}},
+			err: pkgError.ValidationError("message_id: cannot be blank."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMessageRevisions(context.Background(), tt.args.request)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tt.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateStreamMedia(t *testing.T) {
+	type args struct {
+		request domainMessage.StreamMediaRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success with message id and phone",
+			args: args{request: domainMessage.StreamMediaRequest{
+				MessageID: "3EB0789ABC123456",
+				Phone:     "6281234567890@s.whatsapp.net",
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with empty phone",
+			args: args{request: domainMessage.StreamMediaRequest{
+				MessageID: "3EB0789ABC123456",
+			}},
+			err: pkgError.ValidationError("phone: cannot be blank."),
+		},
+		{
+			name: "should error with empty message id",
+			args: args{request: domainMessage.StreamMediaRequest{
+				Phone: "6281234567890@s.whatsapp.net",
+			}},
+			err: pkgError.ValidationError("message_id: cannot be blank."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStreamMedia(context.Background(), tt.args.request)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tt.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateMediaURL(t *testing.T) {
+	type args struct {
+		request domainMessage.MediaURLRequest
+	}
+	tests := []struct {
+		name             string
+		args             args
+		withoutPublicURL bool
+		err              any
+	}{
+		{
+			name: "should success with default expiry",
+			args: args{request: domainMessage.MediaURLRequest{
+				MessageID: "3EB0789ABC123456",
+				Phone:     "6281234567890@s.whatsapp.net",
+			}},
+			err: nil,
+		},
+		{
+			name: "should success with custom expiry",
+			args: args{request: domainMessage.MediaURLRequest{
+				MessageID: "3EB0789ABC123456",
+				Phone:     "6281234567890@s.whatsapp.net",
+				ExpiresIn: 3600,
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with negative expiry",
+			args: args{request: domainMessage.MediaURLRequest{
+				MessageID: "3EB0789ABC123456",
+				Phone:     "6281234567890@s.whatsapp.net",
+				ExpiresIn: -1,
+			}},
+			err: pkgError.ValidationError("expires_in: must be no less than 0."),
+		},
+		{
+			name: "should error with expiry over a day",
+			args: args{request: domainMessage.MediaURLRequest{
+				MessageID: "3EB0789ABC123456",
+				Phone:     "6281234567890@s.whatsapp.net",
+				ExpiresIn: 86401,
+			}},
+			err: pkgError.ValidationError("expires_in: must be no greater than 86400."),
+		},
+		{
+			name: "should error with empty phone",
+			args: args{request: domainMessage.MediaURLRequest{
+				MessageID: "3EB0789ABC123456",
+			}},
+			err: pkgError.ValidationError("phone: cannot be blank."),
+		},
+		{
+			name: "should error without a public url",
+			args: args{request: domainMessage.MediaURLRequest{
+				MessageID: "3EB0789ABC123456",
+				Phone:     "6281234567890@s.whatsapp.net",
+			}},
+			withoutPublicURL: true,
+			err:              pkgError.ValidationError("signed media URLs need --public-url to be set"),
+		},
+	}
+
+	origPublicURL := config.AppPublicURL
+	defer func() { config.AppPublicURL = origPublicURL }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.AppPublicURL = "https://wa.example.com"
+			if tt.withoutPublicURL {
+				config.AppPublicURL = ""
+			}
+			err := ValidateMediaURL(context.Background(), tt.args.request)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tt.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateForwardMessage(t *testing.T) {
+	type args struct {
+		request domainMessage.ForwardMessageRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success with message id and phone",
+			args: args{request: domainMessage.ForwardMessageRequest{
+				MessageID: "3EB0789ABC123456",
+				Phone:     "6281234567890@s.whatsapp.net",
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with empty phone",
+			args: args{request: domainMessage.ForwardMessageRequest{
+				MessageID: "3EB0789ABC123456",
+			}},
+			err: pkgError.ValidationError("phone: cannot be blank."),
+		},
+		{
+			name: "should error with empty message id",
+			args: args{request: domainMessage.ForwardMessageRequest{
+				Phone: "6281234567890@s.whatsapp.net",
+			}},
+			err: pkgError.ValidationError("message_id: cannot be blank."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateForwardMessage(context.Background(), tt.args.request)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tt.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateFullMessage(t *testing.T) {
+	type args struct {
+		request domainMessage.FullMessageRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success with message id only",
+			args: args{request: domainMessage.FullMessageRequest{
+				MessageID: "3EB0789ABC123456",
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with empty message id",
+			args: args{request: domainMessage.FullMessageRequest{
+				Phone: "6281234567890@s.whatsapp.net",
+			}},
+			err: pkgError.ValidationError("message_id: cannot be blank."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFullMessage(context.Background(), tt.args.request)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tt.err, err)
+			}
+		})
+	}
+}
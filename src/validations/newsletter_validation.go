@@ -2,9 +2,16 @@ package validations
 
 import (
 	"context"
+	"fmt"
+	"mime/multipart"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
 	domainNewsletter "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/newsletter"
 	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/dustin/go-humanize"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
 )
 
 func ValidateUnfollowNewsletter(ctx context.Context, request domainNewsletter.UnfollowRequest) error {
@@ -18,3 +25,237 @@ func ValidateUnfollowNewsletter(ctx context.Context, request domainNewsletter.Un
 
 	return nil
 }
+
+func ValidateSendNewsletterMessage(ctx context.Context, request domainNewsletter.SendMessageRequest) error {
+	err := validation.ValidateStructWithContext(ctx, &request,
+		validation.Field(&request.NewsletterID, validation.Required),
+		validation.Field(&request.Type, validation.Required, validation.In(
+			domainNewsletter.SendMessageTypeText,
+			domainNewsletter.SendMessageTypeImage,
+			domainNewsletter.SendMessageTypeVideo,
+			domainNewsletter.SendMessageTypePoll,
+		)),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	switch request.Type {
+	case domainNewsletter.SendMessageTypeText:
+		if request.Message == "" {
+			return pkgError.ValidationError("message: cannot be blank.")
+		}
+	case domainNewsletter.SendMessageTypeImage:
+		if request.Image == nil && (request.ImageURL == nil || *request.ImageURL == "") {
+			return pkgError.ValidationError("either Image or ImageURL must be provided")
+		}
+		if request.Image != nil {
+			availableMimes := map[string]bool{
+				"image/jpeg": true,
+				"image/jpg":  true,
+				"image/png":  true,
+			}
+			if !availableMimes[request.Image.Header.Get("Content-Type")] {
+				return pkgError.ValidationError("your image is not allowed. please use jpg/jpeg/png")
+			}
+		}
+		if request.ImageURL != nil && *request.ImageURL != "" {
+			if err := validation.Validate(*request.ImageURL, is.URL); err != nil {
+				return pkgError.ValidationError("ImageURL must be a valid URL")
+			}
+		}
+	case domainNewsletter.SendMessageTypeVideo:
+		if request.Video == nil && (request.VideoURL == nil || *request.VideoURL == "") {
+			return pkgError.ValidationError("either Video or VideoURL must be provided")
+		}
+		if request.Video != nil {
+			if request.Video.Header.Get("Content-Type") != "video/mp4" {
+				return pkgError.ValidationError("your video type is not allowed. please use mp4")
+			}
+			if request.Video.Size > config.WhatsappSettingMaxVideoSize {
+				maxSizeString := humanize.Bytes(uint64(config.WhatsappSettingMaxVideoSize))
+				return pkgError.ValidationError(fmt.Sprintf("max video upload is %s", maxSizeString))
+			}
+		}
+		if request.VideoURL != nil && *request.VideoURL != "" {
+			if err := validation.Validate(*request.VideoURL, is.URL); err != nil {
+				return pkgError.ValidationError("VideoURL must be a valid URL")
+			}
+		}
+	case domainNewsletter.SendMessageTypePoll:
+		if len(request.Options) == 0 {
+			return pkgError.ValidationError("options: cannot be blank.")
+		}
+
+		err := validation.ValidateStructWithContext(ctx, &request,
+			validation.Field(&request.Question, validation.Required),
+			validation.Field(&request.Options, validation.Each(validation.Required)),
+			validation.Field(&request.MaxAnswer, validation.Required, validation.Min(1), validation.Max(len(request.Options))),
+		)
+		if err != nil {
+			return pkgError.ValidationError(err.Error())
+		}
+
+		// validate options should be unique each other
+		uniqueOptions := make(map[string]bool)
+		for _, option := range request.Options {
+			if _, ok := uniqueOptions[option]; ok {
+				return pkgError.ValidationError("options should be unique")
+			}
+			uniqueOptions[option] = true
+		}
+	}
+
+	return nil
+}
+
+func ValidateListNewsletterMessages(ctx context.Context, request *domainNewsletter.ListMessagesRequest) error {
+	// Set default count if not provided
+	if request.Count == 0 {
+		request.Count = 25
+	}
+
+	err := validation.ValidateStructWithContext(ctx, request,
+		validation.Field(&request.NewsletterID, validation.Required),
+		validation.Field(&request.Count, validation.Min(1), validation.Max(100)),
+		validation.Field(&request.Before, validation.Min(0)),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
+
+func ValidateCreateNewsletter(ctx context.Context, request domainNewsletter.CreateRequest) error {
+	err := validation.ValidateStructWithContext(ctx, &request,
+		validation.Field(&request.Name, validation.Required, validation.Length(1, 100)),
+		validation.Field(&request.Description, validation.Length(0, 2048)),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return validateNewsletterPicture(request.Picture)
+}
+
+func ValidateUpdateNewsletter(ctx context.Context, request domainNewsletter.UpdateRequest) error {
+	err := validation.ValidateStructWithContext(ctx, &request,
+		validation.Field(&request.NewsletterID, validation.Required),
+		validation.Field(&request.Name, validation.Length(1, 100)),
+		validation.Field(&request.Description, validation.Length(0, 2048)),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	if request.Name == "" && request.Description == "" && request.Picture == nil {
+		return pkgError.ValidationError("at least one of name, description or picture must be provided")
+	}
+
+	return validateNewsletterPicture(request.Picture)
+}
+
+func ValidateFollowNewsletter(ctx context.Context, request domainNewsletter.FollowRequest) error {
+	if request.NewsletterID == "" && request.InviteLink == "" {
+		return pkgError.ValidationError("either newsletter_id or invite_link must be provided")
+	}
+	if request.NewsletterID != "" && request.InviteLink != "" {
+		return pkgError.ValidationError("provide either newsletter_id or invite_link, not both")
+	}
+
+	return nil
+}
+
+func ValidateMuteNewsletter(ctx context.Context, request domainNewsletter.MuteRequest) error {
+	err := validation.ValidateStructWithContext(ctx, &request,
+		validation.Field(&request.NewsletterID, validation.Required),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
+
+func ValidateNewsletterInfoFromInvite(ctx context.Context, request domainNewsletter.InfoFromInviteRequest) error {
+	err := validation.ValidateStructWithContext(ctx, &request,
+		validation.Field(&request.InviteLink, validation.Required),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
+
+func ValidateListNewsletterUpdates(ctx context.Context, request *domainNewsletter.ListUpdatesRequest) error {
+	// Set default count if not provided
+	if request.Count == 0 {
+		request.Count = 25
+	}
+
+	err := validation.ValidateStructWithContext(ctx, request,
+		validation.Field(&request.NewsletterID, validation.Required),
+		validation.Field(&request.Count, validation.Min(1), validation.Max(100)),
+		validation.Field(&request.After, validation.Min(0)),
+		validation.Field(&request.Since, validation.Date(time.RFC3339)),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
+
+func ValidateReactNewsletterMessage(ctx context.Context, request domainNewsletter.ReactRequest) error {
+	err := validation.ValidateStructWithContext(ctx, &request,
+		validation.Field(&request.NewsletterID, validation.Required),
+		validation.Field(&request.ServerID, validation.Required, validation.Min(1)),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
+
+func ValidateMarkNewsletterViewed(ctx context.Context, request domainNewsletter.MarkViewedRequest) error {
+	err := validation.ValidateStructWithContext(ctx, &request,
+		validation.Field(&request.NewsletterID, validation.Required),
+		validation.Field(&request.ServerIDs, validation.Required, validation.Length(1, 100), validation.Each(validation.Min(1))),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
+
+// validateNewsletterPicture checks the optional picture of a newsletter is a supported image
+func validateNewsletterPicture(picture *multipart.FileHeader) error {
+	if picture == nil {
+		return nil
+	}
+
+	availableMimes := map[string]bool{
+		"image/jpeg": true,
+		"image/jpg":  true,
+		"image/png":  true,
+		"image/webp": true,
+	}
+	if contentType := picture.Header.Get("Content-Type"); contentType != "" && !availableMimes[contentType] {
+		return pkgError.ValidationError("your picture is not allowed. please use jpg/jpeg/png/webp")
+	}
+
+	return nil
+}
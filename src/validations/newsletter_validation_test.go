@@ -2,6 +2,8 @@ package validations
 
 import (
 	"context"
+	"mime/multipart"
+	"net/textproto"
 	"testing"
 
 	domainNewsletter "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/newsletter"
@@ -48,3 +50,400 @@ func TestValidateUnfollowNewsletter(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateSendNewsletterMessage(t *testing.T) {
+	imageURL := "https://example.com/image.jpg"
+	invalidURL := "not-a-url"
+
+	type args struct {
+		request domainNewsletter.SendMessageRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success with text message",
+			args: args{request: domainNewsletter.SendMessageRequest{
+				NewsletterID: "120363123456789@newsletter",
+				Type:         domainNewsletter.SendMessageTypeText,
+				Message:      "Hello subscribers",
+			}},
+			err: nil,
+		},
+		{
+			name: "should success with image url",
+			args: args{request: domainNewsletter.SendMessageRequest{
+				NewsletterID: "120363123456789@newsletter",
+				Type:         domainNewsletter.SendMessageTypeImage,
+				ImageURL:     &imageURL,
+			}},
+			err: nil,
+		},
+		{
+			name: "should success with poll",
+			args: args{request: domainNewsletter.SendMessageRequest{
+				NewsletterID: "120363123456789@newsletter",
+				Type:         domainNewsletter.SendMessageTypePoll,
+				Question:     "Which product next?",
+				Options:      []string{"A", "B"},
+				MaxAnswer:    1,
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with empty newsletter id",
+			args: args{request: domainNewsletter.SendMessageRequest{
+				Type:    domainNewsletter.SendMessageTypeText,
+				Message: "Hello",
+			}},
+			err: pkgError.ValidationError("newsletter_id: cannot be blank."),
+		},
+		{
+			name: "should error with unknown type",
+			args: args{request: domainNewsletter.SendMessageRequest{
+				NewsletterID: "120363123456789@newsletter",
+				Type:         "sticker",
+			}},
+			err: pkgError.ValidationError("type: must be a valid value."),
+		},
+		{
+			name: "should error with empty text message",
+			args: args{request: domainNewsletter.SendMessageRequest{
+				NewsletterID: "120363123456789@newsletter",
+				Type:         domainNewsletter.SendMessageTypeText,
+			}},
+			err: pkgError.ValidationError("message: cannot be blank."),
+		},
+		{
+			name: "should error with image without source",
+			args: args{request: domainNewsletter.SendMessageRequest{
+				NewsletterID: "120363123456789@newsletter",
+				Type:         domainNewsletter.SendMessageTypeImage,
+			}},
+			err: pkgError.ValidationError("either Image or ImageURL must be provided"),
+		},
+		{
+			name: "should error with invalid video url",
+			args: args{request: domainNewsletter.SendMessageRequest{
+				NewsletterID: "120363123456789@newsletter",
+				Type:         domainNewsletter.SendMessageTypeVideo,
+				VideoURL:     &invalidURL,
+			}},
+			err: pkgError.ValidationError("VideoURL must be a valid URL"),
+		},
+		{
+			name: "should error with duplicate poll options",
+			args: args{request: domainNewsletter.SendMessageRequest{
+				NewsletterID: "120363123456789@newsletter",
+				Type:         domainNewsletter.SendMessageTypePoll,
+				Question:     "Which product next?",
+				Options:      []string{"A", "A"},
+				MaxAnswer:    1,
+			}},
+			err: pkgError.ValidationError("options should be unique"),
+		},
+		{
+			name: "should error with max answer above options",
+			args: args{request: domainNewsletter.SendMessageRequest{
+				NewsletterID: "120363123456789@newsletter",
+				Type:         domainNewsletter.SendMessageTypePoll,
+				Question:     "Which product next?",
+				Options:      []string{"A", "B"},
+				MaxAnswer:    3,
+			}},
+			err: pkgError.ValidationError("max_answer: must be no greater than 2."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSendNewsletterMessage(context.Background(), tt.args.request)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestValidateListNewsletterMessages(t *testing.T) {
+	type args struct {
+		request domainNewsletter.ListMessagesRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success with default count",
+			args: args{request: domainNewsletter.ListMessagesRequest{
+				NewsletterID: "120363123456789@newsletter",
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with count too high",
+			args: args{request: domainNewsletter.ListMessagesRequest{
+				NewsletterID: "120363123456789@newsletter",
+				Count:        101,
+			}},
+			err: pkgError.ValidationError("count: must be no greater than 100."),
+		},
+		{
+			name: "should error with empty newsletter id",
+			args: args{request: domainNewsletter.ListMessagesRequest{}},
+			err:  pkgError.ValidationError("newsletter_id: cannot be blank."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateListNewsletterMessages(context.Background(), &tt.args.request)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestValidateCreateNewsletter(t *testing.T) {
+	type args struct {
+		request domainNewsletter.CreateRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success with name and description",
+			args: args{request: domainNewsletter.CreateRequest{
+				Name:        "Product Updates",
+				Description: "Release notes and announcements",
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with empty name",
+			args: args{request: domainNewsletter.CreateRequest{
+				Description: "Release notes and announcements",
+			}},
+			err: pkgError.ValidationError("name: cannot be blank."),
+		},
+		{
+			name: "should error with unsupported picture",
+			args: args{request: domainNewsletter.CreateRequest{
+				Name: "Product Updates",
+				Picture: &multipart.FileHeader{
+					Header: textproto.MIMEHeader{"Content-Type": []string{"image/gif"}},
+				},
+			}},
+			err: pkgError.ValidationError("your picture is not allowed. please use jpg/jpeg/png/webp"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCreateNewsletter(context.Background(), tt.args.request)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestValidateUpdateNewsletter(t *testing.T) {
+	type args struct {
+		request domainNewsletter.UpdateRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success updating only the description",
+			args: args{request: domainNewsletter.UpdateRequest{
+				NewsletterID: "120363123456789@newsletter",
+				Description:  "New description",
+			}},
+			err: nil,
+		},
+		{
+			name: "should error without any update",
+			args: args{request: domainNewsletter.UpdateRequest{
+				NewsletterID: "120363123456789@newsletter",
+			}},
+			err: pkgError.ValidationError("at least one of name, description or picture must be provided"),
+		},
+		{
+			name: "should error with empty newsletter id",
+			args: args{request: domainNewsletter.UpdateRequest{
+				Name: "Product Updates",
+			}},
+			err: pkgError.ValidationError("newsletter_id: cannot be blank."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateUpdateNewsletter(context.Background(), tt.args.request)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestValidateFollowNewsletter(t *testing.T) {
+	type args struct {
+		request domainNewsletter.FollowRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success with newsletter id",
+			args: args{request: domainNewsletter.FollowRequest{
+				NewsletterID: "120363123456789@newsletter",
+			}},
+			err: nil,
+		},
+		{
+			name: "should success with invite link",
+			args: args{request: domainNewsletter.FollowRequest{
+				InviteLink: "https://whatsapp.com/channel/0029VaABCDEF",
+			}},
+			err: nil,
+		},
+		{
+			name: "should error without newsletter id and invite link",
+			args: args{request: domainNewsletter.FollowRequest{}},
+			err:  pkgError.ValidationError("either newsletter_id or invite_link must be provided"),
+		},
+		{
+			name: "should error with both newsletter id and invite link",
+			args: args{request: domainNewsletter.FollowRequest{
+				NewsletterID: "120363123456789@newsletter",
+				InviteLink:   "https://whatsapp.com/channel/0029VaABCDEF",
+			}},
+			err: pkgError.ValidationError("provide either newsletter_id or invite_link, not both"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFollowNewsletter(context.Background(), tt.args.request)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestValidateListNewsletterUpdates(t *testing.T) {
+	type args struct {
+		request domainNewsletter.ListUpdatesRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success with since timestamp",
+			args: args{request: domainNewsletter.ListUpdatesRequest{
+				NewsletterID: "120363123456789@newsletter",
+				Since:        "2025-01-02T15:04:05Z",
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with invalid since timestamp",
+			args: args{request: domainNewsletter.ListUpdatesRequest{
+				NewsletterID: "120363123456789@newsletter",
+				Since:        "yesterday",
+			}},
+			err: pkgError.ValidationError("since: must be a valid date."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateListNewsletterUpdates(context.Background(), &tt.args.request)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestValidateReactNewsletterMessage(t *testing.T) {
+	type args struct {
+		request domainNewsletter.ReactRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success with reaction",
+			args: args{request: domainNewsletter.ReactRequest{
+				NewsletterID: "120363123456789@newsletter",
+				ServerID:     150,
+				Reaction:     "👍",
+			}},
+			err: nil,
+		},
+		{
+			name: "should success removing reaction",
+			args: args{request: domainNewsletter.ReactRequest{
+				NewsletterID: "120363123456789@newsletter",
+				ServerID:     150,
+			}},
+			err: nil,
+		},
+		{
+			name: "should error without server id",
+			args: args{request: domainNewsletter.ReactRequest{
+				NewsletterID: "120363123456789@newsletter",
+				Reaction:     "👍",
+			}},
+			err: pkgError.ValidationError("server_id: cannot be blank."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateReactNewsletterMessage(context.Background(), tt.args.request)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestValidateMarkNewsletterViewed(t *testing.T) {
+	type args struct {
+		request domainNewsletter.MarkViewedRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success with server ids",
+			args: args{request: domainNewsletter.MarkViewedRequest{
+				NewsletterID: "120363123456789@newsletter",
+				ServerIDs:    []int{150, 151},
+			}},
+			err: nil,
+		},
+		{
+			name: "should error without server ids",
+			args: args{request: domainNewsletter.MarkViewedRequest{
+				NewsletterID: "120363123456789@newsletter",
+			}},
+			err: pkgError.ValidationError("server_ids: cannot be blank."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMarkNewsletterViewed(context.Background(), tt.args.request)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
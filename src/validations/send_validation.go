@@ -8,6 +8,7 @@ import (
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
 	domainSend "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/send"
 	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
 	"github.com/dustin/go-humanize"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/go-ozzo/ozzo-validation/v4/is"
@@ -53,6 +54,7 @@ func ValidateSendMessage(ctx context.Context, request domainSend.MessageRequest)
 	err := validation.ValidateStructWithContext(ctx, &request,
 		validation.Field(&request.Phone, validation.Required),
 		validation.Field(&request.Message, validation.Required),
+		validation.Field(&request.Format, validation.In(utils.MessageFormatMarkdown)),
 	)
 
 	if err != nil {
@@ -74,6 +76,7 @@ func ValidateSendMessage(ctx context.Context, request domainSend.MessageRequest)
 func ValidateSendImage(ctx context.Context, request domainSend.ImageRequest) error {
 	err := validation.ValidateStructWithContext(ctx, &request,
 		validation.Field(&request.Phone, validation.Required),
+		validation.Field(&request.Format, validation.In(utils.MessageFormatMarkdown)),
 	)
 
 	if err != nil {
@@ -124,6 +127,7 @@ func ValidateSendFile(ctx context.Context, request domainSend.FileRequest) error
 	err := validation.ValidateStructWithContext(ctx, &request,
 		validation.Field(&request.Phone, validation.Required),
 		validation.Field(&request.File, validation.Required),
+		validation.Field(&request.Format, validation.In(utils.MessageFormatMarkdown)),
 	)
 
 	if err != nil {
@@ -151,6 +155,7 @@ func ValidateSendVideo(ctx context.Context, request domainSend.VideoRequest) err
 	// Validate common required fields
 	err := validation.ValidateStructWithContext(ctx, &request,
 		validation.Field(&request.Phone, validation.Required),
+		validation.Field(&request.Format, validation.In(utils.MessageFormatMarkdown)),
 	)
 
 	if err != nil {
@@ -176,7 +181,15 @@ func ValidateSendVideo(ctx context.Context, request domainSend.VideoRequest) err
 			"video/x-msvideo":  true,
 		}
 
+		// GIF files are only accepted when they are sent as GIF playback
+		if request.GifPlayback {
+			availableMimes["image/gif"] = true
+		}
+
 		if !availableMimes[request.Video.Header.Get("Content-Type")] {
+			if request.GifPlayback {
+				return pkgError.ValidationError("your video type is not allowed. please use gif/mp4/mkv/avi/x-msvideo")
+			}
 			return pkgError.ValidationError("your video type is not allowed. please use mp4/mkv/avi/x-msvideo")
 		}
 
@@ -237,6 +250,7 @@ func ValidateSendLink(ctx context.Context, request domainSend.LinkRequest) error
 		validation.Field(&request.Phone, validation.Required),
 		validation.Field(&request.Link, validation.Required, is.URL),
 		validation.Field(&request.Caption, validation.Required),
+		validation.Field(&request.Format, validation.In(utils.MessageFormatMarkdown)),
 	)
 
 	if err != nil {
@@ -50,6 +50,28 @@ func TestValidateSendMessage(t *testing.T) {
 			}},
 			err: pkgError.ValidationError("message: cannot be blank."),
 		},
+		{
+			name: "should success with markdown format",
+			args: args{request: domainSend.MessageRequest{
+				BaseRequest: domainSend.BaseRequest{
+					Phone: "1728937129312@s.whatsapp.net",
+				},
+				Message: "**Hello** this is testing",
+				Format:  "markdown",
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with unknown format",
+			args: args{request: domainSend.MessageRequest{
+				BaseRequest: domainSend.BaseRequest{
+					Phone: "1728937129312@s.whatsapp.net",
+				},
+				Message: "<b>Hello</b>",
+				Format:  "html",
+			}},
+			err: pkgError.ValidationError("format: must be a valid value."),
+		},
 	}
 
 	for _, tt := range tests {
@@ -286,6 +308,39 @@ func TestValidateSendVideo(t *testing.T) {
 			}},
 			err: nil,
 		},
+		{
+			name: "should success with gif sent as gif playback",
+			args: args{request: domainSend.VideoRequest{
+				BaseRequest: domainSend.BaseRequest{
+					Phone: "1728937129312@s.whatsapp.net",
+				},
+				Video: func() *multipart.FileHeader {
+					return &multipart.FileHeader{
+						Filename: "sample.gif",
+						Size:     100,
+						Header:   map[string][]string{"Content-Type": {"image/gif"}},
+					}
+				}(),
+				GifPlayback: true,
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with gif sent as regular video",
+			args: args{request: domainSend.VideoRequest{
+				BaseRequest: domainSend.BaseRequest{
+					Phone: "1728937129312@s.whatsapp.net",
+				},
+				Video: func() *multipart.FileHeader {
+					return &multipart.FileHeader{
+						Filename: "sample.gif",
+						Size:     100,
+						Header:   map[string][]string{"Content-Type": {"image/gif"}},
+					}
+				}(),
+			}},
+			err: pkgError.ValidationError("your video type is not allowed. please use mp4/mkv/avi/x-msvideo"),
+		},
 	}
 
 	for _, tt := range tests {
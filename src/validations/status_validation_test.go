@@ -0,0 +1,59 @@
+package validations
+
+import (
+	"context"
+	"testing"
+
+	domainStatus "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/status"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateStatusFeed(t *testing.T) {
+	type args struct {
+		request domainStatus.FeedRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success with valid request",
+			args: args{request: domainStatus.FeedRequest{
+				Limit:  25,
+				Offset: 0,
+			}},
+			err: nil,
+		},
+		{
+			name: "should success with zero limit (auto set to default)",
+			args: args{request: domainStatus.FeedRequest{
+				Sender: "6289685028129@s.whatsapp.net",
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with limit too high",
+			args: args{request: domainStatus.FeedRequest{
+				Limit: 101,
+			}},
+			err: pkgError.ValidationError("limit: must be no greater than 100."),
+		},
+		{
+			name: "should error with negative offset",
+			args: args{request: domainStatus.FeedRequest{
+				Limit:  25,
+				Offset: -1,
+			}},
+			err: pkgError.ValidationError("offset: must be no less than 0."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStatusFeed(context.Background(), &tt.args.request)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
@@ -44,3 +44,15 @@ func ValidateBusinessProfile(ctx context.Context, request domainUser.BusinessPro
 
 	return nil
 }
+
+func ValidateSetDefaultDisappearingTimer(ctx context.Context, request domainUser.SetDefaultDisappearingTimerRequest) error {
+	err := validation.ValidateStructWithContext(ctx, &request,
+		validation.Field(&request.Timer, validation.Required, validation.In(disappearingTimers...)),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
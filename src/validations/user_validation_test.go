@@ -110,3 +110,41 @@ func TestValidateBusinessProfile(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateSetDefaultDisappearingTimer(t *testing.T) {
+	type args struct {
+		request domainUser.SetDefaultDisappearingTimerRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success with 90d timer",
+			args: args{request: domainUser.SetDefaultDisappearingTimerRequest{
+				Timer: "90d",
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with empty timer",
+			args: args{request: domainUser.SetDefaultDisappearingTimerRequest{}},
+			err:  pkgError.ValidationError("timer: cannot be blank."),
+		},
+		{
+			name: "should error with unsupported timer",
+			args: args{request: domainUser.SetDefaultDisappearingTimerRequest{
+				Timer: "1h",
+			}},
+			err: pkgError.ValidationError("timer: must be a valid value."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSetDefaultDisappearingTimer(context.Background(), tt.args.request)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}